@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr/pkg/action"
 
 	"github.com/plasmash/plasmactl-model/internal/compose"
@@ -15,6 +16,8 @@ type AddResult struct {
 	Type    string `json:"type,omitempty"`
 	Ref     string `json:"ref,omitempty"`
 	URL     string `json:"url,omitempty"`
+	Subpath string `json:"subpath,omitempty"`
+	Asset   string `json:"asset,omitempty"`
 }
 
 // Add implements the model:add action
@@ -22,12 +25,15 @@ type Add struct {
 	action.WithLogger
 	action.WithTerm
 
+	Keyring      keyring.Keyring
 	WorkingDir   string
 	AllowCreate  bool
 	Package      string
 	Type         string
 	Ref          string
 	URL          string
+	Subpath      string
+	Asset        string
 	Strategy     []string
 	StrategyPath []string
 
@@ -55,9 +61,11 @@ func (a *Add) Execute() error {
 	dependency := &compose.Dependency{
 		Name: a.Package,
 		Source: compose.Source{
-			Type: a.Type,
-			Ref:  ref,
-			URL:  a.URL,
+			Type:    a.Type,
+			Ref:     ref,
+			URL:     a.URL,
+			Subpath: a.Subpath,
+			Asset:   a.Asset,
 		},
 	}
 
@@ -66,7 +74,13 @@ func (a *Add) Execute() error {
 		Paths: a.StrategyPath,
 	}
 
-	fa := &compose.FormsAction{}
+	unlock, err := compose.LockModel(a.WorkingDir, "model:add", false, a.Term(), a.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fa := &compose.FormsAction{Keyring: a.Keyring}
 	fa.SetLogger(a.Log())
 	fa.SetTerm(a.Term())
 
@@ -79,12 +93,18 @@ func (a *Add) Execute() error {
 		Type:    a.Type,
 		Ref:     ref,
 		URL:     a.URL,
+		Subpath: dependency.Source.Subpath,
+		Asset:   dependency.Source.Asset,
 	}
 	return nil
 }
 
 // validate validates input options
 func (a *Add) validate() error {
+	if a.Type == compose.ReleaseType && (a.Ref == "" || a.Asset == "") {
+		return errors.New("release packages require --ref of the release tag and --asset")
+	}
+
 	if len(a.Strategy) > 0 || len(a.StrategyPath) > 0 {
 		if len(a.Strategy) != len(a.StrategyPath) {
 			return errors.New("number of strategies and paths must be equal")
@@ -95,6 +115,8 @@ func (a *Add) validate() error {
 			compose.StrategyRemoveExtraLocal:   true,
 			compose.StrategyIgnoreExtraPackage: true,
 			compose.StrategyFilterPackage:      true,
+			compose.StrategyPreferNewest:       true,
+			compose.StrategyMergeYAML:          true,
 		}
 
 		for _, strategy := range a.Strategy {
@@ -2,33 +2,69 @@ package bundle
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/launchrctl/launchr/pkg/action"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/fsutil"
+	irelease "github.com/plasmash/plasmactl-model/internal/release"
 	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
+// ManifestFile is the path of the manifest inside of the bundle.
+const ManifestFile = model.BundleManifestFile
+
+// ChecksumsFile is the path of SHA-256 checksums of bundled files inside of the bundle.
+const ChecksumsFile = model.BundleChecksumsFile
+
 // BundleResult is the structured result of model:bundle.
 type BundleResult struct {
-	BundlePath string `json:"bundle_path"`
-	RepoName   string `json:"repo_name"`
-	Version    string `json:"version"`
+	BundlePath  string          `json:"bundle_path"`
+	RepoName    string          `json:"repo_name"`
+	Version     string          `json:"version"`
+	Commit      string          `json:"commit"`
+	Composition *model.Metadata `json:"composition,omitempty"`
+	// SHA256 is the digest of the bundle, reproducible bundles of the same content have the same digest.
+	SHA256 string `json:"sha256"`
+	// Signature is the detached signature written next to the bundle by --sign.
+	Signature string `json:"signature,omitempty"`
+	// Attestation is the in-toto statement written next to the bundle by --attest, signed like the bundle.
+	Attestation string `json:"attestation,omitempty"`
 }
 
+// Manifest describes the bundled composition and its provenance.
+type Manifest = model.BundleManifest
+
 // Bundle implements the model:bundle command
 type Bundle struct {
 	action.WithLogger
 	action.WithTerm
 
+	// Layout locates the composed and prepared model and the bundle directory, empty paths are defaults.
+	Layout           model.Layout
 	HasPrepareAction bool
+	// SourceDir overrides the bundled directory, the prepare or merged directory of Layout otherwise.
+	SourceDir    string
+	Sign         string
+	Key          string
+	Attest       bool
+	Reproducible bool
 
 	result *BundleResult
 }
@@ -40,105 +76,318 @@ func (b *Bundle) Result() any {
 
 // Execute runs the model:bundle action
 func (b *Bundle) Execute() error {
+	if err := irelease.ValidateSignMethod(b.Sign); err != nil {
+		return err
+	}
+	if b.Attest && b.Sign == "" {
+		return errors.New("--attest requires --sign, attestations are signed like the bundle")
+	}
+
+	// Model directories are relative to the working directory
+	unlock, err := compose.LockModel(".", "model:bundle", false, b.Term(), b.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Determine source directory, the prepared model unless overridden by --source-dir
+	layout := b.Layout.WithDefaults()
+	srcDir, reportDir, err := b.sourceDir(layout)
+	if err != nil {
+		return err
+	}
+
 	// Get repository information
-	repoName, version, err := getRepoInfo()
+	repo, err := getRepoInfo()
 	if err != nil {
 		b.Log().Error("error", "error", err)
 		return fmt.Errorf("error getting repository information: %w", err)
 	}
 
 	// Construct bundle file name: {name}-{version}.pm
-	bundleFile := fmt.Sprintf("%s-%s.pm", repoName, version)
+	bundleFile := fmt.Sprintf("%s-%s.pm", repo.name, repo.version)
 
-	// Determine source directory based on prepare action availability
-	prepareDir := model.PrepareDir
-	composeDir := model.MergedDir
-	var srcDir string
+	// Output to bundle/ - visible to users as final distributable artifact
+	bundleTempDir := layout.BundleTempDir()
+	bundleFinalDir := layout.BundleDir
 
-	if b.HasPrepareAction {
-		// prepare action exists - must use prepare output for deployable bundle
-		if _, err := os.Stat(prepareDir); os.IsNotExist(err) {
-			return fmt.Errorf("model:prepare action exists but %s not found: run model:prepare first", prepareDir)
-		}
-		srcDir = prepareDir
-	} else {
-		// prepare action doesn't exist - use compose output directly
-		if _, err := os.Stat(composeDir); os.IsNotExist(err) {
-			return fmt.Errorf("no source directory found: run model:compose first")
-		}
-		srcDir = composeDir
+	cfg, err := model.Lookup(os.DirFS("."))
+	if err != nil && !errors.Is(err, model.ErrComposeNotExists) {
+		return err
+	}
+	manifest, err := b.manifest(cfg, repo, reportDir)
+	if err != nil {
+		return err
+	}
+	manifestContent, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
 	}
-
-	// Output to bundle/ - visible to users as final distributable artifact
-	bundleTempDir := "bundle/.tmp"
-	bundleFinalDir := "bundle"
 
 	b.Term().Printfln("Creating Platform Model bundle %s from %s...", bundleFile, srcDir)
-	err = createArchive(srcDir, bundleTempDir, bundleFinalDir, bundleFile)
+	err = createArchive(srcDir, bundleTempDir, bundleFinalDir, bundleFile, manifestContent, b.Reproducible)
 	if err != nil {
 		return fmt.Errorf("error creating bundle: %w", err)
 	}
 
+	bundlePath := filepath.Join(bundleFinalDir, bundleFile)
+	digest, err := irelease.DigestFile(bundlePath)
+	if err != nil {
+		return err
+	}
+
 	b.result = &BundleResult{
-		BundlePath: filepath.Join(bundleFinalDir, bundleFile),
-		RepoName:   repoName,
-		Version:    version,
+		BundlePath: bundlePath,
+		RepoName:   repo.name,
+		Version:    repo.version,
+		Commit:     repo.commit,
+		SHA256:     digest,
+	}
+	if !manifest.IsEmpty() {
+		b.result.Composition = &manifest.Metadata
 	}
 
 	b.Term().Success().Printfln("Platform Model bundle created: %s/%s", bundleFinalDir, bundleFile)
+	if b.Reproducible {
+		b.Term().Info().Printfln("SHA-256: %s", digest)
+	}
+
+	if b.Sign != "" {
+		return b.sign(b.result.BundlePath, manifest)
+	}
+
 	return nil
 }
 
-// getRepoInfo returns repository name, version (tag or commit SHA), and error
-func getRepoInfo() (repoName, version string, err error) {
+// sign writes a detached signature of the bundle and, with --attest, a signed in-toto attestation
+// of its manifest next to it
+func (b *Bundle) sign(bundlePath string, manifest Manifest) error {
+	sig, err := irelease.SignFile(b.Sign, b.Key, bundlePath)
+	if err != nil {
+		return err
+	}
+	b.result.Signature = sig
+	b.Term().Success().Printfln("Bundle signed with %s: %s", b.Sign, sig)
+
+	if !b.Attest {
+		return nil
+	}
+
+	attestation, err := irelease.WriteAttestation(bundlePath, irelease.BundlePredicateType, manifest)
+	if err != nil {
+		return fmt.Errorf("failed to write attestation: %w", err)
+	}
+	if _, err = irelease.SignFile(b.Sign, b.Key, attestation); err != nil {
+		return err
+	}
+	b.result.Attestation = attestation
+	b.Term().Success().Printfln("Attestation written: %s", attestation)
+
+	return nil
+}
+
+// sourceDir returns the directory to bundle and the prepare directory its report is read from, empty if it
+// isn't prepared. Missing sources are diagnosed with the command creating them.
+func (b *Bundle) sourceDir(layout model.Layout) (string, string, error) {
+	if b.SourceDir != "" {
+		if err := checkSourceDir(b.SourceDir); err != nil {
+			return "", "", err
+		}
+		// Prepared directories have a report next to them
+		if _, err := os.Stat(model.PrepareReportPath(b.SourceDir)); err == nil {
+			return b.SourceDir, b.SourceDir, nil
+		}
+		return b.SourceDir, "", nil
+	}
+
+	if !b.HasPrepareAction {
+		// prepare action doesn't exist - use compose output directly
+		if _, err := os.Stat(layout.MergedDir); os.IsNotExist(err) {
+			return "", "", fmt.Errorf("merged model %s not found: run model:compose first", layout.MergedDir)
+		}
+		return layout.MergedDir, "", checkSourceDir(layout.MergedDir)
+	}
+
+	// prepare action exists - must use prepare output for deployable bundle
+	if _, err := os.Stat(layout.PrepareDir); os.IsNotExist(err) {
+		if _, err = os.Stat(layout.MergedDir); os.IsNotExist(err) {
+			return "", "", fmt.Errorf("prepared model %s not found: run model:compose and model:prepare first", layout.PrepareDir)
+		}
+		return "", "", fmt.Errorf("prepared model %s not found: run model:prepare first, or bundle another directory with --source-dir", layout.PrepareDir)
+	}
+	if err := checkSourceDir(layout.PrepareDir); err != nil {
+		return "", "", err
+	}
+
+	// A prepared model older than the merged result misses changes of the last compose
+	prepared, errPrepared := os.Stat(model.PrepareReportPath(layout.PrepareDir))
+	merged, errMerged := os.Stat(layout.MergedDir)
+	if errPrepared == nil && errMerged == nil && prepared.ModTime().Before(merged.ModTime()) {
+		b.Term().Warning().Printfln("%s is older than the merged model %s: rerun model:prepare to bundle the last compose", layout.PrepareDir, layout.MergedDir)
+	}
+
+	return layout.PrepareDir, layout.PrepareDir, nil
+}
+
+// checkSourceDir fails if dir isn't a directory with files to bundle
+func checkSourceDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("source directory %s not found", dir)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("source directory %s is not a directory", dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("source directory %s is empty, nothing to bundle", dir)
+	}
+
+	return nil
+}
+
+// manifest records the provenance of the bundle: the repository, tag and commit it's built from, the build time,
+// composed packages with hashes of compose.lock and transformations of model:prepare if prepareDir is bundled
+func (b *Bundle) manifest(cfg *model.Composition, repo *repoInfo, prepareDir string) (Manifest, error) {
+	manifest := Manifest{
+		Metadata: cfg.Metadata(),
+		Version:  repo.version,
+		Commit:   repo.commit,
+		Repo:     repo.url,
+		Tag:      repo.tag,
+	}
+
+	// Reproducible bundles record the time of their entries instead of the build host clock
+	builtAt := time.Now().UTC().Truncate(time.Second)
+	if b.Reproducible {
+		epoch, err := sourceDateEpoch()
+		if err != nil {
+			return manifest, err
+		}
+		builtAt = epoch.UTC()
+	}
+	manifest.BuiltAt = &builtAt
+
+	lock, err := compose.LoadVersionLock(".")
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Packages = compose.ComposedPackages(cfg, lock)
+
+	if prepareDir != "" {
+		if manifest.Prepare, err = model.LoadPrepareReport(prepareDir); err != nil {
+			return manifest, err
+		}
+		if manifest.Prepare == nil {
+			b.Term().Warning().Printfln("%s not found, prepare transformations aren't recorded: rerun model:prepare", model.PrepareReportPath(prepareDir))
+		}
+	}
+
+	return manifest, nil
+}
+
+// repoInfo identifies the revision of the domain repository a bundle is built from.
+type repoInfo struct {
+	name    string
+	url     string
+	version string
+	tag     string
+	commit  string
+}
+
+// getRepoInfo returns repository name and origin URL, version (tag or short commit SHA), tag and HEAD commit
+func getRepoInfo() (*repoInfo, error) {
 	// Open repository
 	r, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// Get repository name from remote URL
 	remote, err := r.Remote("origin")
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	repoName = remote.Config().URLs[0]
-	repoName = filepath.Base(repoName)
-	repoName = strings.TrimSuffix(repoName, ".git")
+	info := &repoInfo{url: compose.StripCredentials(remote.Config().URLs[0])}
+	info.name = strings.TrimSuffix(filepath.Base(info.url), ".git")
 
 	// Get HEAD reference
 	head, err := r.Head()
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
+	info.commit = head.Hash().String()
 
 	// Check if HEAD points to a tag
 	tags, err := r.Tags()
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	var tagName string
-	err = tags.ForEach(func(ref *plumbing.Reference) error {
+	_ = tags.ForEach(func(ref *plumbing.Reference) error {
 		if ref.Hash() == head.Hash() {
-			tagName = ref.Name().Short()
-			return fmt.Errorf("found") // Break iteration
+			info.tag = ref.Name().Short()
+			return storer.ErrStop
 		}
 		return nil
 	})
 
-	if tagName != "" {
+	if info.tag != "" {
 		// HEAD is on a tag, use tag name as version
-		version = tagName
+		info.version = info.tag
 	} else {
 		// Not on a tag, use short commit SHA
-		version = head.Hash().String()[:7]
+		info.version = info.commit[:7]
 	}
 
-	return repoName, version, nil
+	return info, nil
 }
 
-func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile string) error {
+// sourceDateEpoch returns the modification time of entries of reproducible bundles,
+// SOURCE_DATE_EPOCH if it's set, the Unix epoch otherwise
+func sourceDateEpoch() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+// normalizeHeader strips metadata of the build host from a header of reproducible bundles,
+// only names, modes, sizes and link targets are kept
+func normalizeHeader(header *tar.Header, mtime time.Time) {
+	header.ModTime = mtime
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	header.Devmajor, header.Devminor = 0, 0
+	header.PAXRecords = nil
+	header.Format = tar.FormatUnknown
+}
+
+func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile string, manifest []byte, reproducible bool) error {
+	modTime := time.Now()
+	if reproducible {
+		var err error
+		if modTime, err = sourceDateEpoch(); err != nil {
+			return err
+		}
+	}
+
 	// Ensure archive directory exists
 	if err := os.MkdirAll(archiveTempDir, 0750); err != nil {
 		return err
@@ -160,6 +409,22 @@ func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile stri
 
 	tw := tar.NewWriter(gw)
 
+	// Write the manifest first, so it can be read without unpacking the bundle
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ManifestFile,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = tw.Write(manifest); err != nil {
+		return err
+	}
+
+	// Walk visits entries in lexical order, archives of the same content list them in the same order
+	var checksums bytes.Buffer
 	err = filepath.Walk(srcDir, func(fpath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -171,14 +436,27 @@ func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile stri
 			return err
 		}
 
+		// Symlinks are stored with their target
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(fpath)
+			if err != nil {
+				return err
+			}
+			link = filepath.ToSlash(link)
+		}
+
 		// Create a tar header
-		header, err := tar.FileInfoHeader(info, relPath)
+		header, err := tar.FileInfoHeader(info, link)
 		if err != nil {
 			return err
 		}
 
 		// Modify the name to preserve the directory structure
 		header.Name = filepath.ToSlash(relPath)
+		if reproducible {
+			normalizeHeader(header, modTime)
+		}
 
 		// Write the header to the tar archive
 		if err := tw.WriteHeader(header); err != nil {
@@ -193,22 +471,11 @@ func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile stri
 			}
 			defer file.Close()
 
-			if _, err := io.Copy(tw, file); err != nil {
-				return err
-			}
-		}
-
-		// If it's a symlink, add it to the archive
-		if info.Mode()&os.ModeSymlink != 0 {
-			link, err := os.Readlink(fpath)
-			if err != nil {
-				return err
-			}
-
-			header.Linkname = link
-			if err := tw.WriteHeader(header); err != nil {
+			h := sha256.New()
+			if _, err := fsutil.Copy(io.MultiWriter(tw, h), file); err != nil {
 				return err
 			}
+			fmt.Fprintf(&checksums, "%x  %s\n", h.Sum(nil), header.Name)
 		}
 
 		return nil
@@ -218,6 +485,20 @@ func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile stri
 		return fmt.Errorf("error walking directory: %v", err)
 	}
 
+	// Checksums of files are the last entry, they are verified by model:unbundle
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ChecksumsFile,
+		Mode:    0644,
+		Size:    int64(checksums.Len()),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = tw.Write(checksums.Bytes()); err != nil {
+		return err
+	}
+
 	// Close the tar writer
 	if err = tw.Close(); err != nil {
 		return fmt.Errorf("error closing tar writer: %v", err)
@@ -229,19 +510,7 @@ func createArchive(srcDir, archiveTempDir, archiveFinalDir, archiveDestFile stri
 	}
 
 	// Copy archive to final directory
-	srcFile, err := os.Open(path.Clean(archivePath))
-	if err != nil {
-		return fmt.Errorf("error opening archive file: %v", err)
-	}
-	defer srcFile.Close()
-
-	destFile, err := os.Create(path.Clean(artifactPath))
-	if err != nil {
-		return fmt.Errorf("error creating image file: %v", err)
-	}
-	defer destFile.Close()
-
-	if _, err := io.Copy(destFile, srcFile); err != nil {
+	if _, err := fsutil.CopyFile(path.Clean(archivePath), path.Clean(artifactPath), 0666); err != nil {
 		return fmt.Errorf("error copying archive to image directory: %v", err)
 	}
 
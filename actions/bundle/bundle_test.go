@@ -0,0 +1,156 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestCreateArchiveReproducible(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "prepare")
+	if err := os.MkdirAll(filepath.Join(srcDir, "roles"), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	file := filepath.Join(srcDir, "roles", "main.yaml")
+	if err := os.WriteFile(file, []byte("key: value\n"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	manifest := []byte("name: platform\nversion: v1.0.0\n")
+	build := func(name string, reproducible bool) []byte {
+		err := createArchive(srcDir, filepath.Join(dir, "tmp"), filepath.Join(dir, "bundle"), name, manifest, reproducible)
+		if err != nil {
+			t.Fatalf("createArchive failed: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "bundle", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	first := build("first.pm", true)
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, build("second.pm", true)) {
+		t.Error("expected reproducible bundles of the same content to be identical")
+	}
+
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, build("third.pm", false)) {
+		t.Error("expected bundles to keep modification times without --reproducible")
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if err := createArchive(srcDir, filepath.Join(dir, "tmp"), filepath.Join(dir, "bundle"), "fourth.pm", manifest, true); err == nil {
+		t.Error("expected invalid SOURCE_DATE_EPOCH to fail")
+	}
+}
+
+func TestManifestProvenance(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	lock := "integrity:\n  - name: core\n    ref: v1.2.0\n    commit: 4c1e0f9a\n    hash: sha256:aa\n"
+	if err := os.WriteFile(model.VersionLockFile, []byte(lock), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(model.PrepareDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	report := &model.PrepareReport{Transformations: []string{"created ansible.cfg"}, Layers: []string{"platform"}}
+	if err := model.SavePrepareReport(model.PrepareDir, report); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &model.Composition{Name: "platform", Dependencies: []model.Dependency{
+		{Name: "core", Source: model.Source{URL: "https://example.com/core.git", Ref: "v1.2.0"}},
+	}}
+	repo := &repoInfo{name: "platform", url: "https://example.com/platform.git", version: "v1.0.0", tag: "v1.0.0", commit: "77d2e1b0"}
+
+	b := &Bundle{Reproducible: true}
+	b.SetTerm(launchr.Term())
+	m, err := b.manifest(cfg, repo, model.PrepareDir)
+	if err != nil {
+		t.Fatalf("manifest failed: %v", err)
+	}
+
+	if m.Repo != repo.url || m.Tag != "v1.0.0" || m.Commit != "77d2e1b0" || m.Name != "platform" {
+		t.Errorf("unexpected provenance %+v", m)
+	}
+	if m.BuiltAt == nil || !m.BuiltAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected build time of SOURCE_DATE_EPOCH, got %v", m.BuiltAt)
+	}
+	if len(m.Packages) != 1 || m.Packages[0].Commit != "4c1e0f9a" || m.Packages[0].Hash != "sha256:aa" {
+		t.Errorf("unexpected packages %+v", m.Packages)
+	}
+	if m.Prepare == nil || len(m.Prepare.Transformations) != 1 || m.Prepare.Layers[0] != "platform" {
+		t.Errorf("unexpected prepare report %+v", m.Prepare)
+	}
+
+	if m, err = b.manifest(cfg, repo, ""); err != nil || m.Prepare != nil {
+		t.Errorf("expected no prepare report for composed files, got %+v, %v", m.Prepare, err)
+	}
+}
+
+func TestSourceDir(t *testing.T) {
+	dir := t.TempDir()
+	layout := model.Layout{
+		MergedDir:  filepath.Join(dir, "merged"),
+		PrepareDir: filepath.Join(dir, "prepare"),
+	}
+	b := &Bundle{HasPrepareAction: true}
+	b.SetTerm(launchr.Term())
+
+	if _, _, err := b.sourceDir(layout); err == nil || !strings.Contains(err.Error(), "run model:compose and model:prepare first") {
+		t.Errorf("expected compose and prepare to be suggested, got %v", err)
+	}
+
+	if err := os.MkdirAll(layout.MergedDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.sourceDir(layout); err == nil || !strings.Contains(err.Error(), "run model:prepare first") {
+		t.Errorf("expected prepare to be suggested, got %v", err)
+	}
+
+	if err := os.MkdirAll(layout.PrepareDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.sourceDir(layout); err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Errorf("expected empty prepare directory to fail, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(layout.PrepareDir, "ansible.cfg"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if src, report, err := b.sourceDir(layout); err != nil || src != layout.PrepareDir || report != layout.PrepareDir {
+		t.Errorf("expected prepare directory, got %s, %s, %v", src, report, err)
+	}
+
+	// --source-dir overrides the layout, its report is read only if it was prepared
+	if err := os.WriteFile(filepath.Join(layout.MergedDir, "main.yaml"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	b.SourceDir = layout.MergedDir
+	if src, report, err := b.sourceDir(layout); err != nil || src != layout.MergedDir || report != "" {
+		t.Errorf("expected merged directory without report, got %s, %s, %v", src, report, err)
+	}
+
+	b.SourceDir = filepath.Join(dir, "missing")
+	if _, _, err := b.sourceDir(layout); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing source directory to fail, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+// Entry types of bundle contents.
+const (
+	EntryFile    = "file"
+	EntryDir     = "dir"
+	EntrySymlink = "symlink"
+)
+
+// ListEntry describes a path shipped by a bundle.
+type ListEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	Link string `json:"link,omitempty"`
+}
+
+// ListResult is the structured result of model:bundle:list.
+type ListResult struct {
+	File     string      `json:"file"`
+	Manifest *Manifest   `json:"manifest,omitempty"`
+	Entries  []ListEntry `json:"entries"`
+	Files    int         `json:"files"`
+	Size     int64       `json:"size"`
+}
+
+// List implements the model:bundle:list command
+type List struct {
+	action.WithLogger
+	action.WithTerm
+
+	File string
+
+	result *ListResult
+}
+
+// Result returns the structured result for JSON output.
+func (l *List) Result() any {
+	return l.result
+}
+
+// Execute reads the bundle without extracting it and prints its contents
+func (l *List) Execute() error {
+	f, err := os.Open(filepath.Clean(l.File))
+	if err != nil {
+		return fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	l.result = &ListResult{File: l.File}
+	if err = l.read(f); err != nil {
+		return fmt.Errorf("error reading bundle %s: %w", l.File, err)
+	}
+
+	PrintManifest(l.Term(), l.result.Manifest)
+	printContents(l.Term(), l.result)
+
+	return nil
+}
+
+// printContents prints entries of a bundle with sizes of files
+func printContents(term *launchr.Terminal, result *ListResult) {
+	term.Info().Printfln("Contents (%d files, %s)", result.Files, compose.FormatBytes(result.Size))
+	for _, e := range result.Entries {
+		switch e.Type {
+		case EntryDir:
+			term.Printfln("%10s  %s/", "-", e.Path)
+		case EntrySymlink:
+			term.Printfln("%10s  %s -> %s", "-", e.Path, e.Link)
+		default:
+			term.Printfln("%10s  %s", compose.FormatBytes(e.Size), e.Path)
+		}
+	}
+}
+
+// PrintManifest prints the version, provenance and metadata of a bundle, it's shared with model:show --bundle
+func PrintManifest(term *launchr.Terminal, m *Manifest) {
+	if m == nil {
+		term.Warning().Printfln("Bundle has no %s", ManifestFile)
+		return
+	}
+
+	if m.Name != "" {
+		term.Info().Printfln("Bundle %s %s", m.Name, m.Version)
+	} else {
+		term.Info().Printfln("Bundle %s", m.Version)
+	}
+	if m.Repo != "" {
+		term.Printfln("  repo\t%s", m.Repo)
+	}
+	if m.Tag != "" {
+		term.Printfln("  tag\t%s", m.Tag)
+	}
+	if m.Commit != "" {
+		term.Printfln("  commit\t%s", m.Commit)
+	}
+	if m.BuiltAt != nil {
+		term.Printfln("  built\t%s", m.BuiltAt.Format(time.RFC3339))
+	}
+	if m.Description != "" {
+		term.Printfln("  %s", m.Description)
+	}
+	for _, maintainer := range m.Maintainers {
+		term.Printfln("  maintainer\t%s", maintainer)
+	}
+
+	if len(m.Packages) > 0 {
+		term.Info().Printfln("Packages (%d)", len(m.Packages))
+		for _, p := range m.Packages {
+			id := p.Ref
+			if p.Commit != "" {
+				id = fmt.Sprintf("%s@%.12s", id, p.Commit)
+			}
+			if p.Hash != "" {
+				id = fmt.Sprintf("%s %s", id, p.Hash)
+			}
+			term.Printfln("  %s\t%s", p.Name, strings.TrimSpace(id))
+		}
+	}
+	if m.Prepare != nil {
+		term.Info().Printfln("Prepare transformations (%d)", len(m.Prepare.Transformations))
+		for _, t := range m.Prepare.Transformations {
+			term.Printfln("  %s", t)
+		}
+	}
+}
+
+// read collects entries and the manifest of the bundle archive
+func (l *List) read(r io.Reader) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if path.Clean(header.Name) == "." || header.Name == ChecksumsFile {
+			continue
+		}
+		if header.Name == ManifestFile {
+			manifest := &Manifest{}
+			if err = yaml.NewDecoder(tr).Decode(manifest); err != nil {
+				return fmt.Errorf("invalid %s: %w", ManifestFile, err)
+			}
+			l.result.Manifest = manifest
+			continue
+		}
+
+		entry := ListEntry{Path: header.Name, Type: EntryFile}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.Type = EntryDir
+		case tar.TypeSymlink:
+			entry.Type = EntrySymlink
+			entry.Link = header.Linkname
+		default:
+			entry.Size = header.Size
+			l.result.Files++
+			l.result.Size += header.Size
+		}
+		l.result.Entries = append(l.result.Entries, entry)
+	}
+}
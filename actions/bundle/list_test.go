@@ -0,0 +1,65 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestListBundle(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "merged")
+	if err := os.MkdirAll(filepath.Join(srcDir, "src"), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "src", "main.yaml"), []byte("key: value\n"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink("src/main.yaml", filepath.Join(srcDir, "main.yaml")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	manifest := []byte("name: platform\nversion: v1.0.0\ncommit: 4c1e0f9a\n")
+	err := createArchive(srcDir, filepath.Join(dir, "tmp"), filepath.Join(dir, "bundle"), "platform-v1.0.0.pm", manifest, false)
+	if err != nil {
+		t.Fatalf("createArchive failed: %v", err)
+	}
+
+	l := &List{File: filepath.Join(dir, "bundle", "platform-v1.0.0.pm")}
+	l.SetTerm(launchr.Term())
+	if err = l.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	res := l.result
+	if res.Manifest == nil || res.Manifest.Name != "platform" || res.Manifest.Version != "v1.0.0" {
+		t.Errorf("unexpected manifest %+v", res.Manifest)
+	}
+
+	f, err := os.Open(l.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if m, err := model.ReadBundleManifest(f); err != nil || m == nil || m.Commit != "4c1e0f9a" {
+		t.Errorf("expected build commit in manifest, got %+v, %v", m, err)
+	}
+	if res.Files != 1 || res.Size != int64(len("key: value\n")) {
+		t.Errorf("expected 1 file of %d bytes, got %d of %d", len("key: value\n"), res.Files, res.Size)
+	}
+
+	if len(res.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", res.Entries)
+	}
+	types := make(map[string]string)
+	for _, e := range res.Entries {
+		types[e.Path] = e.Type
+	}
+	if types["src"] != EntryDir || types["src/main.yaml"] != EntryFile || types["main.yaml"] != EntrySymlink {
+		t.Errorf("unexpected entries %+v", res.Entries)
+	}
+}
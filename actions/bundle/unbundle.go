@@ -0,0 +1,281 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+var (
+	errChecksumMismatch = errors.New("bundle doesn't match its checksums")
+	errTargetNotEmpty   = errors.New("target directory is not empty")
+)
+
+// UnbundleResult is the structured result of model:unbundle.
+type UnbundleResult struct {
+	*ListResult
+	// TargetDir is empty when the bundle is only listed.
+	TargetDir string `json:"target_dir,omitempty"`
+	// Verified is the number of files matching checksums of the bundle, bundles created by older versions have none.
+	Verified int `json:"verified"`
+}
+
+// Unbundle implements the model:unbundle command
+type Unbundle struct {
+	action.WithLogger
+	action.WithTerm
+
+	File      string
+	TargetDir string
+	List      bool
+	Force     bool
+
+	result *UnbundleResult
+}
+
+// Result returns the structured result for JSON output.
+func (u *Unbundle) Result() any {
+	return u.result
+}
+
+// Execute extracts the bundle into the target directory, or only lists it with --list,
+// files are verified against checksums of the bundle in both cases. The bundle is extracted into a staging
+// directory next to the target and moved into it only once it's verified.
+func (u *Unbundle) Execute() error {
+	targetDir, stagingDir := "", ""
+	if !u.List {
+		targetDir = u.TargetDir
+		if targetDir == "" {
+			targetDir = bundleName(u.File)
+		}
+		if err := u.checkTarget(targetDir); err != nil {
+			return err
+		}
+
+		parent := filepath.Dir(filepath.Clean(targetDir))
+		if err := os.MkdirAll(parent, 0750); err != nil {
+			return err
+		}
+		var err error
+		if stagingDir, err = os.MkdirTemp(parent, "."+filepath.Base(targetDir)+"-unbundle-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(stagingDir)
+		if err = os.Chmod(stagingDir, 0750); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(filepath.Clean(u.File))
+	if err != nil {
+		return fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	u.result = &UnbundleResult{ListResult: &ListResult{File: u.File}, TargetDir: targetDir}
+	checksums, digests, err := u.read(f, stagingDir)
+	if err != nil {
+		return fmt.Errorf("error reading bundle %s: %w", u.File, err)
+	}
+
+	PrintManifest(u.Term(), u.result.Manifest)
+	if u.List {
+		printContents(u.Term(), u.result.ListResult)
+	}
+
+	if checksums == nil {
+		u.Term().Warning().Printfln("Bundle has no %s, its files can't be verified. Rebuild it with model:bundle.", ChecksumsFile)
+	} else {
+		if u.result.Verified, err = verifyChecksums(checksums, digests); err != nil {
+			return err
+		}
+		u.Term().Success().Printfln("%d files match checksums of the bundle", u.result.Verified)
+	}
+
+	if targetDir != "" {
+		if err = moveInto(stagingDir, targetDir); err != nil {
+			return err
+		}
+		u.Term().Success().Printfln("Bundle extracted to %s", targetDir)
+	}
+
+	return nil
+}
+
+// bundleName returns the name of the bundle file without its archive extension
+func bundleName(file string) string {
+	name := filepath.Base(file)
+	for _, ext := range []string{".tar.gz", ".tgz", ".pm"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// moveInto moves the extracted bundle into the target directory. A missing or empty target is replaced,
+// entries of a non-empty target, extracted into with --force, are replaced by the ones of the bundle.
+func moveInto(stagingDir, targetDir string) error {
+	if err := os.Remove(targetDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		entries, err := os.ReadDir(stagingDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			dst := filepath.Join(targetDir, e.Name())
+			if err = os.RemoveAll(dst); err != nil {
+				return err
+			}
+			if err = os.Rename(filepath.Join(stagingDir, e.Name()), dst); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return os.Rename(stagingDir, targetDir)
+}
+
+// checkTarget fails on a non-empty target directory unless --force is set
+func (u *Unbundle) checkTarget(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 && !u.Force {
+		return fmt.Errorf("%w: %s, use --force to extract into it", errTargetNotEmpty, dir)
+	}
+
+	return nil
+}
+
+// read collects entries, the manifest and checksums of the bundle archive and SHA-256 digests of its files,
+// entries are extracted into targetDir unless it's empty. Links pointing outside of the bundle are rejected.
+func (u *Unbundle) read(r io.Reader, targetDir string) ([]byte, map[string]string, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gr.Close()
+
+	var checksums []byte
+	digests := make(map[string]string)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return checksums, digests, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if path.Clean(header.Name) == "." {
+			continue
+		}
+
+		var content io.Reader = tr
+		switch header.Name {
+		case ManifestFile, ChecksumsFile:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			if header.Name == ChecksumsFile {
+				checksums = data
+			} else if err = yaml.Unmarshal(data, &u.result.Manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+			}
+			content = bytes.NewReader(data)
+		default:
+			entry := ListEntry{Path: header.Name, Type: EntryFile}
+			switch header.Typeflag {
+			case tar.TypeDir:
+				entry.Type = EntryDir
+			case tar.TypeSymlink:
+				entry.Type = EntrySymlink
+				entry.Link = header.Linkname
+			default:
+				entry.Size = header.Size
+				u.result.Files++
+				u.result.Size += header.Size
+			}
+			u.result.Entries = append(u.result.Entries, entry)
+		}
+
+		// Checksums list bundled files, the manifest and checksums themselves aren't listed
+		var h hash.Hash
+		if header.Typeflag == tar.TypeReg && content == tr {
+			h = sha256.New()
+			content = io.TeeReader(content, h)
+		}
+
+		if targetDir == "" {
+			_, err = io.Copy(io.Discard, content)
+		} else {
+			_, err = compose.ExtractTarEntry(targetDir, header, content, compose.ArchiveLinksInternal)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if h != nil {
+			digests[header.Name] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+}
+
+// verifyChecksums compares digests of files read from the bundle with its checksums
+func verifyChecksums(checksums []byte, digests map[string]string) (int, error) {
+	var errs []error
+	listed := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+		listed[name] = true
+		digest, found := digests[name]
+		switch {
+		case !found:
+			errs = append(errs, fmt.Errorf("%s is missing", name))
+		case digest != sum:
+			errs = append(errs, fmt.Errorf("%s has digest %s, expected %s", name, digest, sum))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(digests)) {
+		if !listed[name] {
+			errs = append(errs, fmt.Errorf("%s isn't listed", name))
+		}
+	}
+	if len(errs) > 0 {
+		return 0, fmt.Errorf("%w: %w", errChecksumMismatch, errors.Join(errs...))
+	}
+
+	return len(digests), nil
+}
@@ -0,0 +1,120 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestUnbundle(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "prepare")
+	if err := os.MkdirAll(filepath.Join(srcDir, "roles"), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "roles", "main.yaml"), []byte("key: value\n"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink("roles/main.yaml", filepath.Join(srcDir, "main.yaml")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	manifest := []byte("name: platform\nversion: v1.0.0\n")
+	err := createArchive(srcDir, filepath.Join(dir, "tmp"), filepath.Join(dir, "bundle"), "platform-v1.0.0.pm", manifest, false)
+	if err != nil {
+		t.Fatalf("createArchive failed: %v", err)
+	}
+	file := filepath.Join(dir, "bundle", "platform-v1.0.0.pm")
+
+	target := filepath.Join(dir, "extracted")
+	u := &Unbundle{File: file, TargetDir: target}
+	u.SetTerm(launchr.Term())
+	if err = u.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if u.result.Verified != 1 || u.result.Manifest == nil || u.result.Manifest.Version != "v1.0.0" {
+		t.Errorf("unexpected result %+v", u.result)
+	}
+	if data, err := os.ReadFile(filepath.Join(target, "main.yaml")); err != nil || string(data) != "key: value\n" {
+		t.Errorf("expected extracted symlink to resolve to the file, got %q, %v", data, err)
+	}
+	if _, err = os.Stat(filepath.Join(target, ManifestFile)); err != nil {
+		t.Errorf("expected manifest to be extracted: %v", err)
+	}
+
+	if err = u.Execute(); !errors.Is(err, errTargetNotEmpty) {
+		t.Errorf("expected non-empty target to fail, got %v", err)
+	}
+	u.Force = true
+	if err = u.Execute(); err != nil {
+		t.Errorf("expected --force to extract into non-empty target, got %v", err)
+	}
+
+	l := &Unbundle{File: file, List: true}
+	l.SetTerm(launchr.Term())
+	if err = l.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if l.result.TargetDir != "" || l.result.Verified != 1 || len(l.result.Entries) != 3 {
+		t.Errorf("unexpected list result %+v", l.result)
+	}
+}
+
+func TestUnbundleTampered(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "platform-v1.0.0.tar.gz")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range map[string]string{ChecksumsFile: "00  main.yaml\n", "main.yaml": "tampered"} {
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = errors.Join(tw.Close(), gw.Close(), f.Close()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(dir)
+	u := &Unbundle{File: file}
+	u.SetTerm(launchr.Term())
+	if err = u.Execute(); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	// Nothing of a bundle failing verification is left on disk.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the bundle in %s, got %v", dir, entries)
+	}
+	if u.result.TargetDir != "platform-v1.0.0" {
+		t.Errorf("expected target named after the bundle, got %s", u.result.TargetDir)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	checksums := []byte("aa  roles/main.yaml\nbb  roles/other.yaml\n")
+
+	if n, err := verifyChecksums(checksums, map[string]string{"roles/main.yaml": "aa", "roles/other.yaml": "bb"}); err != nil || n != 2 {
+		t.Errorf("expected 2 verified files, got %d, %v", n, err)
+	}
+	for _, digests := range []map[string]string{
+		{"roles/main.yaml": "aa", "roles/other.yaml": "cc"},
+		{"roles/main.yaml": "aa"},
+		{"roles/main.yaml": "aa", "roles/other.yaml": "bb", "roles/extra.yaml": "dd"},
+	} {
+		if _, err := verifyChecksums(checksums, digests); !errors.Is(err, errChecksumMismatch) {
+			t.Errorf("expected %v to mismatch, got %v", digests, err)
+		}
+	}
+}
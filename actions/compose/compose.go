@@ -5,11 +5,14 @@ import (
 	"github.com/launchrctl/launchr/pkg/action"
 
 	icompose "github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
 // ComposeResult is the structured result of model:compose.
 type ComposeResult struct {
-	Status string `json:"status"`
+	Status  string                `json:"status"`
+	Summary *model.ComposeSummary `json:"summary,omitempty"`
 }
 
 // Compose implements the model:compose action
@@ -24,6 +27,25 @@ type Compose struct {
 	SkipNotVersioned   bool
 	ConflictsVerbosity bool
 	Interactive        bool
+	Strict             bool
+	Wait               bool
+	Overlays           []string
+	Permissions        string
+	ArchiveLinks       string
+	Symlinks           string
+	Groups             []string
+	ExcludeGroups      []string
+	IgnoreNested       bool
+	Integrity          string
+	Locked             bool
+	FrozenLockfile     bool
+	NoKeyring          bool
+	DebugGit           bool
+	RejectLegacy       bool
+	RespectGitignore   bool
+	IncludeStaged      bool
+	FullMerge          bool
+	Plain              bool
 
 	result *ComposeResult
 }
@@ -35,14 +57,39 @@ func (c *Compose) Result() any {
 
 // Execute runs the model:compose action
 func (c *Compose) Execute() error {
+	defer output.SetPlain(c.Plain)()
+
+	unlock, err := icompose.LockModel(c.BaseDir, "model:compose", c.Wait, c.Term(), c.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	composer, err := icompose.CreateComposer(
 		c.BaseDir,
 		icompose.ComposerOptions{
-			Clean:              c.Clean,
-			WorkingDir:         c.WorkingDir,
-			SkipNotVersioned:   c.SkipNotVersioned,
-			ConflictsVerbosity: c.ConflictsVerbosity,
-			Interactive:        c.Interactive,
+			Clean:                  c.Clean,
+			WorkingDir:             c.WorkingDir,
+			SkipNotVersioned:       c.SkipNotVersioned,
+			ConflictsVerbosity:     c.ConflictsVerbosity,
+			Interactive:            c.Interactive,
+			Strict:                 c.Strict,
+			Overlays:               c.Overlays,
+			Permissions:            c.Permissions,
+			ArchiveLinks:           c.ArchiveLinks,
+			Symlinks:               c.Symlinks,
+			Groups:                 c.Groups,
+			ExcludeGroups:          c.ExcludeGroups,
+			IgnoreNestedStrategies: c.IgnoreNested,
+			Integrity:              c.Integrity,
+			Locked:                 c.Locked,
+			FrozenLockfile:         c.FrozenLockfile,
+			NoKeyring:              c.NoKeyring,
+			DebugGit:               c.DebugGit,
+			RejectLegacyLayout:     c.RejectLegacy,
+			RespectGitignore:       c.RespectGitignore,
+			IncludeStaged:          c.IncludeStaged,
+			FullMerge:              c.FullMerge,
 		},
 		c.Keyring,
 	)
@@ -57,6 +104,6 @@ func (c *Compose) Execute() error {
 		return err
 	}
 
-	c.result = &ComposeResult{Status: "completed"}
+	c.result = &ComposeResult{Status: "completed", Summary: (*model.ComposeSummary)(composer.Summary())}
 	return nil
 }
@@ -0,0 +1,75 @@
+package explain
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/output"
+)
+
+// Explain implements the model:explain action
+type Explain struct {
+	action.WithLogger
+	action.WithTerm
+
+	BaseDir      string
+	WorkingDir   string
+	Path         string
+	IgnoreNested bool
+
+	result *compose.Explanation
+}
+
+// Result returns the structured result for JSON output.
+func (e *Explain) Result() any {
+	return e.result
+}
+
+// Execute runs the model:explain action
+func (e *Explain) Execute() error {
+	cfg, err := compose.Lookup(os.DirFS(e.BaseDir))
+	if err != nil {
+		return err
+	}
+
+	e.result, err = compose.Explain(e.BaseDir, filepath.Join(e.BaseDir, e.WorkingDir), cfg, e.Path, e.IgnoreNested)
+	if err != nil {
+		return err
+	}
+
+	term := e.Term()
+	term.Info().Printfln("Merge order: %s", e.order())
+	if len(e.result.Steps) == 0 {
+		term.Warning().Printfln("%s is not provided by the domain repo or any package", e.result.Path)
+		return nil
+	}
+
+	for _, step := range e.result.Steps {
+		if step.DeclaredBy != "" {
+			term.Printfln("%s\t%s\t%s: %s, strategy declared by %s", step.Package, step.Source, step.Decision, step.Reason, step.DeclaredBy)
+			continue
+		}
+		term.Printfln("%s\t%s\t%s: %s", step.Package, step.Source, step.Decision, step.Reason)
+	}
+
+	if e.result.Winner == "" {
+		term.Warning().Printfln("%s is not part of the merged result", e.result.Path)
+		return nil
+	}
+
+	term.Success().Printfln("%s comes from %s", e.result.Path, e.result.Winner)
+	return nil
+}
+
+func (e *Explain) order() string {
+	order := "domain repo"
+	arrow := output.Get().Arrow
+	for _, name := range e.result.Order {
+		order += " " + arrow + " " + name
+	}
+
+	return order
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+var errOutputExists = errors.New("output file already exists, use --force to overwrite")
+
+// ExportResult is the structured result of model:export.
+type ExportResult struct {
+	Output       string `json:"output"`
+	Flattened    bool   `json:"flattened"`
+	Dependencies int    `json:"dependencies"`
+}
+
+// Export implements the model:export action
+type Export struct {
+	action.WithLogger
+	action.WithTerm
+
+	BaseDir    string
+	WorkingDir string
+	Output     string
+	Flatten    bool
+	Force      bool
+
+	result *ExportResult
+}
+
+// Result returns the structured result for JSON output.
+func (e *Export) Result() any {
+	return e.result
+}
+
+// Execute runs the model:export action
+func (e *Export) Execute() error {
+	output := e.Output
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(e.BaseDir, output)
+	}
+	if _, err := os.Stat(output); err == nil && !e.Force {
+		return errOutputExists
+	}
+
+	cfg, err := compose.Lookup(os.DirFS(e.BaseDir))
+	if err != nil {
+		return err
+	}
+
+	exported, err := compose.Export(e.BaseDir, filepath.Join(e.BaseDir, e.WorkingDir), cfg, e.Flatten)
+	if err != nil {
+		return err
+	}
+
+	if err = compose.WriteComposition(output, exported); err != nil {
+		return err
+	}
+
+	e.result = &ExportResult{Output: e.Output, Flattened: exported.Flattened, Dependencies: len(exported.Dependencies)}
+	for _, d := range exported.Dependencies {
+		pkg := d.ToPackage(d.Name)
+		e.Term().Printfln("  %s %s", d.Name, pkg.GetTarget())
+	}
+	e.Term().Success().Printfln("Exported %d packages to %s", len(exported.Dependencies), e.Output)
+	return nil
+}
@@ -0,0 +1,54 @@
+package freeze
+
+import (
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+// FreezeResult is the structured result of model:freeze and model:unfreeze.
+type FreezeResult struct {
+	Package string `json:"package"`
+	Frozen  bool   `json:"frozen"`
+}
+
+// Freeze implements the model:freeze and model:unfreeze actions
+type Freeze struct {
+	action.WithLogger
+	action.WithTerm
+
+	WorkingDir string
+	Package    string
+	Frozen     bool
+
+	result *FreezeResult
+}
+
+// Result returns the structured result for JSON output.
+func (f *Freeze) Result() any {
+	return f.result
+}
+
+// Execute sets the frozen flag of the package
+func (f *Freeze) Execute() error {
+	operation := "model:freeze"
+	if !f.Frozen {
+		operation = "model:unfreeze"
+	}
+	unlock, err := compose.LockModel(f.WorkingDir, operation, false, f.Term(), f.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fa := &compose.FormsAction{}
+	fa.SetLogger(f.Log())
+	fa.SetTerm(f.Term())
+
+	if err := fa.FreezePackage(f.Package, f.Frozen, f.WorkingDir); err != nil {
+		return err
+	}
+
+	f.result = &FreezeResult{Package: f.Package, Frozen: f.Frozen}
+	return nil
+}
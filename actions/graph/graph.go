@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+// Graph implements the model:graph action
+type Graph struct {
+	action.WithLogger
+	action.WithTerm
+
+	BaseDir    string
+	WorkingDir string
+	Format     string
+
+	result *compose.DependencyGraph
+}
+
+// Result returns the structured result for JSON output.
+func (g *Graph) Result() any {
+	return g.result
+}
+
+// Execute runs the model:graph action
+func (g *Graph) Execute() error {
+	if err := compose.ValidateGraphFormat(g.Format); err != nil {
+		return err
+	}
+
+	cfg, err := compose.Lookup(os.DirFS(g.BaseDir))
+	if err != nil {
+		return err
+	}
+
+	g.result, err = compose.Graph(g.BaseDir, filepath.Join(g.BaseDir, g.WorkingDir), cfg)
+	if err != nil {
+		return err
+	}
+
+	switch g.Format {
+	case compose.GraphFormatMermaid:
+		g.Term().Printf("%s", g.result.Mermaid())
+	case compose.GraphFormatJSON:
+		data, err := json.MarshalIndent(g.result, "", "  ")
+		if err != nil {
+			return err
+		}
+		g.Term().Printfln("%s", data)
+	default:
+		g.Term().Printf("%s", g.result.DOT())
+	}
+
+	return nil
+}
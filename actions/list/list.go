@@ -3,18 +3,35 @@ package list
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/launchrctl/launchr/pkg/action"
 	"github.com/plasmash/plasmactl-component/pkg/component"
 	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/listing"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 	"github.com/plasmash/plasmactl-platform/pkg/graph"
 )
 
 // PackageListItem represents a package in the list output
 type PackageListItem struct {
-	Name string `json:"name"`
-	Ref  string `json:"ref"`
+	Name       string              `json:"name"`
+	Ref        string              `json:"ref"`
+	Components []ComponentListItem `json:"components,omitempty"`
+
+	Stats *compose.PackageStats `json:"stats,omitempty"`
+}
+
+// ComponentListItem represents a component of a package with its zone and nodes (--tree only)
+type ComponentListItem struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version,omitempty"`
+	Zone    string   `json:"zone,omitempty"`
+	Nodes   []string `json:"nodes,omitempty"`
 }
 
 // ListResult is the structured output for model:list
@@ -28,7 +45,13 @@ type List struct {
 	action.WithTerm
 
 	WorkingDir string
-	Tree       bool
+	// Layout locates downloaded packages, empty paths are defaults.
+	Layout  model.Layout
+	Tree    bool
+	Plain   bool
+	Stats   bool
+	Listing listing.Options
+	Output  output.Mode
 
 	result *ListResult
 }
@@ -40,50 +63,185 @@ func (l *List) Result() any {
 
 // Execute runs the model:list action
 func (l *List) Execute() error {
+	defer output.SetPlain(l.Plain)()
+
 	cfg, err := compose.Lookup(os.DirFS(l.WorkingDir))
 	if err != nil {
 		return fmt.Errorf("compose.yaml not found: %w", err)
 	}
 
+	versionLock, err := compose.LoadVersionLock(l.WorkingDir)
+	if err != nil {
+		return err
+	}
+	versionLock.Apply(cfg)
+
+	if err = l.Listing.Validate(); err != nil {
+		return err
+	}
+	if err = l.Output.Validate(); err != nil {
+		return err
+	}
+
 	// Build result
 	l.result = &ListResult{}
 
 	if len(cfg.Dependencies) == 0 {
-		l.Term().Info().Println("No package dependencies")
+		if l.Output.Human() {
+			l.Term().Info().Println("No package dependencies")
+		}
 		return nil
 	}
-	for _, dep := range cfg.Dependencies {
+
+	var components func(string) int
+	if l.Listing.NeedsComponents() || l.Stats {
+		g, err := graph.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load graph: %w", err)
+		}
+		components = listing.ComponentCounter(g)
+	}
+
+	var merged map[string]int
+	if l.Stats {
+		summary, err := compose.LoadSummary(l.WorkingDir)
+		if err != nil {
+			l.Log().Debug("compose summary is not available", "error", err)
+		}
+		merged = summary.MergedFiles()
+	}
+
+	checkouts := compose.LocateCheckouts(cfg, versionLock, filepath.Join(l.WorkingDir, l.Layout.WithDefaults().PackagesDir))
+	for _, dep := range l.Listing.Dependencies(cfg.Dependencies, checkouts, components) {
 		ref := dep.Source.Ref
 		if ref == "" {
 			ref = "latest"
 		}
-		l.result.Packages = append(l.result.Packages, PackageListItem{
+		item := PackageListItem{
 			Name: dep.Name,
 			Ref:  ref,
-		})
+		}
+		if l.Stats {
+			stats := compose.CollectPackageStats(checkouts, dep, merged)
+			stats.Components = components(dep.Name)
+			item.Stats = &stats
+		}
+		l.result.Packages = append(l.result.Packages, item)
 	}
 
 	// Tree output is special - still needs custom printing
 	if l.Tree {
-		return l.printTreeWithRelations(cfg)
+		return l.printTreeWithRelations()
+	}
+
+	if l.Output.Quiet {
+		return nil
 	}
 
 	term := l.Term()
 	for _, pkg := range l.result.Packages {
+		if l.Output.Porcelain {
+			term.Printfln("%s", porcelainPackage(pkg))
+			continue
+		}
+		if pkg.Stats != nil {
+			term.Printfln("%s@%s\t%d components\t%d files\t%s\t%d merged files",
+				pkg.Name, pkg.Ref, pkg.Stats.Components, pkg.Stats.Files, compose.FormatBytes(pkg.Stats.Size), pkg.Stats.MergedFiles)
+			continue
+		}
 		term.Printfln("%s@%s", pkg.Name, pkg.Ref)
 	}
 	return nil
 }
 
-// printTreeWithRelations prints packages as a tree with components, zones, and nodes
-func (l *List) printTreeWithRelations(cfg *compose.Composition) error {
+// printTreeWithRelations fills packages with components, zones, and nodes and prints them as a tree
+func (l *List) printTreeWithRelations() error {
 	g, err := graph.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load graph: %w", err)
 	}
 
+	l.buildRelations(g)
+
+	if !l.Output.Human() {
+		l.printTreePorcelain()
+		return nil
+	}
+
+	term := l.Term()
+	m := output.Get()
+	for pi, pkg := range l.result.Packages {
+		term.Printfln("%s%s@%s", m.Package, pkg.Name, pkg.Ref)
+
+		for ci, comp := range pkg.Components {
+			compPrefix, compIndent := m.Tree(ci == len(pkg.Components)-1)
+			term.Printfln("%s%s%s", compPrefix, m.Component, component.FormatDisplayName(comp.Name, comp.Version))
+
+			totalChildren := len(comp.Nodes)
+			if comp.Zone != "" {
+				totalChildren++
+			}
+			childIdx := 0
+
+			// Print zone
+			if comp.Zone != "" {
+				childIdx++
+				branch, _ := m.Tree(childIdx == totalChildren)
+				term.Printfln("%s%s%s%s", compIndent, branch, m.Zone, comp.Zone)
+			}
+
+			// Print nodes that serve this zone
+			for _, nd := range comp.Nodes {
+				childIdx++
+				branch, _ := m.Tree(childIdx == totalChildren)
+				term.Printfln("%s%s%s%s", compIndent, branch, m.Node, nd)
+			}
+		}
+
+		if pi < len(l.result.Packages)-1 {
+			term.Printfln("")
+		}
+	}
+
+	return nil
+}
+
+// porcelainPackage formats a package record: name, ref and with --stats components, files, size in bytes and merged files
+func porcelainPackage(pkg PackageListItem) string {
+	fields := []string{pkg.Name, pkg.Ref}
+	if pkg.Stats != nil {
+		fields = append(fields,
+			strconv.Itoa(pkg.Stats.Components),
+			strconv.Itoa(pkg.Stats.Files),
+			strconv.FormatInt(pkg.Stats.Size, 10),
+			strconv.Itoa(pkg.Stats.MergedFiles),
+		)
+	}
+
+	return output.Fields(fields...)
+}
+
+// printTreePorcelain prints a record per component: package, ref, component, version, zone and comma separated nodes.
+// Packages without components are printed with empty component fields.
+func (l *List) printTreePorcelain() {
+	if l.Output.Quiet {
+		return
+	}
+
 	term := l.Term()
+	for _, pkg := range l.result.Packages {
+		if len(pkg.Components) == 0 {
+			term.Printfln("%s", output.Fields(pkg.Name, pkg.Ref, "", "", "", ""))
+			continue
+		}
+		for _, comp := range pkg.Components {
+			term.Printfln("%s", output.Fields(pkg.Name, pkg.Ref, comp.Name, comp.Version, comp.Zone, strings.Join(comp.Nodes, ",")))
+		}
+	}
+}
 
+// buildRelations adds components with their zones and nodes from the graph to listed packages
+func (l *List) buildRelations(g *graph.PlatformGraph) {
 	// Build component→zone map from graph
 	componentToZone := make(map[string]string)
 	for _, n := range g.NodesByType("component") {
@@ -103,85 +261,28 @@ func (l *List) printTreeWithRelations(cfg *compose.Composition) error {
 		sort.Strings(zoneToNodes[k])
 	}
 
-	for pi, dep := range cfg.Dependencies {
-		ref := dep.Source.Ref
-		if ref == "" {
-			ref = "latest"
-		}
-
-		// Print package header
-		term.Printfln("📦 %s@%s", dep.Name, ref)
+	for i := range l.result.Packages {
+		pkg := &l.result.Packages[i]
 
 		// Get components in this package from graph
 		var pkgComponents []string
-		for _, e := range g.EdgesFrom(dep.Name, "contains") {
+		for _, e := range g.EdgesFrom(pkg.Name, "contains") {
 			if e.To().Type == "component" {
 				pkgComponents = append(pkgComponents, e.To().Name)
 			}
 		}
 		sort.Strings(pkgComponents)
 
-		for ci, compName := range pkgComponents {
-			isLastComp := ci == len(pkgComponents)-1
-
-			var compPrefix, compIndent string
-			if isLastComp {
-				compPrefix = "└── "
-				compIndent = "    "
-			} else {
-				compPrefix = "├── "
-				compIndent = "│   "
-			}
-
-			n := g.Node(compName)
-			version := ""
-			if n != nil {
-				version = n.Version
-			}
-			term.Printfln("%s🧩 %s", compPrefix, component.FormatDisplayName(compName, version))
-
-			// Get zone for this component
-			zonePath := componentToZone[compName]
-			nodes := zoneToNodes[zonePath]
-			totalChildren := 0
-			if zonePath != "" {
-				totalChildren++
-			}
-			totalChildren += len(nodes)
-
-			childIdx := 0
-
-			// Print zone
-			if zonePath != "" {
-				childIdx++
-				isLast := childIdx == totalChildren
-				var childPrefix string
-				if isLast {
-					childPrefix = compIndent + "└── "
-				} else {
-					childPrefix = compIndent + "├── "
-				}
-				term.Printfln("%s📍 %s", childPrefix, zonePath)
+		for _, compName := range pkgComponents {
+			item := ComponentListItem{Name: compName}
+			if n := g.Node(compName); n != nil {
+				item.Version = n.Version
 			}
-
-			// Print nodes that serve this zone
-			for _, nd := range nodes {
-				childIdx++
-				isLast := childIdx == totalChildren
-				var childPrefix string
-				if isLast {
-					childPrefix = compIndent + "└── "
-				} else {
-					childPrefix = compIndent + "├── "
-				}
-				term.Printfln("%s🖥  %s", childPrefix, nd)
+			item.Zone = componentToZone[compName]
+			if item.Zone != "" {
+				item.Nodes = zoneToNodes[item.Zone]
 			}
-		}
-
-		if pi < len(cfg.Dependencies)-1 {
-			term.Printfln("")
+			pkg.Components = append(pkg.Components, item)
 		}
 	}
-
-	return nil
 }
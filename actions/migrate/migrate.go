@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// MigrateResult is the structured result of model:migrate.
+type MigrateResult struct {
+	*compose.MigrationPlan
+	Migrated bool `json:"migrated"`
+}
+
+// Migrate implements the model:migrate action
+type Migrate struct {
+	action.WithLogger
+	action.WithTerm
+
+	WorkingDir string
+	// Layout locates directories legacy directories are moved to, empty paths are defaults.
+	Layout model.Layout
+	DryRun bool
+	Force  bool
+
+	result *MigrateResult
+}
+
+// Result returns the structured result for JSON output.
+func (m *Migrate) Result() any {
+	return m.result
+}
+
+// Execute runs the model:migrate action
+func (m *Migrate) Execute() error {
+	if !m.DryRun {
+		unlock, err := compose.LockModel(m.WorkingDir, "model:migrate", false, m.Term(), m.Log())
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	plan, err := compose.PlanMigration(m.WorkingDir, m.Layout.WithDefaults(), m.Force)
+	if err != nil {
+		return err
+	}
+
+	m.result = &MigrateResult{MigrationPlan: plan}
+
+	m.Term().Printfln("%s -> %s", compose.LegacyComposeFile, model.ComposeFile)
+	for _, name := range plan.RefsFromTags {
+		m.Term().Printfln("  %s: tag -> ref", name)
+	}
+	for _, move := range plan.Moves {
+		m.Term().Printfln("%s -> %s", move.From, move.To)
+	}
+
+	if m.DryRun {
+		m.Term().Warning().Println("Dry run - no changes made.")
+		return nil
+	}
+
+	if err = compose.ApplyMigration(m.WorkingDir, plan); err != nil {
+		return err
+	}
+
+	m.result.Migrated = true
+	m.Term().Success().Println("Migration completed.")
+	return nil
+}
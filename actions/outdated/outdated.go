@@ -0,0 +1,119 @@
+package outdated
+
+import (
+	"strconv"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// OutdatedResult is the structured result of model:outdated.
+type OutdatedResult struct {
+	Packages []compose.OutdatedPackage `json:"packages"`
+	Outdated int                       `json:"outdated"`
+}
+
+// Outdated implements the model:outdated action
+type Outdated struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring    keyring.Keyring
+	WorkingDir string
+	// Layout locates downloaded packages, empty paths are defaults.
+	Layout model.Layout
+	Output output.Mode
+
+	result *OutdatedResult
+}
+
+// Result returns the structured result for JSON output.
+func (o *Outdated) Result() any {
+	return o.result
+}
+
+// Execute checks packages of compose.yaml for newer commits and tags
+func (o *Outdated) Execute() error {
+	if err := o.Output.Validate(); err != nil {
+		return err
+	}
+
+	checker := &compose.OutdatedChecker{Keyring: o.Keyring, Layout: o.Layout}
+	checker.SetLogger(o.Log())
+	checker.SetTerm(o.Term())
+
+	packages, err := checker.Check(o.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	o.result = &OutdatedResult{Packages: packages}
+	for _, pkg := range packages {
+		if pkg.IsOutdated() {
+			o.result.Outdated++
+		}
+	}
+
+	switch {
+	case o.Output.Porcelain:
+		for _, pkg := range packages {
+			o.Term().Printfln("%s", porcelainPackage(pkg))
+		}
+	case o.Output.Human():
+		o.printPackages()
+	}
+
+	return nil
+}
+
+func (o *Outdated) printPackages() {
+	term := o.Term()
+	if len(o.result.Packages) == 0 {
+		term.Info().Println("No package dependencies")
+		return
+	}
+
+	arrow := output.Get().Arrow
+	for _, pkg := range o.result.Packages {
+		frozen := ""
+		if pkg.Frozen {
+			frozen = " (frozen)"
+		}
+
+		switch {
+		case pkg.Error != "":
+			term.Warning().Printfln("%s@%s: couldn't check remote: %s", pkg.Name, pkg.Ref, pkg.Error)
+		case pkg.Latest != "" && pkg.Changed:
+			term.Printfln("%s@%s %s %s, new commits at %s%s", pkg.Name, pkg.Ref, arrow, pkg.Latest, pkg.Ref, frozen)
+		case pkg.Latest != "":
+			term.Printfln("%s@%s %s %s%s", pkg.Name, pkg.Ref, arrow, pkg.Latest, frozen)
+		case pkg.Changed:
+			term.Printfln("%s@%s: new commits%s", pkg.Name, pkg.Ref, frozen)
+		case !pkg.Downloaded && pkg.Type == compose.GitType:
+			term.Info().Printfln("%s@%s isn't downloaded, run model:compose to check its commits", pkg.Name, pkg.Ref)
+		}
+	}
+
+	if o.result.Outdated == 0 {
+		term.Success().Println("All packages are up to date")
+		return
+	}
+
+	term.Info().Printfln("%d of %d packages are outdated", o.result.Outdated, len(o.result.Packages))
+}
+
+// porcelainPackage formats a package record: name, ref, newest version tag, new commits, downloaded and frozen flags
+func porcelainPackage(pkg compose.OutdatedPackage) string {
+	return output.Fields(
+		pkg.Name,
+		pkg.Ref,
+		pkg.Latest,
+		strconv.FormatBool(pkg.Changed),
+		strconv.FormatBool(pkg.Downloaded),
+		strconv.FormatBool(pkg.Frozen),
+	)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -15,6 +14,11 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/fsutil"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
 //go:embed templates/*.tmpl
@@ -70,6 +74,13 @@ func (p *Prepare) Result() any {
 
 // Execute runs the model:prepare action
 func (p *Prepare) Execute() error {
+	// Model directories are relative to the working directory
+	unlock, err := compose.LockModel(".", "model:prepare", false, p.Term(), p.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Clean prepare directory if requested
 	if p.Clean {
 		p.Term().Info().Printfln("Cleaning prepare directory: %s", p.PrepareDir)
@@ -95,7 +106,11 @@ func (p *Prepare) Execute() error {
 
 	p.Term().Info().Println("Preparing Ansible runtime...")
 
-	// Structure transformations
+	// Structure transformations, applied ones are recorded in the prepare report
+	var transformations []string
+	if _, err := os.Stat(filepath.Join(p.PrepareDir, "src")); err == nil {
+		transformations = append(transformations, "flattened src/")
+	}
 	if err := p.flattenSrcDirectory(); err != nil {
 		return err
 	}
@@ -106,40 +121,52 @@ func (p *Prepare) Execute() error {
 	if err != nil {
 		return err
 	}
-	p.Term().Info().Printfln("  ✓ Moved %d components to roles/", componentsMoved)
+	p.Term().Info().Printfln("  %s Moved %d components to roles/", output.Get().Check, componentsMoved)
+	transformations = append(transformations, fmt.Sprintf("moved %d components to roles/", componentsMoved))
 
 	layersRenamed, err := p.renameVariablesToGroupVars()
 	if err != nil {
 		return err
 	}
-	p.Term().Info().Printfln("  ✓ Renamed variables/ to group_vars/ in %d layers", layersRenamed)
+	p.Term().Info().Printfln("  %s Renamed variables/ to group_vars/ in %d layers", output.Get().Check, layersRenamed)
+	transformations = append(transformations, fmt.Sprintf("renamed variables/ to group_vars/ in %d layers", layersRenamed))
 
 	galaxyCount, err := p.generateGalaxyFiles()
 	if err != nil {
 		return err
 	}
-	p.Term().Info().Printfln("  ✓ Generated %d galaxy.yml files", galaxyCount)
+	p.Term().Info().Printfln("  %s Generated %d galaxy.yml files", output.Get().Check, galaxyCount)
+	transformations = append(transformations, fmt.Sprintf("generated %d galaxy.yml files", galaxyCount))
 
 	symlinksCreated, err := p.createPlatformSymlinks()
 	if err != nil {
 		return err
 	}
-	p.Term().Info().Printfln("  ✓ Created %d platform symlinks", symlinksCreated)
+	p.Term().Info().Printfln("  %s Created %d platform symlinks", output.Get().Check, symlinksCreated)
+	transformations = append(transformations, fmt.Sprintf("created %d platform symlinks", symlinksCreated))
 
 	if err := p.createAnsibleCfg(); err != nil {
 		return err
 	}
-	p.Term().Info().Println("  ✓ Created ansible.cfg")
+	p.Term().Info().Printfln("  %s Created ansible.cfg", output.Get().Check)
+	transformations = append(transformations, "created ansible.cfg")
 
 	if err := p.createAnsibleCollectionsSymlink(); err != nil {
 		return err
 	}
+	transformations = append(transformations, "linked ansible_collections")
 
 	// Copy library if it exists in compose output
 	if err := p.copyLibrary(); err != nil {
 		p.Term().Warning().Printfln("  ! Library not copied: %v", err)
 	} else {
-		p.Term().Info().Println("  ✓ Copied library/")
+		p.Term().Info().Printfln("  %s Copied library/", output.Get().Check)
+		transformations = append(transformations, "copied library/")
+	}
+
+	report := &model.PrepareReport{Transformations: transformations, Layers: p.layers}
+	if err := model.SavePrepareReport(p.PrepareDir, report); err != nil {
+		return fmt.Errorf("failed to save prepare report: %w", err)
 	}
 
 	p.result = &PrepareResult{
@@ -219,7 +246,7 @@ func (p *Prepare) flattenSrcDirectory() error {
 	if err := os.Remove(srcDir); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove src/ directory: %w", err)
 	}
-	p.Term().Info().Println("  ✓ Flattened src/")
+	p.Term().Info().Printfln("  %s Flattened src/", output.Get().Check)
 	return nil
 }
 
@@ -464,7 +491,7 @@ func (p *Prepare) createPlatformSymlinks() (int, error) {
 			continue // Already exists
 		}
 
-		if err := os.Symlink("../../platform/group_vars/platform", platformLink); err != nil {
+		if err := os.Symlink(filepath.FromSlash("../../platform/group_vars/platform"), platformLink); err != nil {
 			return count, err
 		}
 		count++
@@ -574,23 +601,11 @@ func (p *Prepare) generateGalaxyFiles() (int, error) {
 
 // copyFile copies a file from src to dst
 func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	srcInfo, err := srcFile.Stat()
-	if err != nil {
-		return err
-	}
-
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, srcInfo.Mode())
+	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
+	_, err = fsutil.CopyFile(src, dst, srcInfo.Mode())
 	return err
 }
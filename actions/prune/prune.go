@@ -0,0 +1,82 @@
+package prune
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+)
+
+// PruneResult is the structured result of model:prune.
+type PruneResult struct {
+	Packages []compose.StalePackage `json:"packages"`
+	Removed  bool                   `json:"removed"`
+}
+
+// Prune implements the model:prune action
+type Prune struct {
+	action.WithLogger
+	action.WithTerm
+
+	BaseDir    string
+	WorkingDir string
+	DryRun     bool
+	Wait       bool
+
+	result *PruneResult
+}
+
+// Result returns the structured result for JSON output.
+func (p *Prune) Result() any {
+	return p.result
+}
+
+// Execute runs the model:prune action
+func (p *Prune) Execute() error {
+	unlock, err := compose.LockModel(p.BaseDir, "model:prune", p.Wait, p.Term(), p.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := compose.Lookup(os.DirFS(p.BaseDir))
+	if err != nil {
+		return err
+	}
+
+	versionLock, err := compose.LoadVersionLock(p.BaseDir)
+	if err != nil {
+		return err
+	}
+
+	packagesDir := filepath.Join(p.BaseDir, p.WorkingDir)
+	stale, err := compose.FindStalePackages(cfg, versionLock, packagesDir)
+	if err != nil {
+		return err
+	}
+
+	p.result = &PruneResult{Packages: stale}
+	if len(stale) == 0 {
+		p.Term().Info().Println("No stale packages found")
+		return nil
+	}
+
+	for _, sp := range stale {
+		p.Term().Printfln("%s", sp.Path)
+	}
+
+	if p.DryRun {
+		p.Term().Warning().Printfln("Dry run - %d stale package(s) kept.", len(stale))
+		return nil
+	}
+
+	if err = compose.PrunePackages(stale, packagesDir); err != nil {
+		return err
+	}
+
+	p.result.Removed = true
+	p.Term().Success().Printfln("Removed %d stale package(s).", len(stale))
+	return nil
+}
@@ -3,17 +3,66 @@ package query
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/output"
 	"github.com/plasmash/plasmactl-model/pkg/model"
 	"github.com/plasmash/plasmactl-platform/pkg/graph"
 )
 
 // QueryResult is the structured output for model:query
 type QueryResult struct {
-	Packages []string `json:"packages"`
+	Packages []string       `json:"packages"`
+	Matches  []PackageMatch `json:"matches"`
+}
+
+// PackageMatch stores details of a package matching the query
+type PackageMatch struct {
+	Name string `json:"name"`
+	// Ref is the ref locked in compose.lock, Requested is the ref of compose.yaml if it differs, e.g. a range.
+	Ref        string              `json:"ref"`
+	Requested  string              `json:"requested,omitempty"`
+	URL        string              `json:"url,omitempty"`
+	SHA        string              `json:"sha,omitempty"`
+	Path       string              `json:"path,omitempty"`
+	Provider   string              `json:"provider"` // "package" for dependencies, "model" for local components
+	Components []string            `json:"components"`
+	Provides   []ProvidedComponent `json:"provides"`
+}
+
+// ProvidedComponent is a matching component provided by a package
+type ProvidedComponent struct {
+	Name  string `json:"name"`
+	Layer string `json:"layer,omitempty"`
+	Kind  string `json:"kind,omitempty"`
+}
+
+// newProvidedComponent returns layer and kind of a component named {layer}.{kind}.{name}
+func newProvidedComponent(name string) ProvidedComponent {
+	c := ProvidedComponent{Name: name}
+	if parts := strings.Split(name, "."); len(parts) >= 3 {
+		c.Layer, c.Kind = parts[0], parts[1]
+	}
+
+	return c
+}
+
+// match is a package providing a component
+type match struct {
+	name      string
+	ref       string
+	provider  string
+	component string
+}
+
+func (m match) String() string {
+	return fmt.Sprintf("%s@%s", m.name, m.ref)
 }
 
 // Query implements the model:query action
@@ -22,35 +71,49 @@ type Query struct {
 	action.WithTerm
 
 	WorkingDir string
+	// Layout locates downloaded packages, empty paths are defaults.
+	Layout     model.Layout
 	Identifier string
 	Kind       string // "component", "zone", or "node" to skip auto-detection
+	Output     output.Mode
 
 	result QueryResult
 }
 
 // Execute runs the model:query action
 func (q *Query) Execute() error {
+	if err := q.Output.Validate(); err != nil {
+		return err
+	}
+
 	cfg, err := model.Lookup(os.DirFS(q.WorkingDir))
 	if err != nil {
 		return fmt.Errorf("model.yaml not found: %w", err)
 	}
 
+	versionLock, err := compose.LoadVersionLock(q.WorkingDir)
+	if err != nil {
+		return err
+	}
+
 	g, err := graph.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load graph: %w", err)
 	}
 
-	// Build package name → ref map from config
+	// Build package name → locked ref map from config
 	pkgRefs := make(map[string]string)
+	deps := make(map[string]model.Dependency)
 	for _, dep := range cfg.Dependencies {
-		ref := dep.Source.Ref
+		ref := versionLock.Ref(dep)
 		if ref == "" {
-			ref = "latest"
+			ref = model.TargetLatest
 		}
 		pkgRefs[dep.Name] = ref
+		deps[dep.Name] = dep
 	}
 
-	var found []string
+	var found []match
 
 	// Search based on kind or auto-detect
 	switch q.Kind {
@@ -72,29 +135,101 @@ func (q *Query) Execute() error {
 	}
 
 	if len(found) == 0 {
-		q.Term().Warning().Printfln("No packages found for %q", q.Identifier)
+		if q.Output.Human() {
+			q.Term().Warning().Printfln("No packages found for %q", q.Identifier)
+		}
+		return nil
+	}
+
+	checkouts := compose.LocateCheckouts(cfg, versionLock, filepath.Join(q.WorkingDir, q.Layout.WithDefaults().PackagesDir))
+	q.collectMatches(found, deps, versionLock, checkouts)
+
+	if q.Output.Quiet {
 		return nil
 	}
 
-	// Remove duplicates and sort
-	seen := make(map[string]bool)
+	term := q.Term()
+	for i, pkg := range q.result.Packages {
+		if q.Output.Porcelain {
+			// name, ref, provider, HEAD commit and comma separated components
+			pm := q.result.Matches[i]
+			term.Printfln("%s", output.Fields(pm.Name, pm.Ref, pm.Provider, pm.SHA, strings.Join(pm.Components, ",")))
+			continue
+		}
+		term.Printfln("%s", pkg)
+	}
+
+	return nil
+}
+
+// collectMatches removes duplicates of found packages, sorts them and stores their details in the result
+func (q *Query) collectMatches(found []match, deps map[string]model.Dependency, lock *compose.VersionLock, checkouts *compose.Checkouts) {
+	seen := make(map[string]*PackageMatch)
 	var unique []string
-	for _, pkg := range found {
-		if !seen[pkg] {
-			seen[pkg] = true
-			unique = append(unique, pkg)
+	for _, m := range found {
+		pm, ok := seen[m.String()]
+		if !ok {
+			pm = q.buildMatch(m, deps, lock, checkouts)
+			seen[m.String()] = pm
+			unique = append(unique, m.String())
+		}
+		if !slices.Contains(pm.Components, m.component) {
+			pm.Components = append(pm.Components, m.component)
 		}
 	}
 	sort.Strings(unique)
 
 	q.result.Packages = unique
+	for _, id := range unique {
+		pm := seen[id]
+		sort.Strings(pm.Components)
+		for _, c := range pm.Components {
+			pm.Provides = append(pm.Provides, newProvidedComponent(c))
+		}
+		q.result.Matches = append(q.result.Matches, *pm)
+	}
+}
 
-	term := q.Term()
-	for _, pkg := range unique {
-		term.Printfln("%s", pkg)
+// buildMatch resolves source and local checkout details of a matched package, packages are reported
+// at refs locked in compose.lock
+func (q *Query) buildMatch(m match, deps map[string]model.Dependency, lock *compose.VersionLock, checkouts *compose.Checkouts) *PackageMatch {
+	pm := &PackageMatch{Name: m.name, Ref: m.ref, Provider: m.provider}
+
+	// Monorepo packages are a subpath of their checkout, which may be shared with other packages
+	path, checkout := q.WorkingDir, q.WorkingDir
+	if dep, ok := deps[m.name]; ok && m.provider == "package" {
+		pkg := dep.ToPackage(dep.Name)
+		pkg.Source.Ref = lock.Ref(dep)
+		pm.Ref = pkg.GetTarget()
+		if dep.Source.Ref != pkg.Source.Ref {
+			pm.Requested = dep.Source.Ref
+		}
+		pm.URL = pkg.GetURL()
+		path = checkouts.PackageDir(pkg)
+		checkout = checkouts.CheckoutDir(pkg)
 	}
 
-	return nil
+	if _, err := os.Stat(path); err == nil {
+		pm.Path = path
+		pm.SHA = headSHA(checkout)
+	}
+
+	return pm
+}
+
+// headSHA returns HEAD commit of a git checkout, empty if path is not a git repository
+func headSHA(path string) string {
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return ""
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return ""
+	}
+
+	return head.Hash().String()
 }
 
 // Result returns the structured result for JSON output
@@ -105,23 +240,23 @@ func (q *Query) Result() any {
 // queryByComponent finds packages that provide a specific component.
 // Both "package" nodes (external deps) and "model" nodes (local root) use
 // contains edges — model ⊃ package, so both are valid answers.
-func (q *Query) queryByComponent(g *graph.PlatformGraph, pkgRefs map[string]string, componentName string) []string {
-	var found []string
+func (q *Query) queryByComponent(g *graph.PlatformGraph, pkgRefs map[string]string, componentName string) []match {
+	var found []match
 	for _, e := range g.EdgesTo(componentName, "contains") {
 		switch e.From().Type {
 		case "package":
 			if ref, ok := pkgRefs[e.From().Name]; ok {
-				found = append(found, fmt.Sprintf("%s@%s", e.From().Name, ref))
+				found = append(found, match{e.From().Name, ref, "package", componentName})
 			}
 		case "model":
-			found = append(found, fmt.Sprintf("%s@%s", e.From().Name, e.From().Version))
+			found = append(found, match{e.From().Name, e.From().Version, "model", componentName})
 		}
 	}
 	return found
 }
 
 // queryByZone finds packages with components attached to a zone
-func (q *Query) queryByZone(g *graph.PlatformGraph, pkgRefs map[string]string, zonePath string) []string {
+func (q *Query) queryByZone(g *graph.PlatformGraph, pkgRefs map[string]string, zonePath string) []match {
 	// Find components attached to this zone or descendant zones
 	var componentNames []string
 	for _, n := range g.NodesByType("component") {
@@ -134,7 +269,7 @@ func (q *Query) queryByZone(g *graph.PlatformGraph, pkgRefs map[string]string, z
 	}
 
 	// Find packages that provide these components
-	var found []string
+	var found []match
 	for _, compName := range componentNames {
 		found = append(found, q.queryByComponent(g, pkgRefs, compName)...)
 	}
@@ -142,7 +277,7 @@ func (q *Query) queryByZone(g *graph.PlatformGraph, pkgRefs map[string]string, z
 }
 
 // queryByNode finds packages with components running on a node
-func (q *Query) queryByNode(g *graph.PlatformGraph, pkgRefs map[string]string, hostname string) []string {
+func (q *Query) queryByNode(g *graph.PlatformGraph, pkgRefs map[string]string, hostname string) []match {
 	nodeNode := g.Node(hostname)
 	if nodeNode == nil || nodeNode.Type != "node" {
 		return nil
@@ -165,7 +300,7 @@ func (q *Query) queryByNode(g *graph.PlatformGraph, pkgRefs map[string]string, h
 	}
 
 	// Find packages that provide these components
-	var found []string
+	var found []match
 	for _, compName := range componentNames {
 		found = append(found, q.queryByComponent(g, pkgRefs, compName)...)
 	}
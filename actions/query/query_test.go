@@ -0,0 +1,60 @@
+package query
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestCollectMatchesJSON(t *testing.T) {
+	dir := t.TempDir()
+	packagesDir := filepath.Join(dir, model.PackagesDir)
+	if err := os.MkdirAll(filepath.Join(packagesDir, "core", "1.4.1"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &model.Composition{Dependencies: []model.Dependency{
+		{Name: "core", Source: model.Source{Type: "git", URL: "https://example.com/core.git", Ref: "^1.2"}},
+	}}
+	lock := &compose.VersionLock{Packages: []compose.LockedPackage{{Name: "core", Ref: "1.4.1"}}}
+	deps := map[string]model.Dependency{"core": cfg.Dependencies[0]}
+
+	q := &Query{WorkingDir: dir}
+	q.collectMatches([]match{
+		{"core", "1.4.1", "package", "platform.services.nginx"},
+		{"core", "1.4.1", "package", "interaction.applications.im"},
+		{"platform", "v1.0.0", "model", "platform.services.nginx"},
+	}, deps, lock, compose.LocateCheckouts(cfg, lock, packagesDir))
+
+	data, err := json.Marshal(q.Result())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"packages":["core@1.4.1","platform@v1.0.0"],"matches":[` +
+		`{"name":"core","ref":"1.4.1","requested":"^1.2","url":"https://example.com/core.git",` +
+		`"path":` + jsonString(t, filepath.Join(packagesDir, "core", "1.4.1")) + `,"provider":"package",` +
+		`"components":["interaction.applications.im","platform.services.nginx"],` +
+		`"provides":[{"name":"interaction.applications.im","layer":"interaction","kind":"applications"},` +
+		`{"name":"platform.services.nginx","layer":"platform","kind":"services"}]},` +
+		`{"name":"platform","ref":"v1.0.0","path":` + jsonString(t, dir) + `,"provider":"model",` +
+		`"components":["platform.services.nginx"],` +
+		`"provides":[{"name":"platform.services.nginx","layer":"platform","kind":"services"}]}]}`
+	if string(data) != expected {
+		t.Errorf("unexpected result:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
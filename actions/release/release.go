@@ -1,16 +1,29 @@
 package release
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/actions/bundle"
+	"github.com/plasmash/plasmactl-model/actions/prepare"
+	"github.com/plasmash/plasmactl-model/internal/auth"
 	irelease "github.com/plasmash/plasmactl-model/internal/release"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
-const imageDir = "img"
+var (
+	errConflictingNotes = errors.New("--notes and --notes-file can't be used together")
+	errEmptyNotes       = errors.New("release notes are empty")
+	errStaleBundle      = errors.New("bundle doesn't match the released commit")
+	errTagCollision     = errors.New("tag already exists on origin")
+	errReleaseCollision = errors.New("release already exists")
+)
 
 // ReleaseResult is the structured result of model:release.
 type ReleaseResult struct {
@@ -18,7 +31,17 @@ type ReleaseResult struct {
 	DryRun    bool   `json:"dry_run"`
 	TagOnly   bool   `json:"tag_only"`
 	ReleaseID string `json:"release_id,omitempty"`
+	URL       string `json:"url,omitempty"`
 	Asset     string `json:"asset,omitempty"`
+	AssetName string `json:"asset_name,omitempty"`
+	AssetURL  string `json:"asset_url,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+	// BundleBuilt is set when the asset was built by --build-bundle.
+	BundleBuilt bool `json:"bundle_built,omitempty"`
+	// ForgeChecked is set when a dry run validated forge access with --check-forge.
+	ForgeChecked bool `json:"forge_checked,omitempty"`
+	// Signature is the verified signature of the asset uploaded next to it, with --verify-signature.
+	Signature string `json:"signature,omitempty"`
 }
 
 // Release implements the model:release command
@@ -26,14 +49,35 @@ type Release struct {
 	action.WithLogger
 	action.WithTerm
 
-	Keyring  keyring.Keyring
-	Version  string
-	DryRun   bool
-	TagOnly  bool
-	ForgeURL string
-	Token    string
-
-	result *ReleaseResult
+	Keyring          keyring.Keyring
+	Version          string
+	DryRun           bool
+	CheckForge       bool
+	TagOnly          bool
+	Forge            string
+	ForgeURL         string
+	Token            string
+	RollbackTag      bool
+	Local            bool
+	Since            string
+	Notes            string
+	NotesFile        string
+	DigestNames      bool
+	AllowStaleBundle bool
+	BuildBundle      bool
+	GitLabAssets     string
+	VerifySignature  bool
+	VerifyKey        string
+	VerifyMethod     string
+	// Layout locates the bundle directory and the directories of --build-bundle, empty paths are defaults.
+	Layout model.Layout
+
+	// existingTag is set when the release is created for a tag pushed before, the tag is never rolled back.
+	existingTag bool
+	// needsBundle is set by --build-bundle without an up-to-date Platform Model, bundlePath is the built one.
+	needsBundle bool
+	bundlePath  string
+	result      *ReleaseResult
 }
 
 // Result returns the structured result for JSON output.
@@ -84,20 +128,29 @@ func (r *Release) Execute() error {
 		r.Term().Info().Printfln("Latest tag: %s", latestTag)
 	}
 
-	// Generate changelog
-	changelogGen, err := irelease.NewChangelogGenerator(workDir)
+	notes, err := r.readNotes()
 	if err != nil {
 		return err
 	}
 
-	changelog, err := changelogGen.Generate(latestTag)
-	if err != nil {
-		return fmt.Errorf("failed to generate changelog: %w", err)
+	// Release of an existing tag reuses its annotation
+	if r.Version != "" && !irelease.IsBumpType(r.Version) {
+		v, errParse := irelease.ParseVersion(r.Version)
+		if errParse != nil {
+			return fmt.Errorf("invalid version %q: %w", r.Version, errParse)
+		}
+		if gitOps.TagExists(v.String()) {
+			return r.releaseExistingTag(gitOps, workDir, v.String(), notes)
+		}
 	}
 
-	if changelog == "" && latestTag != "" {
-		r.Term().Info().Printfln("No changes since %s. Nothing to release.", latestTag)
-		return nil
+	changelog := notes
+	if changelog == "" {
+		var nothing bool
+		changelog, nothing, err = r.generateChangelog(workDir, latestTag)
+		if err != nil || nothing {
+			return err
+		}
 	}
 
 	r.Term().Println()
@@ -132,18 +185,29 @@ func (r *Release) Execute() error {
 	newTag := newVersion.String()
 	r.Term().Info().Printfln("New version: %s", newTag)
 
+	if err = r.checkPolicy(gitOps, workDir, newTag); err != nil {
+		return err
+	}
+
+	if err = r.verifyBundle(gitOps, "HEAD"); err != nil {
+		return err
+	}
+
 	// Dry run - stop here
 	if r.DryRun {
 		r.result = &ReleaseResult{Tag: newTag, DryRun: true, TagOnly: r.TagOnly}
 		r.Term().Println()
 		r.Term().Warning().Println("Dry run - no changes made.")
 		r.Term().Info().Printfln("Would create tag: %s", newTag)
-		if r.TagOnly {
+		switch {
+		case r.TagOnly:
 			r.Term().Info().Println("Would push tag only (no forge release)")
-		} else {
+		case r.needsBundle:
+			r.Term().Info().Println("Would build .pm with model:prepare and model:bundle, create forge release and upload it")
+		default:
 			r.Term().Info().Println("Would create forge release and upload .pm")
 		}
-		return nil
+		return r.checkForge(gitOps, workDir, newTag)
 	}
 
 	// Create and push tag
@@ -167,10 +231,382 @@ func (r *Release) Execute() error {
 		return nil
 	}
 
+	return r.publish(gitOps, workDir, newTag, changelog)
+}
+
+// readNotes returns release notes given by --notes or --notes-file, empty string if none is given
+func (r *Release) readNotes() (string, error) {
+	if r.Notes != "" && r.NotesFile != "" {
+		return "", errConflictingNotes
+	}
+
+	notes := r.Notes
+	if r.NotesFile != "" {
+		data, err := os.ReadFile(r.NotesFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read release notes: %w", err)
+		}
+		notes = string(data)
+	}
+
+	notes = strings.TrimSpace(notes)
+	if notes == "" && (r.Notes != "" || r.NotesFile != "") {
+		return "", errEmptyNotes
+	}
+
+	return notes, nil
+}
+
+// generateChangelog generates the changelog from conventional commits since latestTag,
+// nothing is true if there are no changes to release
+func (r *Release) generateChangelog(workDir, latestTag string) (string, bool, error) {
+	changelogGen, err := irelease.NewChangelogGenerator(workDir)
+	if err != nil {
+		return "", false, err
+	}
+
+	if err = changelogGen.SetSince(r.Since); err != nil {
+		return "", false, err
+	}
+
+	changelog, err := changelogGen.Generate(latestTag)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to generate changelog: %w", err)
+	}
+
+	if changelogGen.Truncated() {
+		r.Term().Warning().Println("Git history is shallow, the changelog starts at the oldest fetched commit. Fetch more history or use --since.")
+	}
+
+	if changelog == "" && (latestTag != "" || r.Since != "") {
+		since := latestTag
+		if since == "" {
+			since = r.Since
+		}
+		r.Term().Info().Printfln("No changes since %s. Nothing to release.", since)
+		return "", true, nil
+	}
+
+	return changelog, false, nil
+}
+
+// releaseExistingTag creates the forge release of a tag pushed before, e.g. by CI,
+// the message of the annotated tag is used as the release body instead of a regenerated changelog, unless notes are given.
+func (r *Release) releaseExistingTag(gitOps *irelease.GitOps, workDir, tag, notes string) error {
+	r.existingTag = true
+	if err := r.checkPolicy(gitOps, workDir, tag); err != nil {
+		return err
+	}
+
+	if err := r.verifyBundle(gitOps, tag); err != nil {
+		return err
+	}
+	if r.needsBundle {
+		// Bundles are built from HEAD
+		head, errHead := gitOps.ResolveCommit("HEAD")
+		tagCommit, errTag := gitOps.ResolveCommit(tag)
+		if err := errors.Join(errHead, errTag); err != nil {
+			return err
+		}
+		if head != tagCommit {
+			return fmt.Errorf("%w: HEAD isn't at %s, check it out to build its bundle", errStaleBundle, tag)
+		}
+	}
+
+	body := notes
+	if body == "" {
+		r.Term().Info().Printfln("Tag %s exists, creating its release from the tag annotation.", tag)
+		var err error
+		if body, err = gitOps.TagMessage(tag); err != nil {
+			return err
+		}
+	} else {
+		r.Term().Info().Printfln("Tag %s exists, creating its release with given notes.", tag)
+	}
+
+	r.Term().Println()
+	r.Term().Println(body)
+	r.Term().Println()
+
+	if r.DryRun {
+		r.result = &ReleaseResult{Tag: tag, DryRun: true, TagOnly: r.TagOnly}
+		r.Term().Warning().Println("Dry run - no changes made.")
+		if !r.TagOnly {
+			r.Term().Info().Printfln("Would create forge release of existing tag %s and upload .pm", tag)
+		}
+		return r.checkForge(gitOps, workDir, tag)
+	}
+
+	if r.TagOnly {
+		r.result = &ReleaseResult{Tag: tag, TagOnly: true}
+		r.Term().Success().Printfln("Tag %s already exists, nothing to do.", tag)
+		return nil
+	}
+
+	return r.publish(gitOps, workDir, tag, body)
+}
+
+// checkPolicy checks the release policy of compose.yaml, violations are only reported on dry run
+func (r *Release) checkPolicy(gitOps *irelease.GitOps, workDir, tag string) error {
+	cfg, err := model.Lookup(os.DirFS(workDir))
+	if err != nil && !errors.Is(err, model.ErrComposeNotExists) {
+		return err
+	}
+
+	if ci := irelease.DetectCI(); ci != "" {
+		r.Log().Debug("running in CI", "ci", ci)
+	}
+
+	err = irelease.CheckPolicy(cfg.Release, tag, r.Local, gitOps)
+	if err != nil && r.DryRun {
+		r.Term().Warning().Printfln("Release policy: %v", err)
+		return nil
+	}
+
+	return err
+}
+
+// verifyBundle checks the Platform Model was built from the released commit rev, bundles without build commit
+// can't be verified. Mismatches fail unless --allow-stale-bundle is set, they are only reported on dry run.
+// With --build-bundle, a missing or not up-to-date bundle is built after the tag is pushed instead.
+func (r *Release) verifyBundle(gitOps *irelease.GitOps, rev string) error {
+	if r.TagOnly {
+		return nil
+	}
+
+	image := findImage(r.Layout.WithDefaults().BundleDir)
+	if image == "" {
+		r.needsBundle = r.BuildBundle
+		return nil
+	}
+
+	f, err := os.Open(filepath.Clean(image))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := model.ReadBundleManifest(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", image, err)
+	}
+	if (manifest == nil || manifest.Commit == "") && r.BuildBundle {
+		r.needsBundle = true
+		return nil
+	}
+	if manifest == nil || manifest.Commit == "" {
+		r.Term().Warning().Printfln("%s has no build commit, can't verify it was built from the released commit. Rebuild it with model:bundle.", image)
+		return nil
+	}
+
+	commit, err := gitOps.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	if manifest.Commit == commit {
+		return nil
+	}
+	if r.BuildBundle {
+		r.Term().Info().Printfln("%s was built from %s, a new bundle will be built.", image, shortCommit(manifest.Commit))
+		r.needsBundle = true
+		return nil
+	}
+
+	err = fmt.Errorf("%w: %s was built from %s, the release is at %s, rebuild it with model:bundle or use --allow-stale-bundle",
+		errStaleBundle, image, shortCommit(manifest.Commit), shortCommit(commit))
+	if r.AllowStaleBundle || r.DryRun {
+		r.Term().Warning().Printfln("%v", err)
+		return nil
+	}
+
+	return err
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// publish creates the forge release of the pushed tag with changelog as its body and uploads the Platform Model
+func (r *Release) publish(gitOps *irelease.GitOps, workDir, newTag, changelog string) error {
+	if r.needsBundle {
+		if err := r.buildBundle(); err != nil {
+			r.rollbackTag(gitOps, newTag)
+			return fmt.Errorf("failed to build bundle: %w", err)
+		}
+	}
+
+	forge, err := r.connectForge(gitOps, workDir)
+	if err != nil {
+		return err
+	}
+	if err = forge.SetGitLabAssets(r.GitLabAssets); err != nil {
+		r.rollbackTag(gitOps, newTag)
+		return err
+	}
+
+	// Fail on missing permissions before a release is created
+	if err = forge.CheckPermissions(); err != nil {
+		r.rollbackTag(gitOps, newTag)
+		return err
+	}
+
+	// Find Platform Model (.pm) file, built by --build-bundle or found in the bundle directory
+	bundleDir := r.Layout.WithDefaults().BundleDir
+	image := r.bundlePath
+	if image == "" {
+		image = findImage(bundleDir)
+	}
+
+	// Signed bundles are verified before anything is published, signature files are uploaded next to the asset
+	verification, err := r.verifySignature(image)
+	if err != nil {
+		r.rollbackTag(gitOps, newTag)
+		return err
+	}
+
+	// Digest of the asset is recorded in the release body and embedded in its name
+	body := changelog
+	var asset irelease.AssetDigest
+	if image != "" {
+		asset.Name = filepath.Base(image)
+		if r.DigestNames {
+			asset.SHA256, err = irelease.DigestFile(image)
+			if err != nil {
+				r.rollbackTag(gitOps, newTag)
+				return fmt.Errorf("failed to compute digest of %s: %w", image, err)
+			}
+			asset.Name = irelease.DigestAssetName(asset.Name, asset.SHA256)
+			body = strings.TrimSpace(changelog + "\n\n" + irelease.FormatAssetDigests([]irelease.AssetDigest{asset}))
+		}
+	}
+
+	// Create release, as a draft if there is an asset to upload, so it is published only when complete
+	r.Term().Println()
+	draft := image != ""
+	releaseInfo, err := forge.CreateRelease(newTag, body, draft)
+	if err != nil {
+		r.rollbackTag(gitOps, newTag)
+		return fmt.Errorf("failed to create release: %w", err)
+	}
+
+	r.Term().Success().Printfln("Release created (ID: %s)", releaseInfo.ID)
+	if releaseInfo.URL != "" && !draft {
+		r.Term().Info().Printfln("Release URL: %s", releaseInfo.URL)
+	}
+
+	if image == "" {
+		r.result = &ReleaseResult{Tag: newTag, ReleaseID: releaseInfo.ID, URL: releaseInfo.URL}
+		r.Term().Println()
+		r.Term().Warning().Printfln("No Platform Model (.pm) found in %s - skipping artifact upload.", bundleDir)
+		r.Term().Println()
+		r.Term().Success().Printfln("Release %s created successfully.", newTag)
+		return nil
+	}
+
+	r.Term().Println()
+	r.Term().Info().Printfln("Uploading Platform Model: %s as %s", image, asset.Name)
+
+	assetURL, err := forge.UploadAssetAs(releaseInfo.ID, image, asset.Name)
+	if err != nil {
+		r.rollbackRelease(forge, gitOps, releaseInfo.ID, newTag)
+		return fmt.Errorf("failed to upload asset: %w", err)
+	}
+
+	if err = r.uploadSignature(forge, releaseInfo.ID, asset.Name, verification); err != nil {
+		r.rollbackRelease(forge, gitOps, releaseInfo.ID, newTag)
+		return err
+	}
+
+	published, err := forge.PublishRelease(releaseInfo.ID)
+	if err != nil {
+		r.rollbackRelease(forge, gitOps, releaseInfo.ID, newTag)
+		return fmt.Errorf("failed to publish release: %w", err)
+	}
+	if published.URL != "" {
+		releaseInfo.URL = published.URL
+	}
+	// Download URLs of draft assets change with publishing
+	if published.Assets[asset.Name] != "" {
+		assetURL = published.Assets[asset.Name]
+	}
+
+	if releaseInfo.URL != "" {
+		r.Term().Info().Printfln("Release URL: %s", releaseInfo.URL)
+	}
+	if assetURL != "" {
+		r.Term().Info().Printfln("Asset URL: %s", assetURL)
+	}
+
+	r.result = &ReleaseResult{
+		Tag:         newTag,
+		ReleaseID:   releaseInfo.ID,
+		URL:         releaseInfo.URL,
+		Asset:       image,
+		AssetName:   asset.Name,
+		AssetURL:    assetURL,
+		SHA256:      asset.SHA256,
+		BundleBuilt: r.bundlePath != "",
+	}
+	if verification != nil {
+		r.result.Signature = verification.Signature
+	}
+
+	r.Term().Println()
+	r.Term().Success().Printfln("Release %s created successfully with Platform Model!", newTag)
+
+	return nil
+}
+
+// verifySignature verifies the signature of the Platform Model with --verify-signature, unsigned bundles fail
+func (r *Release) verifySignature(image string) (*irelease.BundleVerification, error) {
+	if !r.VerifySignature || image == "" {
+		return nil, nil
+	}
+
+	verification, err := irelease.VerifyBundle(r.VerifyMethod, r.VerifyKey, image, "")
+	if err != nil {
+		return nil, err
+	}
+	r.Term().Success().Printfln("Signature %s of %s verified with %s", verification.Signature, image, verification.Method)
+
+	return verification, nil
+}
+
+// uploadSignature uploads the verified signature and attestation named after the uploaded asset
+func (r *Release) uploadSignature(forge *irelease.Forge, releaseID, assetName string, verification *irelease.BundleVerification) error {
+	if verification == nil {
+		return nil
+	}
+
+	files := [][2]string{{verification.Signature, assetName + filepath.Ext(verification.Signature)}}
+	if verification.Attestation != "" {
+		attestationName := assetName + irelease.AttestationSuffix
+		attestationSig := irelease.SignatureFile(verification.Method, verification.Attestation)
+		files = append(files,
+			[2]string{verification.Attestation, attestationName},
+			[2]string{attestationSig, attestationName + filepath.Ext(attestationSig)},
+		)
+	}
+
+	for _, f := range files {
+		r.Term().Info().Printfln("Uploading %s as %s", f[0], f[1])
+		if _, err := forge.UploadAssetAs(releaseID, f[0], f[1]); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", f[0], err)
+		}
+	}
+
+	return nil
+}
+
+// connectForge detects the forge of origin and returns its client with the resolved API token
+func (r *Release) connectForge(gitOps *irelease.GitOps, workDir string) (*irelease.Forge, error) {
 	// Get remote info
 	remoteInfo, err := gitOps.GetRemoteInfo()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	r.Term().Println()
@@ -179,21 +615,31 @@ func (r *Release) Execute() error {
 	// Create forge client
 	forge := irelease.NewForge(remoteInfo.Host, remoteInfo.Repo, r.Token)
 
-	forgeType, err := forge.DetectType()
+	forgeType, err := r.detectForge(forge)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	r.Term().Info().Printfln("Detected forge: %s", forgeType)
+	if v := forge.EnterpriseVersion(); v != "" {
+		r.Term().Info().Printfln("Detected forge: %s (Enterprise Server %s)", forgeType, v)
+	} else {
+		r.Term().Info().Printfln("Detected forge: %s", forgeType)
+	}
 
 	// Resolve token
-	token := irelease.ResolveToken(r.Token, forgeType)
+	authConfig, err := auth.Load(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	token := irelease.ResolveToken(r.Token, forgeType, authConfig.Match(remoteInfo.Host))
 	if token == "" {
 		r.Term().Println()
 		r.Term().Error().Printfln("No API token available for %s", forgeType)
 		r.Term().Println()
 		r.Term().Println("Provide a token via one of:")
 		r.Term().Println("  --token <token>")
+		r.Term().Printfln("  token method for %s in %s", remoteInfo.Host, model.AuthFile)
 		switch forgeType {
 		case irelease.ForgeGitHub:
 			r.Term().Println("  GITHUB_TOKEN environment variable")
@@ -201,46 +647,130 @@ func (r *Release) Execute() error {
 			r.Term().Println("  GITLAB_TOKEN environment variable")
 		case irelease.ForgeGitea, irelease.ForgeForgejo:
 			r.Term().Println("  GITEA_TOKEN environment variable")
+		case irelease.ForgeBitbucket:
+			r.Term().Println("  BITBUCKET_TOKEN environment variable, an access token or username:app_password")
 		}
-		return fmt.Errorf("no API token available")
+		return nil, fmt.Errorf("no API token available")
 	}
 
 	// Recreate forge with resolved token
 	forge = irelease.NewForge(remoteInfo.Host, remoteInfo.Repo, token)
-	forge.DetectType() // Re-detect with token
+	_, _ = r.detectForge(forge) // Re-detect with token
 
-	// Create release
-	r.Term().Println()
-	releaseID, err := forge.CreateRelease(newTag, changelog)
-	if err != nil {
-		return fmt.Errorf("failed to create release: %w", err)
+	return forge, nil
+}
+
+// checkForge validates forge access on dry run with --check-forge, only read-only requests are made:
+// the token must be accepted and allowed to create releases, the tag must not be pushed by another release
+// and no release of the tag may exist.
+func (r *Release) checkForge(gitOps *irelease.GitOps, workDir, tag string) error {
+	if !r.CheckForge {
+		return nil
 	}
+	r.result.ForgeChecked = true
 
-	r.Term().Success().Printfln("Release created (ID: %s)", releaseID)
+	tagPushed, err := gitOps.RemoteTagExists(tag)
+	if err != nil {
+		return err
+	}
+	switch {
+	case tagPushed && !r.existingTag:
+		return fmt.Errorf("%w: %s", errTagCollision, tag)
+	case !tagPushed && r.existingTag:
+		r.Term().Warning().Printfln("Tag %s isn't pushed to origin yet", tag)
+	}
 
-	// Find and upload Platform Model (.pm) file
-	image := findImage(imageDir)
-	if image == "" {
-		r.result = &ReleaseResult{Tag: newTag, ReleaseID: releaseID}
-		r.Term().Println()
-		r.Term().Warning().Printfln("No Platform Model (.pm) found in %s - skipping artifact upload.", imageDir)
-		r.Term().Println()
-		r.Term().Success().Printfln("Release %s created successfully.", newTag)
+	if r.TagOnly {
 		return nil
 	}
 
-	r.Term().Println()
-	r.Term().Info().Printfln("Uploading Platform Model: %s", image)
+	forge, err := r.connectForge(gitOps, workDir)
+	if err != nil {
+		return err
+	}
+	if err = forge.SetGitLabAssets(r.GitLabAssets); err != nil {
+		return err
+	}
+	if err = forge.CheckPermissions(); err != nil {
+		return err
+	}
 
-	if err := forge.UploadAsset(releaseID, image); err != nil {
-		return fmt.Errorf("failed to upload asset: %w", err)
+	exists, err := forge.ReleaseExists(tag)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", errReleaseCollision, tag)
+	}
+
+	r.Term().Success().Printfln("Forge access checked, release %s can be created", tag)
+	return nil
+}
+
+// detectForge sets the forge type given by --forge, otherwise detects it from the host
+func (r *Release) detectForge(forge *irelease.Forge) (irelease.ForgeType, error) {
+	if r.Forge == "" {
+		return forge.DetectType()
+	}
+
+	if err := forge.SetType(irelease.ForgeType(r.Forge)); err != nil {
+		return "", err
+	}
+
+	return forge.Type(), nil
+}
+
+// rollbackRelease deletes the incomplete release and, if requested, the pushed tag
+func (r *Release) rollbackRelease(forge *irelease.Forge, gitOps *irelease.GitOps, releaseID, tag string) {
+	r.Term().Warning().Printfln("Rolling back release %s...", tag)
+	if err := forge.DeleteRelease(releaseID); err != nil {
+		r.Term().Error().Printfln("Failed to delete release %s: %v", releaseID, err)
 	}
 
-	r.result = &ReleaseResult{Tag: newTag, ReleaseID: releaseID, Asset: image}
+	r.rollbackTag(gitOps, tag)
+}
+
+// rollbackTag deletes the pushed tag if requested
+func (r *Release) rollbackTag(gitOps *irelease.GitOps, tag string) {
+	if r.existingTag {
+		r.Term().Warning().Printfln("Tag %s existed before the release, it is kept.", tag)
+		return
+	}
+
+	if !r.RollbackTag {
+		r.Term().Warning().Printfln("Tag %s is kept, delete it manually or use --rollback-tag.", tag)
+		return
+	}
+
+	if err := gitOps.DeleteTag(tag); err != nil {
+		r.Term().Error().Printfln("Failed to delete tag: %v", err)
+		return
+	}
+
+	r.Term().Info().Printfln("Tag %s deleted.", tag)
+}
 
+// buildBundle prepares the composed model and creates the Platform Model like model:prepare and model:bundle
+func (r *Release) buildBundle() error {
 	r.Term().Println()
-	r.Term().Success().Printfln("Release %s created successfully with Platform Model!", newTag)
+	r.Term().Info().Println("Building Platform Model...")
+
+	layout := r.Layout.WithDefaults()
+	pr := &prepare.Prepare{ComposeDir: layout.MergedDir, PrepareDir: layout.PrepareDir, Clean: true}
+	pr.SetLogger(r.Log())
+	pr.SetTerm(r.Term())
+	if err := pr.Execute(); err != nil {
+		return err
+	}
+
+	b := &bundle.Bundle{Layout: layout, HasPrepareAction: true}
+	b.SetLogger(r.Log())
+	b.SetTerm(r.Term())
+	if err := b.Execute(); err != nil {
+		return err
+	}
 
+	r.bundlePath = b.Result().(*bundle.BundleResult).BundlePath
 	return nil
 }
 
@@ -0,0 +1,42 @@
+package release
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadNotes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "NOTES.md")
+	if err := os.WriteFile(file, []byte("\n## Highlights\n\n- curated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		release Release
+		notes   string
+		err     error
+	}{
+		{"none", Release{}, "", nil},
+		{"string", Release{Notes: "Hand written"}, "Hand written", nil},
+		{"file", Release{NotesFile: file}, "## Highlights\n\n- curated", nil},
+		{"both", Release{Notes: "x", NotesFile: file}, "", errConflictingNotes},
+		{"blank", Release{Notes: "  \n"}, "", errEmptyNotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes, err := tt.release.readNotes()
+			if notes != tt.notes || !errors.Is(err, tt.err) {
+				t.Errorf("expected %q, %v, got %q, %v", tt.notes, tt.err, notes, err)
+			}
+		})
+	}
+
+	r := Release{NotesFile: filepath.Join(t.TempDir(), "missing.md")}
+	if _, err := r.readNotes(); err == nil {
+		t.Error("expected missing notes file to fail")
+	}
+}
@@ -18,6 +18,7 @@ type Remove struct {
 
 	WorkingDir string
 	Packages   []string
+	Yes        bool
 
 	result *RemoveResult
 }
@@ -29,7 +30,13 @@ func (r *Remove) Result() any {
 
 // Execute runs the model:remove action
 func (r *Remove) Execute() error {
-	fa := &compose.FormsAction{}
+	unlock, err := compose.LockModel(r.WorkingDir, "model:remove", false, r.Term(), r.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fa := &compose.FormsAction{SkipConfirm: r.Yes}
 	fa.SetLogger(r.Log())
 	fa.SetTerm(r.Term())
 
@@ -5,12 +5,18 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/launchrctl/launchr/pkg/action"
 
 	"github.com/plasmash/plasmactl-component/pkg/component"
+	"github.com/plasmash/plasmactl-model/actions/bundle"
 	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/internal/listing"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 	"github.com/plasmash/plasmactl-platform/pkg/graph"
 )
 
@@ -22,11 +28,18 @@ type PackageInfo struct {
 	Type       string   `json:"type"`
 	Strategies []string `json:"strategies,omitempty"`
 	Components []string `json:"components,omitempty"`
+
+	Stats *compose.PackageStats `json:"stats,omitempty"`
 }
 
 // ShowResult is the structured output for model:show
 type ShowResult struct {
-	Packages []PackageInfo `json:"packages"`
+	Composition      *compose.Metadata        `json:"composition,omitempty"`
+	Packages         []PackageInfo            `json:"packages"`
+	NestedStrategies []compose.NestedStrategy `json:"nested_strategies,omitempty"`
+	Issues           []compose.Issue          `json:"issues,omitempty"`
+	Conflicts        []compose.Conflict       `json:"conflicts,omitempty"`
+	Bundle           *model.BundleManifest    `json:"bundle,omitempty"`
 }
 
 // Show implements the model:show action
@@ -35,14 +48,23 @@ type Show struct {
 	action.WithTerm
 
 	WorkingDir string
-	Package    string
+	// Layout locates downloaded packages and the merged result, empty paths are defaults.
+	Layout  model.Layout
+	Package string
 
 	// Filter flags
-	Packages bool // Show only external packages
-	Src      bool // Show only local src/ components
-	Composed bool // Show composed result
+	Packages  bool // Show only external packages
+	Src       bool // Show only local src/ components
+	Composed  bool // Show composed result
+	Conflicts bool // Show conflicts of the last compose run
+
+	Bundle string // Path of a .pm bundle, its manifest is shown instead of the working directory
+
+	Listing listing.Options
+	Output  output.Mode
 
-	result *ShowResult
+	result    *ShowResult
+	checkouts *compose.Checkouts
 }
 
 // Result returns the structured result for JSON output
@@ -52,11 +74,34 @@ func (s *Show) Result() any {
 
 // Execute runs the model:show action
 func (s *Show) Execute() error {
+	// Handle --bundle: bundles are shown without compose.yaml of the working directory
+	if s.Bundle != "" {
+		if err := s.Output.Validate(); err != nil {
+			return err
+		}
+		s.result = &ShowResult{}
+		return s.showBundle()
+	}
+
 	cfg, err := compose.Lookup(os.DirFS(s.WorkingDir))
 	if err != nil {
 		return fmt.Errorf("compose.yaml not found: %w", err)
 	}
 
+	versionLock, err := compose.LoadVersionLock(s.WorkingDir)
+	if err != nil {
+		return err
+	}
+	versionLock.Apply(cfg)
+	s.checkouts = compose.LocateCheckouts(cfg, versionLock, filepath.Join(s.WorkingDir, s.Layout.WithDefaults().PackagesDir))
+
+	if err = s.Listing.Validate(); err != nil {
+		return err
+	}
+	if err = s.Output.Validate(); err != nil {
+		return err
+	}
+
 	// Initialize result
 	s.result = &ShowResult{}
 
@@ -65,6 +110,11 @@ func (s *Show) Execute() error {
 		return s.showComposed()
 	}
 
+	// Handle --conflicts flag: show conflicts report of the last compose run
+	if s.Conflicts {
+		return s.showConflicts()
+	}
+
 	// Handle --src flag: show only local src/ components (filesystem-based)
 	if s.Src {
 		return s.showSrc(filepath.Join(s.WorkingDir, "src"))
@@ -91,8 +141,18 @@ func (s *Show) Execute() error {
 		for _, dep := range cfg.Dependencies {
 			if dep.Name == pkgName {
 				pkg := s.buildPackageInfo(dep, g)
+				pkg.Stats = s.packageStats(dep, len(pkg.Components), s.mergedFiles())
 				s.result.Packages = append(s.result.Packages, pkg)
+				for _, ns := range s.nestedStrategies() {
+					if ns.DeclaredBy == pkgName || ns.Package == pkgName {
+						s.result.NestedStrategies = append(s.result.NestedStrategies, ns)
+					}
+				}
 				// Output is handled by launchr based on result schema
+				s.porcelainPackage(pkg)
+				for _, ns := range s.result.NestedStrategies {
+					s.porcelainStrategy(ns)
+				}
 				return nil
 			}
 		}
@@ -127,7 +187,10 @@ func (s *Show) buildPackageInfo(dep compose.Dependency, g *graph.PlatformGraph)
 		pkg.Strategies = append(pkg.Strategies, strat.Name)
 	}
 
-	// Discover components from graph
+	// Discover components from graph, if it's loaded
+	if g == nil {
+		return pkg
+	}
 	for _, e := range g.EdgesFrom(dep.Name, "contains") {
 		if e.To().Type == "component" {
 			pkg.Components = append(pkg.Components, e.To().Name)
@@ -138,6 +201,94 @@ func (s *Show) buildPackageInfo(dep compose.Dependency, g *graph.PlatformGraph)
 	return pkg
 }
 
+// mergedFiles returns number of merged files per package of the last compose run
+func (s *Show) mergedFiles() map[string]int {
+	summary, err := compose.LoadSummary(s.WorkingDir)
+	if err != nil {
+		s.Log().Debug("compose summary is not available", "error", err)
+	}
+
+	return summary.MergedFiles()
+}
+
+// nestedStrategies returns strategies declared by nested compose files in the last compose run
+func (s *Show) nestedStrategies() []compose.NestedStrategy {
+	summary, err := compose.LoadSummary(s.WorkingDir)
+	if err != nil {
+		s.Log().Debug("compose summary is not available", "error", err)
+		return nil
+	}
+
+	return summary.NestedStrategies
+}
+
+// packageStats collects on-disk statistics of a package
+func (s *Show) packageStats(dep compose.Dependency, components int, merged map[string]int) *compose.PackageStats {
+	stats := compose.CollectPackageStats(s.checkouts, dep, merged)
+	stats.Components = components
+
+	return &stats
+}
+
+// printMetadata outputs descriptive fields of the composition
+func (s *Show) printMetadata(meta compose.Metadata) {
+	if meta.IsEmpty() {
+		return
+	}
+	s.result.Composition = &meta
+
+	keys := make([]string, 0, len(meta.Annotations))
+	for key := range meta.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if s.Output.Porcelain {
+		s.porcelain("composition", meta.Name, meta.Description, strings.Join(meta.Maintainers, ","))
+		for _, key := range keys {
+			s.porcelain("annotation", key, meta.Annotations[key])
+		}
+		return
+	}
+	if s.Output.Quiet {
+		return
+	}
+
+	term := s.Term()
+	if meta.Name != "" {
+		term.Info().Printfln("Composition %s", meta.Name)
+	}
+	if meta.Description != "" {
+		term.Printfln("  %s", meta.Description)
+	}
+	if len(meta.Maintainers) > 0 {
+		term.Printfln("  maintainers\t%s", strings.Join(meta.Maintainers, ", "))
+	}
+	for _, key := range keys {
+		term.Printfln("  %s\t%s", key, meta.Annotations[key])
+	}
+}
+
+// porcelain prints a record of tab separated fields in porcelain mode
+func (s *Show) porcelain(fields ...string) {
+	if s.Output.Porcelain {
+		s.Term().Printfln("%s", output.Fields(fields...))
+	}
+}
+
+// porcelainPackage prints records of a package: name, ref, type and url, followed by its components
+func (s *Show) porcelainPackage(pkg PackageInfo) {
+	s.porcelain("package", pkg.Name, pkg.Ref, pkg.Type, pkg.URL)
+	for _, comp := range pkg.Components {
+		s.porcelain("component", pkg.Name, comp)
+	}
+}
+
+// porcelainStrategy prints a record of a nested strategy: package, strategy, declaring package and if it was ignored
+func (s *Show) porcelainStrategy(ns compose.NestedStrategy) {
+	s.porcelain("strategy", ns.Package, ns.Strategy, ns.DeclaredBy, strconv.FormatBool(ns.Ignored))
+}
+
 // printPackage outputs human-readable package details
 func (s *Show) printPackage(pkg PackageInfo) {
 	term := s.Term()
@@ -150,6 +301,11 @@ func (s *Show) printPackage(pkg PackageInfo) {
 	for _, strat := range pkg.Strategies {
 		term.Printfln("strategy\t%s", strat)
 	}
+	if pkg.Stats != nil {
+		term.Printfln("files\t%d", pkg.Stats.Files)
+		term.Printfln("size\t%s", compose.FormatBytes(pkg.Stats.Size))
+		term.Printfln("merged files\t%d", pkg.Stats.MergedFiles)
+	}
 
 	if len(pkg.Components) > 0 {
 		term.Info().Printfln("Components (%d)", len(pkg.Components))
@@ -159,6 +315,45 @@ func (s *Show) printPackage(pkg PackageInfo) {
 	}
 }
 
+// showBundle displays the manifest of a bundle: its provenance, composed packages and prepare transformations
+func (s *Show) showBundle() error {
+	f, err := os.Open(filepath.Clean(s.Bundle))
+	if err != nil {
+		return fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	manifest, err := model.ReadBundleManifest(f)
+	if err != nil {
+		return fmt.Errorf("error reading bundle %s: %w", s.Bundle, err)
+	}
+	if manifest == nil {
+		return fmt.Errorf("bundle %s has no %s, rebuild it with model:bundle", s.Bundle, model.BundleManifestFile)
+	}
+	s.result.Bundle = manifest
+
+	if !s.Output.Human() {
+		builtAt := ""
+		if manifest.BuiltAt != nil {
+			builtAt = manifest.BuiltAt.Format(time.RFC3339)
+		}
+		s.porcelain("bundle", manifest.Name, manifest.Version, manifest.Repo, manifest.Tag, manifest.Commit, builtAt)
+		for _, p := range manifest.Packages {
+			s.porcelain("package", p.Name, p.Ref, p.Type, p.URL, p.Commit, p.Hash)
+		}
+		if manifest.Prepare != nil {
+			for _, t := range manifest.Prepare.Transformations {
+				s.porcelain("transformation", t)
+			}
+		}
+		return nil
+	}
+
+	bundle.PrintManifest(s.Term(), manifest)
+
+	return nil
+}
+
 // showComposed displays the composed result from the graph
 func (s *Show) showComposed() error {
 	g, err := graph.Load()
@@ -168,7 +363,9 @@ func (s *Show) showComposed() error {
 
 	components := g.NodesByType("component")
 	if len(components) == 0 {
-		s.Term().Info().Println("No components in composition")
+		if s.Output.Human() {
+			s.Term().Info().Println("No components in composition")
+		}
 		return nil
 	}
 
@@ -178,6 +375,13 @@ func (s *Show) showComposed() error {
 	}
 	sort.Strings(names)
 
+	if !s.Output.Human() {
+		for _, name := range names {
+			s.porcelain("component", "", name)
+		}
+		return nil
+	}
+
 	term := s.Term()
 	term.Info().Printfln("Components (%d)", len(components))
 	for _, name := range names {
@@ -187,16 +391,92 @@ func (s *Show) showComposed() error {
 	return nil
 }
 
+// showConflicts displays conflicting paths of the last compose run and how they were resolved
+func (s *Show) showConflicts() error {
+	report, err := compose.LoadConflicts(s.WorkingDir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("conflicts report not found, run model:compose first")
+	} else if err != nil {
+		return fmt.Errorf("failed to load conflicts report: %w", err)
+	}
+	s.result.Conflicts = report.Conflicts
+
+	if !s.Output.Human() {
+		for _, c := range report.Conflicts {
+			s.porcelain("conflict", c.Path, c.Winner, c.Source, c.Strategy, strings.Join(c.Packages, ","))
+		}
+		return nil
+	}
+
+	term := s.Term()
+	if len(report.Conflicts) == 0 {
+		term.Info().Println("No conflicts in the last compose run")
+		return nil
+	}
+
+	// Group conflicts by component in order of their first conflict, paths outside of components go last.
+	var components []string
+	byComponent := make(map[string][]compose.Conflict)
+	for _, c := range report.Conflicts {
+		if _, ok := byComponent[c.Component]; !ok && c.Component != "" {
+			components = append(components, c.Component)
+		}
+		byComponent[c.Component] = append(byComponent[c.Component], c)
+	}
+	if len(byComponent[""]) > 0 {
+		components = append(components, "")
+	}
+
+	term.Info().Printfln("Conflicts (%d)", len(report.Conflicts))
+	for _, component := range components {
+		conflicts := byComponent[component]
+		local := 0
+		for _, c := range conflicts {
+			if c.Winner == "domain repo" {
+				local++
+			}
+		}
+		if component == "" {
+			component = "outside of components"
+		}
+		term.Printfln("  %s: %d conflicts, %d overridden locally", component, len(conflicts), local)
+
+		for _, c := range conflicts {
+			strategy := c.Strategy
+			if strategy == "" {
+				strategy = "conflict_default"
+				if report.ConflictDefault != "" {
+					strategy += " " + report.ConflictDefault
+				}
+			}
+			term.Printfln("    %s\t%s wins (%s)\t%s\t%s", c.Path, c.Winner, c.Source, strategy, strings.Join(c.Packages, ", "))
+		}
+	}
+
+	return nil
+}
+
 // showSrc displays only local src/ components (filesystem-based, not in graph)
 func (s *Show) showSrc(srcDir string) error {
 	if _, err := os.Stat(srcDir); os.IsNotExist(err) {
-		s.Term().Info().Println("No src/ directory found")
+		if s.Output.Human() {
+			s.Term().Info().Println("No src/ directory found")
+		}
 		return nil
 	}
 
 	components, _ := component.LoadFromPath(srcDir)
 	if len(components) == 0 {
-		s.Term().Info().Println("No components in src/")
+		if s.Output.Human() {
+			s.Term().Info().Println("No components in src/")
+		}
+		return nil
+	}
+
+	if !s.Output.Human() {
+		for _, comp := range components {
+			s.porcelain("component", "", comp.Name)
+		}
 		return nil
 	}
 
@@ -214,13 +494,32 @@ func (s *Show) showSrc(srcDir string) error {
 // showPackagesOnly displays packages without component details
 func (s *Show) showPackagesOnly(cfg *compose.Composition) error {
 	if len(cfg.Dependencies) == 0 {
-		s.Term().Info().Println("No package dependencies")
+		if s.Output.Human() {
+			s.Term().Info().Println("No package dependencies")
+		}
+		return nil
+	}
+
+	var components func(string) int
+	if s.Listing.NeedsComponents() {
+		g, err := graph.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load graph: %w", err)
+		}
+		components = listing.ComponentCounter(g)
+	}
+
+	deps := s.Listing.Dependencies(cfg.Dependencies, s.checkouts, components)
+	if !s.Output.Human() {
+		for _, dep := range deps {
+			s.porcelainPackage(s.buildPackageInfo(dep, nil))
+		}
 		return nil
 	}
 
 	term := s.Term()
 	term.Info().Printfln("Packages (%d)", len(cfg.Dependencies))
-	for _, dep := range cfg.Dependencies {
+	for _, dep := range deps {
 		ref := dep.Source.Ref
 		if ref == "" {
 			ref = "latest"
@@ -238,25 +537,62 @@ func (s *Show) showOverview(cfg *compose.Composition) error {
 		return fmt.Errorf("failed to load graph: %w", err)
 	}
 
-	// Show packages summary with component counts from graph
 	term := s.Term()
+	s.printMetadata(cfg.Metadata())
+
+	// Show packages summary with component counts from graph
 	if len(cfg.Dependencies) > 0 {
-		term.Info().Printfln("Packages (%d)", len(cfg.Dependencies))
-		for _, dep := range cfg.Dependencies {
-			ref := dep.Source.Ref
-			if ref == "" {
-				ref = "latest"
+		countComponents := listing.ComponentCounter(g)
+		merged := s.mergedFiles()
+		if s.Output.Human() {
+			term.Info().Printfln("Packages (%d)", len(cfg.Dependencies))
+		}
+		for _, dep := range s.Listing.Dependencies(cfg.Dependencies, s.checkouts, countComponents) {
+			pkg := s.buildPackageInfo(dep, g)
+			pkg.Stats = s.packageStats(dep, len(pkg.Components), merged)
+			s.result.Packages = append(s.result.Packages, pkg)
+
+			if !s.Output.Human() {
+				s.porcelainPackage(pkg)
+				continue
 			}
+			term.Printfln("  %s@%s\t(%d components, %d files, %s, %d merged files)",
+				pkg.Name, pkg.Ref, pkg.Stats.Components, pkg.Stats.Files, compose.FormatBytes(pkg.Stats.Size), pkg.Stats.MergedFiles)
+		}
+	}
 
-			var count int
-			for _, e := range g.EdgesFrom(dep.Name, "contains") {
-				if e.To().Type == "component" {
-					count++
-				}
+	// Strategies of packages declared by nested compose files of other packages
+	s.result.NestedStrategies = s.nestedStrategies()
+	for _, ns := range s.result.NestedStrategies {
+		s.porcelainStrategy(ns)
+	}
+	if len(s.result.NestedStrategies) > 0 && s.Output.Human() {
+		term.Info().Printfln("Nested strategies (%d)", len(s.result.NestedStrategies))
+		for _, ns := range s.result.NestedStrategies {
+			line := fmt.Sprintf("  %s\t%s\tdeclared by %s", ns.Package, ns.Strategy, ns.DeclaredBy)
+			if ns.Ignored {
+				line += "\tignored"
 			}
+			term.Printfln("%s", line)
+		}
+	}
 
-			term.Printfln("  %s@%s\t(%d components)", dep.Name, ref, count)
+	// Warn about inconsistencies between compose.yaml, downloaded packages and merged result
+	issues, err := compose.CheckConsistency(cfg, s.WorkingDir, s.Layout.WithDefaults())
+	if err != nil {
+		s.Log().Debug("failed to check model consistency", "error", err)
+	}
+	s.result.Issues = issues
+	for _, issue := range issues {
+		if !s.Output.Human() {
+			s.porcelain("issue", issue.Kind, issue.Message, issue.Fix)
+			continue
 		}
+		term.Warning().Printfln("%s, run %s", issue.Message, issue.Fix)
+	}
+
+	if !s.Output.Human() {
+		return nil
 	}
 
 	// Show src/ summary (filesystem-based, local uncomposed code)
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr/pkg/action"
 
 	"github.com/plasmash/plasmactl-model/internal/compose"
@@ -22,6 +23,7 @@ type Update struct {
 	action.WithLogger
 	action.WithTerm
 
+	Keyring      keyring.Keyring
 	WorkingDir   string
 	Package      string
 	Type         string
@@ -29,6 +31,7 @@ type Update struct {
 	URL          string
 	Strategy     []string
 	StrategyPath []string
+	Yes          bool
 
 	result *UpdateResult
 }
@@ -45,7 +48,13 @@ func (u *Update) Execute() error {
 		return err
 	}
 
-	fa := &compose.FormsAction{}
+	unlock, err := compose.LockModel(u.WorkingDir, "model:update", false, u.Term(), u.Log())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fa := &compose.FormsAction{Keyring: u.Keyring, SkipConfirm: u.Yes}
 	fa.SetLogger(u.Log())
 	fa.SetTerm(u.Term())
 
@@ -103,6 +112,8 @@ func (u *Update) validate() error {
 			compose.StrategyRemoveExtraLocal:   true,
 			compose.StrategyIgnoreExtraPackage: true,
 			compose.StrategyFilterPackage:      true,
+			compose.StrategyPreferNewest:       true,
+			compose.StrategyMergeYAML:          true,
 		}
 
 		for _, strategy := range u.Strategy {
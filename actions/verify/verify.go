@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"github.com/launchrctl/launchr/pkg/action"
+
+	irelease "github.com/plasmash/plasmactl-model/internal/release"
+)
+
+// VerifyResult is the structured result of model:verify.
+type VerifyResult struct {
+	File string `json:"file"`
+	*irelease.BundleVerification
+}
+
+// Verify implements the model:verify action
+type Verify struct {
+	action.WithLogger
+	action.WithTerm
+
+	File      string
+	Key       string
+	Signature string
+	// Method pins the signing method, signatures of other methods are rejected.
+	Method string
+
+	result *VerifyResult
+}
+
+// Result returns the structured result for JSON output.
+func (v *Verify) Result() any {
+	return v.result
+}
+
+// Execute verifies the signature of the bundle and its attestation
+func (v *Verify) Execute() error {
+	verification, err := irelease.VerifyBundle(v.Method, v.Key, v.File, v.Signature)
+	if err != nil {
+		return err
+	}
+
+	v.result = &VerifyResult{File: v.File, BundleVerification: verification}
+	v.Term().Success().Printfln("Signature %s of %s verified with %s", verification.Signature, v.File, verification.Method)
+	if verification.Attestation != "" {
+		v.Term().Success().Printfln("Attestation %s matches the bundle", verification.Attestation)
+	}
+
+	return nil
+}
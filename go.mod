@@ -11,7 +11,7 @@ require (
 	github.com/leodido/go-conventionalcommits v0.12.0
 	github.com/plasmash/plasmactl-component v1.2.3
 	github.com/plasmash/plasmactl-platform v1.5.1
-	github.com/stevenle/topsort v0.2.0
+	golang.org/x/sys v0.39.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -141,7 +141,6 @@ require (
 	golang.org/x/mod v0.29.0 // indirect
 	golang.org/x/net v0.46.0 // indirect
 	golang.org/x/oauth2 v0.32.0 // indirect
-	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/term v0.36.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 	golang.org/x/time v0.14.0 // indirect
@@ -0,0 +1,191 @@
+// Package auth provides per-host authentication configuration used by package downloaders and forge clients.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Method is an authentication method.
+type Method string
+
+// Supported authentication methods.
+const (
+	MethodNone    Method = "none"
+	MethodBasic   Method = "basic"
+	MethodToken   Method = "token"
+	MethodSSH     Method = "ssh"
+	MethodKeyring Method = "keyring"
+)
+
+// DefaultTokenHeader is used for token authentication if header is not set.
+const DefaultTokenHeader = "Authorization"
+
+var errInvalidConfig = errors.New("invalid auth configuration")
+
+// rgxScpURL matches scp-like git URLs, e.g. git@github.com:org/repo.git
+var rgxScpURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):`)
+
+// Rule configures authentication for hosts matching Host pattern.
+// String values support environment variables expansion, e.g. token: ${GITLAB_TOKEN}.
+type Rule struct {
+	// Host is a host glob pattern, e.g. gitlab.example.com or *.example.com.
+	Host   string `yaml:"host"`
+	Method Method `yaml:"method"`
+	// Username and Password are used by basic method.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Token and Header are used by token method.
+	Token  string `yaml:"token,omitempty"`
+	Header string `yaml:"header,omitempty"`
+	// Key and Passphrase are used by ssh method.
+	Key        string `yaml:"key,omitempty"`
+	Passphrase string `yaml:"passphrase,omitempty"`
+	// Keyring is a keyring item URL used by keyring method, defaults to the requested URL.
+	Keyring string `yaml:"keyring,omitempty"`
+}
+
+// Config stores authentication rules, the first matching rule is used.
+type Config struct {
+	Hosts []Rule `yaml:"hosts"`
+}
+
+// Load reads authentication configuration of baseDir. Empty config is returned if it doesn't exist.
+func Load(baseDir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, model.AuthFile)) //nolint:gosec // path is built from base dir
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates authentication configuration.
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfig, err)
+	}
+
+	for i := range cfg.Hosts {
+		r := &cfg.Hosts[i]
+		r.Username = os.ExpandEnv(r.Username)
+		r.Password = os.ExpandEnv(r.Password)
+		r.Token = os.ExpandEnv(r.Token)
+		r.Key = expandHome(os.ExpandEnv(r.Key))
+		r.Passphrase = os.ExpandEnv(r.Passphrase)
+		r.Keyring = os.ExpandEnv(r.Keyring)
+
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// Match returns the first rule matching host of rawURL, nil if there is no such rule.
+func (c *Config) Match(rawURL string) *Rule {
+	if c == nil {
+		return nil
+	}
+
+	host, hostname := urlHost(rawURL)
+	if host == "" {
+		return nil
+	}
+
+	for i := range c.Hosts {
+		pattern := strings.ToLower(c.Hosts[i].Host)
+		if ok, _ := path.Match(pattern, host); ok {
+			return &c.Hosts[i]
+		}
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return &c.Hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// TokenHeader returns header name and value for token method.
+func (r *Rule) TokenHeader() (string, string) {
+	if r.Header == "" || strings.EqualFold(r.Header, DefaultTokenHeader) {
+		return DefaultTokenHeader, "Bearer " + r.Token
+	}
+
+	return r.Header, r.Token
+}
+
+func (r *Rule) validate() error {
+	if r.Host == "" {
+		return fmt.Errorf("%w: host is required", errInvalidConfig)
+	}
+	if _, err := path.Match(r.Host, ""); err != nil {
+		return fmt.Errorf("%w: host pattern %q: %w", errInvalidConfig, r.Host, err)
+	}
+
+	switch r.Method {
+	case MethodNone, MethodKeyring:
+	case MethodBasic:
+		if r.Username == "" {
+			return fmt.Errorf("%w: %s: username is required for basic method", errInvalidConfig, r.Host)
+		}
+	case MethodToken:
+		if r.Token == "" {
+			return fmt.Errorf("%w: %s: token is required for token method", errInvalidConfig, r.Host)
+		}
+	case MethodSSH:
+		if r.Key == "" {
+			return fmt.Errorf("%w: %s: key is required for ssh method", errInvalidConfig, r.Host)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown method %q", errInvalidConfig, r.Host, r.Method)
+	}
+
+	return nil
+}
+
+// urlHost returns host with port and hostname of rawURL, scp-like git URLs are supported.
+func urlHost(rawURL string) (string, string) {
+	if !strings.Contains(rawURL, "://") {
+		if m := rgxScpURL.FindStringSubmatch(rawURL); m != nil {
+			h := strings.ToLower(m[1])
+			return h, h
+		}
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ""
+	}
+
+	return strings.ToLower(u.Host), strings.ToLower(u.Hostname())
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
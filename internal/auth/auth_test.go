@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_GITLAB_TOKEN", "secret")
+
+	cfg, err := Parse([]byte(`
+hosts:
+  - host: gitlab.example.com
+    method: token
+    token: ${TEST_GITLAB_TOKEN}
+    header: PRIVATE-TOKEN
+  - host: "*.internal.example.com"
+    method: ssh
+    key: /keys/id_ed25519
+  - host: github.com
+    method: none
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rule := cfg.Match("https://gitlab.example.com/group/sub/repo.git")
+	if rule == nil || rule.Method != MethodToken || rule.Token != "secret" {
+		t.Fatalf("expected token rule, got %+v", rule)
+	}
+	if name, value := rule.TokenHeader(); name != "PRIVATE-TOKEN" || value != "secret" {
+		t.Errorf("unexpected token header %s: %s", name, value)
+	}
+
+	tests := []struct {
+		url    string
+		method Method
+	}{
+		{"git@git.internal.example.com:org/repo.git", MethodSSH},
+		{"ssh://git@git.internal.example.com:2222/org/repo.git", MethodSSH},
+		{"https://GitHub.com/org/repo", MethodNone},
+		{"gitlab.example.com", MethodToken},
+	}
+	for _, tt := range tests {
+		rule = cfg.Match(tt.url)
+		if rule == nil || rule.Method != tt.method {
+			t.Errorf("%s: expected %s rule, got %+v", tt.url, tt.method, rule)
+		}
+	}
+
+	if rule = cfg.Match("https://bitbucket.org/org/repo.git"); rule != nil {
+		t.Errorf("expected no rule, got %+v", rule)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing host":     "hosts: [{method: none}]",
+		"unknown method":   "hosts: [{host: example.com, method: magic}]",
+		"missing username": "hosts: [{host: example.com, method: basic}]",
+		"empty token":      "hosts: [{host: example.com, method: token, token: $TEST_UNSET_TOKEN}]",
+		"missing key":      "hosts: [{host: example.com, method: ssh}]",
+		"bad pattern":      "hosts: [{host: '[', method: none}]",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); !errors.Is(err, errInvalidConfig) {
+				t.Errorf("expected invalid config error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMatchNilConfig(t *testing.T) {
+	var cfg *Config
+	if rule := cfg.Match("https://github.com/org/repo"); rule != nil {
+		t.Errorf("expected no rule, got %+v", rule)
+	}
+}
@@ -0,0 +1,169 @@
+package compose
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policies of symlink and hardlink entries in package archives.
+const (
+	// ArchiveLinksSkip ignores link entries.
+	ArchiveLinksSkip = "skip"
+	// ArchiveLinksReject fails extraction on link entries.
+	ArchiveLinksReject = "reject"
+	// ArchiveLinksInternal extracts links pointing inside of the archive and fails on others.
+	ArchiveLinksInternal = "internal"
+)
+
+var (
+	errUnsafeArchive      = errors.New("unsafe archive entry")
+	errInvalidLinksPolicy = errors.New("invalid archive links policy")
+)
+
+func validateArchiveLinksPolicy(policy string) error {
+	switch policy {
+	case "", ArchiveLinksSkip, ArchiveLinksReject, ArchiveLinksInternal:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s, %s", errInvalidLinksPolicy, policy, ArchiveLinksSkip, ArchiveLinksReject, ArchiveLinksInternal)
+	}
+}
+
+// archiveTarget returns extraction path of an archive entry.
+// Absolute names and names escaping the extraction directory are rejected.
+func archiveTarget(root, name string) (string, error) {
+	clean := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if clean == "" || filepath.IsAbs(clean) || strings.HasPrefix(name, "/") || !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%w: %s: path is absolute or escapes extraction directory", errUnsafeArchive, name)
+	}
+
+	return filepath.Join(root, clean), nil
+}
+
+// archiveLinkTarget validates a link entry according to policy.
+// Empty path and nil error mean the entry must be skipped.
+// For symlinks linkname is relative to the link directory, for hardlinks it's relative to the archive root.
+func archiveLinkTarget(root, target, name, linkname string, hardlink bool, policy string) (string, error) {
+	switch policy {
+	case ArchiveLinksReject:
+		return "", fmt.Errorf("%w: %s: links are not allowed", errUnsafeArchive, name)
+	case ArchiveLinksInternal:
+	default:
+		return "", nil
+	}
+
+	if linkname == "" || filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return "", fmt.Errorf("%w: %s: link to absolute path %s", errUnsafeArchive, name, linkname)
+	}
+
+	// Resolve links extracted earlier, so a chain of links can't escape the archive.
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	base := realRoot
+	if !hardlink {
+		rel, _ := filepath.Rel(root, filepath.Dir(target))
+		base = filepath.Join(realRoot, rel)
+		if real, errEval := filepath.EvalSymlinks(base); errEval == nil {
+			base = real
+		}
+	}
+
+	resolved := filepath.Join(base, filepath.FromSlash(linkname))
+	if !withinDir(realRoot, resolved) {
+		return "", fmt.Errorf("%w: %s: link %s points outside of archive", errUnsafeArchive, name, linkname)
+	}
+
+	return resolved, nil
+}
+
+// extractLink creates a link entry according to policy. Symlinks keep the original relative target,
+// hardlinks are extracted as a copy of the already extracted file.
+func extractLink(root, target, name, linkname string, hardlink bool, policy string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+
+	resolved, err := archiveLinkTarget(root, target, name, linkname, hardlink, policy)
+	if err != nil || resolved == "" {
+		return err
+	}
+
+	if err = os.RemoveAll(target); err != nil {
+		return err
+	}
+
+	if !hardlink {
+		return createSymlink(linkname, target)
+	}
+
+	info, err := os.Lstat(resolved)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: hardlink target %s is not a regular file", errUnsafeArchive, linkname)
+	}
+	_, err = fcopy(resolved, target)
+
+	return err
+}
+
+// ExtractTarEntry extracts a tar entry into root: directories, regular files read from r and links according
+// to policy. Entries of other types are ignored. The extraction path is returned.
+func ExtractTarEntry(root string, header *tar.Header, r io.Reader, linksPolicy string) (string, error) {
+	target, err := archiveTarget(root, header.Name)
+	if err != nil {
+		return "", err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if _, err = os.Stat(target); err != nil {
+			err = os.MkdirAll(target, 0750)
+		}
+	case tar.TypeReg:
+		err = createArchiveFile(target, header.FileInfo().Mode(), r)
+	case tar.TypeSymlink, tar.TypeLink:
+		err = extractLink(root, target, header.Name, header.Linkname, header.Typeflag == tar.TypeLink, linksPolicy)
+	}
+
+	return target, err
+}
+
+// createArchiveFile creates a file of an archive entry. Existing symlinks at target are removed,
+// so a file can't be written through a link.
+func createArchiveFile(target string, mode os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(target); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Clean(target), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	for {
+		_, err = io.CopyN(f, r, 1024)
+		if err != nil {
+			if err != io.EOF {
+				_ = f.Close()
+				return err
+			}
+			break
+		}
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,122 @@
+package compose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+func writeTarGz(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "package.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0644, Size: int64(len(e.content))}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err = tw.Write([]byte(e.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestUntarRejectsUnsafePaths(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "pkg/../../evil.txt", "/etc/evil.txt"} {
+		archive := writeTarGz(t, []tarEntry{
+			{name: "pkg/", typeflag: tar.TypeDir},
+			{name: name, typeflag: tar.TypeReg, content: "evil"},
+		})
+
+		_, err := untar(archive, t.TempDir(), ArchiveLinksSkip)
+		if !errors.Is(err, errUnsafeArchive) {
+			t.Errorf("%s: expected unsafe archive error, got %v", name, err)
+		}
+	}
+}
+
+func TestUntarLinksPolicy(t *testing.T) {
+	entries := []tarEntry{
+		{name: "pkg/", typeflag: tar.TypeDir},
+		{name: "pkg/main.yaml", typeflag: tar.TypeReg, content: "a: b\n"},
+		{name: "pkg/link.yaml", typeflag: tar.TypeSymlink, linkname: "main.yaml"},
+		{name: "pkg/hard.yaml", typeflag: tar.TypeLink, linkname: "pkg/main.yaml"},
+	}
+
+	dir := t.TempDir()
+	if _, err := untar(writeTarGz(t, entries), dir, ArchiveLinksSkip); err != nil {
+		t.Fatalf("untar failed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "pkg", "link.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected symlink to be skipped, got %v", err)
+	}
+
+	if _, err := untar(writeTarGz(t, entries), t.TempDir(), ArchiveLinksReject); !errors.Is(err, errUnsafeArchive) {
+		t.Errorf("expected links to be rejected, got %v", err)
+	}
+
+	dir = t.TempDir()
+	rootDir, err := untar(writeTarGz(t, entries), dir, ArchiveLinksInternal)
+	if err != nil {
+		t.Fatalf("untar failed: %v", err)
+	}
+	if rootDir != "pkg" {
+		t.Errorf("expected root dir pkg, got %q", rootDir)
+	}
+	for _, name := range []string{"link.yaml", "hard.yaml"} {
+		content, err := os.ReadFile(filepath.Join(dir, "pkg", name))
+		if err != nil || string(content) != "a: b\n" {
+			t.Errorf("%s: unexpected content %q, %v", name, content, err)
+		}
+	}
+
+	escaping := [][]tarEntry{
+		{{name: "pkg/", typeflag: tar.TypeDir}, {name: "pkg/link", typeflag: tar.TypeSymlink, linkname: "../../etc/passwd"}},
+		{{name: "pkg/", typeflag: tar.TypeDir}, {name: "pkg/link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		{{name: "pkg/", typeflag: tar.TypeDir}, {name: "pkg/hard", typeflag: tar.TypeLink, linkname: "../etc/passwd"}},
+		{
+			{name: "pkg/", typeflag: tar.TypeDir},
+			{name: "pkg/self", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "pkg/self/link", typeflag: tar.TypeSymlink, linkname: "../etc/passwd"},
+		},
+	}
+	for i, e := range escaping {
+		if _, err = untar(writeTarGz(t, e), t.TempDir(), ArchiveLinksInternal); !errors.Is(err, errUnsafeArchive) {
+			t.Errorf("case %d: expected escaping link to be rejected, got %v", i, err)
+		}
+	}
+}
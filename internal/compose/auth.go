@@ -0,0 +1,74 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	nethttp "net/http"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+
+	"github.com/plasmash/plasmactl-model/internal/auth"
+)
+
+var errUnsupportedAuth = errors.New("unsupported authentication method")
+
+// gitAuth returns git transport authentication configured by the rule.
+func (kw *keyringWrapper) gitAuth(rule *auth.Rule, url string) (transport.AuthMethod, error) {
+	switch rule.Method {
+	case auth.MethodNone:
+		return nil, nil
+	case auth.MethodBasic:
+		return &http.BasicAuth{Username: rule.Username, Password: rule.Password}, nil
+	case auth.MethodToken:
+		// Git over http expects token as a password, e.g. GitLab oauth2:<token>.
+		if rule.Username != "" {
+			return &http.BasicAuth{Username: rule.Username, Password: rule.Token}, nil
+		}
+		return &http.TokenAuth{Token: rule.Token}, nil
+	case auth.MethodSSH:
+		user := rule.Username
+		if user == "" {
+			user = "git"
+		}
+		return ssh.NewPublicKeysFromFile(user, rule.Key, rule.Passphrase)
+	case auth.MethodKeyring:
+		ci, err := kw.getForURL(ruleKeyringURL(rule, url))
+		if err != nil {
+			return nil, err
+		}
+		return &http.BasicAuth{Username: ci.Username, Password: ci.Password}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedAuth, rule.Method)
+	}
+}
+
+// httpAuth sets authentication configured by the rule to the request.
+func (kw *keyringWrapper) httpAuth(rule *auth.Rule, req *nethttp.Request) error {
+	switch rule.Method {
+	case auth.MethodNone:
+	case auth.MethodBasic:
+		req.SetBasicAuth(rule.Username, rule.Password)
+	case auth.MethodToken:
+		req.Header.Set(rule.TokenHeader())
+	case auth.MethodKeyring:
+		ci, err := kw.getForURL(ruleKeyringURL(rule, req.URL.String()))
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(ci.Username, ci.Password)
+	default:
+		return fmt.Errorf("%w for http source: %s", errUnsupportedAuth, rule.Method)
+	}
+
+	return nil
+}
+
+func ruleKeyringURL(rule *auth.Rule, url string) string {
+	if rule.Keyring != "" {
+		return rule.Keyring
+	}
+
+	return url
+}
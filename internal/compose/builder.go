@@ -3,26 +3,33 @@ package compose
 import (
 	"context"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/launchrctl/launchr/pkg/action"
-	"github.com/stevenle/topsort"
+
+	"github.com/plasmash/plasmactl-model/internal/fsutil"
+	"github.com/plasmash/plasmactl-model/internal/output"
 )
 
 const (
 	// DependencyRoot is a dependencies graph main node
 	DependencyRoot = "root"
 	gitPrefix      = ".git"
+	localOrigin    = "domain repo"
 )
 
+var errInvalidStrategy = fmt.Errorf("%w: strategy", ErrConflictPolicy)
+
 var excludedFolders = map[string]struct{}{".plasma": {}}
-var excludedFiles = map[string]struct{}{composeFile: {}}
+var excludedFiles = map[string]struct{}{composeFile: {}, versionLockFile: {}}
 
 type mergeConflictResolve uint8
 type mergeStrategyType uint8
@@ -31,6 +38,8 @@ type mergeStrategy struct {
 	s     mergeStrategyType
 	t     mergeStrategyTarget
 	paths []string
+	// declaredBy is the package which nested compose.yaml declares the strategy.
+	declaredBy string
 }
 
 const (
@@ -39,9 +48,12 @@ const (
 	removeExtraLocalFiles   mergeStrategyType    = 2
 	ignoreExtraPackageFiles mergeStrategyType    = 3
 	filterPackageFiles      mergeStrategyType    = 4
+	preferNewestFiles       mergeStrategyType    = 5
+	mergeYAMLFiles          mergeStrategyType    = 6
 	noConflict              mergeConflictResolve = iota
 	resolveToLocal          mergeConflictResolve = 1
 	resolveToPackage        mergeConflictResolve = 2
+	resolveMerged           mergeConflictResolve = 3
 	localStrategy           mergeStrategyTarget  = 1
 	packageStrategy         mergeStrategyTarget  = 2
 )
@@ -56,6 +68,10 @@ var (
 	StrategyIgnoreExtraPackage = "ignore-extra-package-files"
 	// StrategyFilterPackage string const
 	StrategyFilterPackage = "filter-package-files"
+	// StrategyPreferNewest string const
+	StrategyPreferNewest = "prefer-newest"
+	// StrategyMergeYAML string const
+	StrategyMergeYAML = "merge-yaml"
 )
 
 // return conflict const (0 - no warning, 1 - conflict with local, 2 conflict with package)
@@ -63,12 +79,18 @@ var (
 func cleanStrategyPaths(paths []string) []string {
 	// remove trailing separators and add only one separator at the end.
 	// so prefix won't be greedy during comparison.
+	// Merged paths are slash separated on every platform, like io/fs paths.
 	var r []string
 
 	for _, p := range paths {
-		path := filepath.Clean(p)
-		if !strings.HasSuffix(path, string(os.PathSeparator)) {
-			path += string(os.PathSeparator)
+		if isStrategyPattern(p) {
+			r = append(r, p)
+			continue
+		}
+
+		path := filepath.ToSlash(filepath.Clean(p))
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
 		}
 
 		r = append(r, path)
@@ -77,30 +99,93 @@ func cleanStrategyPaths(paths []string) []string {
 	return r
 }
 
-func retrieveStrategies(packages []*Package) ([]*mergeStrategy, map[string][]*mergeStrategy) {
+// retrieveStrategies returns strategies targeting local files and strategies of each package.
+// Shared strategies of compose.yaml apply to every package, localModern tells
+// if the domain repo keeps layers in src/.
+func retrieveStrategies(packages []*Package, shared []Strategy, localModern bool) ([]*mergeStrategy, map[string][]*mergeStrategy) {
 	var ls []*mergeStrategy
+	var sharedPs []*mergeStrategy
+	for _, item := range shared {
+		if ms := newMergeStrategy(item, localModern); ms != nil && ms.t == localStrategy {
+			ls = append(ls, ms)
+		} else if ms != nil {
+			sharedPs = append(sharedPs, ms)
+		}
+	}
+
 	ps := make(map[string][]*mergeStrategy)
 	for _, pkg := range packages {
 		var strategies []*mergeStrategy
 		for _, item := range pkg.GetStrategies() {
-			s, t := identifyStrategy(item.Name)
-			if s == undefinedStrategy {
+			ms := newMergeStrategy(item, localModern)
+			if ms == nil {
 				continue
 			}
-			strategy := &mergeStrategy{s, t, cleanStrategyPaths(item.Paths)}
+			ms.declaredBy = pkg.DeclaredBy
 
-			if t == localStrategy {
-				ls = append(ls, strategy)
+			if ms.t == localStrategy {
+				ls = append(ls, ms)
 			} else {
-				strategies = append(strategies, strategy)
+				strategies = append(strategies, ms)
 			}
 		}
-		ps[pkg.GetName()] = strategies
+		ps[pkg.GetName()] = append(strategies, sharedPs...)
 	}
 
 	return ls, ps
 }
 
+// newMergeStrategy returns nil for unknown strategies. Package paths are normalized
+// to src/ layout before strategies apply, local paths follow the domain repo layout.
+func newMergeStrategy(item Strategy, localModern bool) *mergeStrategy {
+	s, t := identifyStrategy(item.Name)
+	if s == undefinedStrategy {
+		return nil
+	}
+
+	return &mergeStrategy{s: s, t: t, paths: strategyPaths(item, t == packageStrategy || localModern)}
+}
+
+// strategyPaths expands layer scopes of a strategy to paths of the given layout.
+func strategyPaths(item Strategy, modern bool) []string {
+	paths := slices.Clone(item.Paths)
+	for _, layer := range item.AppliesTo {
+		layer = strings.Trim(filepath.ToSlash(layer), "/")
+		if modern {
+			layer = "src/" + layer
+		}
+		paths = append(paths, layer)
+	}
+
+	return cleanStrategyPaths(paths)
+}
+
+// validateStrategies checks layer scopes of compose.yaml strategies and names of shared strategies.
+func validateStrategies(cfg *Composition) error {
+	for _, item := range cfg.Strategies {
+		if s, _ := identifyStrategy(item.Name); s == undefinedStrategy {
+			return fmt.Errorf("%w: unknown strategy %q in %s", errInvalidStrategy, item.Name, composeFile)
+		}
+	}
+
+	items := slices.Clone(cfg.Strategies)
+	for _, d := range cfg.Dependencies {
+		items = append(items, d.Source.Strategies...)
+	}
+	for _, item := range items {
+		if err := validateStrategyPaths(item); err != nil {
+			return err
+		}
+		for _, layer := range item.AppliesTo {
+			if !layerNames[strings.Trim(filepath.ToSlash(layer), "/")] {
+				return fmt.Errorf("%w: strategy %s applies to unknown layer %q", errInvalidStrategy, item.Name, layer)
+			}
+		}
+	}
+
+	return nil
+}
+
 func identifyStrategy(name string) (mergeStrategyType, mergeStrategyTarget) {
 	s := undefinedStrategy
 	t := packageStrategy
@@ -115,6 +200,10 @@ func identifyStrategy(name string) (mergeStrategyType, mergeStrategyTarget) {
 		s = ignoreExtraPackageFiles
 	case StrategyFilterPackage:
 		s = filterPackageFiles
+	case StrategyPreferNewest:
+		s = preferNewestFiles
+	case StrategyMergeYAML:
+		s = mergeYAMLFiles
 	}
 
 	return s, t
@@ -134,7 +223,7 @@ var layerNames = map[string]bool{
 // isLayerDirectory checks if a path is a layer directory
 func isLayerDirectory(path string) bool {
 	// Get the first segment of the path
-	segments := strings.Split(filepath.Clean(path), string(filepath.Separator))
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
 	if len(segments) == 0 {
 		return false
 	}
@@ -178,7 +267,7 @@ func adjustDestinationPath(path string, isModernLayout bool) string {
 
 	// Legacy: if it's a layer, prefix with src/
 	if isLayerDirectory(path) {
-		return filepath.Join("src", path)
+		return "src/" + path
 	}
 
 	// Non-layer: keep at root
@@ -191,7 +280,7 @@ func stripRolesFromPath(path string) string {
 	// Skip stripping for special type directories that are not component types
 	// Only match when actions/docs is the TYPE (second segment), not a subdirectory
 	// e.g., platform/actions/... should skip, but cognition/services/data_relay/actions/... should NOT skip
-	parts := strings.Split(path, string(filepath.Separator))
+	parts := strings.Split(path, "/")
 	if len(parts) >= 2 {
 		typeDir := parts[1] // Second segment is the type directory
 		if typeDir == "actions" || typeDir == "docs" {
@@ -199,19 +288,19 @@ func stripRolesFromPath(path string) string {
 		}
 	}
 
-	const rolesSegment = string(filepath.Separator) + "roles" + string(filepath.Separator)
-	const rolesSuffix = string(filepath.Separator) + "roles"
+	const rolesSegment = "/roles/"
+	const rolesSuffix = "/roles"
 	// Handle /roles/ in middle of path
 	if idx := strings.Index(path, rolesSegment); idx != -1 {
 		// Remove the /roles/ segment
-		return path[:idx] + string(filepath.Separator) + path[idx+len(rolesSegment):]
+		return path[:idx] + "/" + path[idx+len(rolesSegment):]
 	}
 	// Handle paths ending with /roles (directory itself)
 	if strings.HasSuffix(path, rolesSuffix) {
 		return path[:len(path)-len(rolesSuffix)]
 	}
 	// Handle paths starting with roles/
-	const rolesPrefix = "roles" + string(filepath.Separator)
+	const rolesPrefix = "roles/"
 	if strings.HasPrefix(path, rolesPrefix) {
 		return path[len(rolesPrefix):]
 	}
@@ -224,10 +313,10 @@ func stripRolesFromPath(path string) string {
 
 // normalizeGroupVarsToVariables renames group_vars to variables in paths
 func normalizeGroupVarsToVariables(path string) string {
-	const groupVarsSegment = string(filepath.Separator) + "group_vars" + string(filepath.Separator)
-	const variablesSegment = string(filepath.Separator) + "variables" + string(filepath.Separator)
-	const groupVarsSuffix = string(filepath.Separator) + "group_vars"
-	const variablesSuffix = string(filepath.Separator) + "variables"
+	const groupVarsSegment = "/group_vars/"
+	const variablesSegment = "/variables/"
+	const groupVarsSuffix = "/group_vars"
+	const variablesSuffix = "/variables"
 	// Handle /group_vars/ in middle of path
 	if idx := strings.Index(path, groupVarsSegment); idx != -1 {
 		return path[:idx] + variablesSegment + path[idx+len(groupVarsSegment):]
@@ -237,8 +326,8 @@ func normalizeGroupVarsToVariables(path string) string {
 		return path[:len(path)-len(groupVarsSuffix)] + variablesSuffix
 	}
 	// Handle paths starting with group_vars/
-	const groupVarsPrefix = "group_vars" + string(filepath.Separator)
-	const variablesPrefix = "variables" + string(filepath.Separator)
+	const groupVarsPrefix = "group_vars/"
+	const variablesPrefix = "variables/"
 	if strings.HasPrefix(path, groupVarsPrefix) {
 		return variablesPrefix + path[len(groupVarsPrefix):]
 	}
@@ -260,18 +349,63 @@ type Builder struct {
 	skipNotVersioned bool
 	logConflicts     bool
 	packages         []*Package
+	aliases          map[string]string
+	summary          *Summary
+	overlays         []Overlay
+	applyOverlays    []Overlay
+	compose          *Composition
+	permissions      *permissionPolicy
+	strict           bool
+	symlinks         string
+	rejectLegacy     bool
+	excluded         exclusions
+	respectGitignore bool
+	includeStaged    bool
+	// fullMerge merges all paths, otherwise paths of unchanged packages are reused from the previous merge.
+	fullMerge   bool
+	previousDir string
+	integrity   []PackageIntegrity
+	// manifest records the merge for partial merges of the next compose.
+	manifest *mergeManifest
 }
 
+// fsEntry is a path of the merge plan. Huge compositions keep hundreds of thousands of them in memory,
+// paths share strings of the walk and only the metadata needed for merging is stored.
 type fsEntry struct {
-	Prefix   string
-	SrcPath  string // Original source path within package
-	DstPath  string // Adjusted destination path (may have src/ prefix)
-	Entry    fs.FileInfo
-	Excluded bool
-	From     string
+	Prefix  string
+	SrcPath string // Original source path within package
+	DstPath string // Adjusted destination path (may have src/ prefix)
+	From    string
+	Entry   fileMeta
+	// mergedYAML is the next file deep-merged into this one by merge-yaml.
+	mergedYAML *fsEntry
+}
+
+// fileMeta is the part of fs.FileInfo used for merging, full FileInfo values hold the name and raw stat of the file.
+type fileMeta struct {
+	modTime int64 // Unix nanoseconds
+	mode    fs.FileMode
+	// reused metadata is recorded by the previous merge, partial merges link its winner from the previous result.
+	reused bool
+}
+
+func newFileMeta(info fs.FileInfo) fileMeta {
+	return fileMeta{modTime: info.ModTime().UnixNano(), mode: info.Mode()}
+}
+
+func (m fileMeta) Mode() fs.FileMode {
+	return m.mode
+}
+
+func (m fileMeta) IsDir() bool {
+	return m.mode.IsDir()
+}
+
+func (m fileMeta) ModTime() time.Time {
+	return time.Unix(0, m.modTime)
 }
 
-func createBuilder(c *Composer, targetDir, sourceDir string, packages []*Package) *Builder {
+func createBuilder(c *Composer, targetDir, sourceDir string, packages []*Package, aliases map[string]string, overlays []Overlay, perms *permissionPolicy, integrity []PackageIntegrity) *Builder {
 	return &Builder{
 		c.WithLogger,
 		c.WithTerm,
@@ -281,37 +415,128 @@ func createBuilder(c *Composer, targetDir, sourceDir string, packages []*Package
 		c.options.SkipNotVersioned,
 		c.options.ConflictsVerbosity,
 		packages,
+		aliases,
+		c.summary,
+		c.getCompose().Overlays,
+		overlays,
+		c.getCompose(),
+		perms,
+		c.options.Strict,
+		c.options.Symlinks,
+		c.options.RejectLegacyLayout,
+		newExclusions(c.getCompose()),
+		c.options.RespectGitignore,
+		c.options.IncludeStaged,
+		c.options.FullMerge || c.options.Clean,
+		c.getPath(BuildDir),
+		integrity,
+		nil,
+	}
+}
+
+// openVersionedRepo opens the git repository of dir: GIT_DIR if it's set, otherwise the repository containing dir,
+// found in parent directories like git does, worktrees and submodules included. The path of dir inside of the
+// worktree of the repository is returned, "." if dir is its root.
+func openVersionedRepo(dir string) (*git.Repository, string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		repo, err := git.PlainOpenWithOptions(gitDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+		if err != nil {
+			return nil, "", fmt.Errorf("GIT_DIR %s: %w", gitDir, err)
+		}
+		root := os.Getenv("GIT_WORK_TREE")
+		if root == "" {
+			return repo, ".", nil
+		}
+		if root, err = filepath.Abs(root); err != nil {
+			return nil, "", err
+		}
+		prefix, err := filepath.Rel(root, absDir)
+		if err != nil || strings.HasPrefix(prefix, "..") {
+			return nil, "", fmt.Errorf("%s is outside of GIT_WORK_TREE %s", dir, root)
+		}
+		return repo, filepath.ToSlash(prefix), nil
+	}
+
+	repo, err := git.PlainOpenWithOptions(absDir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", dir, err)
+	}
+	prefix, err := filepath.Rel(wt.Filesystem.Root(), absDir)
+	if err != nil {
+		return nil, "", err
 	}
+
+	return repo, filepath.ToSlash(prefix), nil
 }
 
-func getVersionedMap(gitDir string) (map[string]bool, error) {
+// getVersionedMap returns files committed at HEAD of the repository of gitDir and their parent directories,
+// paths are relative to gitDir. Files added to the index are included with includeIndex.
+func getVersionedMap(gitDir string, includeIndex bool) (map[string]bool, error) {
 	versionedFiles := make(map[string]bool)
-	repo, err := git.PlainOpenWithOptions(gitDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	repo, prefix, err := openVersionedRepo(gitDir)
 	if err != nil {
 		return versionedFiles, err
 	}
 	head, err := repo.Head()
+	if err != nil {
+		return versionedFiles, fmt.Errorf("HEAD: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return versionedFiles, err
+	}
+	tree, err := commit.Tree()
 	if err != nil {
 		return versionedFiles, err
 	}
+	add := func(name string) {
+		if prefix != "." {
+			var ok bool
+			if name, ok = strings.CutPrefix(name, prefix+"/"); !ok {
+				return
+			}
+		}
 
-	commit, _ := repo.CommitObject(head.Hash())
-	tree, _ := commit.Tree()
-	err = tree.Files().ForEach(func(f *object.File) error {
-		dir := filepath.Dir(f.Name)
-		if _, ok := versionedFiles[dir]; !ok {
+		versionedFiles[name] = true
+		for dir := path.Dir(name); !versionedFiles[dir]; dir = path.Dir(dir) {
 			versionedFiles[dir] = true
+			if dir == "." {
+				break
+			}
 		}
-
-		versionedFiles[f.Name] = true
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		add(f.Name)
 		return nil
 	})
+	if err != nil || !includeIndex {
+		return versionedFiles, err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return versionedFiles, fmt.Errorf("index: %w", err)
+	}
+	for _, e := range idx.Entries {
+		add(e.Name)
+	}
 
-	return versionedFiles, err
+	return versionedFiles, nil
 }
 
 func (b *Builder) build(ctx context.Context) error {
 	b.Term().Printfln("Merging packages...")
+	start := time.Now()
 	err := EnsureDirExists(b.targetDir)
 	if err != nil {
 		return err
@@ -320,15 +545,29 @@ func (b *Builder) build(ctx context.Context) error {
 	versionedMap := make(map[string]bool)
 	checkVersioned := b.skipNotVersioned
 	if checkVersioned {
-		versionedMap, err = getVersionedMap(b.platformDir)
+		versionedMap, err = getVersionedMap(b.platformDir, b.includeStaged)
 		if err != nil {
+			b.Term().Warning().Printfln("Unversioned files are merged, versioned files of the source directory can't be read: %v", err)
 			checkVersioned = false
 		}
 	}
 
-	ls, ps := retrieveStrategies(b.packages)
+	var shared []Strategy
+	if b.compose != nil {
+		shared = b.compose.Strategies
+	}
+	ls, ps := retrieveStrategies(b.packages, shared, hasModernLayout(b.platformDir))
+	cr := newConflictResolver(b.conflictDefault())
 	baseFs := os.DirFS(b.platformDir)
 
+	// Skipping unversioned files drops ignored ones already, .gitignore is read only when all local files are merged.
+	var ignored *gitignoreRules
+	if b.respectGitignore && !checkVersioned {
+		if ignored, err = newGitignoreRules(baseFs); err != nil {
+			return err
+		}
+	}
+
 	// Build package map for identifier lookup
 	packagesMap := make(map[string]*Package)
 	for _, p := range b.packages {
@@ -337,6 +576,7 @@ func (b *Builder) build(ctx context.Context) error {
 
 	entriesMap := make(map[string]*fsEntry)
 	var entriesTree []*fsEntry
+	records := make(mergeRecords)
 
 	// @todo move to function
 	err = fs.WalkDir(baseFs, ".", func(path string, d fs.DirEntry, err error) error {
@@ -352,6 +592,31 @@ func (b *Builder) build(ctx context.Context) error {
 			if _, ok := excludedFolders[root]; ok {
 				return nil
 			}
+			if _, ok := b.excluded.match(path, d.IsDir()); ok {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
+			if ignored != nil && path != gitPrefix && !strings.HasPrefix(path, gitPrefix+"/") {
+				if ignored.match(path, d.IsDir()) {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() {
+					if err := ignored.enter(path); err != nil {
+						return err
+					}
+				}
+			}
+
+			// Overlays are applied over the merged result, not merged as local files.
+			if d.IsDir() && isOverlayPath(path, b.overlays) {
+				return fs.SkipDir
+			}
 
 			if !d.IsDir() {
 				filename := filepath.Base(path)
@@ -376,10 +641,14 @@ func (b *Builder) build(ctx context.Context) error {
 				}
 			}
 
-			finfo, _ := d.Info()
-			entry := &fsEntry{Prefix: b.platformDir, SrcPath: path, DstPath: path, Entry: finfo, Excluded: false, From: "domain repo"}
+			finfo, err := d.Info()
+			if err != nil {
+				return err
+			}
+			entry := &fsEntry{Prefix: b.platformDir, SrcPath: path, DstPath: path, Entry: newFileMeta(finfo), From: localOrigin}
 			entriesTree = append(entriesTree, entry)
 			entriesMap[path] = entry
+			records.add(path, entry)
 			return nil
 		}
 	})
@@ -392,73 +661,128 @@ func (b *Builder) build(ctx context.Context) error {
 	items, _ := graph.TopSort(DependencyRoot)
 	targetsMap := getTargetsMap(b.packages)
 
+	conflicts := newConflictReport(b.conflictDefault())
 	if b.logConflicts {
 		b.Term().Info().Printf("Conflicting files:\n")
 	}
 
-	for i := 0; i < len(items); i++ {
+	// Packages unchanged since the previous merge keep their paths, only paths of changed ones are merged again.
+	order := slices.DeleteFunc(slices.Clone(items), func(name string) bool { return name == DependencyRoot })
+	pkgPaths := make(map[string]string, len(order))
+	for _, pkgName := range order {
+		pkgPaths[pkgName] = b.packagePath(pkgName, packagesMap, targetsMap)
+	}
+	fingerprint, states := b.mergeFingerprint(), b.packageStates(order, packagesMap, pkgPaths)
+	partial := b.planPartialMerge(fingerprint, states, order, packagesMap, pkgPaths, entriesMap)
+	if partial != nil {
+		entriesTree, records, err = b.mergeChanged(partial, entriesMap, packagesMap, ps, cr, conflicts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < len(items) && partial == nil; i++ {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 			pkgName := items[i]
 			if pkgName != DependencyRoot {
-				pkgPath := filepath.Join(b.sourceDir, pkgName, targetsMap[pkgName])
-
-				// Detect package layout
-				isModern := hasModernLayout(pkgPath)
-				if isModern {
-					b.Log().Debug("package has modern layout with src/", "package", pkgName)
-				} else {
-					b.Log().Debug("package has legacy layout, normalizing layers to src/", "package", pkgName)
+				var exclude []string
+				if pkg, ok := packagesMap[pkgName]; ok {
+					exclude = pkg.Source.Exclude
 				}
 
-				packageFs := os.DirFS(pkgPath)
-				strategies, ok := ps[pkgName]
-				err = fs.WalkDir(packageFs, ".", func(path string, d fs.DirEntry, err error) error {
-					if err != nil {
-						return err
+				strategies := ps[pkgName]
+				replaced := make(map[string]replacedFile)
+				err = b.walkPackage(pkgName, pkgPaths[pkgName], exclude, func(packageFs fs.FS, isModern bool, entry *fsEntry) (bool, error) {
+					path, adjustedPath := entry.SrcPath, entry.DstPath
+					if to, moved := normalizedPath(path, isModern); moved {
+						b.summary.addNormalized(Normalization{Package: pkgName, From: path, To: to})
 					}
 
-					// Skip .git folder from packages
-					if strings.HasPrefix(path, gitPrefix) {
-						return nil
+					// The first conflicting file of a component selects the whole component directory.
+					if entry.Entry.IsDir() && b.conflictUnit() == ConflictUnitComponent && isComponentDir(adjustedPath) {
+						if _, found := entriesMap[adjustedPath]; found {
+							skip, err := b.mergeComponent(packageFs, path, isModern, exclude, entry, strategies, cr, &entriesTree, entriesMap, replaced, conflicts)
+							if err != nil || skip {
+								return skip, err
+							}
+						}
 					}
 
-					var conflictReslv mergeConflictResolve
-					finfo, _ := d.Info()
-
-					// Adjust destination path based on layout
-					adjustedPath := adjustDestinationPath(path, isModern)
-
-					entry := &fsEntry{Prefix: pkgPath, SrcPath: path, DstPath: adjustedPath, Entry: finfo, Excluded: false, From: pkgName}
+					var previous string
+					if existing, found := entriesMap[adjustedPath]; found {
+						previous = existing.From
+					}
 
-					if !ok {
-						// No strategies for package. Proceed with default merge.
-						entriesTree, conflictReslv = addEntries(entriesTree, entriesMap, entry, adjustedPath)
-					} else {
-						entriesTree, conflictReslv = addStrategyEntries(strategies, entriesTree, entriesMap, entry, adjustedPath)
+					// Packages without strategies proceed with default merge.
+					var conflictReslv mergeConflictResolve
+					var ms *mergeStrategy
+					entriesTree, conflictReslv, ms = addStrategyEntries(strategies, entriesTree, entriesMap, entry, adjustedPath, cr)
+					if r, ok := replaced[adjustedPath]; ok && conflictReslv == noConflict && entriesMap[adjustedPath] == entry {
+						previous, conflictReslv, ms = r.from, resolveToPackage, r.ms
+					}
+					if _, merged := entriesMap[adjustedPath]; merged {
+						records.add(adjustedPath, entry)
 					}
 
-					if b.logConflicts && !finfo.IsDir() {
-						b.logConflictResolve(conflictReslv, adjustedPath, pkgName, entriesMap[adjustedPath])
+					if !entry.Entry.IsDir() {
+						b.summary.addConflict(conflictReslv)
+						records.resolved(adjustedPath, conflictReslv)
+						if conflictReslv != noConflict {
+							conflicts.add(adjustedPath, previous, pkgName, entriesMap[adjustedPath], ms)
+						}
+						if b.logConflicts {
+							b.logConflictResolve(conflictReslv, adjustedPath, pkgName, entriesMap[adjustedPath])
+						}
 					}
 
-					return nil
+					return false, nil
 				})
 
 				if err != nil {
 					return err
 				}
 
+				if b.rejectLegacy {
+					if err = legacyLayoutError(pkgName, b.summary.Normalized); err != nil {
+						return err
+					}
+				}
+
 				// Print checkmark for merged package
 				if pkg, ok := packagesMap[pkgName]; ok {
-					b.Term().Printfln("  ✓ %s", pkg.GetIdentifier())
+					b.Term().Printfln("  %s %s", output.Get().Check, pkg.GetIdentifier())
 				}
 			}
 		}
 	}
 
+	b.summary.Files = countMergedFiles(entriesTree, items)
+	b.summary.Components = countMergedComponents(entriesTree, items, conflicts)
+	b.summary.addPhase(PhaseMerge, start)
+	if err = SaveConflicts(b.platformDir, conflicts); err != nil {
+		b.Log().Warn("failed to save conflicts report", "error", err)
+	}
+	b.manifest = &mergeManifest{
+		Version:     mergeManifestVersion,
+		Fingerprint: fingerprint,
+		Order:       order,
+		States:      states,
+		Normalized:  b.summary.Normalized,
+		Records:     records.list(entriesTree),
+	}
+	if err = b.verifyStructure(entriesTree); err != nil {
+		return err
+	}
+	if caseInsensitiveDir(b.targetDir) {
+		for _, c := range caseCollisions(entriesTree) {
+			b.Term().Warning().Printfln("%s of %s and %s of %s differ only in case, the latter overwrites the former on this volume", c[0].DstPath, c[0].From, c[1].DstPath, c[1].From)
+		}
+	}
+	start = time.Now()
+
 	// @todo check rsync
 	for _, treeItem := range entriesTree {
 		select {
@@ -468,7 +792,12 @@ func (b *Builder) build(ctx context.Context) error {
 			sourcePath := filepath.Join(treeItem.Prefix, treeItem.SrcPath)
 			destPath := filepath.Join(b.targetDir, treeItem.DstPath)
 			isSymlink := false
-			permissions := os.FileMode(dirPermissions)
+			permissions := b.permissions.dirMode(filepath.ToSlash(treeItem.DstPath))
+
+			// Paths of packages unchanged since the previous merge are linked from its result.
+			if treeItem.Entry.reused && b.reuse(treeItem, destPath) {
+				continue
+			}
 
 			switch treeItem.Entry.Mode() & os.ModeType {
 			case os.ModeDir:
@@ -476,15 +805,26 @@ func (b *Builder) build(ctx context.Context) error {
 					return err
 				}
 			case os.ModeSymlink:
-				if err := lcopy(sourcePath, destPath); err != nil {
+				// Materialized symlinks get modes of the permissions policy while copying.
+				if err := b.copySymlink(treeItem, sourcePath, destPath); err != nil {
 					return err
 				}
 				isSymlink = true
 			default:
-				permissions = treeItem.Entry.Mode()
-				if err := fcopy(sourcePath, destPath); err != nil {
+				permissions = b.permissions.fileMode(filepath.ToSlash(treeItem.DstPath), treeItem.Entry.Mode())
+				if treeItem.mergedYAML != nil {
+					written, err := b.writeMergedYAML(treeItem, destPath)
+					if err != nil {
+						return err
+					}
+					b.summary.BytesCopied += written
+					break
+				}
+				written, err := fcopy(sourcePath, destPath)
+				if err != nil {
 					return err
 				}
+				b.summary.BytesCopied += written
 			}
 
 			if !isSymlink {
@@ -495,18 +835,250 @@ func (b *Builder) build(ctx context.Context) error {
 		}
 	}
 
-	b.Term().Printfln("Composition completed.")
+	b.summary.addPhase(PhaseCopy, start)
+
+	if err = b.overlay(); err != nil {
+		return err
+	}
+
+	if err = b.substitute(); err != nil {
+		return err
+	}
+
+	return b.verify()
+}
+
+// verify checks merged output for broken and escaping symlinks.
+// Problems fail the build in strict mode, otherwise they are reported as warnings.
+func (b *Builder) verify() error {
+	problems, err := verifyMergedOutput(b.targetDir)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if b.strict {
+		return fmt.Errorf("%w:\n  %s", errUnsafeOutput, strings.Join(problems, "\n  "))
+	}
+
+	for _, p := range problems {
+		b.Term().Warning().Printfln("%s", p)
+	}
+
 	return nil
 }
 
+// overlay applies selected overlays over the merged result.
+func (b *Builder) overlay() error {
+	if len(b.applyOverlays) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	for _, o := range b.applyOverlays {
+		files, err := applyOverlay(b.platformDir, b.targetDir, o, b.permissions)
+		if err != nil {
+			return err
+		}
+		b.Term().Printfln("  %s overlay %s", output.Get().Check, o.GetName())
+		b.summary.Overlays = append(b.summary.Overlays, PackageFiles{Name: o.GetName(), Files: files})
+	}
+	b.summary.addPhase(PhaseOverlay, start)
+
+	return nil
+}
+
+// substitute replaces composition placeholders in merged files declared in compose.yaml.
+func (b *Builder) substitute() error {
+	if b.compose == nil || b.compose.Substitution == nil || len(b.compose.Substitution.Paths) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	vars := substitutionVars(b.compose, b.platformDir, b.packages)
+	if _, ok := vars[VarModelVersion]; !ok {
+		b.Term().Warning().Printfln("Model version is unknown, %s placeholders are kept", VarModelVersion)
+	}
+
+	changed, err := substituteFiles(b.targetDir, b.compose.Substitution.Paths, vars)
+	if err != nil {
+		return fmt.Errorf("variable substitution failed: %w", err)
+	}
+	b.summary.Substituted = changed
+	b.summary.addPhase(PhaseSubstitute, start)
+
+	return nil
+}
+
+// packagePath returns the directory of files of the package merged into the model.
+func (b *Builder) packagePath(pkgName string, packagesMap map[string]*Package, targetsMap map[string]string) string {
+	pkgPath := filepath.Join(b.sourceDir, b.checkoutName(pkgName), targetsMap[pkgName])
+	if pkg, ok := packagesMap[pkgName]; ok {
+		pkgPath = packageDir(pkgPath, pkg)
+	}
+
+	return pkgPath
+}
+
+// walkPackage walks files of the package which aren't excluded, their destination paths are adjusted to the layout
+// of the package. Directories are skipped if fn reports so.
+func (b *Builder) walkPackage(pkgName, pkgPath string, exclude []string, fn func(packageFs fs.FS, isModern bool, entry *fsEntry) (bool, error)) error {
+	// Detect package layout
+	isModern := hasModernLayout(pkgPath)
+	if isModern {
+		b.Log().Debug("package has modern layout with src/", "package", pkgName)
+	} else {
+		b.Log().Debug("package has legacy layout, normalizing layers to src/", "package", pkgName)
+	}
+
+	packageFs := os.DirFS(pkgPath)
+	return fs.WalkDir(packageFs, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip .git folder from packages
+		if strings.HasPrefix(path, gitPrefix) {
+			return nil
+		}
+
+		_, excluded := excludedPath(path, exclude)
+		if _, ok := b.excluded.match(path, d.IsDir()); ok || excluded {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// Adjust destination path based on layout
+		entry := &fsEntry{Prefix: pkgPath, SrcPath: path, DstPath: adjustDestinationPath(path, isModern), Entry: newFileMeta(finfo), From: pkgName}
+		skip, err := fn(packageFs, isModern, entry)
+		if err != nil {
+			return err
+		}
+		if skip && d.IsDir() {
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+}
+
+// checkoutName returns name of the package directory holding the package files.
+func (b *Builder) checkoutName(pkgName string) string {
+	if owner, ok := b.aliases[pkgName]; ok {
+		return owner
+	}
+
+	return pkgName
+}
+
+func (b *Builder) conflictDefault() string {
+	if b.compose == nil {
+		return ""
+	}
+
+	return b.compose.ConflictDefault
+}
+
+func (b *Builder) conflictUnit() string {
+	if b.compose == nil {
+		return ""
+	}
+
+	return b.compose.ConflictUnit
+}
+
+// mergeComponent resolves conflicts of the package component directory entry as a whole by its first conflicting file.
+// Merged component is dropped when the package wins, its files are tracked in replaced to be reported as conflicts.
+// It reports if the package component is skipped. Deep-merged YAML falls back to the merge file by file.
+func (b *Builder) mergeComponent(packageFs fs.FS, dir string, isModern bool, exclude []string, entry *fsEntry, strategies []*mergeStrategy, cr *conflictResolver, entriesTree *[]*fsEntry, entriesMap map[string]*fsEntry, replaced map[string]replacedFile, conflicts *ConflictReport) (bool, error) {
+	files, err := componentConflicts(packageFs, dir, isModern, exclude, entry.Prefix, entry.From, entriesMap)
+	if err != nil || len(files) == 0 {
+		return false, err
+	}
+
+	action, ms := decideEntry(strategies, entriesMap[files[0].DstPath], files[0], files[0].DstPath, cr)
+	switch action {
+	case replaceEntry:
+		var removed map[string]string
+		*entriesTree, removed = removeComponent(*entriesTree, entriesMap, entry.DstPath)
+		for path, from := range removed {
+			replaced[path] = replacedFile{from: from, ms: ms}
+		}
+		b.Log().Debug("component selected from package", "path", entry.DstPath, "package", entry.From)
+
+		return false, nil
+	case skipEntry:
+		for _, f := range files {
+			existing := entriesMap[f.DstPath]
+			b.summary.addConflict(resolveToLocal)
+			conflicts.add(f.DstPath, existing.From, entry.From, existing, ms)
+			if b.logConflicts {
+				b.logConflictResolve(resolveToLocal, f.DstPath, entry.From, existing)
+			}
+		}
+
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 func (b *Builder) logConflictResolve(resolveto mergeConflictResolve, path, pkgName string, entry *fsEntry) {
 	if resolveto == noConflict {
 		return
 	}
 
+	if resolveto == resolveMerged {
+		b.Term().Info().Printfln("[%s] - %s > Merged into file of %s", pkgName, path, entry.From)
+		return
+	}
+
 	b.Term().Info().Printfln("[%s] - %s > Selected from %s", pkgName, path, entry.From)
 }
 
+// writeMergedYAML writes the file deep-merged from the entry and files merged into it by merge-yaml.
+func (b *Builder) writeMergedYAML(item *fsEntry, destPath string) (int64, error) {
+	var sources []string
+	for e := item; e != nil; e = e.mergedYAML {
+		sources = append(sources, filepath.Join(e.Prefix, e.SrcPath))
+	}
+
+	content, err := deepMergeYAML(sources)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(content)), os.WriteFile(destPath, content, 0666) //nolint:gosec // modes are set by the permissions policy
+}
+
+// countMergedFiles returns number of merged files per origin, domain repo first, then packages in merge order.
+func countMergedFiles(entriesTree []*fsEntry, order []string) []PackageFiles {
+	counts := make(map[string]int)
+	for _, entry := range entriesTree {
+		if !entry.Entry.IsDir() {
+			counts[entry.From]++
+		}
+	}
+
+	var result []PackageFiles
+	for _, name := range append([]string{localOrigin}, order...) {
+		if n, ok := counts[name]; ok {
+			result = append(result, PackageFiles{Name: name, Files: n})
+		}
+	}
+
+	return result
+}
+
 func getTargetsMap(packages []*Package) map[string]string {
 	targets := make(map[string]string)
 	for _, p := range packages {
@@ -516,21 +1088,20 @@ func getTargetsMap(packages []*Package) map[string]string {
 	return targets
 }
 
-func addEntries(entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *fsEntry, path string) ([]*fsEntry, mergeConflictResolve) {
-	conflictResolve := noConflict
-	if _, ok := entriesMap[path]; !ok {
-		entriesTree = append(entriesTree, entry)
-		entriesMap[path] = entry
-	} else {
-		// Be default all conflicts auto-resolved to local.
-		conflictResolve = resolveToLocal
-	}
+type mergeAction uint8
 
-	return entriesTree, conflictResolve
-}
+const (
+	skipEntry mergeAction = iota
+	appendEntry
+	replaceEntry
+	mergeEntry
+)
 
-func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *fsEntry, path string) ([]*fsEntry, mergeConflictResolve) {
-	conflictResolve := noConflict
+// decideEntry returns how a package entry is merged at path and the strategy which decided it,
+// nil strategy means the default merge: new paths are added, conflicts are resolved by conflict_default.
+// Existing entry is nil when path isn't merged yet.
+func decideEntry(strategies []*mergeStrategy, existing, entry *fsEntry, path string, cr *conflictResolver) (mergeAction, *mergeStrategy) {
+	exists := existing != nil
 
 	// Apply strategies package strategies
 	for _, ms := range strategies {
@@ -541,23 +1112,15 @@ func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, ent
 				continue
 			}
 
-			if localMapEntry, ok := entriesMap[path]; !ok {
-				entriesTree = append(entriesTree, entry)
-				entriesMap[path] = entry
-			} else if ensureStrategyPrefixPath(path, ms.paths) {
-				localMapEntry.Prefix = entry.Prefix
-				localMapEntry.SrcPath = entry.SrcPath
-				localMapEntry.DstPath = entry.DstPath
-				localMapEntry.Entry = entry.Entry
-				localMapEntry.From = entry.From
-
-				// Strategy replaces local Paths by package one.
-				conflictResolve = resolveToPackage
+			if !exists {
+				return appendEntry, ms
 			}
+
+			// Strategy replaces local Paths by package one.
+			return replaceEntry, ms
 		case filterPackageFiles:
-			if _, ok := entriesMap[path]; !ok && (ensureStrategyPrefixPath(path, ms.paths) || (entry.Entry.IsDir() && ensureStrategyContainsPath(path, ms.paths))) {
-				entriesTree = append(entriesTree, entry)
-				entriesMap[path] = entry
+			if !exists && (ensureStrategyPrefixPath(path, ms.paths) || (entry.Entry.IsDir() && ensureStrategyContainsPath(path, ms.paths))) {
+				return appendEntry, ms
 			}
 
 		case ignoreExtraPackageFiles:
@@ -566,27 +1129,90 @@ func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, ent
 				continue
 			}
 			// just do nothing and skip
+		case preferNewestFiles:
+			// Skip strategy if filepath does not match strategy Paths
+			if !ensureStrategyPrefixPath(path, ms.paths) {
+				continue
+			}
+
+			if !exists {
+				return appendEntry, ms
+			}
+
+			// Strategy replaces existing file only by a more recent one.
+			if cr.newer(existing, entry) {
+				return replaceEntry, ms
+			}
+		case mergeYAMLFiles:
+			// Strategy applies only to conflicts of YAML files, other paths are merged as usual.
+			if !ensureStrategyPrefixPath(path, ms.paths) || !exists || !isYAMLPath(path) ||
+				!existing.Entry.Mode().IsRegular() || !entry.Entry.Mode().IsRegular() {
+				continue
+			}
+
+			return mergeEntry, ms
 		}
 
-		return entriesTree, conflictResolve
+		return skipEntry, ms
+	}
+
+	if !exists {
+		return appendEntry, nil
 	}
 
-	return addEntries(entriesTree, entriesMap, entry, path)
+	if cr.resolveToPackage(existing, entry) {
+		return replaceEntry, nil
+	}
+
+	return skipEntry, nil
 }
 
-func ensureStrategyPrefixPath(path string, strategyPaths []string) bool {
-	for _, sp := range strategyPaths {
-		if strings.HasPrefix(path, sp) {
-			return true
+// addStrategyEntries adds the entry to the merge plan, it returns the strategy which decided, nil if none did.
+func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *fsEntry, path string, cr *conflictResolver) ([]*fsEntry, mergeConflictResolve, *mergeStrategy) {
+	existing, exists := entriesMap[path]
+	action, ms := decideEntry(strategies, existing, entry, path, cr)
+
+	switch action {
+	case appendEntry:
+		entriesTree = append(entriesTree, entry)
+		entriesMap[path] = entry
+	case replaceEntry:
+		existing.Prefix = entry.Prefix
+		existing.SrcPath = entry.SrcPath
+		existing.DstPath = entry.DstPath
+		existing.Entry = entry.Entry
+		existing.From = entry.From
+		existing.mergedYAML = nil
+
+		return entriesTree, resolveToPackage, ms
+	case mergeEntry:
+		last := existing
+		for last.mergedYAML != nil {
+			last = last.mergedYAML
+		}
+		last.mergedYAML = entry
+
+		return entriesTree, resolveMerged, ms
+	case skipEntry:
+		if exists && (ms == nil || ms.s == preferNewestFiles) {
+			return entriesTree, resolveToLocal, ms
 		}
 	}
 
-	return false
+	return entriesTree, noConflict, ms
 }
 
-func ensureStrategyContainsPath(path string, strategyPaths []string) bool {
+// ensureStrategyPrefixPath reports if the path is inside of a literal strategy path or matches a glob or regex
+// strategy path, see strategypath.go. Invalid patterns are rejected by validateStrategies and match nothing.
+func ensureStrategyPrefixPath(path string, strategyPaths []string) bool {
 	for _, sp := range strategyPaths {
-		if strings.Contains(sp, path) {
+		if !isStrategyPattern(sp) {
+			if strings.HasPrefix(path, sp) {
+				return true
+			}
+			continue
+		}
+		if pattern, err := compileStrategyPattern(sp); err == nil && pattern.match(path) {
 			return true
 		}
 	}
@@ -594,31 +1220,21 @@ func ensureStrategyContainsPath(path string, strategyPaths []string) bool {
 	return false
 }
 
-func buildDependenciesGraph(packages []*Package) *topsort.Graph {
-	graph := topsort.NewGraph()
-	packageNames := make(map[string]bool)
-
-	for _, a := range packages {
-		if _, k := packageNames[a.GetName()]; !k {
-			packageNames[a.GetName()] = true
-		}
-
-		graph.AddNode(a.GetName())
-		if a.Dependencies != nil {
-			for _, d := range a.Dependencies {
-				_ = graph.AddEdge(a.GetName(), d)
-				packageNames[d] = false
+// ensureStrategyContainsPath reports if the directory leads to paths of the strategy.
+func ensureStrategyContainsPath(path string, strategyPaths []string) bool {
+	for _, sp := range strategyPaths {
+		if !isStrategyPattern(sp) {
+			if strings.Contains(sp, path) {
+				return true
 			}
+			continue
 		}
-	}
-
-	for n, k := range packageNames {
-		if k {
-			_ = graph.AddEdge(DependencyRoot, n)
+		if pattern, err := compileStrategyPattern(sp); err == nil && pattern.leadsTo(path) {
+			return true
 		}
 	}
 
-	return graph
+	return false
 }
 
 func lcopy(src, dest string) error {
@@ -629,35 +1245,11 @@ func lcopy(src, dest string) error {
 		}
 		return err
 	}
-	return os.Symlink(src, dest)
+	return createSymlink(filepath.ToSlash(src), dest)
 }
 
-func fcopy(src, dst string) error {
-	sourceFileStat, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-
-	if !sourceFileStat.Mode().IsRegular() {
-		return fmt.Errorf("%s is not a regular file", src)
-	}
-
-	source, err := os.Open(filepath.Clean(src))
-	if err != nil {
-		return err
-	}
-	defer source.Close()
-
-	destination, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-
-	if _, err := io.Copy(destination, source); err != nil {
-		return err
-	}
-
-	return destination.Close()
+func fcopy(src, dst string) (int64, error) {
+	return fsutil.CopyFile(src, dst, 0666)
 }
 
 func exists(path string) bool {
@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
+	"unsafe"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -49,7 +51,7 @@ func TestGetVersionedMap(t *testing.T) {
 		t.Fatalf("failed to commit: %v", err)
 	}
 
-	versionedMap, err := getVersionedMap(repoDir)
+	versionedMap, err := getVersionedMap(repoDir, false)
 	if err != nil {
 		t.Fatalf("getVersionedMap failed: %v", err)
 	}
@@ -63,6 +65,23 @@ func TestGetVersionedMap(t *testing.T) {
 	if !versionedMap["dir"] {
 		t.Error("expected 'dir' in versioned map")
 	}
+
+	if err := os.MkdirAll(filepath.Join(repoDir, "staged"), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "staged", "file3.txt"), []byte("content"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := wt.Add("staged/file3.txt"); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+
+	if versionedMap, err = getVersionedMap(repoDir, false); err != nil || versionedMap["staged/file3.txt"] {
+		t.Errorf("expected staged file to be left out of HEAD files, got %v", err)
+	}
+	if versionedMap, err = getVersionedMap(repoDir, true); err != nil || !versionedMap["staged/file3.txt"] || !versionedMap["staged"] {
+		t.Errorf("expected staged file and its dir with index entries, got %v", err)
+	}
 }
 
 func TestGetVersionedMapWorktree(t *testing.T) {
@@ -110,7 +129,7 @@ func TestGetVersionedMapWorktree(t *testing.T) {
 		t.Fatal("expected .git to be a file in worktree, got directory")
 	}
 
-	versionedMap, err := getVersionedMap(worktreeDir)
+	versionedMap, err := getVersionedMap(worktreeDir, false)
 	if err != nil {
 		t.Fatalf("getVersionedMap failed on worktree: %v", err)
 	}
@@ -119,3 +138,239 @@ func TestGetVersionedMapWorktree(t *testing.T) {
 		t.Errorf("expected %q in versioned map from worktree", testFile)
 	}
 }
+
+func TestGetVersionedMapSubdir(t *testing.T) {
+	repoDir := t.TempDir()
+
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	files := []string{"other.txt", "platform/src/platform/file.txt"}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	for _, f := range files {
+		p := filepath.Join(repoDir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("content"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add(f); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+	}
+	_, err = wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	check := func(versionedMap map[string]bool) {
+		t.Helper()
+		for _, p := range []string{".", "src", "src/platform", "src/platform/file.txt"} {
+			if !versionedMap[p] {
+				t.Errorf("expected %q in versioned map", p)
+			}
+		}
+		if versionedMap["other.txt"] || versionedMap["../other.txt"] {
+			t.Errorf("expected files outside of the platform dir to be left out, got %v", versionedMap)
+		}
+	}
+
+	versionedMap, err := getVersionedMap(filepath.Join(repoDir, "platform"), false)
+	if err != nil {
+		t.Fatalf("getVersionedMap failed in subdirectory: %v", err)
+	}
+	check(versionedMap)
+
+	t.Setenv("GIT_DIR", filepath.Join(repoDir, ".git"))
+	t.Setenv("GIT_WORK_TREE", repoDir)
+	versionedMap, err = getVersionedMap(filepath.Join(repoDir, "platform"), false)
+	if err != nil {
+		t.Fatalf("getVersionedMap failed with GIT_DIR: %v", err)
+	}
+	check(versionedMap)
+
+	t.Setenv("GIT_DIR", "")
+	if _, err = getVersionedMap(t.TempDir(), false); err == nil {
+		t.Error("expected directory outside of a repository to fail")
+	}
+}
+
+// createAgedEntries returns entries of files old.txt and new.txt outside of git, modified an hour apart.
+func createAgedEntries(t *testing.T) map[string]*fsEntry {
+	t.Helper()
+	dir := t.TempDir()
+	entries := make(map[string]*fsEntry)
+	for i, name := range []string{"old.txt", "new.txt"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(name), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		mtime := time.Now().Add(time.Duration(i-1) * time.Hour)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("failed to set mtime: %v", err)
+		}
+		finfo, err := os.Lstat(p)
+		if err != nil {
+			t.Fatalf("failed to stat file: %v", err)
+		}
+		entries[name] = &fsEntry{Prefix: dir, SrcPath: name, DstPath: name, Entry: newFileMeta(finfo), From: name}
+	}
+
+	return entries
+}
+
+func TestConflictDefault(t *testing.T) {
+	entries := createAgedEntries(t)
+
+	tests := []struct {
+		conflictDefault string
+		existing        string
+		entry           string
+		expected        mergeAction
+	}{
+		{"", "new.txt", "old.txt", skipEntry},
+		{ConflictDefaultLocal, "old.txt", "new.txt", skipEntry},
+		{ConflictDefaultPackage, "new.txt", "old.txt", replaceEntry},
+		{ConflictDefaultNewest, "old.txt", "new.txt", replaceEntry},
+		{ConflictDefaultNewest, "new.txt", "old.txt", skipEntry},
+	}
+
+	for _, tt := range tests {
+		action, ms := decideEntry(nil, entries[tt.existing], entries[tt.entry], "file.txt", newConflictResolver(tt.conflictDefault))
+		if action != tt.expected || ms != nil {
+			t.Errorf("conflict_default %q, %s over %s: expected %d, got %d", tt.conflictDefault, tt.entry, tt.existing, tt.expected, action)
+		}
+	}
+
+	if err := validateConflictDefault("remote"); err == nil {
+		t.Error("expected error for unknown conflict_default")
+	}
+}
+
+func TestPreferNewestStrategy(t *testing.T) {
+	entries := createAgedEntries(t)
+
+	s, target := identifyStrategy(StrategyPreferNewest)
+	if s != preferNewestFiles || target != packageStrategy {
+		t.Fatalf("expected %s to be a package strategy", StrategyPreferNewest)
+	}
+	strategies := []*mergeStrategy{{s: s, t: target, paths: cleanStrategyPaths([]string{"src"})}}
+	cr := newConflictResolver("")
+
+	entriesMap := map[string]*fsEntry{"src/file.txt": entries["old.txt"]}
+	_, resolve, _ := addStrategyEntries(strategies, nil, entriesMap, entries["new.txt"], "src/file.txt", cr)
+	if resolve != resolveToPackage || entriesMap["src/file.txt"].From != "new.txt" {
+		t.Errorf("expected newer file to win, got %d from %s", resolve, entriesMap["src/file.txt"].From)
+	}
+
+	entriesMap = map[string]*fsEntry{"src/file.txt": entries["new.txt"]}
+	_, resolve, _ = addStrategyEntries(strategies, nil, entriesMap, entries["old.txt"], "src/file.txt", cr)
+	if resolve != resolveToLocal {
+		t.Errorf("expected older file to be skipped, got %d", resolve)
+	}
+
+	if action, _ := decideEntry(strategies, entries["old.txt"], entries["new.txt"], "other/file.txt", cr); action != skipEntry {
+		t.Errorf("expected strategy to ignore paths outside of its paths, got %d", action)
+	}
+}
+
+func TestLayerScopedStrategies(t *testing.T) {
+	shared := []Strategy{
+		{Name: StrategyOverwriteLocal, AppliesTo: Layers{"interaction/"}},
+		{Name: StrategyRemoveExtraLocal, AppliesTo: Layers{"platform"}},
+	}
+	pkg := &Package{Name: "core", Source: Source{Strategies: []Strategy{
+		{Name: StrategyIgnoreExtraPackage, Paths: []string{"src/foundation/"}, AppliesTo: Layers{"cognition"}},
+	}}}
+
+	ls, ps := retrieveStrategies([]*Package{pkg}, shared, false)
+	if len(ls) != 1 || !ensureStrategyPrefixPath("platform/services/nginx", ls[0].paths) {
+		t.Errorf("expected local strategy scoped to legacy platform layer, got %+v", ls)
+	}
+
+	strategies := ps["core"]
+	if len(strategies) != 2 {
+		t.Fatalf("expected package and shared strategies, got %d", len(strategies))
+	}
+	for path, expected := range map[string]mergeStrategyType{
+		"src/foundation/applications/app/tasks/main.yaml": ignoreExtraPackageFiles,
+		"src/cognition/skills/search/defaults/main.yaml":  ignoreExtraPackageFiles,
+		"src/interaction/softwares/ui/defaults/main.yaml": overwriteLocalFile,
+	} {
+		matched := undefinedStrategy
+		for _, ms := range strategies {
+			if ensureStrategyPrefixPath(path, ms.paths) {
+				matched = ms.s
+				break
+			}
+		}
+		if matched != expected {
+			t.Errorf("%s: expected strategy %d, got %d", path, expected, matched)
+		}
+	}
+
+	cfg, err := Lookup(fstest.MapFS{composeFile: {Data: []byte(`name: test
+strategies:
+  - name: overwrite-local-file
+    applies_to: interaction/
+  - name: prefer-newest
+    applies_to: [platform, foundation]
+`)}})
+	if err != nil {
+		t.Fatalf("failed to parse compose.yaml: %v", err)
+	}
+	if len(cfg.Strategies) != 2 || len(cfg.Strategies[0].AppliesTo) != 1 || len(cfg.Strategies[1].AppliesTo) != 2 {
+		t.Errorf("unexpected layer scopes: %+v", cfg.Strategies)
+	}
+	if err = validateStrategies(cfg); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	invalid := &Composition{Strategies: []Strategy{{Name: StrategyOverwriteLocal, AppliesTo: Layers{"unknown"}}}}
+	if err := validateStrategies(invalid); err == nil {
+		t.Error("expected error for unknown layer")
+	}
+}
+
+func TestFileMeta(t *testing.T) {
+	// Entries of the merge plan must stay small, they are kept for every merged path.
+	// The merge-yaml chain adds a pointer.
+	if size := unsafe.Sizeof(fsEntry{}); size > 88 {
+		t.Errorf("fsEntry takes %d bytes, expected at most 88", size)
+	}
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "file")
+	if err := os.WriteFile(p, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := newFileMeta(info)
+	if meta.Mode() != info.Mode() || meta.IsDir() || !meta.ModTime().Equal(info.ModTime()) {
+		t.Errorf("unexpected metadata %v %v of %v %v", meta.Mode(), meta.ModTime(), info.Mode(), info.ModTime())
+	}
+
+	dirInfo, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newFileMeta(dirInfo).IsDir() {
+		t.Error("expected directory metadata")
+	}
+}
@@ -0,0 +1,43 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// caseInsensitiveDir reports if dir is on a case-insensitive volume, e.g. default macOS and Windows volumes.
+func caseInsensitiveDir(dir string) bool {
+	f, err := os.CreateTemp(dir, ".casecheck-")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	f.Close()
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	folded, err := os.Stat(filepath.Join(filepath.Dir(name), strings.ToUpper(filepath.Base(name))))
+
+	return err == nil && os.SameFile(info, folded)
+}
+
+// caseCollisions returns pairs of merged paths differing only in case, the latter overwrites the former
+// on a case-insensitive volume.
+func caseCollisions(entries []*fsEntry) [][2]*fsEntry {
+	seen := make(map[string]*fsEntry, len(entries))
+	var collisions [][2]*fsEntry
+	for _, e := range entries {
+		key := strings.ToLower(e.DstPath)
+		if prev, ok := seen[key]; ok && prev.DstPath != e.DstPath {
+			collisions = append(collisions, [2]*fsEntry{prev, e})
+			continue
+		}
+		seen[key] = e
+	}
+
+	return collisions
+}
@@ -0,0 +1,61 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCaseCollisions(t *testing.T) {
+	entries := []*fsEntry{
+		{DstPath: "src/platform/services/app/README.md", From: localOrigin},
+		{DstPath: "src/platform/services/app/readme.md", From: "pkg"},
+		{DstPath: "src/platform/services/app/main.yaml", From: "pkg"},
+	}
+
+	collisions := caseCollisions(entries)
+	if len(collisions) != 1 || collisions[0][0].From != localOrigin || collisions[0][1].From != "pkg" {
+		t.Errorf("expected README.md and readme.md to collide, got %v", collisions)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := os.Stat(filepath.Join(dir, "FILE"))
+	if caseInsensitiveDir(dir) != (err == nil) {
+		t.Errorf("case-insensitive detection of %s doesn't match the volume on %s", dir, runtime.GOOS)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected detection to clean up, got %d entries", len(entries))
+	}
+}
+
+func TestMergedPathsAreSlashSeparated(t *testing.T) {
+	got := adjustDestinationPath("platform/services/roles/app/group_vars/all.yaml", false)
+	if got != "src/platform/services/app/variables/all.yaml" {
+		t.Errorf("unexpected merged path %s", got)
+	}
+
+	paths := cleanStrategyPaths([]string{filepath.Join("src", "platform") + string(filepath.Separator)})
+	if len(paths) != 1 || paths[0] != "src/platform/" || strings.Contains(paths[0], `\`) {
+		t.Errorf("expected slash separated strategy path, got %v", paths)
+	}
+}
+
+func TestStripRolesFromPath(t *testing.T) {
+	tests := map[string]string{
+		"roles":                             "",
+		"roles/nginx/tasks/main.yaml":       "nginx/tasks/main.yaml",
+		"platform/services/roles/nginx":     "platform/services/nginx",
+		"platform/services/roles":           "platform/services",
+		"platform/actions/roles/deploy.yml": "platform/actions/roles/deploy.yml",
+	}
+	for path, want := range tests {
+		if got := stripRolesFromPath(path); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
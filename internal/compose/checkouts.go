@@ -0,0 +1,68 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Checkouts locates downloaded packages of a composition like compose lays them out:
+// packages with the same source as a package downloaded before them share its checkout.
+type Checkouts struct {
+	packagesDir string
+	// dirs maps package names to their checkout directories relative to packagesDir.
+	dirs map[string]string
+}
+
+// LocateCheckouts walks dependencies of cfg and nested compositions of packages downloaded to packagesDir
+// in the order of compose, refs resolved by lock are used.
+func LocateCheckouts(cfg *Composition, lock *VersionLock, packagesDir string) *Checkouts {
+	c := &Checkouts{packagesDir: packagesDir, dirs: make(map[string]string)}
+	sources := make(map[string]string)
+
+	var walk func(yc *Composition)
+	walk = func(yc *Composition) {
+		for _, d := range yc.Dependencies {
+			pkg := d.ToPackage(d.Name)
+			pkg.Source.Ref = lock.Ref(d)
+			// Packages are resolved to one version, packages requested again are walked once.
+			if _, ok := c.dirs[pkg.GetName()]; ok {
+				continue
+			}
+
+			dir := filepath.Join(pkg.GetName(), pkg.GetTarget())
+			key := sourceKey(pkg)
+			if owner, ok := sources[key]; ok {
+				dir = c.dirs[owner]
+			} else if exists(filepath.Join(packagesDir, dir)) {
+				// Packages which failed to download don't own their source.
+				sources[key] = pkg.GetName()
+			}
+			c.dirs[pkg.GetName()] = dir
+
+			if yc.Flattened {
+				continue
+			}
+			nested, err := Lookup(os.DirFS(packageDir(filepath.Join(packagesDir, dir), pkg)))
+			if err == nil {
+				walk(nested)
+			}
+		}
+	}
+	walk(cfg)
+
+	return c
+}
+
+// CheckoutDir returns the checkout directory of pkg, the checkout of the package sharing it for aliases.
+func (c *Checkouts) CheckoutDir(pkg *Package) string {
+	if dir, ok := c.dirs[pkg.GetName()]; ok {
+		return filepath.Join(c.packagesDir, dir)
+	}
+
+	return filepath.Join(c.packagesDir, pkg.GetName(), pkg.GetTarget())
+}
+
+// PackageDir returns the directory holding content of pkg, the subpath of monorepo packages in their checkout.
+func (c *Checkouts) PackageDir(pkg *Package) string {
+	return packageDir(c.CheckoutDir(pkg), pkg)
+}
@@ -0,0 +1,62 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocateCheckouts(t *testing.T) {
+	packagesDir := t.TempDir()
+	nestedCompose := "name: storage\ndependencies:\n  - name: disks\n    source:\n      url: https://example.com/disks.git\n      ref: v1.0.0\n"
+	for path, content := range map[string]string{
+		"network/v2.1.0/network/main.yaml":      "a",
+		"network/v2.1.0/storage/" + composeFile: nestedCompose,
+		"disks/v1.0.0/main.yaml":                "b",
+	} {
+		p := filepath.Join(packagesDir, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := func(subpath string) Source {
+		return Source{Type: GitType, URL: "https://example.com/packages.git", Ref: "v2.1.0", Subpath: subpath}
+	}
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "network", Source: source("network")},
+			{Name: "storage", Source: source("storage")},
+			{Name: "missing", Source: Source{URL: "https://example.com/missing.git", Ref: "v1.0.0"}},
+		},
+	}
+
+	disks := Dependency{Name: "disks", Source: Source{URL: "https://example.com/disks.git", Ref: "v1.0.0"}}
+	checkouts := LocateCheckouts(cfg, nil, packagesDir)
+	for _, tt := range []struct {
+		dep  Dependency
+		want string
+	}{
+		{cfg.Dependencies[0], "network/v2.1.0/network"},
+		{cfg.Dependencies[1], "network/v2.1.0/storage"},
+		{cfg.Dependencies[2], "missing/v1.0.0"},
+		{disks, "disks/v1.0.0"},
+	} {
+		got := checkouts.PackageDir(tt.dep.ToPackage(tt.dep.Name))
+		if got != filepath.Join(packagesDir, tt.want) {
+			t.Errorf("expected %s in %s, got %s", tt.dep.Name, tt.want, got)
+		}
+	}
+
+	// Checkouts shared by aliases and nested packages of aliases aren't stale.
+	stale, err := FindStalePackages(cfg, nil, packagesDir)
+	if err != nil {
+		t.Fatalf("FindStalePackages failed: %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("expected no stale packages, got %v", stale)
+	}
+}
@@ -0,0 +1,169 @@
+package compose
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Conflict units of compose.yaml, the unit selected as a whole by a conflict resolution.
+const (
+	// ConflictUnitFile resolves conflicts file by file, components may mix files of several packages.
+	ConflictUnitFile = "file"
+	// ConflictUnitComponent resolves a conflict on any file of a component for the whole component directory.
+	ConflictUnitComponent = "component"
+)
+
+var errInvalidConflictUnit = fmt.Errorf("%w: conflict_unit", ErrConflictPolicy)
+
+func validateConflictUnit(conflictUnit string) error {
+	switch conflictUnit {
+	case "", ConflictUnitFile, ConflictUnitComponent:
+		return nil
+	default:
+		return fmt.Errorf("%w %q in %s, expected one of: %s, %s", errInvalidConflictUnit, conflictUnit, composeFile, ConflictUnitFile, ConflictUnitComponent)
+	}
+}
+
+// isComponentDir reports if the merged path is a component directory src/{layer}/{type}/{component}.
+// Actions, docs and variables of a layer aren't components.
+func isComponentDir(path string) bool {
+	parts := strings.Split(path, "/")
+	if len(parts) != 4 || parts[0] != "src" || !layerNames[parts[1]] {
+		return false
+	}
+
+	switch parts[2] {
+	case "actions", "docs", "variables":
+		return false
+	}
+
+	return true
+}
+
+// componentName returns the component of a merged path inside of a component directory as {layer}.{type}.{component}.
+func componentName(path string) (string, bool) {
+	parts := strings.SplitN(path, "/", 5)
+	if len(parts) < 5 || !isComponentDir(strings.Join(parts[:4], "/")) {
+		return "", false
+	}
+
+	return strings.Join(parts[1:4], "."), true
+}
+
+// countMergedComponents groups merged files by components, origins are ordered like in countMergedFiles.
+// Conflicts deep-merged by merge-yaml aren't overrides.
+func countMergedComponents(entriesTree []*fsEntry, order []string, conflicts *ConflictReport) []ComponentFiles {
+	counts := make(map[string]map[string]int)
+	for _, entry := range entriesTree {
+		if entry.Entry.IsDir() {
+			continue
+		}
+		name, ok := componentName(entry.DstPath)
+		if !ok {
+			continue
+		}
+		if counts[name] == nil {
+			counts[name] = make(map[string]int)
+		}
+		counts[name][entry.From]++
+	}
+
+	components := make(map[string]*ComponentFiles)
+	for name, origins := range counts {
+		c := &ComponentFiles{Name: name}
+		for _, origin := range append([]string{localOrigin}, order...) {
+			if n, ok := origins[origin]; ok {
+				c.Files = append(c.Files, PackageFiles{Name: origin, Files: n})
+			}
+		}
+		components[name] = c
+	}
+
+	for _, conflict := range conflicts.Conflicts {
+		name, ok := componentName(conflict.Path)
+		if !ok || components[name] == nil || conflict.Strategy == StrategyMergeYAML {
+			continue
+		}
+		if conflict.Winner == localOrigin {
+			components[name].OverriddenLocally++
+		} else {
+			components[name].OverriddenByPackage++
+		}
+	}
+
+	result := make([]ComponentFiles, 0, len(components))
+	for _, name := range slices.Sorted(maps.Keys(components)) {
+		result = append(result, *components[name])
+	}
+
+	return result
+}
+
+// replacedFile is a merged file dropped with its component by a package component.
+type replacedFile struct {
+	from string
+	ms   *mergeStrategy
+}
+
+// componentConflicts lists files of the package component directory dir conflicting with merged files.
+// Paths of the package are mapped to merged paths by adjustDestinationPath, excluded paths are skipped.
+func componentConflicts(packageFs fs.FS, dir string, isModern bool, exclude []string, prefix, pkgName string, entriesMap map[string]*fsEntry) ([]*fsEntry, error) {
+	var conflicts []*fsEntry
+	err := fs.WalkDir(packageFs, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if _, excluded := excludedPath(path, exclude); excluded {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		adjustedPath := adjustDestinationPath(path, isModern)
+		existing, found := entriesMap[adjustedPath]
+		if !found {
+			return nil
+		}
+
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := &fsEntry{Prefix: prefix, SrcPath: path, DstPath: adjustedPath, Entry: newFileMeta(finfo), From: pkgName}
+		if isFileConflict(existing, entry) {
+			conflicts = append(conflicts, entry)
+		}
+
+		return nil
+	})
+
+	return conflicts, err
+}
+
+// removeComponent drops merged entries inside of the component directory dir, the directory itself is kept.
+// It returns origins of the dropped files by their paths.
+func removeComponent(entriesTree []*fsEntry, entriesMap map[string]*fsEntry, dir string) ([]*fsEntry, map[string]string) {
+	removed := make(map[string]string)
+	prefix := dir + "/"
+	entriesTree = slices.DeleteFunc(entriesTree, func(e *fsEntry) bool {
+		if !strings.HasPrefix(e.DstPath, prefix) {
+			return false
+		}
+		if !e.Entry.IsDir() {
+			removed[e.DstPath] = e.From
+		}
+		delete(entriesMap, e.DstPath)
+		return true
+	})
+
+	return entriesTree, removed
+}
@@ -0,0 +1,65 @@
+package compose
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestIsComponentDir(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"src/platform/services/nginx":             true,
+		"src/platform/services":                   false,
+		"src/platform/services/nginx/tasks":       false,
+		"src/platform/actions/deploy":             false,
+		"src/platform/variables/all":              false,
+		"src/unknown/services/nginx":              false,
+		"platform/services/nginx":                 false,
+		"src/foundation/applications/app/tasks/x": false,
+	} {
+		if got := isComponentDir(path); got != expected {
+			t.Errorf("%s: expected %t, got %t", path, expected, got)
+		}
+	}
+}
+
+func TestComponentConflicts(t *testing.T) {
+	dir := &fsEntry{Entry: fileMeta{mode: fs.ModeDir | 0755}}
+	file := &fsEntry{Entry: fileMeta{mode: 0644, modTime: time.Now().UnixNano()}}
+	entriesMap := map[string]*fsEntry{
+		"src/platform/services/nginx":                   dir,
+		"src/platform/services/nginx/tasks":             dir,
+		"src/platform/services/nginx/tasks/main.yaml":   file,
+		"src/platform/services/nginx/defaults/main.yml": file,
+	}
+	packageFs := fstest.MapFS{
+		"platform/services/roles/nginx/tasks/main.yaml":   {Data: []byte("- debug:\n")},
+		"platform/services/roles/nginx/tasks/extra.yaml":  {Data: []byte("- debug:\n")},
+		"platform/services/roles/nginx/defaults/main.yml": {Data: []byte("port: 80\n")},
+	}
+
+	conflicts, err := componentConflicts(packageFs, "platform/services/roles/nginx", false, []string{"platform/services/roles/nginx/defaults"}, "/pkg", "core", entriesMap)
+	if err != nil {
+		t.Fatalf("componentConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].DstPath != "src/platform/services/nginx/tasks/main.yaml" || conflicts[0].From != "core" {
+		t.Errorf("unexpected conflicts: %+v", conflicts)
+	}
+
+	var entriesTree []*fsEntry
+	for path, e := range entriesMap {
+		entriesTree = append(entriesTree, &fsEntry{DstPath: path, Entry: e.Entry, From: localOrigin})
+	}
+	entriesTree, removed := removeComponent(entriesTree, entriesMap, "src/platform/services/nginx")
+	if len(entriesTree) != 1 || len(entriesMap) != 1 || entriesMap["src/platform/services/nginx"] == nil {
+		t.Errorf("expected only the component directory to remain, got %d entries", len(entriesTree))
+	}
+	if len(removed) != 2 || removed["src/platform/services/nginx/tasks/main.yaml"] != localOrigin {
+		t.Errorf("unexpected removed files: %v", removed)
+	}
+
+	if err = validateConflictUnit("layer"); err == nil {
+		t.Error("expected error for unknown conflict_unit")
+	}
+}
@@ -4,15 +4,19 @@ package compose
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr/pkg/action"
 
+	"github.com/plasmash/plasmactl-model/internal/auth"
 	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
@@ -27,6 +31,7 @@ const (
 var (
 	errComposeNotExists = model.ErrComposeNotExists
 	composeFile         = model.ComposeFile
+	versionLockFile     = model.VersionLockFile
 )
 
 type keyringWrapper struct {
@@ -34,8 +39,18 @@ type keyringWrapper struct {
 	action.WithTerm
 
 	keyringService keyring.Keyring
+	auth           *auth.Config
 	interactive    bool
 	shouldUpdate   bool
+	// noKeyring disables keyring, credentials come from environment, .netrc or prompt.
+	noKeyring bool
+	// envHosts are hosts of packages of the domain compose.yaml, environment credentials without host
+	// are used only for them.
+	envHosts map[string]bool
+	// sessions stores credentials which succeeded per host.
+	sessions map[string]authSession
+	// debugGit logs progress of git remotes and authentication modes attempted per remote.
+	debugGit bool
 }
 
 func baseURL(fullURL string) (string, error) {
@@ -49,18 +64,84 @@ func baseURL(fullURL string) (string, error) {
 	return u.Scheme + "://" + u.Host, nil
 }
 
+// keyringAvailable reports if credentials may be read from and stored to keyring.
+func (kw *keyringWrapper) keyringAvailable() bool {
+	return kw.keyringService != nil && !kw.noKeyring
+}
+
+// credentialsFromNetrc returns credentials of url from .netrc.
+func (kw *keyringWrapper) credentialsFromNetrc(url string) (keyring.CredentialsItem, bool) {
+	ci, ok := credentialsFromNetrc(url)
+	if ok {
+		kw.Log().Debug("using credentials from .netrc", "url", url)
+	}
+
+	return ci, ok
+}
+
+// credentialsFromEnv returns credentials of url from environment variables.
+func (kw *keyringWrapper) credentialsFromEnv(url string) (keyring.CredentialsItem, bool) {
+	ci, ok := credentialsFromEnv(url, kw.envHosts)
+	if ok {
+		kw.Log().Debug("using credentials from environment", "url", url)
+	}
+
+	return ci, ok
+}
+
 func (kw *keyringWrapper) getForBaseURL(url string) (keyring.CredentialsItem, error) {
-	burl, err := baseURL(url)
+	if kw.keyringAvailable() {
+		burl, err := baseURL(url)
+		if err != nil {
+			return keyring.CredentialsItem{}, err
+		}
+
+		ci, err := kw.keyringService.GetForURL(kw.keyringURL(url, burl))
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return ci, err
+		}
+	}
+
+	if ci, ok := kw.credentialsFromNetrc(url); ok {
+		return ci, nil
+	}
+
+	return keyring.CredentialsItem{}, keyring.ErrNotFound
+}
+
+// keyringURL returns the keyring item URL with the longest path prefix of url, fallback if there is none.
+// Path scoped items allow different credentials for groups of repositories on the same host.
+func (kw *keyringWrapper) keyringURL(url, fallback string) string {
+	urls, err := kw.keyringService.GetUrls()
 	if err != nil {
-		return keyring.CredentialsItem{}, err
+		kw.Log().Debug("failed to list keyring URLs", "error", err)
+		return fallback
+	}
+
+	if prefix := longestPrefixURL(urls, url); prefix != "" {
+		return prefix
 	}
 
-	ci, err := kw.keyringService.GetForURL(burl)
-	return ci, err
+	return fallback
 }
 
 func (kw *keyringWrapper) getForURL(url string) (keyring.CredentialsItem, error) {
-	ci, errGet := kw.keyringService.GetForURL(url)
+	if !kw.keyringAvailable() {
+		if ci, ok := kw.credentialsFromNetrc(url); ok {
+			return ci, nil
+		}
+		// Environment credentials are the last resort before prompt.
+		if ci, ok := kw.credentialsFromEnv(url); ok {
+			return ci, nil
+		}
+		if !kw.interactive {
+			return keyring.CredentialsItem{}, fmt.Errorf("%w: no credentials for %s in environment or .netrc, keyring is not used", keyring.ErrNotFound, url)
+		}
+
+		return kw.fillCredentials(keyring.CredentialsItem{URL: url})
+	}
+
+	ci, errGet := kw.keyringService.GetForURL(kw.keyringURL(url, url))
 	if errGet != nil {
 		if errors.Is(errGet, keyring.ErrEmptyPass) {
 			return ci, errGet
@@ -69,6 +150,13 @@ func (kw *keyringWrapper) getForURL(url string) (keyring.CredentialsItem, error)
 			return ci, errors.New("the keyring is malformed or wrong passphrase provided")
 		}
 
+		// Credentials outside keyring are used only if keyring has no item for url.
+		if ciNetrc, ok := kw.credentialsFromNetrc(url); ok {
+			return ciNetrc, nil
+		}
+		if ciEnv, ok := kw.credentialsFromEnv(url); ok {
+			return ciEnv, nil
+		}
 		if !kw.interactive {
 			return ci, errGet
 		}
@@ -112,6 +200,8 @@ type Composer struct {
 	options *ComposerOptions
 	compose *Composition
 	k       keyring.Keyring
+	auth    *auth.Config
+	summary *Summary
 }
 
 // ComposerOptions - list of possible composer options
@@ -121,6 +211,37 @@ type ComposerOptions struct {
 	SkipNotVersioned   bool
 	ConflictsVerbosity bool
 	Interactive        bool
+	Strict             bool
+	Overlays           []string
+	Permissions        string
+	ArchiveLinks       string
+	Groups             []string
+	ExcludeGroups      []string
+	// IgnoreNestedStrategies drops strategies declared by nested compose files of packages.
+	IgnoreNestedStrategies bool
+	// Integrity sets handling of packages whose content doesn't match compose.lock.
+	Integrity string
+	// Locked restores packages strictly from compose.lock: git packages are checked out at recorded commits,
+	// packages missing from the lock fail and the lock isn't written.
+	Locked bool
+	// FrozenLockfile restores packages from compose.lock like Locked, existing checkouts not matching the lock
+	// fail instead of being updated.
+	FrozenLockfile bool
+	// NoKeyring disables keyring, e.g. in containers without a keyring backend.
+	NoKeyring bool
+	// DebugGit logs progress of git fetches and clones and authentication modes attempted per remote.
+	DebugGit bool
+	// Symlinks sets handling of symlinks merged from packages, see SymlinksKeep.
+	Symlinks string
+	// RejectLegacyLayout fails compose on packages normalized into the canonical layout, see Normalization.
+	RejectLegacyLayout bool
+	// RespectGitignore skips files of the domain repo ignored by its .gitignore, unless SkipNotVersioned is set.
+	RespectGitignore bool
+	// IncludeStaged keeps files added to the index along with committed ones when SkipNotVersioned is set.
+	IncludeStaged bool
+	// FullMerge merges all paths, otherwise only paths of packages changed since the previous merge are merged
+	// again, see partialMerge. Clean composes merge all paths.
+	FullMerge bool
 }
 
 // CreateComposer instance
@@ -130,29 +251,108 @@ func CreateComposer(pwd string, opts ComposerOptions, k keyring.Keyring) (*Compo
 		return nil, err
 	}
 
-	return &Composer{pwd: pwd, options: &opts, compose: config, k: k}, nil
+	authConfig, err := auth.Load(pwd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Composer{pwd: pwd, options: &opts, compose: config, k: k, auth: authConfig}, nil
 }
 
-// RunInstall on Composer
+// RunInstall on Composer. Termination signals cancel the running compose, cleanup waits for downloads and
+// merging to stop. The previous merge result is kept, downloaded packages are resumed by the next run.
+// A second signal terminates right away.
 func (c *Composer) RunInstall() error {
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
 
 	go func() {
-		<-signalChan
-		c.Term().Printfln("\nTermination signal received. Cleaning up...")
-		// cleanup dir
-		_, _, _ = c.prepareInstall(false)
-
-		cancel()
+		select {
+		case <-signalChan:
+			signal.Stop(signalChan)
+			c.Term().Printfln("\nTermination signal received. Stopping...")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 
+	err := c.install(ctx)
+	if err != nil && ctx.Err() != nil {
+		// install returns once downloads and merging are stopped, nothing writes the staging dir anymore.
+		c.Term().Printfln("Cleaning up interrupted compose...")
+		if errClean := os.RemoveAll(c.getPath(model.MergedStagingDir)); errClean != nil {
+			c.Log().Warn("failed to remove merge staging dir", "error", errClean)
+		}
+		if exists(c.getPath(BuildDir)) {
+			c.Term().Info().Printfln("Previous merge result is kept in %s", BuildDir)
+		}
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func (c *Composer) install(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
+		overlays, err := selectOverlays(c.getCompose().Overlays, c.options.Overlays)
+		if err != nil {
+			return err
+		}
+
+		perms, err := newPermissionPolicy(c.options.Permissions, c.getCompose().Permissions)
+		if err != nil {
+			return err
+		}
+
+		if err = validateArchiveLinksPolicy(c.options.ArchiveLinks); err != nil {
+			return err
+		}
+
+		if err = validateIntegrityMode(c.options.Integrity); err != nil {
+			return err
+		}
+		locked := c.options.Locked || c.options.FrozenLockfile
+		if locked && c.options.Integrity != "" && c.options.Integrity != IntegrityStrict {
+			return fmt.Errorf("%w, --integrity %s can't be used with --locked or --frozen-lockfile", errLockedIntegrity, c.options.Integrity)
+		}
+
+		if err = validateSymlinksPolicy(c.options.Symlinks); err != nil {
+			return err
+		}
+
+		if err = checkRequires(c.getCompose().Requires, composeFile, pluginVersion()); err != nil {
+			return err
+		}
+
+		if err = validateConflictDefault(c.getCompose().ConflictDefault); err != nil {
+			return err
+		}
+
+		if err = validateConflictUnit(c.getCompose().ConflictUnit); err != nil {
+			return err
+		}
+
+		if err = validateStrategies(c.getCompose()); err != nil {
+			return err
+		}
+		if err = validateExcludes(c.getCompose()); err != nil {
+			return err
+		}
+
+		deps, skipped, err := selectGroups(c.getCompose().Dependencies, c.options.Groups, c.options.ExcludeGroups)
+		if err != nil {
+			return err
+		}
+		composition := *c.getCompose()
+		composition.Dependencies = deps
+
 		buildDir, packagesDir, err := c.prepareInstall(c.options.Clean)
 		if err != nil {
 			return err
@@ -160,32 +360,121 @@ func (c *Composer) RunInstall() error {
 
 		kw := &keyringWrapper{
 			keyringService: c.getKeyring(),
+			auth:           c.auth,
 			shouldUpdate:   false,
 			interactive:    c.options.Interactive,
+			noKeyring:      c.options.NoKeyring,
+			envHosts:       compositionHosts(c.getCompose()),
+			debugGit:       c.options.DebugGit,
 		}
 		kw.SetLogger(c.Log())
 		kw.SetTerm(c.Term())
-		dm := CreateDownloadManager(kw)
-		packages, err := dm.Download(ctx, c.getCompose(), packagesDir)
+		c.summary = &Summary{Skipped: skipped}
+		if len(skipped) > 0 {
+			c.Term().Info().Printfln("Skipping packages of not composed groups: %s", strings.Join(skipped, ", "))
+		}
+		dm := CreateDownloadManager(kw, c.summary)
+		dm.strict = c.options.Strict
+		dm.archiveLinks = c.options.ArchiveLinks
+		dm.locked = locked
+		dm.frozenLockfile = c.options.FrozenLockfile
+		if dm.progress, err = c.loadProgress(); err != nil {
+			return err
+		}
+		if dm.resolver.lock, err = LoadVersionLock(c.pwd); err != nil {
+			return err
+		}
+		start := time.Now()
+		packages, err := dm.Download(ctx, &composition, packagesDir)
+		if err != nil {
+			return err
+		}
+		c.summary.addPhase(PhaseFetch, start)
+
+		start = time.Now()
+		versionLock := dm.VersionLock()
+		versionLock.Integrity, err = c.verifyIntegrity(packages, dm.Aliases(), packagesDir, dm.resolver.lock)
 		if err != nil {
 			return err
 		}
+		c.summary.addPhase(PhaseVerify, start)
+
+		if !locked {
+			if err = SaveVersionLock(c.pwd, versionLock); err != nil {
+				return err
+			}
+		}
+
+		c.summary.NestedStrategies = nestedStrategies(packages, c.options.IgnoreNestedStrategies)
+		for _, ns := range c.summary.NestedStrategies {
+			if ns.Ignored {
+				c.Term().Info().Printfln("Ignoring strategy %s of %s declared by %s", ns.Strategy, ns.Package, ns.DeclaredBy)
+			} else {
+				c.Term().Info().Printfln("Package %s declares strategy %s for %s", ns.DeclaredBy, ns.Strategy, ns.Package)
+			}
+		}
 
 		builder := createBuilder(
 			c,
 			buildDir,
 			packagesDir,
 			packages,
+			dm.Aliases(),
+			overlays,
+			perms,
+			versionLock.Integrity,
 		)
-		return builder.build(ctx)
+		err = builder.build(ctx)
+		if err != nil {
+			return err
+		}
+
+		// The merge result is complete, it replaces the previous one even if a signal arrives now.
+		// The manifest of the previous result is removed first, a partial merge never reuses a result it doesn't describe.
+		if err = removeMergeManifest(c.pwd); err != nil {
+			return err
+		}
+		if err = c.commitMerge(buildDir); err != nil {
+			return err
+		}
+		if err = saveMergeManifest(c.pwd, builder.manifest); err != nil {
+			c.Log().Warn("failed to save merge manifest", "error", err)
+		}
+		if err = dm.progress.finish(); err != nil {
+			c.Log().Warn("failed to remove compose progress", "error", err)
+		}
+
+		if err = SaveSummary(c.pwd, c.summary); err != nil {
+			c.Log().Warn("failed to save compose summary", "error", err)
+		}
+
+		c.printSummary()
+		return nil
+	}
+}
+
+// Summary returns statistics of the last install run.
+func (c *Composer) Summary() *Summary {
+	return c.summary
+}
+
+func (c *Composer) printSummary() {
+	c.Term().Printfln("Composition completed.")
+	c.Term().Info().Printfln("Summary:")
+	for _, line := range c.summary.Lines() {
+		c.Term().Printfln("  %s", line)
 	}
 }
 
 func (c *Composer) prepareInstall(clean bool) (string, string, error) {
-	buildPath := c.getPath(BuildDir)
+	buildPath := c.getPath(model.MergedStagingDir)
 	packagesPath := c.getPath(c.options.WorkingDir)
 
-	c.Term().Printfln("Cleaning merge dir: %s", BuildDir)
+	// The previous merge result is kept until the new one is complete, see commitMerge.
+	if err := c.restorePreviousMerge(); err != nil {
+		return "", "", err
+	}
+	c.Term().Printfln("Cleaning merge dir: %s", model.MergedStagingDir)
 	err := os.RemoveAll(buildPath)
 	if err != nil {
 		return "", "", err
@@ -202,6 +491,65 @@ func (c *Composer) prepareInstall(clean bool) (string, string, error) {
 	return buildPath, packagesPath, nil
 }
 
+// loadProgress returns packages downloaded by the previous unfinished compose, clean composes start over.
+func (c *Composer) loadProgress() (*composeProgress, error) {
+	progress, err := loadProgress(c.pwd)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.Clean {
+		progress.Completed, progress.resumed = nil, 0
+	} else if progress.resumed > 0 {
+		c.Term().Info().Printfln("Resuming previous compose, %d packages were downloaded and won't be checked again", progress.resumed)
+	}
+
+	return progress, nil
+}
+
+// commitMerge replaces the previous merge result with the complete one from the staging dir.
+// The previous result is moved aside first and removed only once the new one is in place.
+func (c *Composer) commitMerge(stagingPath string) error {
+	buildPath := c.getPath(BuildDir)
+	previousPath := c.getPath(model.MergedPreviousDir)
+	if err := os.RemoveAll(previousPath); err != nil {
+		return err
+	}
+
+	hasPrevious := exists(buildPath)
+	if hasPrevious {
+		if err := os.Rename(buildPath, previousPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(stagingPath, buildPath); err != nil {
+		if hasPrevious {
+			err = errors.Join(err, os.Rename(previousPath, buildPath))
+		}
+		return err
+	}
+
+	return os.RemoveAll(previousPath)
+}
+
+// restorePreviousMerge restores the merge result moved aside by commitMerge of a compose killed while replacing it.
+func (c *Composer) restorePreviousMerge() error {
+	buildPath := c.getPath(BuildDir)
+	previousPath := c.getPath(model.MergedPreviousDir)
+	if !exists(previousPath) {
+		return nil
+	}
+
+	if exists(buildPath) {
+		// The new result was in place, only removal of the previous one was interrupted.
+		return os.RemoveAll(previousPath)
+	}
+
+	c.Term().Info().Printfln("Restoring merge result of interrupted compose: %s", BuildDir)
+	return os.Rename(previousPath, buildPath)
+}
+
 func (c *Composer) getPath(value string) string {
 	return filepath.Join(c.pwd, value)
 }
@@ -0,0 +1,53 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestCommitMerge(t *testing.T) {
+	dir := t.TempDir()
+	c := &Composer{pwd: dir, options: &ComposerOptions{}}
+	c.SetLogger(launchr.Log())
+	c.SetTerm(launchr.Term())
+
+	write := func(rel, content string) {
+		p := filepath.Join(dir, rel, "file")
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(rel string) string {
+		content, _ := os.ReadFile(filepath.Join(dir, rel, "file"))
+		return string(content)
+	}
+
+	write(model.MergedDir, "previous")
+	write(model.MergedStagingDir, "new")
+	if err := c.commitMerge(filepath.Join(dir, model.MergedStagingDir)); err != nil {
+		t.Fatal(err)
+	}
+	if read(model.MergedDir) != "new" || exists(filepath.Join(dir, model.MergedStagingDir)) || exists(filepath.Join(dir, model.MergedPreviousDir)) {
+		t.Errorf("expected staging dir to replace the merge result, got %q", read(model.MergedDir))
+	}
+
+	// Compose killed after the previous result was moved aside
+	if err := os.Rename(filepath.Join(dir, model.MergedDir), filepath.Join(dir, model.MergedPreviousDir)); err != nil {
+		t.Fatal(err)
+	}
+	write(model.MergedStagingDir, "partial")
+	if _, _, err := c.prepareInstall(false); err != nil {
+		t.Fatal(err)
+	}
+	if read(model.MergedDir) != "new" || exists(filepath.Join(dir, model.MergedPreviousDir)) || exists(filepath.Join(dir, model.MergedStagingDir)) {
+		t.Errorf("expected previous merge result to be restored, got %q", read(model.MergedDir))
+	}
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Default resolutions of conflicts not covered by package strategies.
+const (
+	// ConflictDefaultLocal keeps the file merged first, domain repo files win over packages.
+	ConflictDefaultLocal = "local"
+	// ConflictDefaultPackage replaces the file by the package merged later.
+	ConflictDefaultPackage = "package"
+	// ConflictDefaultNewest keeps the most recently updated file, see conflictResolver.newer.
+	ConflictDefaultNewest = "newest"
+)
+
+var errInvalidConflictDefault = fmt.Errorf("%w: conflict_default", ErrConflictPolicy)
+
+func validateConflictDefault(conflictDefault string) error {
+	switch conflictDefault {
+	case "", ConflictDefaultLocal, ConflictDefaultPackage, ConflictDefaultNewest:
+		return nil
+	default:
+		return fmt.Errorf("%w %q in %s, expected one of: %s, %s, %s", errInvalidConflictDefault, conflictDefault, composeFile, ConflictDefaultLocal, ConflictDefaultPackage, ConflictDefaultNewest)
+	}
+}
+
+// conflictResolver resolves conflicts of merged entries not covered by package strategies
+// and compares entries for the prefer-newest strategy.
+type conflictResolver struct {
+	conflictDefault string
+	// commits caches HEAD commit time per source directory, zero when the source isn't a git repository.
+	commits map[string]time.Time
+}
+
+func newConflictResolver(conflictDefault string) *conflictResolver {
+	return &conflictResolver{conflictDefault: conflictDefault, commits: make(map[string]time.Time)}
+}
+
+// resolveToPackage reports if a conflicting package file replaces the existing one by default.
+// Directories are never replaced.
+func (cr *conflictResolver) resolveToPackage(existing, entry *fsEntry) bool {
+	if cr == nil || !isFileConflict(existing, entry) {
+		return false
+	}
+
+	switch cr.conflictDefault {
+	case ConflictDefaultPackage:
+		return true
+	case ConflictDefaultNewest:
+		return cr.newer(existing, entry)
+	default:
+		// Be default all conflicts auto-resolved to local.
+		return false
+	}
+}
+
+// newer reports if entry is more recent than the existing one. Sources are compared by their
+// commit timestamps when both are git checkouts with different commit times, by file mtimes otherwise.
+func (cr *conflictResolver) newer(existing, entry *fsEntry) bool {
+	if !isFileConflict(existing, entry) {
+		return false
+	}
+
+	existingCommit, entryCommit := cr.commitTime(existing.Prefix), cr.commitTime(entry.Prefix)
+	if !existingCommit.IsZero() && !entryCommit.IsZero() && !existingCommit.Equal(entryCommit) {
+		return entryCommit.After(existingCommit)
+	}
+
+	return entry.Entry.ModTime().After(existing.Entry.ModTime())
+}
+
+func (cr *conflictResolver) commitTime(dir string) time.Time {
+	if t, ok := cr.commits[dir]; ok {
+		return t
+	}
+
+	var t time.Time
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err == nil {
+		if head, errHead := repo.Head(); errHead == nil {
+			if commit, errCommit := repo.CommitObject(head.Hash()); errCommit == nil {
+				t = commit.Committer.When
+			}
+		}
+	}
+	cr.commits[dir] = t
+
+	return t
+}
+
+func isFileConflict(existing, entry *fsEntry) bool {
+	return existing != nil && !existing.Entry.IsDir() && !entry.Entry.IsDir()
+}
@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Conflict is a merged path provided by several candidates.
+type Conflict struct {
+	Path string `json:"path"`
+	// Component is the component of the path as {layer}.{type}.{component}, empty outside of components.
+	Component string `json:"component,omitempty"`
+	// Packages are the competing candidates in merge order, localOrigin stands for the domain repo.
+	Packages []string `json:"packages"`
+	Winner   string   `json:"winner"`
+	// Source is the path of the winning file within its candidate.
+	Source string `json:"source"`
+	// Strategy resolved the last conflict of the path, empty if conflict_default did.
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// ConflictReport lists conflicts of a compose run, see model.ComposeConflictsFile.
+type ConflictReport struct {
+	ConflictDefault string     `json:"conflict_default,omitempty"`
+	Conflicts       []Conflict `json:"conflicts"`
+
+	index map[string]int
+}
+
+func newConflictReport(conflictDefault string) *ConflictReport {
+	return &ConflictReport{ConflictDefault: conflictDefault, Conflicts: []Conflict{}, index: make(map[string]int)}
+}
+
+// add records a conflict of pkgName over the path previously provided by previous.
+func (r *ConflictReport) add(path, previous, pkgName string, winner *fsEntry, ms *mergeStrategy) {
+	i, ok := r.index[path]
+	if !ok {
+		i = len(r.Conflicts)
+		r.index[path] = i
+		component, _ := componentName(path)
+		r.Conflicts = append(r.Conflicts, Conflict{Path: path, Component: component, Packages: []string{previous}})
+	}
+
+	c := &r.Conflicts[i]
+	c.Packages = append(c.Packages, pkgName)
+	c.Winner = winner.From
+	c.Source = filepath.ToSlash(winner.SrcPath)
+	c.Strategy = ""
+	if ms != nil {
+		c.Strategy = ms.name()
+	}
+}
+
+// keep records a conflict of a path kept from the previous merge.
+func (r *ConflictReport) keep(c Conflict) {
+	r.index[c.Path] = len(r.Conflicts)
+	r.Conflicts = append(r.Conflicts, c)
+}
+
+// SaveConflicts writes conflict report of a compose run to baseDir.
+func SaveConflicts(baseDir string, r *ConflictReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(baseDir, model.ComposeConflictsFile), data, os.FileMode(composePermissions))
+}
+
+// LoadConflicts reads conflict report of the last compose run from baseDir.
+func LoadConflicts(baseDir string) (*ConflictReport, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, model.ComposeConflictsFile)) //nolint:gosec // path is built from base dir
+	if err != nil {
+		return nil, err
+	}
+
+	var r ConflictReport
+	if err = json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,38 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestConflictReport(t *testing.T) {
+	r := newConflictReport(ConflictDefaultPackage)
+	strategy := &mergeStrategy{s: overwriteLocalFile, t: packageStrategy, paths: []string{"src/"}}
+	r.add("src/app.yaml", localOrigin, "core", &fsEntry{SrcPath: "src/app.yaml", From: "core"}, nil)
+	r.add("src/app.yaml", "core", "extra", &fsEntry{SrcPath: "app.yaml", From: "extra"}, strategy)
+	r.add("src/other.yaml", localOrigin, "core", &fsEntry{SrcPath: "src/other.yaml", From: localOrigin}, nil)
+
+	baseDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(baseDir, model.ComposeDir), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConflicts(baseDir, r); err != nil {
+		t.Fatalf("SaveConflicts failed: %v", err)
+	}
+	loaded, err := LoadConflicts(baseDir)
+	if err != nil {
+		t.Fatalf("LoadConflicts failed: %v", err)
+	}
+
+	expected := []Conflict{
+		{Path: "src/app.yaml", Packages: []string{localOrigin, "core", "extra"}, Winner: "extra", Source: "app.yaml", Strategy: StrategyOverwriteLocal},
+		{Path: "src/other.yaml", Packages: []string{localOrigin, "core"}, Winner: localOrigin, Source: "src/other.yaml"},
+	}
+	if loaded.ConflictDefault != ConflictDefaultPackage || !reflect.DeepEqual(loaded.Conflicts, expected) {
+		t.Errorf("unexpected report: %+v", loaded)
+	}
+}
@@ -0,0 +1,106 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Kinds of model inconsistencies.
+const (
+	IssueStalePackage   = "stale-package"
+	IssueNotDownloaded  = "not-downloaded"
+	IssueOutdatedMerged = "outdated-merged"
+)
+
+// Issue stores an inconsistency between compose.yaml, downloaded packages and merged result.
+type Issue struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Fix     string `json:"fix"`
+}
+
+// CheckConsistency returns inconsistencies of the model in baseDir, directories are located by layout:
+// downloaded packages not referenced by compose.yaml, dependencies never downloaded
+// and merged result older than compose.yaml or package checkouts.
+// Packages skipped by the last compose run (dependency groups, optional packages) aren't reported as not downloaded.
+func CheckConsistency(cfg *Composition, baseDir string, layout model.Layout) ([]Issue, error) {
+	packagesDir := filepath.Join(baseDir, layout.PackagesDir)
+
+	lock, err := LoadVersionLock(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := FindStalePackages(cfg, lock, packagesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, sp := range stale {
+		name := sp.Name
+		if sp.Ref != "" {
+			name += "@" + sp.Ref
+		}
+		issues = append(issues, Issue{
+			Kind:    IssueStalePackage,
+			Message: fmt.Sprintf("package %s is on disk but not in %s", name, composeFile),
+			Fix:     "plasmactl model:prune",
+		})
+	}
+
+	// The newest of compose.yaml and package checkouts, merged result must not be older.
+	var latest time.Time
+	var latestSource string
+	if info, errStat := os.Stat(filepath.Join(baseDir, composeFile)); errStat == nil {
+		latest, latestSource = info.ModTime(), composeFile
+	}
+
+	checkouts := LocateCheckouts(cfg, lock, packagesDir)
+	var skipped []string
+	if summary, errSummary := LoadSummary(baseDir); errSummary == nil {
+		skipped = summary.Skipped
+	}
+
+	for _, d := range cfg.Dependencies {
+		pkg := d.ToPackage(d.Name)
+		pkg.Source.Ref = lock.Ref(d)
+		pkgDir := checkouts.CheckoutDir(pkg)
+		info, errStat := os.Stat(pkgDir)
+		if errStat != nil {
+			if slices.Contains(skipped, pkg.GetName()) {
+				continue
+			}
+			issues = append(issues, Issue{
+				Kind:    IssueNotDownloaded,
+				Message: fmt.Sprintf("package %s@%s is in %s but not downloaded", pkg.GetName(), pkg.GetTarget(), composeFile),
+				Fix:     "plasmactl model:compose",
+			})
+			continue
+		}
+
+		modTime := info.ModTime()
+		if head, errHead := os.Stat(filepath.Join(pkgDir, gitPrefix, "HEAD")); errHead == nil && head.ModTime().After(modTime) {
+			modTime = head.ModTime()
+		}
+		if modTime.After(latest) {
+			latest, latestSource = modTime, fmt.Sprintf("package %s@%s", pkg.GetName(), pkg.GetTarget())
+		}
+	}
+
+	merged, err := os.Stat(filepath.Join(baseDir, layout.MergedDir))
+	if err == nil && merged.ModTime().Before(latest) {
+		issues = append(issues, Issue{
+			Kind:    IssueOutdatedMerged,
+			Message: fmt.Sprintf("merged result %s is older than %s", layout.MergedDir, latestSource),
+			Fix:     "plasmactl model:compose",
+		})
+	}
+
+	return issues, nil
+}
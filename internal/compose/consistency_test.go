@@ -0,0 +1,57 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, model.PackagesDir)
+	mergedDir := filepath.Join(baseDir, model.MergedDir)
+
+	for _, dir := range []string{
+		filepath.Join(packagesDir, "core", "v1.0.0"),
+		filepath.Join(packagesDir, "legacy", "v0.1.0"),
+		mergedDir,
+	} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, composeFile), []byte("name: test\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(mergedDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Composition{Dependencies: []Dependency{
+		{Name: "core", Source: Source{Ref: "v1.0.0"}},
+		{Name: "work", Source: Source{Ref: "v2.0.0"}},
+	}}
+
+	issues, err := CheckConsistency(cfg, baseDir, model.DefaultLayout())
+	if err != nil {
+		t.Fatalf("CheckConsistency failed: %v", err)
+	}
+
+	kinds := make(map[string]int)
+	for _, issue := range issues {
+		kinds[issue.Kind]++
+		if issue.Fix == "" {
+			t.Errorf("issue %q has no fix command", issue.Message)
+		}
+	}
+
+	for _, kind := range []string{IssueStalePackage, IssueNotDownloaded, IssueOutdatedMerged} {
+		if kinds[kind] != 1 {
+			t.Errorf("expected 1 %s issue, got %d: %+v", kind, kinds[kind], issues)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package compose
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-model/internal/release"
+)
+
+var rgxPartialVersion = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// versionCheck compares a version with a bound.
+type versionCheck struct {
+	op    string
+	bound *release.Version
+}
+
+// versionConstraint stores semver comparisons of a ref, all of them must be satisfied.
+// Supported are exact versions (v1.2.3), comparisons (>=1.2.0 <2.0.0, also comma separated),
+// caret (^1.2) and tilde (~1.2.3) ranges.
+type versionConstraint struct {
+	raw    string
+	checks []versionCheck
+}
+
+// isVersionRange reports if ref is a range of versions rather than a tag, branch or commit.
+func isVersionRange(ref string) bool {
+	return strings.ContainsAny(ref[:min(len(ref), 1)], "^~<>=")
+}
+
+// parseConstraint parses a ref as version constraint. False is returned for refs which are neither
+// a version nor a range, e.g. branches and commits.
+func parseConstraint(ref string) (*versionConstraint, bool, error) {
+	if !isVersionRange(ref) {
+		v, err := release.ParseVersion(ref)
+		if err != nil {
+			return nil, false, nil
+		}
+		return &versionConstraint{raw: ref, checks: []versionCheck{{"=", v}}}, true, nil
+	}
+
+	c := &versionConstraint{raw: ref}
+	for _, term := range strings.Fields(strings.ReplaceAll(ref, ",", " ")) {
+		op := strings.TrimRight(term, "v0123456789.-abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		lower, parts, err := parsePartialVersion(strings.TrimPrefix(term, op))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid version constraint %q: %w", ref, err)
+		}
+
+		switch op {
+		case "^":
+			// Caret allows changes not modifying the left-most non-zero part.
+			upper := &release.Version{Major: lower.Major + 1}
+			if lower.Major == 0 && parts > 1 {
+				if lower.Minor > 0 || parts == 2 {
+					upper = &release.Version{Minor: lower.Minor + 1}
+				} else {
+					upper = &release.Version{Patch: lower.Patch + 1}
+				}
+			}
+			c.checks = append(c.checks, versionCheck{">=", lower}, versionCheck{"<", upper})
+		case "~":
+			upper := &release.Version{Major: lower.Major, Minor: lower.Minor + 1}
+			if parts == 1 {
+				upper = &release.Version{Major: lower.Major + 1}
+			}
+			c.checks = append(c.checks, versionCheck{">=", lower}, versionCheck{"<", upper})
+		case ">=", ">", "<=", "<", "=", "":
+			if op == "" {
+				op = "="
+			}
+			c.checks = append(c.checks, versionCheck{op, lower})
+		default:
+			return nil, false, fmt.Errorf("invalid version constraint %q: unknown operator %q", ref, op)
+		}
+	}
+
+	if len(c.checks) == 0 {
+		return nil, false, fmt.Errorf("invalid version constraint %q", ref)
+	}
+
+	return c, true, nil
+}
+
+// parsePartialVersion parses versions like 1, 1.2 or v1.2.3-rc.1, missing parts are zero.
+// It returns number of parts present.
+func parsePartialVersion(s string) (*release.Version, int, error) {
+	m := rgxPartialVersion.FindStringSubmatch(s)
+	if m == nil {
+		return nil, 0, fmt.Errorf("invalid version %q", s)
+	}
+
+	v := &release.Version{Prerelease: m[4]}
+	parts := 0
+	for i, dst := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if m[i+1] == "" {
+			break
+		}
+		*dst, _ = strconv.Atoi(m[i+1])
+		parts++
+	}
+
+	return v, parts, nil
+}
+
+// allows reports if version satisfies the constraint. Prereleases satisfy only constraints
+// mentioning a prerelease of the same version.
+func (c *versionConstraint) allows(v *release.Version) bool {
+	if v.Prerelease != "" && !c.mentionsPrerelease(v) {
+		return false
+	}
+
+	for _, check := range c.checks {
+		cmp := v.Compare(check.bound)
+		var ok bool
+		switch check.op {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *versionConstraint) mentionsPrerelease(v *release.Version) bool {
+	for _, check := range c.checks {
+		b := check.bound
+		if b.Prerelease != "" && b.Major == v.Major && b.Minor == v.Minor && b.Patch == v.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *versionConstraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,198 @@
+package compose
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+)
+
+// Environment variables with credentials for non-interactive runs, e.g. in CI.
+// Per-host variables take precedence, host is upper-cased with non-alphanumeric
+// characters replaced by underscore: PLASMA_GIT_GITHUB_COM_USERNAME.
+// Variables without host are used only for hosts of packages of the domain compose.yaml,
+// so they aren't sent to hosts of nested packages.
+const (
+	envCredentialsPrefix = "PLASMA_GIT_"
+	envUsernameSuffix    = "USERNAME"
+	envPasswordSuffix    = "PASSWORD"
+)
+
+// credentialsFromEnv returns credentials for rawURL from environment variables,
+// variables without host are used if host of rawURL is one of hosts.
+func credentialsFromEnv(rawURL string, hosts map[string]bool) (keyring.CredentialsItem, bool) {
+	var prefixes []string
+	if host := hostEnvKey(rawURL); host != "" {
+		prefixes = append(prefixes, envCredentialsPrefix+host+"_")
+	}
+	if host, _, ok := splitCredentialsURL(rawURL); ok && hosts[host] {
+		prefixes = append(prefixes, envCredentialsPrefix)
+	}
+
+	for _, prefix := range prefixes {
+		username, okUser := os.LookupEnv(prefix + envUsernameSuffix)
+		password, okPass := os.LookupEnv(prefix + envPasswordSuffix)
+		if okUser || okPass {
+			return keyring.CredentialsItem{URL: rawURL, Username: username, Password: password}, true
+		}
+	}
+
+	return keyring.CredentialsItem{}, false
+}
+
+// compositionHosts returns hosts of packages declared by cfg.
+func compositionHosts(cfg *Composition) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, d := range cfg.Dependencies {
+		if host, _, ok := splitCredentialsURL(d.Source.URL); ok {
+			hosts[host] = true
+		}
+	}
+
+	return hosts
+}
+
+// credentialsFromNetrc returns credentials for rawURL from the netrc file, $NETRC or ~/.netrc.
+// The machine entry of the URL host takes precedence over default.
+func credentialsFromNetrc(rawURL string) (keyring.CredentialsItem, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return keyring.CredentialsItem{}, false
+	}
+
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, errHome := os.UserHomeDir()
+		if errHome != nil {
+			return keyring.CredentialsItem{}, false
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // netrc path is chosen by user
+	if err != nil {
+		return keyring.CredentialsItem{}, false
+	}
+
+	machines := parseNetrc(string(data))
+	for _, name := range []string{u.Hostname(), ""} {
+		if m, ok := machines[name]; ok {
+			return keyring.CredentialsItem{URL: rawURL, Username: m.login, Password: m.password}, true
+		}
+	}
+
+	return keyring.CredentialsItem{}, false
+}
+
+type netrcMachine struct {
+	login    string
+	password string
+}
+
+// parseNetrc returns netrc entries by machine name, the default entry has an empty name.
+// The first entry of a machine wins, macros end parsing.
+func parseNetrc(data string) map[string]netrcMachine {
+	machines := make(map[string]netrcMachine)
+	var current *netrcMachine
+	var name string
+	commit := func() {
+		if _, exists := machines[name]; current != nil && !exists {
+			machines[name] = *current
+		}
+	}
+
+	tokens := strings.Fields(data)
+	for i := 0; i < len(tokens); i++ {
+		value := ""
+		if i+1 < len(tokens) {
+			value = tokens[i+1]
+		}
+
+		switch tokens[i] {
+		case "machine":
+			commit()
+			current, name = &netrcMachine{}, value
+			i++
+		case "default":
+			commit()
+			current, name = &netrcMachine{}, ""
+		case "login":
+			if current != nil {
+				current.login = value
+			}
+			i++
+		case "password":
+			if current != nil {
+				current.password = value
+			}
+			i++
+		case "account":
+			i++
+		case "macdef":
+			commit()
+			return machines
+		}
+	}
+	commit()
+
+	return machines
+}
+
+// longestPrefixURL returns the stored URL which path is the longest prefix of rawURL path on the same host,
+// empty string if there is none. Stored URLs may end with /* to scope credentials to a group of repositories.
+func longestPrefixURL(stored []string, rawURL string) string {
+	host, path, ok := splitCredentialsURL(rawURL)
+	if !ok {
+		return ""
+	}
+
+	best, bestLen := "", -1
+	for _, s := range stored {
+		h, p, okStored := splitCredentialsURL(s)
+		if !okStored || h != host || len(p) <= bestLen {
+			continue
+		}
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			best, bestLen = s, len(p)
+		}
+	}
+
+	return best
+}
+
+// splitCredentialsURL returns host and path of a credentials URL, path has no surrounding slashes,
+// trailing /* and .git suffix.
+func splitCredentialsURL(rawURL string) (string, string, bool) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+
+	path := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/*"), "/")
+	path = strings.Trim(strings.TrimSuffix(path, ".git"), "/")
+
+	return strings.ToLower(u.Host), path, true
+}
+
+func hostEnvKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, u.Host)
+}
@@ -0,0 +1,138 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+)
+
+func TestCredentialsFromEnv(t *testing.T) {
+	hosts := compositionHosts(&Composition{Dependencies: []Dependency{
+		{Name: "repo", Source: Source{URL: "https://GitHub.com/org/repo.git"}},
+	}})
+	if _, ok := credentialsFromEnv("https://github.com/org/repo.git", hosts); ok {
+		t.Fatal("expected no credentials without environment")
+	}
+
+	t.Setenv("PLASMA_GIT_USERNAME", "global")
+	t.Setenv("PLASMA_GIT_PASSWORD", "global-pass")
+	t.Setenv("PLASMA_GIT_GITLAB_EXAMPLE_COM_8443_USERNAME", "host")
+	t.Setenv("PLASMA_GIT_GITLAB_EXAMPLE_COM_8443_PASSWORD", "host-pass")
+
+	ci, ok := credentialsFromEnv("https://gitlab.example.com:8443/group/sub/repo.git", hosts)
+	if !ok || ci.Username != "host" || ci.Password != "host-pass" {
+		t.Errorf("expected per-host credentials, got %+v", ci)
+	}
+
+	ci, ok = credentialsFromEnv("https://github.com/org/repo.git", hosts)
+	if !ok || ci.Username != "global" || ci.Password != "global-pass" {
+		t.Errorf("expected global credentials, got %+v", ci)
+	}
+
+	// Hosts of nested packages don't get credentials without host.
+	if ci, ok = credentialsFromEnv("https://bitbucket.org/org/repo.git", hosts); ok {
+		t.Errorf("expected no global credentials for host outside compose.yaml, got %+v", ci)
+	}
+}
+
+func TestCredentialsFromNetrc(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	data := "machine gitlab.example.com\n  login host\n  password host-pass\n\ndefault login any password any-pass\n"
+	if err := os.WriteFile(netrc, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", netrc)
+
+	ci, ok := credentialsFromNetrc("https://gitlab.example.com:8443/group/repo.git")
+	if !ok || ci.Username != "host" || ci.Password != "host-pass" {
+		t.Errorf("expected machine credentials, got %+v", ci)
+	}
+
+	ci, ok = credentialsFromNetrc("https://github.com/org/repo.git")
+	if !ok || ci.Username != "any" || ci.Password != "any-pass" {
+		t.Errorf("expected default credentials, got %+v", ci)
+	}
+}
+
+func TestGetForURLWithoutKeyring(t *testing.T) {
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+
+	kw := &keyringWrapper{noKeyring: true, envHosts: map[string]bool{"github.com": true}}
+	if _, err := kw.getForURL("https://github.com/org/repo.git"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("expected not found error without keyring, got %v", err)
+	}
+
+	t.Setenv("PLASMA_GIT_USERNAME", "ci")
+	t.Setenv("PLASMA_GIT_PASSWORD", "token")
+	ci, err := kw.getForURL("https://github.com/org/repo.git")
+	if err != nil || ci.Username != "ci" {
+		t.Errorf("expected credentials from environment, got %+v, %v", ci, err)
+	}
+}
+
+func TestGetForURLKeyringFirst(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(netrc, []byte("default login netrc password netrc-pass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", netrc)
+
+	k := keyring.NewService(keyring.NewFileStore(keyring.NewPlainFile(filepath.Join(t.TempDir(), "keyring.yaml"))), nil)
+	if err := k.AddItem(keyring.CredentialsItem{URL: "https://gitlab.com", Username: "stored", Password: "stored-pass"}); err != nil {
+		t.Fatal(err)
+	}
+	kw := &keyringWrapper{keyringService: k}
+
+	ci, err := kw.getForURL("https://gitlab.com/org/repo.git")
+	if err != nil || ci.Username != "stored" {
+		t.Errorf("expected keyring credentials to take precedence, got %+v, %v", ci, err)
+	}
+	ci, err = kw.getForBaseURL("https://gitlab.com/org/repo.git")
+	if err != nil || ci.Username != "stored" {
+		t.Errorf("expected keyring credentials of base URL to take precedence, got %+v, %v", ci, err)
+	}
+
+	ci, err = kw.getForURL("https://github.com/org/repo.git")
+	if err != nil || ci.Username != "netrc" {
+		t.Errorf("expected .netrc credentials without keyring item, got %+v, %v", ci, err)
+	}
+
+	// Environment credentials are used after keyring and .netrc.
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("PLASMA_GIT_GITLAB_COM_USERNAME", "env")
+	t.Setenv("PLASMA_GIT_GITHUB_COM_USERNAME", "env")
+	ci, err = kw.getForURL("https://gitlab.com/org/repo.git")
+	if err != nil || ci.Username != "stored" {
+		t.Errorf("expected keyring credentials to take precedence over environment, got %+v, %v", ci, err)
+	}
+	ci, err = kw.getForURL("https://github.com/org/repo.git")
+	if err != nil || ci.Username != "env" {
+		t.Errorf("expected environment credentials without keyring item, got %+v, %v", ci, err)
+	}
+}
+
+func TestLongestPrefixURL(t *testing.T) {
+	stored := []string{
+		"https://gitlab.com",
+		"https://gitlab.com/org-a/*",
+		"https://gitlab.com/org-a/team",
+		"https://gitlab.com/org-b/",
+		"https://github.com/org-a",
+	}
+
+	for rawURL, expected := range map[string]string{
+		"https://gitlab.com/org-a/repo.git":         "https://gitlab.com/org-a/*",
+		"https://gitlab.com/org-a/team/repo.git":    "https://gitlab.com/org-a/team",
+		"https://gitlab.com/org-b/repo.git":         "https://gitlab.com/org-b/",
+		"https://gitlab.com/org-ab/repo.git":        "https://gitlab.com",
+		"https://github.com/org-b/repo.git":         "",
+		"https://gitlab.example.com/org-a/repo.git": "",
+	} {
+		if got := longestPrefixURL(stored, rawURL); got != expected {
+			t.Errorf("%s: expected %q, got %q", rawURL, expected, got)
+		}
+	}
+}
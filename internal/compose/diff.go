@@ -0,0 +1,118 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	a, b int // line numbers (0-based) in old and new content
+}
+
+// unifiedDiff returns a unified diff of two texts, empty string if they are equal.
+func unifiedDiff(name string, oldContent, newContent []byte) string {
+	a := splitLines(string(oldContent))
+	b := splitLines(string(newContent))
+	lines := diffLines(a, b)
+
+	var changes []int
+	for i, l := range lines {
+		if l.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-diffContextLines, 0)
+		end := changes[i]
+		// Merge changes which context overlaps into a single hunk.
+		for i < len(changes) && changes[i] <= end+2*diffContextLines {
+			end = changes[i]
+			i++
+		}
+		end = min(end+diffContextLines, len(lines)-1)
+
+		hunk := lines[start : end+1]
+		var oldCount, newCount int
+		for _, l := range hunk {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(hunk[0].a, oldCount), hunkRange(hunk[0].b, newCount))
+		for _, l := range hunk {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.text)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines builds line edit script using longest common subsequence.
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result = append(result, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			result = append(result, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			result = append(result, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,35 @@
+package compose
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	oldContent := "name: plasma\ndependencies:\n  - name: a\n    source:\n      ref: v1.0.0\n  - name: b\n"
+	newContent := "name: plasma\ndependencies:\n  - name: a\n    source:\n      ref: v1.1.0\n  - name: b\n  - name: c\n"
+
+	expected := `--- a/compose.yaml
++++ b/compose.yaml
+@@ -2,5 +2,6 @@
+ dependencies:
+   - name: a
+     source:
+-      ref: v1.0.0
++      ref: v1.1.0
+   - name: b
++  - name: c
+`
+
+	if got := unifiedDiff(composeFile, []byte(oldContent), []byte(newContent)); got != expected {
+		t.Errorf("unexpected diff:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	if got := unifiedDiff(composeFile, []byte(oldContent), []byte(oldContent)); got != "" {
+		t.Errorf("expected empty diff for equal content, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	expected := "--- a/compose.yaml\n+++ b/compose.yaml\n@@ -0,0 +1,2 @@\n+name: plasma\n+dependencies: []\n"
+	if got := unifiedDiff(composeFile, nil, []byte("name: plasma\ndependencies: []\n")); got != expected {
+		t.Errorf("unexpected diff:\n%s\nexpected:\n%s", got, expected)
+	}
+}
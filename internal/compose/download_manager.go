@@ -2,9 +2,14 @@ package compose
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
@@ -12,6 +17,15 @@ const (
 	GitType = "git"
 	// HTTPType is const for http source type download.
 	HTTPType = "http"
+	// ReleaseType is const for assets of forge releases source type download.
+	ReleaseType = "release"
+)
+
+var (
+	errDependencyCycle      = errors.New("dependency cycle detected")
+	errInvalidNestedCompose = errors.New("invalid compose.yaml")
+	errUnknownRequired      = errors.New("unknown required package")
+	errInvalidSubpath       = errors.New("invalid package subpath")
 )
 
 // Downloader interface
@@ -20,9 +34,49 @@ type Downloader interface {
 	EnsureLatest(pkg *Package, downloadPath string) (bool, error)
 }
 
+// downloadStats collects metrics of a single package download.
+type downloadStats struct {
+	// bytes are transferred by archive and release asset downloads.
+	bytes int64
+	// diskBytes is growth of git repositories on disk by clones and fetches, git doesn't report transferred bytes.
+	diskBytes int64
+	auth      authenticationMode
+}
+
+// statsReporter is implemented by downloaders collecting download metrics.
+type statsReporter interface {
+	Stats() downloadStats
+	// ResetStats clears metrics of the previous package, downloaders are shared by packages.
+	ResetStats()
+}
+
+// siblingDownloader is implemented by downloaders able to reuse a download of another ref of the package.
+type siblingDownloader interface {
+	DownloadFromSibling(ctx context.Context, pkg *Package, packagePath, targetDir string) (bool, error)
+}
+
 // DownloadManager struct, provides methods to fetch packages
 type DownloadManager struct {
-	kw *keyringWrapper
+	kw      *keyringWrapper
+	summary *Summary
+	// sources maps package source (url@target) to the name of the package it was downloaded for.
+	sources map[string]string
+	// aliases maps package name to the name of the package sharing its checkout.
+	aliases map[string]string
+	// subpaths holds subpaths checked out per package source, empty subpath is the whole repository.
+	subpaths map[string][]string
+	// strict fails on invalid compose.yaml of packages instead of warning.
+	strict bool
+	// archiveLinks is a policy of link entries in archives of http packages.
+	archiveLinks string
+	// resolver chooses versions of packages requested by several compositions or by version ranges.
+	resolver *versionResolver
+	// progress records downloaded packages, so a rerun after a failure resumes from the failed package.
+	progress *composeProgress
+	// locked checks out git packages at commits recorded in compose.lock, unlocked packages fail.
+	locked bool
+	// frozenLockfile fails on existing checkouts not matching compose.lock instead of updating them.
+	frozenLockfile bool
 }
 
 func (m DownloadManager) getKeyring() *keyringWrapper {
@@ -30,14 +84,90 @@ func (m DownloadManager) getKeyring() *keyringWrapper {
 }
 
 // CreateDownloadManager instance
-func CreateDownloadManager(keyring *keyringWrapper) DownloadManager {
-	return DownloadManager{kw: keyring}
+func CreateDownloadManager(keyring *keyringWrapper, summary *Summary) DownloadManager {
+	if summary == nil {
+		summary = &Summary{}
+	}
+
+	return DownloadManager{
+		kw:       keyring,
+		summary:  summary,
+		sources:  make(map[string]string),
+		aliases:  make(map[string]string),
+		subpaths: make(map[string][]string),
+		resolver: newVersionResolver(keyring),
+	}
+}
+
+// VersionLock returns package versions resolved by the last download.
+func (m DownloadManager) VersionLock() *VersionLock {
+	return m.resolver.versionLock()
+}
+
+// Aliases returns packages which share a checkout with another package (name => checkout owner name).
+func (m DownloadManager) Aliases() map[string]string {
+	return m.aliases
+}
+
+// sourceKey identifies package source regardless of package name.
+func sourceKey(pkg *Package) string {
+	url, err := normalizeSourceURL(pkg.GetType(), pkg.GetURL())
+	if err != nil {
+		url = pkg.GetURL()
+	}
+
+	return url + "@" + pkg.GetTarget()
+}
+
+// shareCheckout adds the subpath of pkg to the sparse checkout of another package of the same repository,
+// so packages of a monorepo share a single clone.
+func (m DownloadManager) shareCheckout(pkg *Package, key, checkoutDir string) error {
+	subpaths := m.subpaths[key]
+	if slices.Contains(subpaths, "") || slices.Contains(subpaths, pkg.GetSubpath()) {
+		return nil
+	}
+
+	subpaths = append(subpaths, pkg.GetSubpath())
+	m.subpaths[key] = subpaths
+	if pkg.GetType() == GitType {
+		// Empty subpath requires the whole repository.
+		if slices.Contains(subpaths, "") {
+			subpaths = nil
+		}
+		m.kw.Log().Debug("extending checkout", "package", pkg.GetName(), "subpaths", subpaths)
+		if err := sparseCheckout(checkoutDir, subpaths); err != nil {
+			return err
+		}
+	}
+
+	return ensureSubpath(pkg, checkoutDir)
+}
+
+// ensureSubpath checks that the subpath of a monorepo package exists in its checkout.
+func ensureSubpath(pkg *Package, checkoutDir string) error {
+	subpath := pkg.GetSubpath()
+	if subpath == "" {
+		return nil
+	}
+
+	if info, err := os.Stat(packageDir(checkoutDir, pkg)); err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: subpath %s of package %s doesn't exist at %s", errInvalidSubpath, subpath, pkg.GetName(), pkg.GetTarget())
+	}
+
+	return nil
+}
+
+// packageDir returns the directory holding package content in its checkout, the subpath of monorepo packages.
+func packageDir(checkoutDir string, pkg *Package) string {
+	return filepath.Join(checkoutDir, filepath.FromSlash(pkg.GetSubpath()))
 }
 
 func (m DownloadManager) getDownloaderForPackage(downloadType string) Downloader {
 	switch downloadType {
 	case HTTPType:
-		return newHTTP(m.kw)
+		return newHTTP(m.kw, m.archiveLinks)
+	case ReleaseType:
+		return newRelease(m.kw, m.archiveLinks)
 	case GitType:
 		fallthrough
 	default:
@@ -55,23 +185,57 @@ func (m DownloadManager) Download(ctx context.Context, c *Composition, targetDir
 	}
 
 	kw := m.getKeyring()
-	// Unlock keyring proactively to trigger passphrase prompt before output
-	_ = kw.keyringService.Unlock()
+	switch {
+	case kw.noKeyring:
+		kw.Term().Info().Printfln("Keyring is disabled, using credentials from environment and .netrc")
+	case kw.keyringService != nil:
+		// Unlock keyring proactively to trigger passphrase prompt before output
+		if errUnlock := kw.keyringService.Unlock(); errUnlock != nil {
+			kw.Term().Warning().Printfln("Keyring is not available (%s), falling back to credentials from environment and .netrc", errUnlock)
+			kw.noKeyring = true
+		}
+	}
 	kw.Term().Printfln("Fetching packages...")
-	packages, err = m.recursiveDownload(ctx, c, packages, nil, targetDir)
-	if err != nil {
-		return packages, err
+	for round := 1; ; round++ {
+		m.resolver.reset()
+		packages, err = m.recursiveDownload(ctx, c, nil, nil, nil, targetDir)
+		if err != nil {
+			return packages, err
+		}
+		if err = validateRequires(packages, m.summary.Skipped); err != nil {
+			return packages, err
+		}
+
+		changed, errResolve := m.resolver.resolve()
+		if errResolve != nil {
+			return packages, errResolve
+		}
+		if !changed {
+			break
+		}
+		if round == maxResolveRounds {
+			return packages, fmt.Errorf("%w: versions didn't settle after %d rounds", errVersionConflict, maxResolveRounds)
+		}
+
+		// Download again with resolved versions, nested compositions may differ between versions.
+		kw.Term().Info().Printfln("Resolved package versions, fetching again...")
+		m.summary.resetDownloads()
+		clear(m.sources)
+		clear(m.aliases)
+		clear(m.subpaths)
 	}
 
 	// store keyring credentials
-	if kw.shouldUpdate {
+	if kw.shouldUpdate && kw.keyringAvailable() {
 		err = kw.keyringService.Save()
 	}
 
 	return packages, err
 }
 
-func (m DownloadManager) recursiveDownload(ctx context.Context, yc *Composition, packages []*Package, parent *Package, targetDir string) ([]*Package, error) {
+// recursiveDownload downloads dependencies of yc and follows their nested compose files.
+// chain holds names of packages leading to yc and is used to detect dependency cycles.
+func (m DownloadManager) recursiveDownload(ctx context.Context, yc *Composition, packages []*Package, parent *Package, chain []string, targetDir string) ([]*Package, error) {
 	for _, d := range yc.Dependencies {
 		select {
 		case <-ctx.Done():
@@ -80,33 +244,90 @@ func (m DownloadManager) recursiveDownload(ctx context.Context, yc *Composition,
 			// build package from dependency struct
 			// add dependency if parent exists
 			pkg := d.ToPackage(d.Name)
-			if parent != nil {
-				parent.AddDependency(d.Name)
-			}
 
 			url := pkg.GetURL()
 			if url == "" {
 				return packages, errNoURL
 			}
 
-			packagePath := filepath.Join(targetDir, pkg.GetName(), pkg.GetTarget())
-
-			err := m.downloadPackage(ctx, pkg, targetDir)
+			requester := localOrigin
+			if parent != nil {
+				requester = parent.GetName()
+				pkg.DeclaredBy = parent.GetName()
+			}
+			ref, err := m.resolver.ref(pkg, requester)
 			if err != nil {
+				if m.skipOptional(ctx, d, err) {
+					continue
+				}
 				return packages, err
 			}
+			pkg.Source.Ref = ref
+			if m.locked {
+				if pkg.Commit, err = m.resolver.lock.lockedCommit(pkg); err != nil {
+					return packages, err
+				}
+			}
+
+			if slices.Contains(chain, pkg.GetName()) {
+				cycle := append(slices.Clone(chain), pkg.GetName())
+				return packages, fmt.Errorf("%w: %s", errDependencyCycle, strings.Join(cycle, " -> "))
+			}
+
+			packagePath := filepath.Join(targetDir, pkg.GetName(), pkg.GetTarget())
+
+			key := sourceKey(pkg)
+			owner, downloaded := m.sources[key]
+			switch {
+			case !downloaded:
+				err := m.downloadPackage(ctx, pkg, targetDir)
+				if err != nil {
+					if m.skipOptional(ctx, d, err) {
+						continue
+					}
+					return packages, err
+				}
+				m.sources[key] = pkg.GetName()
+				m.subpaths[key] = []string{pkg.GetSubpath()}
+			case owner != pkg.GetName():
+				// Same source requested under another name, reuse existing checkout.
+				m.kw.Term().Info().Printfln("Package %s has the same source as %s, reusing its checkout", pkg.GetName(), owner)
+				m.aliases[pkg.GetName()] = owner
+				packagePath = filepath.Join(targetDir, owner, pkg.GetTarget())
+				if err = m.shareCheckout(pkg, key, packagePath); err != nil {
+					if m.skipOptional(ctx, d, err) {
+						continue
+					}
+					return packages, err
+				}
+			}
 
 			// If package has compose.yaml, proceed with it
-			if _, err = os.Stat(filepath.Join(packagePath, composeFile)); !os.IsNotExist(err) {
-				cfg, err := Lookup(os.DirFS(packagePath))
-				if err == nil {
-					packages, err = m.recursiveDownload(ctx, cfg, packages, pkg, targetDir)
+			packagePath = packageDir(packagePath, pkg)
+			if _, err := os.Stat(filepath.Join(packagePath, composeFile)); !yc.Flattened && !os.IsNotExist(err) {
+				cfg, err := m.lookupNested(pkg, packagePath)
+				if err != nil {
+					return packages, err
+				}
+
+				if cfg != nil {
+					if err = checkRequires(cfg.Requires, "package "+pkg.GetName(), pluginVersion()); err != nil {
+						return packages, err
+					}
+
+					packages, err = m.recursiveDownload(ctx, cfg, packages, pkg, append(slices.Clone(chain), pkg.GetName()), targetDir)
 					if err != nil {
 						return packages, err
 					}
 				}
 			}
 
+			for _, name := range d.Requires {
+				pkg.AddDependency(name)
+			}
+			if parent != nil {
+				parent.AddDependency(d.Name)
+			}
 			packages = append(packages, pkg)
 		}
 	}
@@ -114,17 +335,104 @@ func (m DownloadManager) recursiveDownload(ctx context.Context, yc *Composition,
 	return packages, nil
 }
 
+// validateRequires checks that packages required by a dependency are part of the composition.
+// Skipped packages are dropped from requirements.
+func validateRequires(packages []*Package, skipped []string) error {
+	names := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		names[pkg.GetName()] = true
+	}
+
+	for _, pkg := range packages {
+		deps := pkg.Dependencies[:0]
+		for _, name := range pkg.Dependencies {
+			switch {
+			case names[name]:
+				deps = append(deps, name)
+			case !slices.Contains(skipped, name):
+				return fmt.Errorf("%w: package %s requires %s which is not in the composition", errUnknownRequired, pkg.GetName(), name)
+			}
+		}
+		pkg.Dependencies = deps
+	}
+
+	return nil
+}
+
+// skipOptional reports if a failed package is optional. Optional packages are left out
+// of the composition with their nested dependencies.
+func (m DownloadManager) skipOptional(ctx context.Context, d Dependency, err error) bool {
+	if !d.Optional || ctx.Err() != nil {
+		return false
+	}
+
+	m.kw.Term().Warning().Printfln("Skipping optional package %s: %s", d.Name, err)
+	m.summary.addSkipped(d.Name)
+
+	return true
+}
+
+// lookupNested parses compose.yaml of a downloaded package. Invalid files fail in strict mode,
+// otherwise a warning is shown and the file is parsed leniently or skipped if it's malformed.
+func (m DownloadManager) lookupNested(pkg *Package, packagePath string) (*Composition, error) {
+	cfg, err := LookupStrict(os.DirFS(packagePath))
+	if err == nil {
+		return cfg, nil
+	}
+
+	err = fmt.Errorf("%w: package %s (%s)", errInvalidNestedCompose, pkg.GetName(), err)
+	if m.strict {
+		return nil, err
+	}
+
+	cfg, errLenient := Lookup(os.DirFS(packagePath))
+	if errLenient != nil {
+		m.kw.Term().Warning().Printfln("%s, skipping its dependencies", err)
+		return nil, nil
+	}
+
+	m.kw.Term().Warning().Printfln("%s", err)
+	return cfg, nil
+}
+
 func (m DownloadManager) downloadPackage(ctx context.Context, pkg *Package, targetDir string) error {
 	downloader := m.getDownloaderForPackage(pkg.GetType())
 	packagePath := filepath.Join(targetDir, pkg.GetName())
 	downloadPath := filepath.Join(packagePath, pkg.GetTarget())
 
-	isLatest, err := downloader.EnsureLatest(pkg, downloadPath)
-	if err != nil {
-		return err
+	start := time.Now()
+	if sr, ok := downloader.(statsReporter); ok {
+		sr.ResetStats()
+	}
+	if m.progress.completed(pkg, downloadPath) {
+		m.kw.Log().Debug("package was downloaded by previous run, skipping source check", "package", pkg.GetName())
+		m.summary.addCached(pkg.GetIdentifier())
+		m.summary.addResumed(pkg.GetIdentifier())
+		m.addPackageMetrics(pkg, downloader, CacheHit, start)
+		return nil
+	}
+
+	var isLatest bool
+	var err error
+	if pkg.Frozen && pkg.Commit == "" && hasEntries(downloadPath) {
+		// Frozen packages keep their current checkout, remote changes of the ref aren't pulled.
+		m.kw.Log().Debug("package is frozen, skipping source check", "package", pkg.GetName())
+		isLatest = true
+	} else {
+		isLatest, err = downloader.EnsureLatest(pkg, downloadPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !isLatest && m.frozenLockfile && hasEntries(downloadPath) {
+		return fmt.Errorf("%w: checkout of %s@%s isn't at commit %s, run compose without --frozen-lockfile to update it", errFrozenCheckout, pkg.GetName(), pkg.GetTarget(), pkg.Commit)
 	}
 
 	if isLatest {
+		m.summary.addCached(pkg.GetIdentifier())
+		m.addPackageMetrics(pkg, downloader, CacheHit, start)
+		m.completePackage(pkg)
 		return nil
 	}
 
@@ -134,6 +442,23 @@ func (m DownloadManager) downloadPackage(ctx context.Context, pkg *Package, targ
 		return err
 	}
 
+	if sd, ok := downloader.(siblingDownloader); ok {
+		reused, errReuse := sd.DownloadFromSibling(ctx, pkg, packagePath, downloadPath)
+		if errReuse == nil && reused {
+			m.summary.addFetched(pkg.GetIdentifier())
+			m.addPackageMetrics(pkg, downloader, CacheMiss, start)
+			m.completePackage(pkg)
+			return nil
+		}
+
+		if errReuse != nil {
+			m.kw.Log().Debug("failed to reuse existing clone, cloning package", "package", pkg.GetName(), "err", errReuse)
+			if err = os.RemoveAll(downloadPath); err != nil {
+				return err
+			}
+		}
+	}
+
 	// temporary
 	if dtype := pkg.GetType(); dtype == HTTPType {
 		downloadPath = packagePath
@@ -145,9 +470,69 @@ func (m DownloadManager) downloadPackage(ctx context.Context, pkg *Package, targ
 		if errRemove != nil {
 			m.kw.Log().Debug("error cleaning package folder", "path", downloadPath, "err", err)
 		}
+
+		if isAuthError(err) {
+			return &ErrAuthFailed{Host: urlHost(pkg.GetURL()), Err: err}
+		}
+
+		return err
 	}
 
-	return err
+	m.summary.addFetched(pkg.GetIdentifier())
+	m.addPackageMetrics(pkg, downloader, CacheMiss, start)
+	m.completePackage(pkg)
+	return nil
+}
+
+// completePackage records pkg in compose progress, failing to record it only costs a source check on rerun.
+func (m DownloadManager) completePackage(pkg *Package) {
+	if err := m.progress.complete(pkg); err != nil {
+		m.kw.Log().Debug("failed to record compose progress", "package", pkg.GetName(), "err", err)
+	}
+}
+
+func (m DownloadManager) addPackageMetrics(pkg *Package, downloader Downloader, cache string, start time.Time) {
+	pm := PackageMetrics{
+		Name:     pkg.GetName(),
+		Cache:    cache,
+		Duration: time.Since(start),
+	}
+
+	if sr, ok := downloader.(statsReporter); ok {
+		stats := sr.Stats()
+		pm.Bytes = stats.bytes
+		pm.DiskBytes = stats.diskBytes
+		// Auth mode is known only if remote was requested.
+		if cache == CacheMiss || stats.auth != authenticationModeNone {
+			pm.Auth = stats.auth.String()
+		}
+	}
+
+	m.summary.addPackageMetrics(pm)
+}
+
+// DirSize returns total size of regular files in the directory.
+func DirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, errInfo := d.Info(); errInfo == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return size
+}
+
+// hasEntries reports if dir exists and isn't empty.
+func hasEntries(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) > 0
 }
 
 // IsEmptyDir check if directory has at least 1 file.
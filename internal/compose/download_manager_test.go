@@ -0,0 +1,204 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/launchrctl/launchr"
+)
+
+func TestRecursiveDownloadCycle(t *testing.T) {
+	targetDir := t.TempDir()
+
+	// HTTP packages are considered up to date once their directory exists.
+	composeFiles := map[string]string{
+		"a": "name: a\ndependencies:\n  - name: b\n    source:\n      type: http\n      url: https://example.com/b.tar.gz\n",
+		"b": "name: b\ndependencies:\n  - name: a\n    source:\n      type: http\n      url: https://example.com/a.tar.gz\n",
+	}
+	for name, content := range composeFiles {
+		dir := filepath.Join(targetDir, name, TargetLatest)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, composeFile), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write compose file: %v", err)
+		}
+	}
+
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "a", Source: Source{Type: HTTPType, URL: "https://example.com/a.tar.gz"}},
+		},
+	}
+
+	dm := CreateDownloadManager(&keyringWrapper{}, nil)
+	_, err := dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir)
+	if !errors.Is(err, errDependencyCycle) {
+		t.Fatalf("expected dependency cycle error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected cycle to be named in error, got %q", err.Error())
+	}
+}
+
+func TestRecursiveDownloadSharedSource(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(targetDir, "a", TargetLatest), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "a", Source: Source{Type: HTTPType, URL: "https://example.com/a.tar.gz"}},
+			{Name: "b", Source: Source{Type: HTTPType, URL: "https://example.com/a.tar.gz/"}},
+		},
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetTerm(launchr.Term())
+	dm := CreateDownloadManager(kw, nil)
+	packages, err := dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir)
+	if err != nil {
+		t.Fatalf("recursiveDownload failed: %v", err)
+	}
+
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(packages))
+	}
+	if owner := dm.Aliases()["b"]; owner != "a" {
+		t.Errorf("expected b to share checkout of a, got %q", owner)
+	}
+	if _, err = os.Stat(filepath.Join(targetDir, "b")); !os.IsNotExist(err) {
+		t.Error("expected b not to be downloaded separately")
+	}
+}
+
+func TestRecursiveDownloadOptional(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(targetDir, "a", TargetLatest), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "a", Source: Source{Type: HTTPType, URL: "https://example.com/a.tar.gz"}},
+			{Name: "missing", Optional: true, Source: Source{Type: HTTPType, URL: srv.URL + "/missing.tar.gz"}},
+		},
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	summary := &Summary{}
+	dm := CreateDownloadManager(kw, summary)
+	packages, err := dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir)
+	if err != nil {
+		t.Fatalf("expected optional package failure to be skipped, got %v", err)
+	}
+	if len(packages) != 1 || packages[0].GetName() != "a" {
+		t.Errorf("expected only package a, got %d packages", len(packages))
+	}
+	if len(summary.Skipped) != 1 || summary.Skipped[0] != "missing" {
+		t.Errorf("expected missing package in skipped, got %v", summary.Skipped)
+	}
+
+	cfg.Dependencies[1].Optional = false
+	if _, err = dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir); err == nil {
+		t.Error("expected required package failure to abort")
+	}
+}
+
+func TestLookupNested(t *testing.T) {
+	kw := &keyringWrapper{}
+	kw.SetTerm(launchr.Term())
+	pkg := &Package{Name: "nested"}
+
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, composeFile), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write compose.yaml: %v", err)
+		}
+		return dir
+	}
+
+	unknownField := write(t, "name: nested\ndependencies:\n  - name: dep\n    source:\n      url: https://example.com/dep.git\n      tag: v1\n")
+	malformed := write(t, "name: nested\ndependencies:\n  - name: dep\n   source: [\n")
+
+	dm := CreateDownloadManager(kw, nil)
+	cfg, err := dm.lookupNested(pkg, unknownField)
+	if err != nil || cfg == nil || len(cfg.Dependencies) != 1 {
+		t.Errorf("expected lenient parsing with warning, got %v, %v", cfg, err)
+	}
+	cfg, err = dm.lookupNested(pkg, malformed)
+	if err != nil || cfg != nil {
+		t.Errorf("expected malformed file to be skipped with warning, got %v, %v", cfg, err)
+	}
+
+	dm.strict = true
+	for _, dir := range []string{unknownField, malformed} {
+		_, err = dm.lookupNested(pkg, dir)
+		if !errors.Is(err, errInvalidNestedCompose) {
+			t.Fatalf("expected invalid compose error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "package nested") || !strings.Contains(err.Error(), "line ") {
+			t.Errorf("expected package name and line in error, got %q", err.Error())
+		}
+	}
+}
+
+func TestDownloadPackageFrozenLockfile(t *testing.T) {
+	targetDir := t.TempDir()
+	repoDir := filepath.Join(targetDir, "core", "v1.0.0")
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.yaml", "README.md"} {
+		if err = os.WriteFile(filepath.Join(repoDir, name), []byte("a: 1\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	head, err := wt.Commit("initial commit", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	dm := CreateDownloadManager(kw, &Summary{})
+	dm.frozenLockfile = true
+
+	pkg := &Package{Name: "core", Source: Source{Type: GitType, Ref: "v1.0.0", URL: "https://example.com/core.git"}, Commit: head.String()}
+	if err = dm.downloadPackage(context.Background(), pkg, targetDir); err != nil {
+		t.Fatalf("expected checkout at locked commit to be used, got %v", err)
+	}
+
+	pkg.Commit = strings.Repeat("0", 40)
+	if err = dm.downloadPackage(context.Background(), pkg, targetDir); !errors.Is(err, errFrozenCheckout) {
+		t.Fatalf("expected frozen checkout error, got %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(repoDir, "main.yaml")); err != nil {
+		t.Errorf("expected checkout to be kept: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/launchrctl/launchr"
+)
+
+func TestErrAuthFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	dm := CreateDownloadManager(kw, &Summary{})
+
+	pkg := &Package{Name: "private", Source: Source{Type: HTTPType, URL: srv.URL + "/private.tar.gz"}}
+	err := dm.downloadPackage(context.Background(), pkg, t.TempDir())
+
+	var authErr *ErrAuthFailed
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected ErrAuthFailed, got %v", err)
+	}
+	if authErr.Host != "127.0.0.1" {
+		t.Errorf("expected host 127.0.0.1, got %s", authErr.Host)
+	}
+}
+
+func TestErrRefNotFound(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Add("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	g := &gitDownloader{k: kw}
+
+	for _, ref := range []string{"v9.9.9", "0123456789abcdef0123456789abcdef01234567"} {
+		pkg := &Package{Name: "pkg", Source: Source{Type: GitType, URL: repoDir, Ref: ref}}
+		err = g.Download(context.Background(), pkg, filepath.Join(t.TempDir(), ref))
+
+		var refErr *ErrRefNotFound
+		if !errors.As(err, &refErr) {
+			t.Fatalf("expected ErrRefNotFound for %s, got %v", ref, err)
+		}
+		if refErr.Package != "pkg" || refErr.Ref != ref {
+			t.Errorf("unexpected error fields %+v", refErr)
+		}
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	if err := validateConflictDefault("oldest"); !errors.Is(err, ErrConflictPolicy) {
+		t.Errorf("expected ErrConflictPolicy, got %v", err)
+	}
+	cfg := &Composition{Strategies: []Strategy{{Name: "unknown"}}}
+	if err := validateStrategies(cfg); !errors.Is(err, ErrConflictPolicy) {
+		t.Errorf("expected ErrConflictPolicy, got %v", err)
+	}
+	if !errors.Is(errIntegrityMismatch, ErrLockOutOfDate) {
+		t.Error("expected integrity mismatch to wrap ErrLockOutOfDate")
+	}
+}
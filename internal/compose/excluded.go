@@ -0,0 +1,72 @@
+package compose
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// exclusions are names of folders and files configured by excluded of compose.yaml, they are never merged.
+type exclusions struct {
+	folders []string
+	files   []string
+}
+
+func newExclusions(cfg *Composition) exclusions {
+	if cfg == nil || cfg.Excluded == nil {
+		return exclusions{}
+	}
+
+	return exclusions{folders: cfg.Excluded.Folders, files: cfg.Excluded.Files}
+}
+
+// match returns the configured name matching a folder of the slash separated path, or its base name if it's a file.
+func (e exclusions) match(p string, isDir bool) (string, bool) {
+	if len(e.folders) == 0 && len(e.files) == 0 {
+		return "", false
+	}
+
+	segments := strings.Split(p, "/")
+	folders := segments
+	if !isDir {
+		folders = segments[:len(segments)-1]
+		if name, ok := matchName(e.files, segments[len(segments)-1]); ok {
+			return name, true
+		}
+	}
+	for _, segment := range folders {
+		if name, ok := matchName(e.folders, segment); ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func matchName(names []string, segment string) (string, bool) {
+	for _, name := range names {
+		if ok, _ := path.Match(name, segment); ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// validateExclusions checks names of excluded folders and files, they can't be paths.
+func validateExclusions(cfg *Composition) error {
+	if cfg.Excluded == nil {
+		return nil
+	}
+
+	for _, name := range append(append([]string{}, cfg.Excluded.Folders...), cfg.Excluded.Files...) {
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("%w: %q of excluded is not a folder or file name", errInvalidExclude, name)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("%w: %q of excluded: %w", errInvalidExclude, name, err)
+		}
+	}
+
+	return nil
+}
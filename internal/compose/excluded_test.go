@@ -0,0 +1,52 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExclusionsMatch(t *testing.T) {
+	e := newExclusions(&Composition{Excluded: &Excluded{
+		Folders: []string{".terraform", "node_modules"},
+		Files:   []string{"*.swp", ".DS_Store"},
+	}})
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected string
+	}{
+		{".terraform", true, ".terraform"},
+		{"src/platform/services/web/node_modules", true, "node_modules"},
+		{"src/platform/services/web/node_modules/left-pad/index.js", false, "node_modules"},
+		{"src/platform/services/web/tasks/main.yaml.swp", false, "*.swp"},
+		{"src/.DS_Store", false, ".DS_Store"},
+		{"src/platform/services/web/tasks/main.yaml", false, ""},
+		{"node_modules", false, ""},
+	}
+	for _, tt := range tests {
+		name, _ := e.match(tt.path, tt.isDir)
+		if name != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, name)
+		}
+	}
+
+	if _, ok := newExclusions(&Composition{}).match("node_modules", true); ok {
+		t.Error("expected nothing to be excluded without configuration")
+	}
+}
+
+func TestValidateExclusions(t *testing.T) {
+	if err := validateExclusions(&Composition{Excluded: &Excluded{Folders: []string{".terraform"}, Files: []string{"*.tmp"}}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, excluded := range []*Excluded{
+		{Folders: []string{"src/node_modules"}},
+		{Files: []string{"[.tmp"}},
+		{Files: []string{""}},
+	} {
+		if err := validateExclusions(&Composition{Excluded: excluded}); !errors.Is(err, errInvalidExclude) {
+			t.Errorf("expected %v to be invalid, got %v", excluded, err)
+		}
+	}
+}
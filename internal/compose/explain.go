@@ -0,0 +1,360 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Merge decisions reported by Explain.
+const (
+	DecisionAdded    = "added"
+	DecisionReplaced = "replaced"
+	DecisionSkipped  = "skipped"
+	DecisionExcluded = "excluded"
+	DecisionMerged   = "merged"
+)
+
+var (
+	errPackageNotDownloaded = errors.New("package is not downloaded")
+	errInvalidExplainPath   = errors.New("invalid path")
+)
+
+// ExplainStep stores merge decision of a candidate providing the explained path.
+type ExplainStep struct {
+	Package  string `json:"package"`
+	Source   string `json:"source"`
+	Strategy string `json:"strategy,omitempty"`
+	// DeclaredBy is the package which nested compose.yaml declares the strategy.
+	DeclaredBy string `json:"declared_by,omitempty"`
+	Decision   string `json:"decision"`
+	Reason     string `json:"reason"`
+}
+
+// Explanation stores how a destination path of the merged result was resolved.
+type Explanation struct {
+	Path   string        `json:"path"`
+	Winner string        `json:"winner,omitempty"`
+	Order  []string      `json:"order"`
+	Steps  []ExplainStep `json:"steps"`
+}
+
+// Explain replays merge of a single destination path across the domain repo and downloaded packages
+// in dependency order. Packages must be downloaded by compose before. Strategies declared by nested
+// compose files are dropped if ignoreNested is set.
+func Explain(baseDir, packagesDir string, cfg *Composition, path string, ignoreNested bool) (*Explanation, error) {
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("%w: %q must be relative to the merged directory", errInvalidExplainPath, path)
+	}
+	// Merged paths are slash separated on every platform
+	path = filepath.ToSlash(path)
+
+	if err := validateConflictDefault(cfg.ConflictDefault); err != nil {
+		return nil, err
+	}
+	if err := validateStrategies(cfg); err != nil {
+		return nil, err
+	}
+
+	lock, err := LoadVersionLock(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, aliases, err := collectPackages(cfg, lock, packagesDir)
+	if err != nil {
+		return nil, err
+	}
+	nestedStrategies(packages, ignoreNested)
+
+	ls, ps := retrieveStrategies(packages, cfg.Strategies, hasModernLayout(baseDir))
+	cr := newConflictResolver(cfg.ConflictDefault)
+	items, _ := buildDependenciesGraph(packages).TopSort(DependencyRoot)
+	targetsMap := getTargetsMap(packages)
+	packagesMap := make(map[string]*Package)
+	for _, p := range packages {
+		packagesMap[p.GetName()] = p
+	}
+
+	e := &Explanation{Path: path, Order: []string{}, Steps: []ExplainStep{}}
+	var existing *fsEntry
+
+	// Domain repo files are merged first.
+	if step, ok := explainLocal(baseDir, cfg, ls, path); ok {
+		e.Steps = append(e.Steps, step)
+		if step.Decision == DecisionAdded {
+			finfo, err := os.Lstat(filepath.Join(baseDir, path))
+			if err != nil {
+				return nil, err
+			}
+			existing = &fsEntry{Prefix: baseDir, SrcPath: path, DstPath: path, Entry: newFileMeta(finfo), From: localOrigin}
+			e.Winner = localOrigin
+		}
+	}
+
+	for _, pkgName := range items {
+		if pkgName == DependencyRoot {
+			continue
+		}
+		e.Order = append(e.Order, pkgName)
+
+		checkout := pkgName
+		if owner, ok := aliases[pkgName]; ok {
+			checkout = owner
+		}
+		pkgPath := filepath.Join(packagesDir, checkout, targetsMap[pkgName])
+		var exclude []string
+		if pkg, ok := packagesMap[pkgName]; ok {
+			pkgPath = packageDir(pkgPath, pkg)
+			exclude = pkg.Source.Exclude
+		}
+		isModern := hasModernLayout(pkgPath)
+
+		sources, err := findPackageSources(pkgPath, path, isModern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, src := range sources {
+			finfo, err := os.Lstat(filepath.Join(pkgPath, src))
+			if err != nil {
+				return nil, err
+			}
+			entry := &fsEntry{Prefix: pkgPath, SrcPath: src, DstPath: path, Entry: newFileMeta(finfo), From: pkgName}
+			action, ms := decideEntry(ps[pkgName], existing, entry, path, cr)
+
+			step := ExplainStep{Package: pkgName, Source: filepath.ToSlash(src)}
+			if pattern, excluded := excludedPath(filepath.ToSlash(src), exclude); excluded {
+				step.Decision, step.Reason = DecisionExcluded, fmt.Sprintf("path matches exclude %s of the package", pattern)
+				e.Steps = append(e.Steps, step)
+				continue
+			}
+			if name, excluded := newExclusions(cfg).match(filepath.ToSlash(src), finfo.IsDir()); excluded {
+				step.Decision, step.Reason = DecisionExcluded, fmt.Sprintf("path matches %s of excluded in %s", name, composeFile)
+				e.Steps = append(e.Steps, step)
+				continue
+			}
+			if ms != nil {
+				step.Strategy = ms.name()
+				step.DeclaredBy = ms.declaredBy
+			}
+
+			switch action {
+			case appendEntry:
+				step.Decision = DecisionAdded
+				step.Reason = "path is not provided by earlier candidates"
+				if ms != nil {
+					step.Reason = fmt.Sprintf("path matches %s paths %s", step.Strategy, strings.Join(ms.paths, ", "))
+				}
+				existing = entry
+				e.Winner = pkgName
+			case replaceEntry:
+				step.Decision = DecisionReplaced
+				switch {
+				case ms != nil && ms.s == preferNewestFiles:
+					step.Reason = fmt.Sprintf("%s paths %s, the file is newer than the one of %s", step.Strategy, strings.Join(ms.paths, ", "), e.Winner)
+				case ms != nil:
+					step.Reason = fmt.Sprintf("%s paths %s replace the file of %s", step.Strategy, strings.Join(ms.paths, ", "), e.Winner)
+				default:
+					step.Reason = fmt.Sprintf("conflict_default %s replaces the file of %s", cfg.ConflictDefault, e.Winner)
+				}
+				existing.Entry = entry.Entry
+				e.Winner = pkgName
+			case mergeEntry:
+				step.Decision = DecisionMerged
+				step.Reason = fmt.Sprintf("%s paths %s, the YAML is deep-merged into the file of %s", step.Strategy, strings.Join(ms.paths, ", "), e.Winner)
+			default:
+				step.Decision = DecisionSkipped
+				switch {
+				case ms == nil && cfg.ConflictDefault == ConflictDefaultNewest:
+					step.Reason = fmt.Sprintf("%s already provides the path with a file not older, conflict_default is %s", e.Winner, ConflictDefaultNewest)
+				case ms == nil:
+					step.Reason = fmt.Sprintf("%s already provides the path, conflicts resolve to the earlier candidate", e.Winner)
+				case ms.s == preferNewestFiles:
+					step.Reason = fmt.Sprintf("%s paths %s, %s already provides a file not older", step.Strategy, strings.Join(ms.paths, ", "), e.Winner)
+				case ms.s == filterPackageFiles && existing != nil:
+					step.Reason = fmt.Sprintf("%s never replaces existing files, %s already provides the path", step.Strategy, e.Winner)
+				case ms.s == filterPackageFiles:
+					step.Reason = fmt.Sprintf("path doesn't match %s paths %s", step.Strategy, strings.Join(ms.paths, ", "))
+				default:
+					step.Reason = fmt.Sprintf("path matches %s paths %s", step.Strategy, strings.Join(ms.paths, ", "))
+				}
+			}
+			e.Steps = append(e.Steps, step)
+		}
+	}
+
+	// Overlays are applied over the merged result.
+	for _, o := range cfg.Overlays {
+		src := filepath.Join(o.Path, path)
+		if o.Template {
+			if _, err = os.Lstat(filepath.Join(baseDir, src+overlayTemplateSuffix)); err == nil {
+				src += overlayTemplateSuffix
+			}
+		}
+		if _, err = os.Lstat(filepath.Join(baseDir, src)); err != nil {
+			continue
+		}
+		e.Steps = append(e.Steps, ExplainStep{
+			Package:  "overlay " + o.GetName(),
+			Source:   filepath.ToSlash(src),
+			Decision: DecisionReplaced,
+			Reason:   "overlays are applied over the merged result when selected",
+		})
+		e.Winner = "overlay " + o.GetName()
+	}
+
+	return e, nil
+}
+
+// explainLocal checks if the domain repo provides path.
+func explainLocal(baseDir string, cfg *Composition, ls []*mergeStrategy, path string) (ExplainStep, bool) {
+	info, err := os.Lstat(filepath.Join(baseDir, path))
+	if err != nil {
+		return ExplainStep{}, false
+	}
+
+	step := ExplainStep{Package: localOrigin, Source: filepath.ToSlash(path), Decision: DecisionAdded, Reason: "domain repo files are merged first"}
+
+	root := rgxPathRoot.FindString(filepath.ToSlash(path))
+	if _, ok := excludedFolders[root]; ok {
+		step.Decision, step.Reason = DecisionExcluded, root+" is never merged"
+		return step, true
+	}
+	if _, ok := excludedFiles[filepath.Base(path)]; ok {
+		step.Decision, step.Reason = DecisionExcluded, filepath.Base(path)+" is never merged"
+		return step, true
+	}
+	if name, ok := newExclusions(cfg).match(filepath.ToSlash(path), info.IsDir()); ok {
+		step.Decision, step.Reason = DecisionExcluded, fmt.Sprintf("path matches %s of excluded in %s", name, composeFile)
+		return step, true
+	}
+	for _, o := range cfg.Overlays {
+		if p := filepath.ToSlash(filepath.Clean(o.Path)); path == p || strings.HasPrefix(path, p+"/") {
+			step.Decision, step.Reason = DecisionExcluded, fmt.Sprintf("path belongs to overlay %s", o.GetName())
+			return step, true
+		}
+	}
+	for _, ms := range ls {
+		if ms.s == removeExtraLocalFiles && ensureStrategyPrefixPath(path, ms.paths) {
+			step.Strategy = ms.name()
+			step.Decision = DecisionExcluded
+			step.Reason = fmt.Sprintf("path matches %s paths %s", step.Strategy, strings.Join(ms.paths, ", "))
+			return step, true
+		}
+	}
+
+	return step, true
+}
+
+// findPackageSources returns paths of a package merged to the destination path.
+func findPackageSources(pkgPath, path string, isModern bool) ([]string, error) {
+	var sources []string
+	err := fs.WalkDir(os.DirFS(pkgPath), ".", func(src string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(src, gitPrefix) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if adjustDestinationPath(src, isModern) == path {
+			sources = append(sources, src)
+		}
+		return nil
+	})
+
+	return sources, err
+}
+
+// collectPackages builds packages of the composition from downloaded packages the same way compose does.
+func collectPackages(cfg *Composition, lock *VersionLock, packagesDir string) ([]*Package, map[string]string, error) {
+	sources := make(map[string]string)
+	aliases := make(map[string]string)
+	var skipped []string
+
+	var collect func(yc *Composition, parent *Package, chain []string, packages []*Package) ([]*Package, error)
+	collect = func(yc *Composition, parent *Package, chain []string, packages []*Package) ([]*Package, error) {
+		for _, d := range yc.Dependencies {
+			pkg := d.ToPackage(d.Name)
+			pkg.Source.Ref = lock.Ref(d)
+			if parent != nil {
+				pkg.DeclaredBy = parent.GetName()
+			}
+			if slices.Contains(chain, pkg.GetName()) {
+				cycle := append(slices.Clone(chain), pkg.GetName())
+				return packages, fmt.Errorf("%w: %s", errDependencyCycle, strings.Join(cycle, " -> "))
+			}
+
+			checkout := pkg.GetName()
+			key := sourceKey(pkg)
+			if owner, ok := sources[key]; ok && owner != pkg.GetName() {
+				aliases[pkg.GetName()] = owner
+				checkout = owner
+			} else {
+				sources[key] = pkg.GetName()
+			}
+
+			packagePath := filepath.Join(packagesDir, checkout, pkg.GetTarget())
+			if _, err := os.Stat(packagePath); err != nil {
+				if d.Optional {
+					// Optional package failed to download and isn't part of the composition.
+					skipped = append(skipped, pkg.GetName())
+					continue
+				}
+				return packages, fmt.Errorf("%w: %s@%s, run model:compose first", errPackageNotDownloaded, pkg.GetName(), pkg.GetTarget())
+			}
+
+			nested, err := Lookup(os.DirFS(packageDir(packagePath, pkg)))
+			if err == nil && !yc.Flattened {
+				packages, err = collect(nested, pkg, append(slices.Clone(chain), pkg.GetName()), packages)
+				if err != nil {
+					return packages, err
+				}
+			}
+
+			for _, name := range d.Requires {
+				pkg.AddDependency(name)
+			}
+			if parent != nil {
+				parent.AddDependency(d.Name)
+			}
+			packages = append(packages, pkg)
+		}
+
+		return packages, nil
+	}
+
+	packages, err := collect(cfg, nil, nil, nil)
+	if err == nil {
+		err = validateRequires(packages, skipped)
+	}
+	return packages, aliases, err
+}
+
+// name returns strategy name as declared in compose.yaml.
+func (ms *mergeStrategy) name() string {
+	switch ms.s {
+	case overwriteLocalFile:
+		return StrategyOverwriteLocal
+	case removeExtraLocalFiles:
+		return StrategyRemoveExtraLocal
+	case ignoreExtraPackageFiles:
+		return StrategyIgnoreExtraPackage
+	case filterPackageFiles:
+		return StrategyFilterPackage
+	case preferNewestFiles:
+		return StrategyPreferNewest
+	case mergeYAMLFiles:
+		return StrategyMergeYAML
+	default:
+		return ""
+	}
+}
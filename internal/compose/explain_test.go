@@ -0,0 +1,56 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, "packages")
+	path := filepath.Join("src", "platform", "services", "nginx", "defaults", "main.yaml")
+
+	for _, f := range []string{
+		filepath.Join(baseDir, path),
+		filepath.Join(packagesDir, "core", "v1.0.0", path),
+		filepath.Join(packagesDir, "extra", "v2.0.0", path),
+	} {
+		if err := os.MkdirAll(filepath.Dir(f), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("key: value\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Composition{Dependencies: []Dependency{
+		{Name: "core", Source: Source{Ref: "v1.0.0"}},
+		{Name: "extra", Source: Source{Ref: "v2.0.0", Strategies: []Strategy{
+			{Name: StrategyOverwriteLocal, Paths: []string{"src/platform/services"}},
+		}}},
+	}}
+
+	e, err := Explain(baseDir, packagesDir, cfg, filepath.ToSlash(path), false)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	// Independent packages may be merged in any order.
+	expected := map[string]string{localOrigin: DecisionAdded, "core": DecisionSkipped, "extra": DecisionReplaced}
+	if len(e.Steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %+v", len(expected), e.Steps)
+	}
+	for _, step := range e.Steps {
+		if step.Decision != expected[step.Package] {
+			t.Errorf("%s: expected %s, got %s", step.Package, expected[step.Package], step.Decision)
+		}
+	}
+	if e.Winner != "extra" {
+		t.Errorf("expected winner extra, got %q", e.Winner)
+	}
+
+	if _, err = Explain(baseDir, packagesDir, cfg, "../outside", false); err == nil {
+		t.Error("expected error for path outside of merged directory")
+	}
+}
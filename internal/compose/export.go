@@ -0,0 +1,144 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Export returns the composition of baseDir with refs resolved by the version lock.
+// Flattened compositions list nested dependencies of downloaded packages in merge order instead,
+// git refs are pinned to checked out commits and layer scopes of strategies are expanded to paths.
+func Export(baseDir, packagesDir string, cfg *Composition, flatten bool) (*Composition, error) {
+	lock, err := LoadVersionLock(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if flatten {
+		return flattenComposition(baseDir, packagesDir, cfg, lock)
+	}
+
+	exported := *cfg
+	exported.Dependencies = slices.Clone(cfg.Dependencies)
+	lock.Apply(&exported)
+
+	return &exported, nil
+}
+
+func flattenComposition(baseDir, packagesDir string, cfg *Composition, lock *VersionLock) (*Composition, error) {
+	if err := validateStrategies(cfg); err != nil {
+		return nil, err
+	}
+
+	// Packages skipped by the last compose aren't downloaded and aren't part of the snapshot.
+	composed := *cfg
+	if summary, err := LoadSummary(baseDir); err == nil {
+		composed.Dependencies = slices.DeleteFunc(slices.Clone(cfg.Dependencies), func(d Dependency) bool {
+			return slices.Contains(summary.Skipped, d.Name)
+		})
+	}
+
+	packages, aliases, err := collectPackages(&composed, lock, packagesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same package may be required by several compositions.
+	unique := make(map[string]*Package)
+	requires := make(map[string][]string)
+	for _, pkg := range packages {
+		if _, ok := unique[pkg.GetName()]; !ok {
+			unique[pkg.GetName()] = pkg
+		}
+		for _, name := range pkg.Dependencies {
+			if !slices.Contains(requires[pkg.GetName()], name) {
+				requires[pkg.GetName()] = append(requires[pkg.GetName()], name)
+			}
+		}
+	}
+
+	localModern := hasModernLayout(baseDir)
+	flat := composed
+	flat.Flattened = true
+	flat.Strategies = expandStrategies(cfg.Strategies, localModern)
+	flat.Dependencies = nil
+
+	items, _ := buildDependenciesGraph(packages).TopSort(DependencyRoot)
+	for _, name := range items {
+		pkg, ok := unique[name]
+		if !ok {
+			continue
+		}
+
+		source := pkg.Source
+		source.Strategies = expandStrategies(pkg.GetStrategies(), localModern)
+		if pkg.GetType() == GitType {
+			checkout := name
+			if owner, ok := aliases[name]; ok {
+				checkout = owner
+			}
+			source.Ref, err = headCommit(filepath.Join(packagesDir, checkout, pkg.GetTarget()))
+			if err != nil {
+				return nil, fmt.Errorf("can't pin %s to a commit: %w", pkg.GetIdentifier(), err)
+			}
+		}
+
+		flat.Dependencies = append(flat.Dependencies, Dependency{Name: name, Requires: requires[name], Source: source})
+	}
+
+	return &flat, nil
+}
+
+// expandStrategies replaces layer scopes of strategies with paths, unknown strategies are left out
+// the same way compose ignores them.
+func expandStrategies(items []Strategy, localModern bool) []Strategy {
+	var expanded []Strategy
+	for _, item := range items {
+		ms := newMergeStrategy(item, localModern)
+		if ms == nil {
+			continue
+		}
+
+		s := Strategy{Name: item.Name}
+		for _, p := range ms.paths {
+			s.Paths = append(s.Paths, strings.TrimSuffix(filepath.ToSlash(p), "/"))
+		}
+		expanded = append(expanded, s)
+	}
+
+	return expanded
+}
+
+// headCommit returns the commit checked out in a git clone.
+func headCommit(dir string) (string, error) {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
+
+// WriteComposition writes cfg to path in compose.yaml format.
+func WriteComposition(path string, cfg *Composition) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Flattened {
+		data = append([]byte("# Generated by model:export --flatten, nested compose files of packages are not followed.\n"), data...)
+	}
+
+	return os.WriteFile(path, data, os.FileMode(composePermissions))
+}
@@ -0,0 +1,103 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestExportFlatten(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, "packages")
+	coreDir := filepath.Join(packagesDir, "core", "v1.0.0")
+	baseDirPkg := filepath.Join(packagesDir, "base", "v0.1.0")
+	for _, dir := range []string{coreDir, baseDirPkg} {
+		if err := os.MkdirAll(filepath.Join(dir, "src", "platform"), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nested := "name: core\ndependencies:\n  - name: base\n    source:\n      type: http\n      url: https://example.com/base.tar.gz\n      ref: v0.1.0\n"
+	if err := os.WriteFile(filepath.Join(coreDir, composeFile), []byte(nested), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainInit(coreDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Add(composeFile); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Composition{Name: "domain", Dependencies: []Dependency{
+		{Name: "core", Groups: []string{"platform"}, Source: Source{URL: "https://example.com/core.git", Ref: "v1.0.0", Strategies: []Strategy{
+			{Name: StrategyOverwriteLocal, AppliesTo: Layers{"platform"}},
+		}}},
+	}}
+
+	flat, err := Export(baseDir, packagesDir, cfg, true)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if !flat.Flattened {
+		t.Error("expected flattened composition")
+	}
+	if len(flat.Dependencies) != 2 || flat.Dependencies[0].Name != "base" || flat.Dependencies[1].Name != "core" {
+		t.Fatalf("expected base and core in merge order, got %+v", flat.Dependencies)
+	}
+
+	core := flat.Dependencies[1]
+	if core.Source.Ref != commit.String() {
+		t.Errorf("expected core pinned to %s, got %s", commit, core.Source.Ref)
+	}
+	if !slices.Equal(core.Requires, []string{"base"}) {
+		t.Errorf("expected core to require base, got %v", core.Requires)
+	}
+	if len(core.Groups) != 0 {
+		t.Errorf("expected groups to be dropped, got %v", core.Groups)
+	}
+	if len(core.Source.Strategies) != 1 || !slices.Equal(core.Source.Strategies[0].Paths, []string{"src/platform"}) || len(core.Source.Strategies[0].AppliesTo) != 0 {
+		t.Errorf("expected expanded strategy paths, got %+v", core.Source.Strategies)
+	}
+	if ref := flat.Dependencies[0].Source.Ref; ref != "v0.1.0" {
+		t.Errorf("expected http package ref to be kept, got %s", ref)
+	}
+
+	// Nested compose files of a flattened composition are not followed.
+	// Reuse the existing checkout instead of downloading the pinned commit.
+	flat.Dependencies[1].Source.Ref = "v1.0.0"
+	packages, _, err := collectPackages(flat, nil, packagesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 {
+		t.Errorf("expected 2 packages, got %d", len(packages))
+	}
+	items, _ := buildDependenciesGraph(packages).TopSort(DependencyRoot)
+	items = slices.DeleteFunc(items, func(name string) bool { return name == DependencyRoot })
+	if !slices.Equal(items, []string{"base", "core"}) {
+		t.Errorf("expected merge order to be kept, got %v", items)
+	}
+
+	flat.Dependencies[1].Requires = []string{"missing"}
+	if _, _, err = collectPackages(flat, nil, packagesDir); err == nil {
+		t.Error("expected error for unknown required package")
+	}
+}
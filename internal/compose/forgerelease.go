@@ -0,0 +1,152 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/internal/release"
+)
+
+var errInvalidReleaseSource = errors.New("invalid release source")
+
+// releaseDownloader downloads packages published as assets of forge releases, e.g. .pm bundles of model:release.
+type releaseDownloader struct {
+	k           *keyringWrapper
+	stats       downloadStats
+	linksPolicy string
+}
+
+func newRelease(kw *keyringWrapper, linksPolicy string) Downloader {
+	return &releaseDownloader{k: kw, linksPolicy: linksPolicy}
+}
+
+// Stats implements statsReporter interface
+func (r *releaseDownloader) Stats() downloadStats {
+	return r.stats
+}
+
+// ResetStats implements statsReporter interface
+func (r *releaseDownloader) ResetStats() {
+	r.stats = downloadStats{}
+}
+
+// EnsureLatest implements Downloader.EnsureLatest interface, assets of a release tag are never updated.
+func (r *releaseDownloader) EnsureLatest(_ *Package, downloadPath string) (bool, error) {
+	if _, err := os.Stat(downloadPath); !os.IsNotExist(err) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Download implements Downloader.Download interface. Asset of the release tagged with the package ref
+// is downloaded and unpacked into targetDir, a single root directory of the archive is unwrapped.
+func (r *releaseDownloader) Download(_ context.Context, pkg *Package, targetDir string) error {
+	tag, pattern := pkg.GetRef(), pkg.Source.Asset
+	if tag == "" || pattern == "" {
+		return fmt.Errorf("%w: %s needs a ref of the release tag and an asset", errInvalidReleaseSource, pkg.GetName())
+	}
+
+	forge, err := r.forge(pkg.GetURL())
+	if err != nil {
+		return err
+	}
+
+	parentDir := filepath.Dir(targetDir)
+	fpath, size, err := forge.DownloadAsset(tag, pattern, parentDir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fpath)
+	r.stats.bytes = size
+
+	if err = r.unpack(fpath, targetDir); err != nil {
+		return err
+	}
+
+	if err = ensureSubpath(pkg, targetDir); err != nil {
+		return err
+	}
+
+	r.k.Term().Printfln("  %s %s", output.Get().Check, pkg.GetIdentifier())
+	return nil
+}
+
+// forge returns the client of the forge hosting the repository of rawURL. The token is resolved like
+// by model:release, from auth configuration or environment variables, then from keyring.
+// Without a token only public releases are downloaded.
+func (r *releaseDownloader) forge(rawURL string) (*release.Forge, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q, expected https://host/owner/repo", errInvalidReleaseSource, rawURL)
+	}
+	repo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+
+	forgeType, err := release.NewForge(u.Host, repo, "").DetectType()
+	if err != nil {
+		return nil, err
+	}
+
+	rule := r.k.auth.Match(rawURL)
+	token := release.ResolveToken("", forgeType, rule)
+	r.stats.auth = authenticationModeNone
+	if rule != nil {
+		r.stats.auth = authenticationModeConfigured
+	}
+	if token == "" {
+		if ci, errGet := r.k.getForBaseURL(rawURL); errGet == nil && ci.Password != "" {
+			token = ci.Password
+			// Bitbucket app passwords are only accepted along with the username
+			if forgeType == release.ForgeBitbucket && ci.Username != "" {
+				token = ci.Username + ":" + ci.Password
+			}
+			r.stats.auth = authenticationModeKeyring
+		}
+	}
+
+	forge := release.NewForge(u.Host, repo, token)
+	if err = forge.SetType(forgeType); err != nil {
+		return nil, err
+	}
+
+	return forge, nil
+}
+
+// unpack extracts the archive into targetDir, .zip archives are unzipped, others (.pm, .tar.gz) untarred.
+func (r *releaseDownloader) unpack(fpath, targetDir string) error {
+	extractDir := targetDir + ".unpack"
+	if err := os.RemoveAll(extractDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(extractDir, dirPermissions); err != nil {
+		return err
+	}
+	defer os.RemoveAll(extractDir)
+
+	var err error
+	if strings.HasSuffix(fpath, ".zip") {
+		_, err = unzip(fpath, extractDir, r.linksPolicy)
+	} else {
+		_, err = untar(fpath, extractDir, r.linksPolicy)
+	}
+	if err != nil {
+		return err
+	}
+
+	root := extractDir
+	entries, err := os.ReadDir(extractDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(extractDir, entries[0].Name())
+	}
+
+	return os.Rename(root, targetDir)
+}
@@ -0,0 +1,42 @@
+package compose
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReleaseUnpack(t *testing.T) {
+	r := &releaseDownloader{linksPolicy: ArchiveLinksSkip}
+
+	bundle := writeTarGz(t, []tarEntry{
+		{name: "manifest.yaml", typeflag: tar.TypeReg, content: "name: model\n"},
+		{name: "src/platform/services/nginx/tasks/main.yaml", typeflag: tar.TypeReg, content: "- debug:\n"},
+	})
+	targetDir := filepath.Join(t.TempDir(), "model", "v1.0.0")
+	if err := r.unpack(bundle, targetDir); err != nil {
+		t.Fatalf("failed to unpack bundle: %v", err)
+	}
+	for _, name := range []string{"manifest.yaml", "src/platform/services/nginx/tasks/main.yaml"} {
+		if _, err := os.Stat(filepath.Join(targetDir, name)); err != nil {
+			t.Errorf("expected %s in package: %v", name, err)
+		}
+	}
+
+	// Single root directory of an archive is unwrapped.
+	archive := writeTarGz(t, []tarEntry{
+		{name: "repo-1.0.0/", typeflag: tar.TypeDir},
+		{name: "repo-1.0.0/src/platform/services/nginx/tasks/main.yaml", typeflag: tar.TypeReg, content: "- debug:\n"},
+	})
+	targetDir = filepath.Join(t.TempDir(), "repo", "v1.0.0")
+	if err := r.unpack(archive, targetDir); err != nil {
+		t.Fatalf("failed to unpack archive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "src/platform/services/nginx/tasks/main.yaml")); err != nil {
+		t.Errorf("expected root directory to be unwrapped: %v", err)
+	}
+	if _, err := os.Stat(targetDir + ".unpack"); !os.IsNotExist(err) {
+		t.Errorf("expected extraction directory to be removed, got %v", err)
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"dario.cat/mergo"
 	"github.com/charmbracelet/huh"
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr/pkg/action"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/auth"
 )
 
 // RawStrategies represents collection of submitted flags for strategies.
@@ -22,6 +27,11 @@ type RawStrategies struct {
 type FormsAction struct {
 	action.WithLogger
 	action.WithTerm
+
+	// Keyring is used to authenticate when listing remote refs, optional.
+	Keyring keyring.Keyring
+	// SkipConfirm writes compose.yaml changes without asking for confirmation.
+	SkipConfirm bool
 }
 
 // AddPackage adds a new package to compose.yaml.
@@ -61,17 +71,27 @@ func (f *FormsAction) AddPackage(doCreate bool, newDependency *Dependency, rawSt
 			return err
 		}
 
+		err = f.processRefForm(newDependency, dir)
+		if err != nil {
+			return err
+		}
+
 		err = f.processStrategiesForm(newDependency)
 		if err != nil {
 			return err
 		}
 	} else {
+		newDependency.Source.URL, err = normalizeSourceURL(newDependency.Source.Type, newDependency.Source.URL)
+		if err != nil {
+			return err
+		}
+
 		for _, originalDep := range config.Dependencies {
 			if originalDep.Name == newDependency.Name {
 				return fmt.Errorf("package with the same name %s already exists", newDependency.Name)
 			}
 
-			if originalDep.Source.URL == newDependency.Source.URL {
+			if sameSource(originalDep, *newDependency) {
 				return fmt.Errorf("package with the same URL as %s already exists", newDependency.Name)
 			}
 		}
@@ -93,6 +113,13 @@ func (f *FormsAction) UpdatePackage(dependency *Dependency, rawStrategies *RawSt
 		return err
 	}
 
+	if dependency.Source.URL != "" {
+		dependency.Source.URL, err = normalizeSourceURL(dependency.Source.Type, dependency.Source.URL)
+		if err != nil {
+			return err
+		}
+	}
+
 	var toUpdate *Dependency
 	for i := range config.Dependencies {
 		if config.Dependencies[i].Name == dependency.Name {
@@ -100,7 +127,7 @@ func (f *FormsAction) UpdatePackage(dependency *Dependency, rawStrategies *RawSt
 			continue
 		}
 
-		if config.Dependencies[i].Source.URL == dependency.Source.URL {
+		if dependency.Source.URL != "" && sameSource(config.Dependencies[i], *dependency) {
 			return errors.New("URL you trying to set is present in other package")
 		}
 
@@ -110,6 +137,10 @@ func (f *FormsAction) UpdatePackage(dependency *Dependency, rawStrategies *RawSt
 		return errors.New("no package to update")
 	}
 
+	if toUpdate.Frozen {
+		return fmt.Errorf("%w: %s, run model:unfreeze first", errPackageFrozen, toUpdate.Name)
+	}
+
 	strategies := convertRawStrategies(rawStrategies)
 	if len(strategies) > 0 {
 		dependency.Source.Strategies = strategies
@@ -120,11 +151,9 @@ func (f *FormsAction) UpdatePackage(dependency *Dependency, rawStrategies *RawSt
 	}
 
 	sanitizeDependency(toUpdate)
-	f.Term().Printfln("Saving compose.yaml...")
-	sortPackages(config)
-	err = writeComposeYaml(config)
 
-	return err
+	// Changes given by flags are written without confirmation, scripts don't block on a prompt.
+	return f.writeComposeChanges(config, dir, false)
 }
 
 // UpdatePackages updates packages in compose.yaml in interactive way.
@@ -139,9 +168,17 @@ func (f *FormsAction) UpdatePackages(dir string) error {
 
 	for i := range config.Dependencies {
 		packagesMap[config.Dependencies[i].Name] = &config.Dependencies[i]
+		if config.Dependencies[i].Frozen {
+			// Frozen packages are kept as they are.
+			continue
+		}
 		options = append(options, huh.NewOption(config.Dependencies[i].Name, config.Dependencies[i].Name))
 	}
 
+	if len(options) == 0 {
+		return fmt.Errorf("%w: all packages are frozen", errPackageFrozen)
+	}
+
 	continueUpdating := true
 	for continueUpdating {
 		var selectedPackage string
@@ -168,6 +205,11 @@ func (f *FormsAction) UpdatePackages(dir string) error {
 			return err
 		}
 
+		err = f.processRefForm(selectedDep, dir)
+		if err != nil {
+			return err
+		}
+
 		err = f.processStrategiesForm(selectedDep)
 		if err != nil {
 			return err
@@ -184,17 +226,14 @@ func (f *FormsAction) UpdatePackages(dir string) error {
 		}
 	}
 
-	f.Term().Printfln("Saving compose.yaml...")
 	var newDeps []Dependency
 	for _, dep := range packagesMap {
 		newDeps = append(newDeps, *dep)
 	}
 
 	config.Dependencies = newDeps
-	sortPackages(config)
-	err = writeComposeYaml(config)
 
-	return err
+	return f.writeComposeChanges(config, dir, true)
 }
 
 // DeletePackages removes packages compose.yaml.
@@ -205,8 +244,9 @@ func (f *FormsAction) DeletePackages(packages []string, dir string) error {
 	}
 
 	// Ask user to select packages to remove.
-	if len(packages) == 0 {
-		var toDelete string
+	selected := len(packages) == 0
+	if selected {
+		var toDelete []string
 		var deleteOptions []huh.Option[string]
 		for _, dep := range config.Dependencies {
 			deleteOptions = append(deleteOptions, huh.NewOption(dep.Name, dep.Name))
@@ -214,8 +254,9 @@ func (f *FormsAction) DeletePackages(packages []string, dir string) error {
 
 		form := huh.NewForm(
 			huh.NewGroup(
-				huh.NewSelect[string]().
+				huh.NewMultiSelect[string]().
 					Title("Packages").
+					Description("Select packages to remove").
 					Options(deleteOptions...).
 					Value(&toDelete),
 			))
@@ -225,7 +266,7 @@ func (f *FormsAction) DeletePackages(packages []string, dir string) error {
 			return err
 		}
 
-		packages = append(packages, toDelete)
+		packages = append(packages, toDelete...)
 	}
 
 	var dependencies []Dependency
@@ -243,16 +284,105 @@ OUTER:
 		dependencies = append(dependencies, dep)
 	}
 
-	if saveRequired {
-		f.Term().Printfln("Updating compose.yaml...")
-		config.Dependencies = dependencies
-		sortPackages(config)
-		err = writeComposeYaml(config)
-	} else {
+	if !saveRequired {
 		f.Term().Printfln("Nothing to update, quiting")
+		return nil
 	}
 
-	return err
+	config.Dependencies = dependencies
+
+	return f.writeComposeChanges(config, dir, selected)
+}
+
+// writeComposeChanges shows compose.yaml changes as unified diff and writes them. Changes made in forms
+// are written after confirmation unless SkipConfirm is set.
+func (f *FormsAction) writeComposeChanges(config *Composition, dir string, confirm bool) error {
+	sortPackages(config)
+	newContent, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	oldContent, err := os.ReadFile(filepath.Join(dir, composeFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	diff := unifiedDiff(composeFile, oldContent, newContent)
+	if diff == "" {
+		f.Term().Printfln("Nothing to update, quiting")
+		return nil
+	}
+
+	f.Term().Println(strings.TrimSuffix(diff, "\n"))
+
+	if confirm && !f.SkipConfirm {
+		apply := false
+		err = huh.NewConfirm().
+			Title("Apply changes to compose.yaml?").
+			Value(&apply).
+			Run()
+		if err != nil {
+			return err
+		}
+
+		if !apply {
+			f.Term().Printfln("Changes discarded")
+			return nil
+		}
+	}
+
+	f.Term().Printfln("Saving compose.yaml...")
+
+	return writeComposeYaml(config)
+}
+
+// processRefForm asks for a package ref. Branches and tags of the remote are offered as options,
+// free text input is used if the remote refs can't be listed.
+func (f *FormsAction) processRefForm(dependency *Dependency, dir string) error {
+	if dependency.Source.Type != GitType {
+		return nil
+	}
+
+	kw := &keyringWrapper{keyringService: f.Keyring}
+	if authConfig, err := auth.Load(dir); err == nil {
+		kw.auth = authConfig
+	} else {
+		f.Log().Debug("failed to load auth configuration", "error", err)
+	}
+	kw.SetLogger(f.Log())
+	kw.SetTerm(f.Term())
+
+	refs, err := listRemoteRefs(kw, dependency.Source.URL)
+	if err != nil || len(refs) == 0 {
+		if err != nil {
+			f.Log().Debug("failed to list remote refs", "url", dependency.Source.URL, "err", err)
+		}
+		f.Term().Warning().Printfln("Couldn't list refs of %s, please enter ref manually", dependency.Source.URL)
+
+		return huh.NewInput().
+			Title("- Enter Ref").
+			Value(&dependency.Source.Ref).
+			Run()
+	}
+
+	options := []huh.Option[string]{
+		huh.NewOption("(default branch)", "").Selected(dependency.Source.Ref == ""),
+	}
+	for _, ref := range refs {
+		kind := "branch"
+		if ref.IsTag {
+			kind = "tag"
+		}
+
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%s)", ref.Name, kind), ref.Name).Selected(ref.Name == dependency.Source.Ref))
+	}
+
+	return huh.NewSelect[string]().
+		Title("- Select Ref").
+		Options(options...).
+		Value(&dependency.Source.Ref).
+		Run()
 }
 
 func (f *FormsAction) processStrategiesForm(dependency *Dependency) error {
@@ -282,6 +412,8 @@ func (f *FormsAction) processStrategiesForm(dependency *Dependency) error {
 							huh.NewOption("Remove Extra Local Files", StrategyRemoveExtraLocal),
 							huh.NewOption("Ignore Extra Package", StrategyIgnoreExtraPackage),
 							huh.NewOption("Filter Package Files", StrategyFilterPackage),
+							huh.NewOption("Prefer Newest", StrategyPreferNewest),
+							huh.NewOption("Merge YAML", StrategyMergeYAML),
 						).
 						Value(&selectedStrategy),
 
@@ -366,9 +498,13 @@ func preparePackageForm(dependency *Dependency, config *Composition, isAdd bool)
 						return errors.New("URL can't be empty")
 					}
 
+					if _, err := normalizeSourceURL(dependency.Source.Type, str); err != nil {
+						return err
+					}
+
 					unique := 0
 					for _, originalDep := range config.Dependencies {
-						if originalDep.Source.URL == str {
+						if sameSourceURL(dependency.Source.Type, originalDep.Source.URL, str) {
 							unique++
 						}
 					}
@@ -380,12 +516,6 @@ func preparePackageForm(dependency *Dependency, config *Composition, isAdd bool)
 					return nil
 				}),
 		),
-
-		huh.NewGroup(
-			huh.NewInput().
-				Title("- Enter Ref").
-				Value(&dependency.Source.Ref),
-		).WithHideFunc(func() bool { return dependency.Source.Type != GitType }),
 	)
 }
 
@@ -420,4 +550,9 @@ func sanitizeDependency(dependency *Dependency) {
 	dependency.Name = strings.TrimSpace(dependency.Name)
 	dependency.Source.URL = strings.TrimSpace(dependency.Source.URL)
 	dependency.Source.Ref = strings.TrimSpace(dependency.Source.Ref)
+	dependency.Source.Subpath = dependency.ToPackage(dependency.Name).GetSubpath()
+
+	if u, err := normalizeSourceURL(dependency.Source.Type, dependency.Source.URL); err == nil {
+		dependency.Source.URL = u
+	}
 }
@@ -0,0 +1,37 @@
+package compose
+
+import (
+	"os"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestFlagChangesWithoutConfirm(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	content := "name: test\ndependencies:\n  - name: a\n    source:\n      type: git\n      url: https://example.com/a.git\n      ref: main\n  - name: b\n    source:\n      type: git\n      url: https://example.com/b.git\n      ref: main\n"
+	if err := os.WriteFile(composeFile, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write compose.yaml: %v", err)
+	}
+
+	// Without --yes changes given by flags are written right away, only forms ask for confirmation.
+	fa := &FormsAction{}
+	fa.SetTerm(launchr.Term())
+
+	if err := fa.UpdatePackage(&Dependency{Name: "a", Source: Source{Ref: "v2.0.0"}}, &RawStrategies{}, dir); err != nil {
+		t.Fatalf("UpdatePackage failed: %v", err)
+	}
+	if err := fa.DeletePackages([]string{"b"}, dir); err != nil {
+		t.Fatalf("DeletePackages failed: %v", err)
+	}
+
+	cfg, err := Lookup(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(cfg.Dependencies) != 1 || cfg.Dependencies[0].Source.Ref != "v2.0.0" {
+		t.Errorf("expected update and removal to be written, got %+v", cfg.Dependencies)
+	}
+}
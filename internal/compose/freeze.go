@@ -0,0 +1,37 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	errPackageFrozen  = errors.New("package is frozen")
+	errUnknownPackage = errors.New("package is not in compose.yaml")
+)
+
+// FreezePackage sets frozen flag of the package in compose.yaml. Frozen packages keep their ref,
+// model:update refuses to change them and compose doesn't pull changes of their branch.
+func (f *FormsAction) FreezePackage(name string, frozen bool, dir string) error {
+	config, err := Lookup(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	var dep *Dependency
+	for i := range config.Dependencies {
+		if config.Dependencies[i].Name == name {
+			dep = &config.Dependencies[i]
+			break
+		}
+	}
+
+	if dep == nil {
+		return fmt.Errorf("%w: %s", errUnknownPackage, name)
+	}
+
+	dep.Frozen = frozen
+
+	return f.writeComposeChanges(config, dir, false)
+}
@@ -0,0 +1,82 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestFreezePackage(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	content := "name: test\ndependencies:\n  - name: a\n    source:\n      type: git\n      url: https://example.com/a.git\n      ref: main\n"
+	if err := os.WriteFile(composeFile, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write compose.yaml: %v", err)
+	}
+
+	fa := &FormsAction{SkipConfirm: true}
+	fa.SetTerm(launchr.Term())
+
+	if err := fa.FreezePackage("a", true, dir); err != nil {
+		t.Fatalf("FreezePackage failed: %v", err)
+	}
+	if err := fa.FreezePackage("missing", true, dir); !errors.Is(err, errUnknownPackage) {
+		t.Errorf("expected unknown package error, got %v", err)
+	}
+
+	dep := &Dependency{Name: "a", Source: Source{Ref: "v2.0.0"}}
+	if err := fa.UpdatePackage(dep, &RawStrategies{}, dir); !errors.Is(err, errPackageFrozen) {
+		t.Fatalf("expected frozen package error, got %v", err)
+	}
+
+	if err := fa.FreezePackage("a", false, dir); err != nil {
+		t.Fatalf("FreezePackage failed: %v", err)
+	}
+	if err := fa.UpdatePackage(dep, &RawStrategies{}, dir); err != nil {
+		t.Fatalf("UpdatePackage failed: %v", err)
+	}
+
+	cfg, err := Lookup(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if cfg.Dependencies[0].Frozen || cfg.Dependencies[0].Source.Ref != "v2.0.0" {
+		t.Errorf("unexpected dependency %+v", cfg.Dependencies[0])
+	}
+}
+
+func TestDownloadFrozenPackage(t *testing.T) {
+	targetDir := t.TempDir()
+	// Not a git checkout, a source check would replace it.
+	downloadPath := filepath.Join(targetDir, "a", "main")
+	if err := os.MkdirAll(downloadPath, 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(downloadPath, "README.md"), []byte("frozen"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "a", Frozen: true, Source: Source{Type: GitType, URL: "https://127.0.0.1:1/a.git", Ref: "main"}},
+		},
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	dm := CreateDownloadManager(kw, nil)
+	if _, err := dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir); err != nil {
+		t.Fatalf("recursiveDownload failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(downloadPath, "README.md"))
+	if err != nil || string(data) != "frozen" {
+		t.Errorf("expected frozen checkout to be kept, got %q, %v", data, err)
+	}
+}
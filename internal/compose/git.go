@@ -4,32 +4,67 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/launchrctl/keyring"
+
+	"github.com/plasmash/plasmactl-model/internal/output"
 )
 
+var rgxCommitRef = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
 type gitDownloader struct {
-	k *keyringWrapper
+	k     *keyringWrapper
+	stats downloadStats
+	// checkOnly reports failed checks of EnsureLatest as errors and doesn't announce pulls, used by model:outdated.
+	checkOnly bool
 }
 
 func newGit(kw *keyringWrapper) Downloader {
 	return &gitDownloader{k: kw}
 }
 
+// Stats implements statsReporter interface
+func (g *gitDownloader) Stats() downloadStats {
+	return g.stats
+}
+
+// ResetStats implements statsReporter interface
+func (g *gitDownloader) ResetStats() {
+	g.stats = downloadStats{}
+}
+
+// repositoryDir returns the git directory of a repository stored on disk.
+func repositoryDir(r *git.Repository) string {
+	if s, ok := r.Storer.(*filesystem.Storage); ok {
+		return s.Filesystem().Root()
+	}
+
+	return ""
+}
+
 func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []config.RefSpec) error {
 	remotes, errR := r.Remotes()
 	if errR != nil {
 		return errR
 	}
 
+	// Fetches of cached checkouts count too, as growth of the repository on disk.
+	if dir := repositoryDir(r); dir != "" {
+		before := DirSize(dir)
+		defer func() { g.stats.diskBytes += max(DirSize(dir)-before, 0) }()
+	}
+
 	for _, rem := range remotes {
 		options := git.FetchOptions{
 			//RefSpecs: []config.RefSpec{"refs/*:refs/*", "HEAD:refs/heads/HEAD"},
@@ -37,10 +72,43 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 			Force:    true,
 		}
 
+		if rule := g.k.auth.Match(url); rule != nil {
+			auth, err := g.k.gitAuth(rule, url)
+			if err != nil {
+				return err
+			}
+
+			g.stats.auth = authenticationModeConfigured
+			options.Auth = auth
+			err = g.fetch(rem, &options, url)
+			if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+				return err
+			}
+
+			continue
+		}
+
+		if sess, ok := g.k.session(url); ok {
+			g.stats.auth = sess.mode
+			options.Auth = sess.gitAuth()
+			err := g.fetch(rem, &options, url)
+			if !isGitAuthError(err) {
+				if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+					return err
+				}
+
+				continue
+			}
+
+			g.k.dropSession(url)
+			options.Auth = nil
+		}
+
 		auths := []authenticationMode{authenticationModeNone, authenticationModeKeyringGlobal, authenticationModeKeyring, authenticationModeManual}
 		for _, authMode := range auths {
+			g.stats.auth = authMode
 			if authMode == authenticationModeNone {
-				err := rem.Fetch(&options)
+				err := g.fetch(rem, &options, url)
 				if err != nil {
 					if errors.Is(err, transport.ErrAuthenticationRequired) {
 						continue
@@ -69,9 +137,10 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 					Password: ci.Password,
 				}
 
-				err = rem.Fetch(&options)
+				err = g.fetch(rem, &options, url)
 				if err != nil {
 					if errors.Is(err, git.NoErrAlreadyUpToDate) {
+						g.k.saveSession(url, authMode, ci)
 						return nil
 					}
 
@@ -81,6 +150,7 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 
 					continue
 				}
+				g.k.saveSession(url, authMode, ci)
 			}
 
 			if authMode == authenticationModeKeyring {
@@ -94,7 +164,7 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 					Password: ci.Password,
 				}
 
-				err = rem.Fetch(&options)
+				err = g.fetch(rem, &options, url)
 				if err != nil {
 					if errors.Is(err, transport.ErrAuthorizationFailed) || errors.Is(err, transport.ErrAuthenticationRequired) {
 						if g.k.interactive {
@@ -105,9 +175,8 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 					if !errors.Is(err, git.NoErrAlreadyUpToDate) {
 						return err
 					}
-
-					return nil
 				}
+				g.k.saveSession(url, authMode, ci)
 			}
 
 			if authMode == authenticationModeManual {
@@ -123,14 +192,11 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 					Password: ci.Password,
 				}
 
-				err = rem.Fetch(&options)
-				if err != nil {
-					if !errors.Is(err, git.NoErrAlreadyUpToDate) {
-						return err
-					}
-
-					return nil
+				err = g.fetch(rem, &options, url)
+				if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+					return err
 				}
+				g.k.saveSession(url, authMode, ci)
 			}
 
 			break
@@ -167,6 +233,11 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 		return false, fmt.Errorf("can't get HEAD of '%s', ensure package is valid", pkg.GetName())
 	}
 
+	// Commits never change.
+	if commit := checkoutCommit(pkg); commit != "" {
+		return head.Hash().String() == commit, nil
+	}
+
 	headName := head.Name().Short()
 	pkgRefName := pkg.GetRef()
 	remoteRefName := pkgRefName
@@ -181,6 +252,9 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 	if headName == pkgRefName {
 		pullTarget = "branch"
 		isLatest, err = g.ensureLatestBranch(r, pkg.GetURL(), pkgRefName, remoteRefName)
+		if err != nil && g.checkOnly {
+			return false, err
+		}
 		if err != nil {
 			g.k.Term().Warning().Printfln("Couldn't check local branch, marking package %s(%s) as outdated, see debug for detailed error.", pkg.GetName(), pkgRefName)
 			g.k.Log().Debug("ensure branch error", "err", err)
@@ -189,6 +263,9 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 	} else {
 		pullTarget = "tag"
 		isLatest, err = g.ensureLatestTag(r, pkg.GetURL(), pkgRefName)
+		if err != nil && g.checkOnly {
+			return false, err
+		}
 		if err != nil {
 			g.k.Term().Warning().Printfln("Couldn't check local tag, marking package %s(%s) as outdated, see debug for detailed error.", pkg.GetName(), pkgRefName)
 			g.k.Log().Debug("ensure tag error", "err", err)
@@ -196,7 +273,7 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 		}
 	}
 
-	if !isLatest {
+	if !isLatest && !g.checkOnly {
 		g.k.Term().Info().Printfln("Pulling new changes from %s '%s' of %s package", pullTarget, pkgRefName, pkg.GetName())
 	}
 
@@ -276,15 +353,25 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 	}
 
 	ref := pkg.GetRef()
+	subpath := pkg.GetSubpath()
 	if ref == "" {
 		// Try to clone latest master branch.
-		err := g.tryDownload(ctx, targetDir, g.buildOptions(url))
+		options := g.buildOptions(url)
+		options.NoCheckout = subpath != ""
+		err := g.tryDownload(ctx, targetDir, options)
 		if err != nil {
 			return err
 		}
 
-		g.k.Term().Printfln("  ✓ %s", pkg.GetIdentifier())
-		return nil
+		return g.finishDownload(pkg, targetDir)
+	}
+
+	if commit := checkoutCommit(pkg); commit != "" {
+		if err := g.downloadCommit(ctx, pkg, url, commit, targetDir); err != nil {
+			return err
+		}
+
+		return g.finishDownload(pkg, targetDir)
 	}
 
 	loaded := false
@@ -294,6 +381,7 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 	for _, r := range refs {
 		options := g.buildOptions(url)
 		options.ReferenceName = r
+		options.NoCheckout = subpath != ""
 
 		err := g.tryDownload(ctx, targetDir, options)
 		if err != nil {
@@ -310,27 +398,169 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 	}
 
 	if !loaded {
-		return fmt.Errorf("couldn't find remote ref %s", ref)
+		return &ErrRefNotFound{Package: pkg.GetName(), Ref: ref}
+	}
+
+	return g.finishDownload(pkg, targetDir)
+}
+
+// finishDownload checks out the subpath of monorepo packages cloned without checkout and reports the download.
+func (g *gitDownloader) finishDownload(pkg *Package, targetDir string) error {
+	if subpath := pkg.GetSubpath(); subpath != "" && checkoutCommit(pkg) == "" {
+		if err := sparseCheckout(targetDir, []string{subpath}); err != nil {
+			return err
+		}
+	}
+	if err := ensureSubpath(pkg, targetDir); err != nil {
+		return err
 	}
 
-	g.k.Term().Printfln("  ✓ %s", pkg.GetIdentifier())
+	g.stats.diskBytes = DirSize(filepath.Join(targetDir, ".git"))
+	g.k.Term().Printfln("  %s %s", output.Get().Check, pkg.GetIdentifier())
 	return nil
 }
 
+// downloadCommit clones all branches of url and checks out commit ref in detached HEAD,
+// only the subpath of monorepo packages is checked out.
+func (g *gitDownloader) downloadCommit(ctx context.Context, pkg *Package, url, ref, targetDir string) error {
+	options := g.buildOptions(url)
+	options.SingleBranch = false
+	options.NoCheckout = true
+	if err := g.tryDownload(ctx, targetDir, options); err != nil {
+		return err
+	}
+
+	r, err := git.PlainOpenWithOptions(targetDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return err
+	}
+
+	hash := plumbing.NewHash(ref)
+	if _, err = r.CommitObject(hash); err != nil {
+		return &ErrRefNotFound{Package: pkg.GetName(), Ref: ref, Err: err}
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	opts := &git.CheckoutOptions{Hash: hash, Force: true}
+	if subpath := pkg.GetSubpath(); subpath != "" {
+		opts.SparseCheckoutDirectories = []string{subpath}
+	}
+
+	return w.Checkout(opts)
+}
+
+// sparseCheckout checks out only subpaths of the clone at its HEAD, the whole repository without subpaths.
+// A branch stays checked out.
+func sparseCheckout(dir string, subpaths []string) error {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// Skipped entries of a previous sparse checkout aren't restored by a wider one.
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		e.SkipWorktree = false
+	}
+	if err = r.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	opts := &git.CheckoutOptions{Force: true, SparseCheckoutDirectories: subpaths}
+	if head.Name().IsBranch() {
+		opts.Branch = head.Name()
+	} else {
+		opts.Hash = head.Hash()
+	}
+
+	return w.Checkout(opts)
+}
+
+// isGitAuthError reports if err is caused by missing or rejected credentials.
+func isGitAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
+
+// isAuthError reports if err of git or http downloads is caused by missing or rejected credentials.
+func isAuthError(err error) bool {
+	return isGitAuthError(err) || errors.Is(err, keyring.ErrNotFound) ||
+		errors.Is(err, errAuthenticationRequired) || errors.Is(err, errAuthorizationFailed)
+}
+
+// isCommitRef reports if ref is a full commit SHA.
+func isCommitRef(ref string) bool {
+	return rgxCommitRef.MatchString(ref)
+}
+
+// checkoutCommit returns the commit to check out for pkg: the commit pinned by compose.lock or the commit ref,
+// empty string for branches and tags.
+func checkoutCommit(pkg *Package) string {
+	if pkg.Commit != "" {
+		return pkg.Commit
+	}
+	if isCommitRef(pkg.GetRef()) {
+		return pkg.GetRef()
+	}
+
+	return ""
+}
+
 func (g *gitDownloader) buildOptions(url string) *git.CloneOptions {
 	return &git.CloneOptions{
 		URL:          url,
-		Progress:     io.Discard,
+		Progress:     g.progress("clone", url),
 		SingleBranch: true,
 	}
 }
 
 func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, options *git.CloneOptions) error {
 	url := options.URL
+	if rule := g.k.auth.Match(url); rule != nil {
+		auth, err := g.k.gitAuth(rule, url)
+		if err != nil {
+			return err
+		}
+
+		g.stats.auth = authenticationModeConfigured
+		options.Auth = auth
+		err = g.clone(ctx, targetDir, options)
+		return err
+	}
+
+	if s, ok := g.k.session(url); ok {
+		g.stats.auth = s.mode
+		options.Auth = s.gitAuth()
+		err := g.clone(ctx, targetDir, options)
+		if !isGitAuthError(err) {
+			return err
+		}
+
+		g.k.dropSession(url)
+		options.Auth = nil
+	}
+
 	auths := []authenticationMode{authenticationModeNone, authenticationModeKeyringGlobal, authenticationModeKeyring, authenticationModeManual}
 	for _, authMode := range auths {
+		g.stats.auth = authMode
 		if authMode == authenticationModeNone {
-			_, err := git.PlainCloneContext(ctx, targetDir, false, options)
+			err := g.clone(ctx, targetDir, options)
 			if err != nil {
 				if errors.Is(err, transport.ErrAuthenticationRequired) {
 					continue
@@ -355,7 +585,7 @@ func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, optio
 				Password: ci.Password,
 			}
 
-			_, err = git.PlainCloneContext(ctx, targetDir, false, options)
+			err = g.clone(ctx, targetDir, options)
 			if err != nil {
 				if !errors.Is(err, transport.ErrAuthorizationFailed) || !errors.Is(err, transport.ErrAuthenticationRequired) {
 					return err
@@ -363,6 +593,7 @@ func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, optio
 
 				continue
 			}
+			g.k.saveSession(url, authMode, ci)
 		}
 
 		if authMode == authenticationModeKeyring {
@@ -376,7 +607,7 @@ func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, optio
 				Password: ci.Password,
 			}
 
-			_, err = git.PlainCloneContext(ctx, targetDir, false, options)
+			err = g.clone(ctx, targetDir, options)
 			if err != nil {
 				if errors.Is(err, transport.ErrAuthorizationFailed) || errors.Is(err, transport.ErrAuthenticationRequired) {
 					if g.k.interactive {
@@ -386,6 +617,7 @@ func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, optio
 
 				return err
 			}
+			g.k.saveSession(url, authMode, ci)
 		}
 
 		if authMode == authenticationModeManual {
@@ -401,10 +633,11 @@ func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, optio
 				Password: ci.Password,
 			}
 
-			_, err = git.PlainCloneContext(ctx, targetDir, false, options)
+			err = g.clone(ctx, targetDir, options)
 			if err != nil {
 				return err
 			}
+			g.k.saveSession(url, authMode, ci)
 		}
 
 		break
@@ -420,4 +653,275 @@ const (
 	authenticationModeKeyringGlobal
 	authenticationModeKeyring
 	authenticationModeManual
+	// authenticationModeConfigured uses method of matching auth.Rule.
+	authenticationModeConfigured
 )
+
+func (m authenticationMode) String() string {
+	switch m {
+	case authenticationModeNone:
+		return "none"
+	case authenticationModeKeyringGlobal:
+		return "keyring-global"
+	case authenticationModeKeyring:
+		return "keyring"
+	case authenticationModeManual:
+		return "manual"
+	case authenticationModeConfigured:
+		return "configured"
+	default:
+		return "unknown"
+	}
+}
+
+// remoteRef stores a branch or tag name available on remote.
+type remoteRef struct {
+	Name  string
+	IsTag bool
+}
+
+// listRemoteRefs lists branches and tags of a remote repository, like `git ls-remote --heads --tags`.
+// Credentials from keyring are used if the remote requires authentication.
+func listRemoteRefs(kw *keyringWrapper, url string) ([]remoteRef, error) {
+	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+
+	options := &git.ListOptions{}
+	if kw != nil {
+		if rule := kw.auth.Match(url); rule != nil {
+			auth, err := kw.gitAuth(rule, url)
+			if err != nil {
+				return nil, err
+			}
+			options.Auth = auth
+		}
+	}
+
+	refs, err := rem.List(options)
+	if errors.Is(err, transport.ErrAuthenticationRequired) && options.Auth == nil && kw != nil {
+		if sess, ok := kw.session(url); ok {
+			options.Auth = sess.gitAuth()
+			if refs, err = rem.List(options); isGitAuthError(err) {
+				kw.dropSession(url)
+				options.Auth = nil
+			}
+		}
+	}
+	if errors.Is(err, transport.ErrAuthenticationRequired) && options.Auth == nil && kw != nil {
+		ci, errGet := kw.getForBaseURL(url)
+		if errGet != nil {
+			ci, errGet = kw.getForURL(url)
+		}
+		if errGet != nil {
+			return nil, err
+		}
+
+		options.Auth = &http.BasicAuth{
+			Username: ci.Username,
+			Password: ci.Password,
+		}
+		if refs, err = rem.List(options); err == nil {
+			kw.saveSession(url, authenticationModeKeyring, ci)
+		}
+	}
+
+	if isGitAuthError(err) {
+		return nil, &ErrAuthFailed{Host: urlHost(url), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []remoteRef
+	for _, ref := range refs {
+		name := ref.Name()
+		switch {
+		case name.IsBranch():
+			result = append(result, remoteRef{Name: name.Short()})
+		case name.IsTag():
+			result = append(result, remoteRef{Name: name.Short(), IsTag: true})
+		}
+	}
+
+	// Branches first, then tags, both sorted by name.
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].IsTag != result[j].IsTag {
+			return !result[i].IsTag
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// DownloadFromSibling prepares targetDir from an existing clone of the same package at another ref.
+// Only objects missing locally are fetched from remote, then the ref is checked out in place.
+// It returns false if there is no clone to reuse.
+func (g *gitDownloader) DownloadFromSibling(ctx context.Context, pkg *Package, packagePath, targetDir string) (bool, error) {
+	ref := pkg.GetRef()
+	if ref == "" || checkoutCommit(pkg) != "" || pkg.GetSubpath() != "" {
+		return false, nil
+	}
+
+	sibling := findSiblingClone(pkg, packagePath, targetDir)
+	if sibling == "" {
+		return false, nil
+	}
+
+	g.k.Log().Debug("reusing existing clone", "package", pkg.GetName(), "ref", ref, "from", sibling)
+	if err := copyDir(filepath.Join(sibling, ".git"), filepath.Join(targetDir, ".git")); err != nil {
+		return true, err
+	}
+
+	r, err := git.PlainOpenWithOptions(targetDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return true, err
+	}
+
+	// As we don't know if ref exists, try both: tag and branch references.
+	refs := []plumbing.ReferenceName{plumbing.NewTagReferenceName(ref), plumbing.NewBranchReferenceName(ref)}
+	for _, name := range refs {
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+
+		local := name
+		if name.IsBranch() {
+			local = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref)
+		}
+
+		refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", name, local))
+		if err = setOriginFetch(r, pkg.GetURL(), refSpec); err != nil {
+			return true, err
+		}
+
+		err = g.fetchRemotes(r, pkg.GetURL(), []config.RefSpec{refSpec})
+		if err != nil {
+			if errors.Is(err, git.NoMatchingRefSpecError{}) {
+				continue
+			}
+
+			return true, err
+		}
+
+		if err = checkoutRef(r, name, local); err != nil {
+			return true, err
+		}
+
+		g.k.Term().Printfln("  %s %s (fetched into existing clone)", output.Get().Check, pkg.GetIdentifier())
+		return true, nil
+	}
+
+	return true, &ErrRefNotFound{Package: pkg.GetName(), Ref: ref}
+}
+
+// findSiblingClone returns a git clone of the package at another ref, empty string if there is none.
+func findSiblingClone(pkg *Package, packagePath, targetDir string) string {
+	var sibling string
+	_ = filepath.WalkDir(packagePath, func(path string, d os.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return filepath.SkipDir
+		case sibling != "":
+			return filepath.SkipAll
+		case !d.IsDir():
+			return nil
+		case path == targetDir:
+			return filepath.SkipDir
+		}
+
+		stat, errStat := os.Stat(filepath.Join(path, ".git"))
+		if errStat != nil || !stat.IsDir() {
+			return nil
+		}
+
+		r, errOpen := git.PlainOpen(path)
+		if errOpen == nil {
+			if rem, errRem := r.Remote(git.DefaultRemoteName); errRem == nil && len(rem.Config().URLs) > 0 {
+				if sameSourceURL(GitType, rem.Config().URLs[0], pkg.GetURL()) {
+					sibling = path
+				}
+			}
+		}
+
+		// Refs may contain slashes, but clones are never nested.
+		return filepath.SkipDir
+	})
+
+	return sibling
+}
+
+func setOriginFetch(r *git.Repository, url string, refSpec config.RefSpec) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+
+	origin, ok := cfg.Remotes[git.DefaultRemoteName]
+	if !ok {
+		return fmt.Errorf("remote %s not found", git.DefaultRemoteName)
+	}
+
+	origin.URLs = []string{url}
+	origin.Fetch = []config.RefSpec{refSpec}
+
+	return r.SetConfig(cfg)
+}
+
+// checkoutRef checks out fetched ref the same way clone does: detached HEAD for tags
+// and a local branch tracking origin for branches.
+func checkoutRef(r *git.Repository, name, fetched plumbing.ReferenceName) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(fetched.String()))
+	if err != nil {
+		return err
+	}
+
+	if name.IsTag() {
+		return w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true})
+	}
+
+	branch := name.Short()
+	_ = r.DeleteBranch(branch)
+	err = r.CreateBranch(&config.Branch{Name: branch, Remote: git.DefaultRemoteName, Merge: name})
+	if err != nil {
+		return err
+	}
+
+	err = r.Storer.SetReference(plumbing.NewHashReference(name, *hash))
+	if err != nil {
+		return err
+	}
+
+	return w.Checkout(&git.CheckoutOptions{Branch: name, Force: true})
+}
+
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, dirPermissions)
+		case d.Type()&os.ModeSymlink != 0:
+			return lcopy(path, target)
+		default:
+			_, err = fcopy(path, target)
+			return err
+		}
+	})
+}
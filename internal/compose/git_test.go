@@ -1,6 +1,8 @@
 package compose
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -8,7 +10,9 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/launchrctl/launchr"
 )
 
 func testGitCommand(t *testing.T, dir string, args ...string) *exec.Cmd {
@@ -96,3 +100,218 @@ func TestPlainOpenWorktree(t *testing.T) {
 		t.Errorf("expected [%s], got %v", testFile, files)
 	}
 }
+
+func TestListRemoteRefs(t *testing.T) {
+	repoDir := t.TempDir()
+
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("file.txt"); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	commitHash, err := wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "test",
+			Email: "test@test.com",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if _, err = repo.CreateTag("v1.0.0", commitHash, nil); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+
+	refs, err := listRemoteRefs(nil, repoDir)
+	if err != nil {
+		t.Fatalf("listRemoteRefs failed: %v", err)
+	}
+
+	expected := []remoteRef{{Name: "master"}, {Name: "v1.0.0", IsTag: true}}
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, refs)
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], refs[i])
+		}
+	}
+}
+
+func TestDownloadFromSibling(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	commit := func(content, tag string) plumbing.Hash {
+		if err := os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add("file.txt"); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		hash, err := wt.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		if _, err = repo.CreateTag(tag, hash, nil); err != nil {
+			t.Fatalf("failed to create tag: %v", err)
+		}
+		return hash
+	}
+	commit("v1", "v1.0.0")
+	v2 := commit("v2", "v2.0.0")
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), v2)); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetTerm(launchr.Term())
+	g := &gitDownloader{k: kw}
+
+	packagePath := filepath.Join(t.TempDir(), "pkg")
+	pkg := &Package{Name: "pkg", Source: Source{Type: GitType, URL: repoDir, Ref: "v1.0.0"}}
+	if err = g.Download(context.Background(), pkg, filepath.Join(packagePath, "v1.0.0")); err != nil {
+		t.Fatalf("failed to download package: %v", err)
+	}
+	if stats := g.Stats(); stats.diskBytes == 0 || stats.bytes != 0 {
+		t.Errorf("expected clone to be reported as size on disk, got %+v", stats)
+	}
+	g.ResetStats()
+
+	for _, ref := range []string{"v2.0.0", "develop"} {
+		pkg.Source.Ref = ref
+		targetDir := filepath.Join(packagePath, ref)
+		reused, err := g.DownloadFromSibling(context.Background(), pkg, packagePath, targetDir)
+		if err != nil || !reused {
+			t.Fatalf("%s: expected clone to be reused, got %v, %v", ref, reused, err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(targetDir, "file.txt"))
+		if err != nil || string(content) != "v2" {
+			t.Errorf("%s: expected checked out v2 content, got %q, %v", ref, content, err)
+		}
+
+		isLatest, err := g.EnsureLatest(pkg, targetDir)
+		if err != nil || !isLatest {
+			t.Errorf("%s: expected reused clone to be up to date, got %v, %v", ref, isLatest, err)
+		}
+	}
+
+	pkg.Source.URL = t.TempDir()
+	reused, err := g.DownloadFromSibling(context.Background(), pkg, packagePath, filepath.Join(packagePath, "other"))
+	if err != nil || reused {
+		t.Errorf("expected no clone of other URL to be reused, got %v, %v", reused, err)
+	}
+}
+
+func TestDownloadSubpath(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	for _, name := range []string{"packages/a/main.yaml", "packages/b/main.yaml", "README.md"} {
+		path := filepath.Join(repoDir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if err = wt.AddGlob("."); err != nil {
+		t.Fatalf("failed to add files: %v", err)
+	}
+	hash, err := wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if _, err = repo.CreateTag("v1.0.0", hash, nil); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	g := &gitDownloader{k: kw}
+
+	for _, ref := range []string{"v1.0.0", hash.String()} {
+		pkg := &Package{Name: "a", Source: Source{Type: GitType, URL: repoDir, Ref: ref, Subpath: "/packages/a/"}}
+		targetDir := filepath.Join(t.TempDir(), "a", ref)
+		if err = g.Download(context.Background(), pkg, targetDir); err != nil {
+			t.Fatalf("failed to download subpath at %s: %v", ref, err)
+		}
+		if _, err = os.Stat(filepath.Join(packageDir(targetDir, pkg), "main.yaml")); err != nil {
+			t.Errorf("expected subpath to be checked out at %s: %v", ref, err)
+		}
+		for _, name := range []string{"packages/b", "README.md"} {
+			if _, err = os.Stat(filepath.Join(targetDir, name)); !os.IsNotExist(err) {
+				t.Errorf("expected %s not to be checked out at %s, got %v", name, ref, err)
+			}
+		}
+	}
+
+	// Packages of the monorepo share a single clone, its checkout is extended by their subpaths.
+	targetDir := t.TempDir()
+	source := func(subpath string) Source {
+		return Source{Type: GitType, URL: repoDir, Ref: "v1.0.0", Subpath: subpath}
+	}
+	cfg := &Composition{Dependencies: []Dependency{
+		{Name: "a", Source: source("packages/a")},
+		{Name: "b", Source: source("packages/b")},
+	}}
+	dm := CreateDownloadManager(kw, &Summary{})
+	if _, err = dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir); err != nil {
+		t.Fatalf("failed to download monorepo packages: %v", err)
+	}
+	if dm.Aliases()["b"] != "a" {
+		t.Errorf("expected b to share the clone of a, got %v", dm.Aliases())
+	}
+	checkout := filepath.Join(targetDir, "a", "v1.0.0")
+	for name, exists := range map[string]bool{"packages/a/main.yaml": true, "packages/b/main.yaml": true, "README.md": false} {
+		if _, err = os.Stat(filepath.Join(checkout, name)); (err == nil) != exists {
+			t.Errorf("expected %s to be checked out: %t, got %v", name, exists, err)
+		}
+	}
+
+	cfg.Dependencies = append(cfg.Dependencies, Dependency{Name: "all", Source: source("")})
+	dm = CreateDownloadManager(kw, &Summary{})
+	if _, err = dm.recursiveDownload(context.Background(), cfg, nil, nil, nil, targetDir); err != nil {
+		t.Fatalf("failed to download whole repository: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(checkout, "README.md")); err != nil {
+		t.Errorf("expected whole repository to be checked out: %v", err)
+	}
+
+	missing := &Package{Name: "c", Source: Source{Type: GitType, URL: repoDir, Ref: "v1.0.0", Subpath: "packages/c"}}
+	if err = g.Download(context.Background(), missing, filepath.Join(t.TempDir(), "c")); !errors.Is(err, errInvalidSubpath) {
+		t.Errorf("expected invalid subpath error, got %v", err)
+	}
+}
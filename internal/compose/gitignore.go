@@ -0,0 +1,74 @@
+package compose
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const (
+	gitignoreFile   = ".gitignore"
+	infoExcludeFile = ".git/info/exclude"
+)
+
+// gitignoreRules are ignore patterns of the domain repo collected while its files are walked,
+// .gitignore of a directory is read when the walk enters it and applies to paths inside of it.
+type gitignoreRules struct {
+	fsys     fs.FS
+	patterns []gitignore.Pattern
+}
+
+// newGitignoreRules returns rules of .git/info/exclude, .gitignore files are added by enter.
+func newGitignoreRules(fsys fs.FS) (*gitignoreRules, error) {
+	r := &gitignoreRules{fsys: fsys}
+	if err := r.read(infoExcludeFile, nil); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// enter adds patterns of the .gitignore of the slash separated directory.
+func (r *gitignoreRules) enter(dir string) error {
+	var domain []string
+	if dir != "." {
+		domain = strings.Split(dir, "/")
+	}
+
+	return r.read(path.Join(dir, gitignoreFile), domain)
+}
+
+func (r *gitignoreRules) read(name string, domain []string) error {
+	f, err := r.fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		r.patterns = append(r.patterns, gitignore.ParsePattern(line, domain))
+	}
+
+	return scanner.Err()
+}
+
+// match reports if the slash separated path is ignored, later patterns take precedence like in git.
+func (r *gitignoreRules) match(p string, isDir bool) bool {
+	if len(r.patterns) == 0 || p == "." {
+		return false
+	}
+
+	return gitignore.NewMatcher(r.patterns).Match(strings.Split(p, "/"), isDir)
+}
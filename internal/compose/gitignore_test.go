@@ -0,0 +1,62 @@
+package compose
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitignoreRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		".git/info/exclude":               {Data: []byte("*.local\n")},
+		".gitignore":                      {Data: []byte("# editor files\n*.swp\nbuild/\n")},
+		"src/platform/.gitignore":         {Data: []byte("cache\n!keep.swp\n")},
+		"src/platform/cache/data":         {},
+		"src/platform/keep.swp":           {},
+		"src/platform/services/main.yaml": {},
+		"src/other/cache":                 {},
+		"build/output":                    {},
+		"notes.local":                     {},
+		"main.yaml.swp":                   {},
+	}
+
+	r, err := newGitignoreRules(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var merged []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == gitPrefix {
+			return fs.SkipDir
+		}
+		if r.match(path, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return r.enter(path)
+		}
+		merged = append(merged, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{".gitignore", "src/other/cache", "src/platform/.gitignore", "src/platform/keep.swp", "src/platform/services/main.yaml"}
+	if len(merged) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, merged)
+	}
+	for i := range expected {
+		if merged[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, merged)
+			break
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// fetch fetches the remote with the authentication mode recorded in stats, attempts are traced with --debug-git.
+func (g *gitDownloader) fetch(rem *git.Remote, options *git.FetchOptions, fetchURL string) error {
+	options.Progress = g.progress("fetch", fetchURL)
+	err := rem.Fetch(options)
+	g.traceAuth("fetch", fetchURL, err)
+
+	return err
+}
+
+// clone clones options.URL with the authentication mode recorded in stats, attempts are traced with --debug-git.
+func (g *gitDownloader) clone(ctx context.Context, targetDir string, options *git.CloneOptions) error {
+	_, err := git.PlainCloneContext(ctx, targetDir, false, options)
+	g.traceAuth("clone", options.URL, err)
+
+	return err
+}
+
+// traceAuth logs the authentication mode attempted for a remote and its result.
+func (g *gitDownloader) traceAuth(op, remoteURL string, err error) {
+	if !g.k.debugGit {
+		return
+	}
+
+	args := []any{"op", op, "url", redactURL(remoteURL), "auth", g.stats.auth.String()}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	g.k.Log().Debug("git auth attempt", args...)
+}
+
+// progress returns the writer of go-git progress, its lines are logged with --debug-git and discarded otherwise.
+func (g *gitDownloader) progress(op, remoteURL string) io.Writer {
+	if !g.k.debugGit {
+		return io.Discard
+	}
+
+	return &progressLogger{kw: g.k, op: op, url: redactURL(remoteURL)}
+}
+
+// progressLogger logs progress lines of the remote, updates of a line end with a carriage return.
+type progressLogger struct {
+	kw  *keyringWrapper
+	op  string
+	url string
+	buf []byte
+}
+
+func (p *progressLogger) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexAny(p.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(p.buf[:i]); len(line) > 0 {
+			p.kw.Log().Debug("git progress", "op", p.op, "url", p.url, "line", string(line))
+		}
+		p.buf = p.buf[i+1:]
+	}
+
+	return len(data), nil
+}
+
+// redactURL hides the password of credentials embedded in a remote URL.
+func redactURL(remoteURL string) string {
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return remoteURL
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,192 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dependencyGraph orders packages by their dependencies. Edges keep their declaration order, so packages
+// independent of each other are merged in the same order on every run and conflicts are resolved the same way.
+type dependencyGraph struct {
+	edges map[string][]string
+}
+
+func (g *dependencyGraph) addEdge(from, to string) {
+	if !slices.Contains(g.edges[from], to) {
+		g.edges[from] = append(g.edges[from], to)
+	}
+}
+
+// TopSort returns nodes reachable from name, dependencies before their dependents, name last.
+func (g *dependencyGraph) TopSort(name string) ([]string, error) {
+	var result []string
+	sorted := make(map[string]bool)
+
+	var visit func(node string, chain []string) error
+	visit = func(node string, chain []string) error {
+		if i := slices.Index(chain, node); i >= 0 {
+			return fmt.Errorf("%w: %s", errDependencyCycle, strings.Join(append(chain[i:], node), " -> "))
+		}
+		if sorted[node] {
+			return nil
+		}
+
+		chain = append(chain, node)
+		for _, edge := range g.edges[node] {
+			if err := visit(edge, chain); err != nil {
+				return err
+			}
+		}
+
+		sorted[node] = true
+		result = append(result, node)
+		return nil
+	}
+
+	if err := visit(name, nil); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// buildDependenciesGraph returns the graph of packages rooted at DependencyRoot, packages no other package
+// depends on are its edges in the order of packages.
+func buildDependenciesGraph(packages []*Package) *dependencyGraph {
+	graph := &dependencyGraph{edges: make(map[string][]string)}
+	dependedOn := make(map[string]bool)
+
+	for _, a := range packages {
+		for _, d := range a.Dependencies {
+			graph.addEdge(a.GetName(), d)
+			dependedOn[d] = true
+		}
+	}
+
+	for _, a := range packages {
+		if !dependedOn[a.GetName()] {
+			graph.addEdge(DependencyRoot, a.GetName())
+		}
+	}
+
+	return graph
+}
+
+// Formats of Graph output.
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+	GraphFormatJSON    = "json"
+)
+
+var errInvalidGraphFormat = errors.New("invalid graph format")
+
+// GraphNode is a package of the dependency graph.
+type GraphNode struct {
+	Name string `json:"name"`
+	Ref  string `json:"ref"`
+	// DeclaredBy is the package which nested compose.yaml declares the package, empty for the domain composition.
+	DeclaredBy string `json:"declared_by,omitempty"`
+}
+
+// GraphEdge points from a package to its dependency, edges from DependencyRoot lead to packages
+// no other package depends on.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DependencyGraph is the graph packages are merged by, see Graph.
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Order lists packages in merge order, dependencies before their dependents.
+	Order []string `json:"order"`
+}
+
+// ValidateGraphFormat checks output format of Graph.
+func ValidateGraphFormat(format string) error {
+	switch format {
+	case GraphFormatDOT, GraphFormatMermaid, GraphFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected %s, %s or %s", errInvalidGraphFormat, format, GraphFormatDOT, GraphFormatMermaid, GraphFormatJSON)
+	}
+}
+
+// Graph returns the dependency graph of the composition including packages of nested compose.yaml files,
+// in the order compose merges them. Packages must be downloaded by compose before.
+func Graph(baseDir, packagesDir string, cfg *Composition) (*DependencyGraph, error) {
+	lock, err := LoadVersionLock(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, _, err := collectPackages(cfg, lock, packagesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := buildDependenciesGraph(packages)
+	items, err := graph.TopSort(DependencyRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DependencyGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}, Order: []string{}}
+	seen := make(map[string]bool)
+	for _, p := range packages {
+		if seen[p.GetName()] {
+			continue
+		}
+		seen[p.GetName()] = true
+		result.Nodes = append(result.Nodes, GraphNode{Name: p.GetName(), Ref: p.GetTarget(), DeclaredBy: p.DeclaredBy})
+	}
+	for _, name := range items {
+		if name != DependencyRoot {
+			result.Order = append(result.Order, name)
+		}
+	}
+	for _, from := range append([]string{DependencyRoot}, result.Order...) {
+		for _, to := range graph.edges[from] {
+			result.Edges = append(result.Edges, GraphEdge{From: from, To: to})
+		}
+	}
+
+	return result, nil
+}
+
+// DOT renders the graph in Graphviz format.
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	fmt.Fprintf(&b, "  %q [label=%q, shape=box];\n", DependencyRoot, localOrigin)
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q];\n", n.Name, n.Name+"@"+n.Ref)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Package names aren't valid node ids, nodes are numbered.
+func (g *DependencyGraph) Mermaid() string {
+	ids := map[string]string{DependencyRoot: "root"}
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	fmt.Fprintf(&b, "  root[%q]\n", localOrigin)
+	for i, n := range g.Nodes {
+		ids[n.Name] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(&b, "  %s[%q]\n", ids[n.Name], n.Name+"@"+n.Ref)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDependenciesGraphOrder(t *testing.T) {
+	packages := []*Package{
+		{Name: "zeta"},
+		{Name: "app", Dependencies: []string{"core", "base"}},
+		{Name: "alpha"},
+		{Name: "core", Dependencies: []string{"base"}},
+		{Name: "base"},
+	}
+	expected := []string{"zeta", "base", "core", "app", "alpha", DependencyRoot}
+
+	// Map iteration order changes between runs, the merge order must not.
+	for range 50 {
+		items, err := buildDependenciesGraph(packages).TopSort(DependencyRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(items, expected) {
+			t.Fatalf("expected merge order %v, got %v", expected, items)
+		}
+	}
+
+	cyclic := []*Package{
+		{Name: "app", Dependencies: []string{"core"}},
+		{Name: "core", Dependencies: []string{"lib"}},
+		{Name: "lib", Dependencies: []string{"core"}},
+	}
+	if _, err := buildDependenciesGraph(cyclic).TopSort("app"); !errors.Is(err, errDependencyCycle) {
+		t.Errorf("expected dependency cycle, got %v", err)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, "packages")
+	nested := "name: a\ndependencies:\n  - name: b\n    source:\n      type: http\n      url: https://example.com/b.tar.gz\n"
+	for name, content := range map[string]string{"a": nested, "b": ""} {
+		dir := filepath.Join(packagesDir, name, TargetLatest)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if content == "" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, composeFile), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Composition{Dependencies: []Dependency{{Name: "a", Source: Source{Type: HTTPType, URL: "https://example.com/a.tar.gz"}}}}
+	g, err := Graph(baseDir, packagesDir, cfg)
+	if err != nil {
+		t.Fatalf("Graph failed: %v", err)
+	}
+
+	if !slices.Equal(g.Order, []string{"b", "a"}) {
+		t.Errorf("expected merge order [b a], got %v", g.Order)
+	}
+	if !slices.Equal(g.Edges, []GraphEdge{{From: DependencyRoot, To: "a"}, {From: "a", To: "b"}}) {
+		t.Errorf("unexpected edges %v", g.Edges)
+	}
+	if len(g.Nodes) != 2 || g.Nodes[0].DeclaredBy != "a" {
+		t.Errorf("expected b to be declared by a, got %+v", g.Nodes)
+	}
+	if dot := g.DOT(); !strings.Contains(dot, `"a" -> "b";`) {
+		t.Errorf("unexpected DOT output:\n%s", dot)
+	}
+	if mermaid := g.Mermaid(); !strings.Contains(mermaid, "root --> p1") || !strings.Contains(mermaid, `p0["b@latest"]`) {
+		t.Errorf("unexpected Mermaid output:\n%s", mermaid)
+	}
+	if err = ValidateGraphFormat("svg"); !errors.Is(err, errInvalidGraphFormat) {
+		t.Errorf("expected invalid format, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var errInvalidGroup = errors.New("invalid dependency group")
+
+// selectGroups returns dependencies composed for the given groups. Dependencies without groups are always
+// composed. If include is set, grouped dependencies are composed only when one of their groups is included.
+// Dependencies having any excluded group are skipped, exclusion takes precedence over inclusion.
+func selectGroups(deps []Dependency, include, exclude []string) (selected []Dependency, skipped []string, err error) {
+	declared := make(map[string]bool)
+	for _, d := range deps {
+		for _, g := range d.Groups {
+			declared[g] = true
+		}
+	}
+
+	for _, g := range append(slices.Clone(include), exclude...) {
+		if !declared[g] {
+			return nil, nil, fmt.Errorf("%w: group %q is not declared by any dependency in %s", errInvalidGroup, g, composeFile)
+		}
+	}
+
+	for _, d := range deps {
+		if isGroupComposed(d.Groups, include, exclude) {
+			selected = append(selected, d)
+		} else {
+			skipped = append(skipped, d.Name)
+		}
+	}
+
+	return selected, skipped, nil
+}
+
+func isGroupComposed(groups, include, exclude []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+
+	for _, g := range groups {
+		if slices.Contains(exclude, g) {
+			return false
+		}
+	}
+
+	if len(include) == 0 {
+		return true
+	}
+
+	for _, g := range groups {
+		if slices.Contains(include, g) {
+			return true
+		}
+	}
+
+	return false
+}
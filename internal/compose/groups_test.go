@@ -0,0 +1,51 @@
+package compose
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelectGroups(t *testing.T) {
+	deps := []Dependency{
+		{Name: "core"},
+		{Name: "monitoring", Groups: []string{"observability"}},
+		{Name: "tracing", Groups: []string{"observability", "dev"}},
+		{Name: "debug", Groups: []string{"dev"}},
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{"all by default", nil, nil, []string{"core", "monitoring", "tracing", "debug"}},
+		{"include", []string{"observability"}, nil, []string{"core", "monitoring", "tracing"}},
+		{"exclude", nil, []string{"dev"}, []string{"core", "monitoring"}},
+		{"exclude wins", []string{"observability"}, []string{"dev"}, []string{"core", "monitoring"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, skipped, err := selectGroups(deps, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("selectGroups failed: %v", err)
+			}
+
+			var names []string
+			for _, d := range selected {
+				names = append(names, d.Name)
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+			if len(names)+len(skipped) != len(deps) {
+				t.Errorf("expected skipped packages to complete selection, got %v", skipped)
+			}
+		})
+	}
+
+	if _, _, err := selectGroups(deps, []string{"unknown"}, nil); err == nil {
+		t.Error("expected error for undeclared group")
+	}
+}
@@ -15,10 +15,12 @@ import (
 	"strings"
 
 	"github.com/launchrctl/keyring"
+
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
 var (
-	errInvalidFilepath        = errors.New("invalid filepath")
 	errNoURL                  = errors.New("invalid package url")
 	errFailedClose            = errors.New("failed to close stream")
 	errRepositoryNotFound     = errors.New("repository not found")
@@ -34,11 +36,23 @@ var (
 )
 
 type httpDownloader struct {
-	k *keyringWrapper
+	k           *keyringWrapper
+	stats       downloadStats
+	linksPolicy string
+}
+
+func newHTTP(kw *keyringWrapper, linksPolicy string) Downloader {
+	return &httpDownloader{k: kw, linksPolicy: linksPolicy}
+}
+
+// Stats implements statsReporter interface
+func (h *httpDownloader) Stats() downloadStats {
+	return h.stats
 }
 
-func newHTTP(kw *keyringWrapper) Downloader {
-	return &httpDownloader{k: kw}
+// ResetStats implements statsReporter interface
+func (h *httpDownloader) ResetStats() {
+	h.stats = downloadStats{}
 }
 
 func (h *httpDownloader) EnsureLatest(_ *Package, downloadPath string) (bool, error) {
@@ -53,6 +67,9 @@ func (h *httpDownloader) EnsureLatest(_ *Package, downloadPath string) (bool, er
 // Download implements Downloader.Download interface
 func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir string) error {
 	url := pkg.GetURL()
+	if strings.Contains(url, model.RefPlaceholder) {
+		return fmt.Errorf("%w: %s of %s needs a ref", errNoURL, model.RefPlaceholder, pkg.GetName())
+	}
 	name := rgxNameFromURL.FindString(url)
 	if name == "" {
 		return errNoURL
@@ -82,12 +99,51 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 	errDownloadFailed := fmt.Errorf("failed to download package: %s", name)
 
 	auths := []authenticationMode{authenticationModeNone, authenticationModeKeyring, authenticationModeManual}
+	rule := h.k.auth.Match(url)
+	if rule != nil {
+		// Configured authentication replaces trying every mode.
+		auths = []authenticationMode{authenticationModeConfigured}
+	}
+
+	if sess, ok := h.k.session(url); ok && rule == nil {
+		h.stats.auth = sess.mode
+		req, errReq := http.NewRequest(http.MethodGet, url, nil)
+		if errReq != nil {
+			return errReq
+		}
+
+		req.SetBasicAuth(sess.ci.Username, sess.ci.Password)
+		resp, err = doRequest(client, req)
+		switch {
+		case err == nil:
+			auths = nil
+		case errors.Is(err, errAuthenticationRequired) || errors.Is(err, errAuthorizationFailed):
+			h.k.dropSession(url)
+		default:
+			h.k.Log().Debug(err.Error())
+			return fmt.Errorf("%w: %w", errDownloadFailed, err)
+		}
+	}
+
 	for _, authMod := range auths {
+		h.stats.auth = authMod
 		req, errReq := http.NewRequest(http.MethodGet, url, nil)
 		if errReq != nil {
 			return errReq
 		}
 
+		if authMod == authenticationModeConfigured {
+			if err = h.k.httpAuth(rule, req); err != nil {
+				return err
+			}
+
+			resp, err = doRequest(client, req)
+			if err != nil {
+				h.k.Log().Debug(err.Error())
+				return fmt.Errorf("%w: %w", errDownloadFailed, err)
+			}
+		}
+
 		if authMod == authenticationModeNone {
 			resp, err = doRequest(client, req)
 			if err != nil {
@@ -96,7 +152,7 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 				}
 
 				h.k.Log().Debug(err.Error())
-				return errDownloadFailed
+				return fmt.Errorf("%w: %w", errDownloadFailed, err)
 			}
 		}
 
@@ -116,8 +172,9 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 				}
 
 				h.k.Log().Debug(err.Error())
-				return errDownloadFailed
+				return fmt.Errorf("%w: %w", errDownloadFailed, err)
 			}
+			h.k.saveSession(url, authMod, ci)
 		}
 
 		if authMod == authenticationModeManual {
@@ -132,8 +189,9 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 			resp, err = doRequest(client, req)
 			if err != nil {
 				h.k.Log().Debug(err.Error())
-				return errDownloadFailed
+				return fmt.Errorf("%w: %w", errDownloadFailed, err)
 			}
+			h.k.saveSession(url, authMod, ci)
 		}
 
 		break
@@ -145,7 +203,7 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 		}
 	}()
 
-	_, err = io.Copy(out, resp.Body)
+	h.stats.bytes, err = io.Copy(out, resp.Body)
 	if err != nil {
 		return err
 	}
@@ -153,9 +211,9 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 	var archiveRootDir string
 	switch at := rgxArchiveType.FindString(name); at {
 	case "tar.gz":
-		archiveRootDir, err = untar(fpath, targetDir)
+		archiveRootDir, err = untar(fpath, targetDir, h.linksPolicy)
 	case "zip":
-		archiveRootDir, err = unzip(fpath, targetDir)
+		archiveRootDir, err = unzip(fpath, targetDir, h.linksPolicy)
 	default:
 		err = fmt.Errorf("not supported archive type: %s", at)
 	}
@@ -177,7 +235,12 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 		}
 	}
 
-	h.k.Term().Printfln("  ✓ %s", pkg.GetIdentifier())
+	// Archives are extracted as a whole, only the subpath is merged.
+	if err = ensureSubpath(pkg, filepath.Join(targetDir, pkg.GetTarget())); err != nil {
+		return err
+	}
+
+	h.k.Term().Printfln("  %s %s", output.Get().Check, pkg.GetIdentifier())
 	return nil
 }
 
@@ -205,12 +268,13 @@ func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 	}
 }
 
-func untar(fpath, tpath string) (string, error) {
+func untar(fpath, tpath, linksPolicy string) (string, error) {
 	var rootDir string
 	r, err := os.Open(filepath.Clean(fpath))
 	if err != nil {
 		return rootDir, err
 	}
+	defer r.Close()
 
 	gzr, err := gzip.NewReader(r)
 	if err != nil {
@@ -242,58 +306,18 @@ func untar(fpath, tpath string) (string, error) {
 			continue
 		}
 
-		// the target location where the dir/file should be created
-		target, err := sanitizeArchivePath(tpath, header.Name)
-		if err != nil {
-			return rootDir, errInvalidFilepath
-		}
-
-		if !strings.HasPrefix(target, filepath.Clean(tpath)) {
-			return rootDir, errInvalidFilepath
+		if _, err = ExtractTarEntry(tpath, header, tr, linksPolicy); err != nil {
+			return rootDir, err
 		}
-
-		// check the file type
-		switch header.Typeflag {
-
-		// if it's a dir, and it doesn't exist create it
-		case tar.TypeDir:
+		if header.Typeflag == tar.TypeDir {
 			rootDir = header.Name
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0750); err != nil {
-					return rootDir, err
-				}
-			}
-
-		// if it's a file create it
-		case tar.TypeReg:
-			f, err := os.OpenFile(filepath.Clean(target), os.O_CREATE|os.O_RDWR, header.FileInfo().Mode())
-			if err != nil {
-				return rootDir, err
-			}
-
-			for {
-				_, err = io.CopyN(f, tr, 1024)
-				if err != nil {
-					if err != io.EOF {
-						return rootDir, err
-					}
-					break
-				}
-			}
-
-			// manually close here after each file operation; defering would cause each file close
-			// to wait until all operations have completed.
-			err = f.Close()
-			if err != nil {
-				return rootDir, err
-			}
 		}
 	}
 }
 
 // Unzip archive
 // returns root folder name
-func unzip(fpath, tpath string) (string, error) {
+func unzip(fpath, tpath, linksPolicy string) (string, error) {
 	var rootDir string
 	archive, err := zip.OpenReader(fpath)
 	if err != nil {
@@ -302,9 +326,9 @@ func unzip(fpath, tpath string) (string, error) {
 	defer archive.Close()
 
 	for _, f := range archive.File {
-		filePath, err := sanitizeArchivePath(tpath, f.Name)
-		if err != nil || !strings.HasPrefix(filePath, filepath.Clean(tpath)+string(os.PathSeparator)) {
-			return rootDir, errInvalidFilepath
+		filePath, err := archiveTarget(tpath, f.Name)
+		if err != nil {
+			return rootDir, err
 		}
 		if f.FileInfo().IsDir() {
 			rootDir = f.Name
@@ -315,32 +339,23 @@ func unzip(fpath, tpath string) (string, error) {
 			continue
 		}
 
-		if err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil { //nolint
-			return rootDir, err
-		}
-
-		dstFile, err := os.OpenFile(filepath.Clean(filePath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return rootDir, err
-		}
-
 		fileInArchive, err := f.Open()
 		if err != nil {
 			return rootDir, err
 		}
 
-		for {
-			_, err = io.CopyN(dstFile, fileInArchive, 1024)
-			if err != nil {
-				if err != io.EOF {
-					return rootDir, err
-				}
-				break
+		if f.Mode()&os.ModeSymlink != 0 {
+			// Zip archives store symlink target as the file content.
+			var linkname []byte
+			linkname, err = io.ReadAll(io.LimitReader(fileInArchive, 4096))
+			if err == nil {
+				err = extractLink(tpath, filePath, f.Name, string(linkname), false, linksPolicy)
 			}
+		} else {
+			err = createArchiveFile(filePath, f.Mode(), fileInArchive)
 		}
-
-		err = dstFile.Close()
 		if err != nil {
+			_ = fileInArchive.Close()
 			return rootDir, err
 		}
 
@@ -357,12 +372,3 @@ func unzip(fpath, tpath string) (string, error) {
 
 	return rootDir, nil
 }
-
-func sanitizeArchivePath(d, t string) (v string, err error) {
-	v = filepath.Join(d, t)
-	if strings.HasPrefix(v, filepath.Clean(d)) {
-		return v, nil
-	}
-
-	return "", fmt.Errorf("%s: %s", "content filepath is tainted", t)
-}
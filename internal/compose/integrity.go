@@ -0,0 +1,218 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Integrity modes of downloaded packages whose content doesn't match compose.lock.
+const (
+	// IntegrityStrict refuses to compose.
+	IntegrityStrict = "strict"
+	// IntegrityWarn composes and keeps the recorded hash.
+	IntegrityWarn = "warn"
+	// IntegrityUpdate records the new hash.
+	IntegrityUpdate = "update"
+)
+
+var (
+	errInvalidIntegrityMode = errors.New("invalid integrity mode")
+	errIntegrityMismatch    = fmt.Errorf("%w: package integrity mismatch", ErrLockOutOfDate)
+	errNotLocked            = fmt.Errorf("%w: package is not locked", ErrLockOutOfDate)
+	errFrozenCheckout       = fmt.Errorf("%w: package checkout doesn't match the lock", ErrLockOutOfDate)
+	errLockedIntegrity      = errors.New("packages composed from the lock are verified strictly")
+)
+
+const treeHashPrefix = "sha256:"
+
+// PackageIntegrity stores the tree hash of a downloaded package at a ref and the commit checked out for git packages.
+type PackageIntegrity struct {
+	Name   string `yaml:"name"`
+	Ref    string `yaml:"ref"`
+	Commit string `yaml:"commit,omitempty"`
+	Hash   string `yaml:"hash"`
+}
+
+func validateIntegrityMode(mode string) error {
+	switch mode {
+	case "", IntegrityStrict, IntegrityWarn, IntegrityUpdate:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected %s, %s or %s", errInvalidIntegrityMode, mode, IntegrityStrict, IntegrityWarn, IntegrityUpdate)
+	}
+}
+
+// recordedIntegrity returns the hash recorded for a package at ref, nil-safe.
+func (l *VersionLock) recordedIntegrity(name, ref string) (string, bool) {
+	pi, ok := l.integrity(name, ref)
+	return pi.Hash, ok
+}
+
+// lockedCommit returns the commit recorded for pkg at its ref, packages without a record fail.
+// Packages of other types have no commit, their content is verified by the tree hash.
+func (l *VersionLock) lockedCommit(pkg *Package) (string, error) {
+	if l != nil {
+		for _, pi := range l.Integrity {
+			if pi.Name != pkg.GetName() || pi.Ref != pkg.GetTarget() {
+				continue
+			}
+			if pi.Commit == "" && pkg.GetType() == GitType {
+				break
+			}
+
+			return pi.Commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s@%s isn't recorded in %s, run compose without --locked to update it", errNotLocked, pkg.GetName(), pkg.GetTarget(), versionLockFile)
+}
+
+// verifyIntegrity compares tree hashes of downloaded packages with hashes recorded in lock and returns
+// hashes to record. Content of branches changes with new commits, their hashes are recorded again.
+// Hashes of skipped packages are kept.
+func (c *Composer) verifyIntegrity(packages []*Package, aliases map[string]string, packagesDir string, lock *VersionLock) ([]PackageIntegrity, error) {
+	var result []PackageIntegrity
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		if seen[pkg.GetName()] {
+			continue
+		}
+		seen[pkg.GetName()] = true
+
+		checkout := pkg.GetName()
+		if owner, ok := aliases[checkout]; ok {
+			checkout = owner
+		}
+		pkgPath := filepath.Join(packagesDir, checkout, pkg.GetTarget())
+
+		hash, err := treeHash(packageDir(pkgPath, pkg))
+		if err != nil {
+			return nil, err
+		}
+
+		pi := PackageIntegrity{Name: pkg.GetName(), Ref: pkg.GetTarget(), Hash: hash}
+		if commit, errHead := headCommit(pkgPath); errHead == nil {
+			pi.Commit = commit
+		}
+		recorded, ok := lock.recordedIntegrity(pi.Name, pi.Ref)
+		switch {
+		case !ok || recorded == hash:
+		case isBranchCheckout(pkgPath):
+			c.Log().Debug("branch content changed, recording new hash", "package", pi.Name, "ref", pi.Ref)
+		case c.options.Integrity == IntegrityUpdate:
+			c.Term().Warning().Printfln("Content of %s@%s changed, recording new hash", pi.Name, pi.Ref)
+		case c.options.Integrity == IntegrityWarn:
+			c.Term().Warning().Printfln("Content of %s@%s doesn't match %s (recorded %s, got %s)", pi.Name, pi.Ref, versionLockFile, recorded, hash)
+			pi.Hash = recorded
+		default:
+			return nil, fmt.Errorf("%w: content of %s@%s doesn't match %s (recorded %s, got %s), the ref may have been moved or the source tampered, use --integrity update to accept it",
+				errIntegrityMismatch, pi.Name, pi.Ref, versionLockFile, recorded, hash)
+		}
+		result = append(result, pi)
+	}
+
+	// Keep hashes of packages skipped by groups or failed optional packages.
+	if lock != nil {
+		for _, pi := range lock.Integrity {
+			if !seen[pi.Name] && slices.Contains(c.summary.Skipped, pi.Name) {
+				result = append(result, pi)
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b PackageIntegrity) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result, nil
+}
+
+// isBranchCheckout reports if dir is a git clone with a branch checked out.
+func isBranchCheckout(dir string) bool {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return false
+	}
+
+	head, err := r.Head()
+	return err == nil && head.Name().IsBranch()
+}
+
+// treeHash returns sha256 of paths, executable bits, contents and link targets of dir, excluding git data.
+func treeHash(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if d.IsDir() && d.Name() == gitPrefix {
+			return fs.SkipDir
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir():
+			_, err = fmt.Fprintf(h, "d %s\n", rel)
+		case d.Type()&fs.ModeSymlink != 0:
+			target, errLink := os.Readlink(path)
+			if errLink != nil {
+				return errLink
+			}
+			_, err = fmt.Fprintf(h, "l %s %s\n", rel, filepath.ToSlash(target))
+		default:
+			info, errInfo := d.Info()
+			if errInfo != nil {
+				return errInfo
+			}
+			sum, errSum := fileHash(path)
+			if errSum != nil {
+				return errSum
+			}
+			mode := "f"
+			if info.Mode()&0111 != 0 {
+				mode = "x"
+			}
+			_, err = fmt.Fprintf(h, "%s %s %s\n", mode, rel, sum)
+		}
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return treeHashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path) //nolint:gosec // path is walked within package directory
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,96 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	packagesDir := t.TempDir()
+	pkgDir := filepath.Join(packagesDir, "core", "v1.0.0")
+	for _, dir := range []string{filepath.Join(pkgDir, "src", "platform"), filepath.Join(pkgDir, gitPrefix)} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(pkgDir, "src", "platform", "main.yaml")
+	if err := os.WriteFile(file, []byte("key: value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := treeHash(pkgDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Git data isn't part of the package content.
+	if err = os.WriteFile(filepath.Join(pkgDir, gitPrefix, "HEAD"), []byte("ref: refs/heads/main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := treeHash(pkgDir); h != hash {
+		t.Errorf("expected git data to be ignored, hash changed from %s to %s", hash, h)
+	}
+
+	packages := []*Package{{Name: "core", Source: Source{Ref: "v1.0.0"}}}
+	lock := &VersionLock{Integrity: []PackageIntegrity{{Name: "core", Ref: "v1.0.0", Hash: hash}}}
+	c := &Composer{options: &ComposerOptions{}, summary: &Summary{}}
+	c.SetLogger(launchr.Log())
+	c.SetTerm(launchr.Term())
+
+	if _, err = c.verifyIntegrity(packages, nil, packagesDir, lock); err != nil {
+		t.Fatalf("expected matching content to pass, got %v", err)
+	}
+
+	if err = os.WriteFile(file, []byte("key: tampered\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.verifyIntegrity(packages, nil, packagesDir, lock); !errors.Is(err, errIntegrityMismatch) {
+		t.Fatalf("expected integrity mismatch, got %v", err)
+	}
+
+	c.options.Integrity = IntegrityWarn
+	recorded, err := c.verifyIntegrity(packages, nil, packagesDir, lock)
+	if err != nil || len(recorded) != 1 || recorded[0].Hash != hash {
+		t.Errorf("expected recorded hash to be kept in warn mode, got %+v, %v", recorded, err)
+	}
+
+	c.options.Integrity = IntegrityUpdate
+	recorded, err = c.verifyIntegrity(packages, nil, packagesDir, lock)
+	if err != nil || len(recorded) != 1 || recorded[0].Hash == hash {
+		t.Errorf("expected new hash to be recorded in update mode, got %+v, %v", recorded, err)
+	}
+}
+
+func TestLockedCommit(t *testing.T) {
+	commit := "4c1e0f9a4c1e0f9a4c1e0f9a4c1e0f9a4c1e0f9a"
+	lock := &VersionLock{Integrity: []PackageIntegrity{
+		{Name: "core", Ref: "main", Commit: commit, Hash: "sha256:1"},
+		{Name: "legacy", Ref: "v1.0.0", Hash: "sha256:2"},
+		{Name: "archive", Ref: "v1.0.0", Hash: "sha256:3"},
+	}}
+
+	got, err := lock.lockedCommit(&Package{Name: "core", Source: Source{Ref: "main"}})
+	if err != nil || got != commit {
+		t.Errorf("expected recorded commit %s, got %q, %v", commit, got, err)
+	}
+
+	got, err = lock.lockedCommit(&Package{Name: "archive", Source: Source{Type: HTTPType, Ref: "v1.0.0"}})
+	if err != nil || got != "" {
+		t.Errorf("expected http package to be locked by hash only, got %q, %v", got, err)
+	}
+
+	// Git packages recorded before commits were locked have to be composed again.
+	if _, err = lock.lockedCommit(&Package{Name: "legacy", Source: Source{Ref: "v1.0.0"}}); !errors.Is(err, errNotLocked) {
+		t.Errorf("expected package without recorded commit to fail, got %v", err)
+	}
+	if _, err = lock.lockedCommit(&Package{Name: "core", Source: Source{Ref: "develop"}}); !errors.Is(err, ErrLockOutOfDate) {
+		t.Errorf("expected package at another ref to fail, got %v", err)
+	}
+	if _, err = (*VersionLock)(nil).lockedCommit(&Package{Name: "core", Source: Source{Ref: "main"}}); !errors.Is(err, errNotLocked) {
+		t.Errorf("expected missing lock to fail, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package compose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// ErrLocked is returned when model directories are locked by another operation.
+var ErrLocked = errors.New("model is locked by another operation")
+
+var (
+	lockPollInterval = 500 * time.Millisecond
+	// lockBrokenTimeout is a time after which unreadable lock file is considered stale.
+	lockBrokenTimeout = time.Minute
+)
+
+// LockInfo stores information about the lock holder.
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+}
+
+func (i LockInfo) String() string {
+	return fmt.Sprintf("%s (pid %d on %s, since %s)", i.Operation, i.PID, i.Host, i.Created.Format(time.RFC3339))
+}
+
+// Lock is an exclusive lock of model directories held by the current process.
+type Lock struct {
+	path string
+}
+
+// AcquireLock locks model directories of baseDir for the operation.
+// If the lock is held by another live process, ErrLocked is returned, or, if wait is set,
+// the lock is polled until released. Locks left by dead processes are taken over.
+func AcquireLock(baseDir, operation string, wait bool) (*Lock, error) {
+	path := filepath.Join(baseDir, model.LockFile)
+	if err := EnsureDirExists(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	for {
+		err := createLockFile(path, operation)
+		if err == nil {
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		info, stale := readLockFile(path)
+		if stale {
+			if err = takeOverStaleLock(path, info); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !wait {
+			if info != nil {
+				return nil, fmt.Errorf("%w: %s", ErrLocked, info)
+			}
+			return nil, ErrLocked
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// LockModel acquires the lock of model directories of baseDir for the operation like AcquireLock, with wait
+// a warning is printed before awaiting the lock held by another operation. Actions writing compose.yaml or
+// the .plasma tree hold it while they run. The returned function releases the lock and logs failures.
+func LockModel(baseDir, operation string, wait bool, term *launchr.Terminal, log *launchr.Logger) (func(), error) {
+	lock, err := AcquireLock(baseDir, operation, false)
+	if errors.Is(err, ErrLocked) && wait {
+		term.Warning().Printfln("%s, waiting for it to finish...", err)
+		lock, err = AcquireLock(baseDir, operation, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if errRelease := lock.Release(); errRelease != nil {
+			log.Warn("failed to release lock", "error", errRelease)
+		}
+	}, nil
+}
+
+// Release removes the lock file.
+func (l *Lock) Release() error {
+	err := os.Remove(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func createLockFile(path, operation string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) //nolint:gosec // path is built from base dir
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	data, err := json.Marshal(LockInfo{
+		PID:       os.Getpid(),
+		Host:      host,
+		Operation: operation,
+		Created:   time.Now(),
+	})
+	if err == nil {
+		_, err = f.Write(data)
+	}
+
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+
+	return err
+}
+
+// readLockFile returns lock holder and whether the lock is stale.
+func readLockFile(path string) (*LockInfo, bool) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		// Lock was released in the meantime.
+		return nil, os.IsNotExist(err)
+	}
+
+	var info LockInfo
+	data, err := os.ReadFile(path) //nolint:gosec // path is built from base dir
+	if err != nil || json.Unmarshal(data, &info) != nil {
+		// Lock file may be still being written by another process.
+		return nil, time.Since(stat.ModTime()) > lockBrokenTimeout
+	}
+
+	host, _ := os.Hostname()
+	if info.Host != host {
+		// Process of another host can't be checked.
+		return &info, false
+	}
+
+	return &info, !processAlive(info.PID)
+}
+
+// takeOverStaleLock removes the stale lock of holder. Another process may have taken the lock over and
+// created a fresh one since it was read, so the lock file is atomically moved aside first and checked to
+// still be the stale lock of holder, a fresh lock moved by mistake is put back.
+func takeOverStaleLock(path string, holder *LockInfo) error {
+	moved := fmt.Sprintf("%s.stale.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, moved); err != nil {
+		if os.IsNotExist(err) {
+			// Lock was released or taken over in the meantime.
+			return nil
+		}
+		return err
+	}
+	defer os.Remove(moved) //nolint:errcheck // the moved lock isn't used anymore
+
+	info, stale := readLockFile(moved)
+	if stale && sameHolder(info, holder) {
+		return nil
+	}
+
+	// Link fails if the lock was created again in the meantime, the new holder keeps it.
+	if err := os.Link(moved, path); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func sameHolder(a, b *LockInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.PID == b.PID && a.Host == b.Host && a.Operation == b.Operation && a.Created.Equal(b.Created)
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone)
+}
@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestAcquireLock(t *testing.T) {
+	baseDir := t.TempDir()
+
+	lock, err := AcquireLock(baseDir, "first", false)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	_, err = AcquireLock(baseDir, "second", false)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+
+	if err = lock.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	lock, err = AcquireLock(baseDir, "second", false)
+	if err != nil {
+		t.Fatalf("failed to acquire released lock: %v", err)
+	}
+	_ = lock.Release()
+}
+
+func TestAcquireLockStale(t *testing.T) {
+	baseDir := t.TempDir()
+
+	// Run and wait for a process to get a pid which is not alive.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run process: %v", err)
+	}
+
+	host, _ := os.Hostname()
+	writeLockInfo(t, baseDir, LockInfo{PID: cmd.Process.Pid, Host: host, Operation: "dead"})
+
+	lock, err := AcquireLock(baseDir, "alive", false)
+	if err != nil {
+		t.Fatalf("expected stale lock to be taken over, got %v", err)
+	}
+	_ = lock.Release()
+
+	// Process on another host can't be checked, lock is kept.
+	writeLockInfo(t, baseDir, LockInfo{PID: cmd.Process.Pid, Host: host + "-other", Operation: "remote"})
+	_, err = AcquireLock(baseDir, "alive", false)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+}
+
+func TestTakeOverStaleLockRace(t *testing.T) {
+	baseDir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run process: %v", err)
+	}
+
+	host, _ := os.Hostname()
+	writeLockInfo(t, baseDir, LockInfo{PID: cmd.Process.Pid, Host: host, Operation: "dead", Created: time.Now()})
+	path := filepath.Join(baseDir, model.LockFile)
+
+	// Both processes find the stale lock, the first one takes it over before the second one does.
+	info, stale := readLockFile(path)
+	if !stale {
+		t.Fatal("expected lock of a dead process to be stale")
+	}
+	lock, err := AcquireLock(baseDir, "first", false)
+	if err != nil {
+		t.Fatalf("expected stale lock to be taken over, got %v", err)
+	}
+	defer lock.Release() //nolint:errcheck
+
+	if err = takeOverStaleLock(path, info); err != nil {
+		t.Fatalf("failed to take over stale lock: %v", err)
+	}
+	if _, err = AcquireLock(baseDir, "second", false); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected fresh lock to be kept, got %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the lock file to be left, got %d files", len(entries))
+	}
+}
+
+func TestAcquireLockWait(t *testing.T) {
+	baseDir := t.TempDir()
+	pollInterval := lockPollInterval
+	t.Cleanup(func() { lockPollInterval = pollInterval })
+	lockPollInterval = 10 * time.Millisecond
+
+	lock, err := AcquireLock(baseDir, "first", false)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = lock.Release()
+	}()
+
+	second, err := AcquireLock(baseDir, "second", true)
+	if err != nil {
+		t.Fatalf("failed to wait for lock: %v", err)
+	}
+	_ = second.Release()
+}
+
+func writeLockInfo(t *testing.T, baseDir string, info LockInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("failed to marshal lock: %v", err)
+	}
+
+	path := filepath.Join(baseDir, model.LockFile)
+	if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+const (
+	// LegacyComposeFile is the compose file of the legacy launchr-compose plugin.
+	LegacyComposeFile = "plasma-compose.yaml"
+	legacyComposeDir  = ".compose"
+)
+
+var (
+	errNoLegacyCompose = errors.New(LegacyComposeFile + " doesn't exist, nothing to migrate")
+	errComposeExists   = errors.New(model.ComposeFile + " already exists, use --force to overwrite it")
+)
+
+// legacySource stores package source of plasma-compose.yaml with deprecated tag field.
+type legacySource struct {
+	Type       string     `yaml:"type"`
+	URL        string     `yaml:"url"`
+	Ref        string     `yaml:"ref,omitempty"`
+	Tag        string     `yaml:"tag,omitempty"`
+	Strategies []Strategy `yaml:"strategy,omitempty"`
+}
+
+type legacyDependency struct {
+	Name   string       `yaml:"name"`
+	Source legacySource `yaml:"source,omitempty"`
+}
+
+type legacyComposition struct {
+	Name         string             `yaml:"name"`
+	Dependencies []legacyDependency `yaml:"dependencies,omitempty"`
+}
+
+// PathMove stores a directory moved to the new layout.
+type PathMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MigrationPlan describes changes required to migrate a legacy composition.
+type MigrationPlan struct {
+	// RefsFromTags lists packages which deprecated tag was converted to ref.
+	RefsFromTags []string   `json:"refs_from_tags"`
+	Moves        []PathMove `json:"moves"`
+
+	composition *Composition
+}
+
+// legacyMoves maps legacy .compose directories to directories of the layout.
+func legacyMoves(layout model.Layout) []PathMove {
+	return []PathMove{
+		{From: filepath.Join(legacyComposeDir, "packages"), To: layout.PackagesDir},
+		{From: filepath.Join(legacyComposeDir, "build"), To: layout.MergedDir},
+	}
+}
+
+// PlanMigration reads plasma-compose.yaml of dir and plans its migration, legacy directories are moved to the layout.
+func PlanMigration(dir string, layout model.Layout, force bool) (*MigrationPlan, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LegacyComposeFile)) //nolint:gosec // path is built from base dir
+	if os.IsNotExist(err) {
+		return nil, errNoLegacyCompose
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !force && exists(filepath.Join(dir, model.ComposeFile)) {
+		return nil, errComposeExists
+	}
+
+	var legacy legacyComposition
+	if err = yaml.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("%s parsing failed - %w", LegacyComposeFile, err)
+	}
+
+	plan := &MigrationPlan{composition: &Composition{Name: legacy.Name}}
+	for _, ld := range legacy.Dependencies {
+		dep := Dependency{
+			Name: ld.Name,
+			Source: Source{
+				Type:       ld.Source.Type,
+				URL:        ld.Source.URL,
+				Ref:        ld.Source.Ref,
+				Strategies: ld.Source.Strategies,
+			},
+		}
+
+		if dep.Source.Ref == "" && ld.Source.Tag != "" {
+			dep.Source.Ref = ld.Source.Tag
+			plan.RefsFromTags = append(plan.RefsFromTags, ld.Name)
+		}
+
+		plan.composition.Dependencies = append(plan.composition.Dependencies, dep)
+	}
+
+	for _, move := range legacyMoves(layout) {
+		if exists(filepath.Join(dir, move.From)) && !exists(filepath.Join(dir, move.To)) {
+			plan.Moves = append(plan.Moves, move)
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyMigration writes compose.yaml, removes plasma-compose.yaml and moves legacy directories.
+func ApplyMigration(dir string, plan *MigrationPlan) error {
+	content, err := yaml.Marshal(plan.composition)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, model.ComposeFile), content, os.FileMode(composePermissions))
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(filepath.Join(dir, LegacyComposeFile)); err != nil {
+		return err
+	}
+
+	for _, move := range plan.Moves {
+		to := filepath.Join(dir, move.To)
+		if err = EnsureDirExists(filepath.Dir(to)); err != nil {
+			return err
+		}
+
+		if err = os.Rename(filepath.Join(dir, move.From), to); err != nil {
+			return err
+		}
+	}
+
+	// Remove legacy directory if nothing else is left there.
+	legacyDir := filepath.Join(dir, legacyComposeDir)
+	if empty, errEmpty := IsEmptyDir(legacyDir); errEmpty == nil && empty {
+		_ = os.Remove(legacyDir)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestMigration(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `name: platform
+dependencies:
+  - name: plasma-core
+    source:
+      type: git
+      url: https://github.com/plasmash/pla-plasma.git
+      tag: v1.0.0
+  - name: plasma-work
+    source:
+      type: git
+      url: https://github.com/plasmash/pla-work.git
+      ref: main
+`
+	if err := os.WriteFile(filepath.Join(dir, LegacyComposeFile), []byte(legacy), 0600); err != nil {
+		t.Fatalf("failed to write legacy compose: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, legacyComposeDir, "packages", "plasma-core", "v1.0.0"), 0750); err != nil {
+		t.Fatalf("failed to create legacy packages: %v", err)
+	}
+
+	plan, err := PlanMigration(dir, model.DefaultLayout(), false)
+	if err != nil {
+		t.Fatalf("failed to plan migration: %v", err)
+	}
+	if len(plan.RefsFromTags) != 1 || plan.RefsFromTags[0] != "plasma-core" {
+		t.Errorf("expected plasma-core tag to be converted, got %v", plan.RefsFromTags)
+	}
+	if len(plan.Moves) != 1 || plan.Moves[0].To != model.PackagesDir {
+		t.Errorf("expected packages dir to be moved, got %v", plan.Moves)
+	}
+
+	if err = ApplyMigration(dir, plan); err != nil {
+		t.Fatalf("failed to apply migration: %v", err)
+	}
+
+	cfg, err := LookupStrict(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("failed to read migrated compose: %v", err)
+	}
+	if len(cfg.Dependencies) != 2 || cfg.Dependencies[0].Source.Ref != "v1.0.0" || cfg.Dependencies[1].Source.Ref != "main" {
+		t.Errorf("unexpected migrated dependencies: %+v", cfg.Dependencies)
+	}
+	if exists(filepath.Join(dir, LegacyComposeFile)) || exists(filepath.Join(dir, legacyComposeDir)) {
+		t.Error("expected legacy files to be removed")
+	}
+	if !exists(filepath.Join(dir, model.PackagesDir, "plasma-core", "v1.0.0")) {
+		t.Error("expected packages to be moved to the new layout")
+	}
+
+	if _, err = PlanMigration(dir, model.DefaultLayout(), false); !errors.Is(err, errNoLegacyCompose) {
+		t.Errorf("expected nothing to migrate, got %v", err)
+	}
+}
+
+func TestMigrationComposeExists(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{LegacyComposeFile, model.ComposeFile} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("name: platform\n"), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", f, err)
+		}
+	}
+
+	if _, err := PlanMigration(dir, model.DefaultLayout(), false); !errors.Is(err, errComposeExists) {
+		t.Errorf("expected compose exists error, got %v", err)
+	}
+	if _, err := PlanMigration(dir, model.DefaultLayout(), true); err != nil {
+		t.Errorf("expected forced migration to be planned, got %v", err)
+	}
+}
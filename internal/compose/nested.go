@@ -0,0 +1,36 @@
+package compose
+
+import "slices"
+
+// nestedStrategies returns strategies declared by nested compositions. Packages declared by several
+// compositions are reported once per declaring package. If ignore is set, the strategies are dropped from packages.
+func nestedStrategies(packages []*Package, ignore bool) []NestedStrategy {
+	var result []NestedStrategy
+	seen := make(map[[2]string]bool)
+	for _, pkg := range packages {
+		if pkg.DeclaredBy == "" || len(pkg.GetStrategies()) == 0 {
+			continue
+		}
+
+		key := [2]string{pkg.DeclaredBy, pkg.GetName()}
+		if !seen[key] {
+			seen[key] = true
+			for _, item := range pkg.GetStrategies() {
+				result = append(result, NestedStrategy{
+					Package:    pkg.GetName(),
+					DeclaredBy: pkg.DeclaredBy,
+					Strategy:   item.Name,
+					Paths:      slices.Clone(item.Paths),
+					AppliesTo:  slices.Clone(item.AppliesTo),
+					Ignored:    ignore,
+				})
+			}
+		}
+
+		if ignore {
+			pkg.Source.Strategies = nil
+		}
+	}
+
+	return result
+}
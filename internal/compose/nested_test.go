@@ -0,0 +1,66 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNestedStrategies(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, "packages")
+	path := filepath.Join("src", "platform", "services", "nginx", "defaults", "main.yaml")
+
+	for _, f := range []string{
+		filepath.Join(baseDir, path),
+		filepath.Join(packagesDir, "extra", "v2.0.0", path),
+	} {
+		if err := os.MkdirAll(filepath.Dir(f), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("key: value\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	coreDir := filepath.Join(packagesDir, "core", "v1.0.0")
+	if err := os.MkdirAll(coreDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	nested := "name: core\ndependencies:\n  - name: extra\n    source:\n      ref: v2.0.0\n      strategy:\n        - name: overwrite-local-file\n          path: [src/platform/services]\n"
+	if err := os.WriteFile(filepath.Join(coreDir, composeFile), []byte(nested), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Composition{Dependencies: []Dependency{{Name: "core", Source: Source{Ref: "v1.0.0"}}}}
+
+	packages, _, err := collectPackages(cfg, nil, packagesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := nestedStrategies(packages, false)
+	if len(report) != 1 || report[0].Package != "extra" || report[0].DeclaredBy != "core" || report[0].Strategy != StrategyOverwriteLocal {
+		t.Fatalf("expected overwrite-local-file of extra declared by core, got %+v", report)
+	}
+
+	e, err := Explain(baseDir, packagesDir, cfg, filepath.ToSlash(path), false)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if e.Winner != "extra" {
+		t.Errorf("expected winner extra, got %q", e.Winner)
+	}
+	for _, step := range e.Steps {
+		if step.Package == "extra" && step.DeclaredBy != "core" {
+			t.Errorf("expected strategy declared by core, got %q", step.DeclaredBy)
+		}
+	}
+
+	e, err = Explain(baseDir, packagesDir, cfg, filepath.ToSlash(path), true)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if e.Winner != localOrigin {
+		t.Errorf("expected local file to win with ignored nested strategies, got %q", e.Winner)
+	}
+}
@@ -0,0 +1,40 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var errLegacyLayout = errors.New("legacy package layout")
+
+// normalizedPath returns the destination of a package path moved by adjustDestinationPath. Paths which
+// aren't moved or follow the move of their parent directory are reported as not moved.
+func normalizedPath(p string, isModern bool) (string, bool) {
+	to := adjustDestinationPath(p, isModern)
+	if to == p {
+		return "", false
+	}
+
+	if dir := path.Dir(p); dir != "." && path.Join(adjustDestinationPath(dir, isModern), path.Base(p)) == to {
+		return "", false
+	}
+
+	return to, true
+}
+
+// legacyLayoutError returns the error of normalizations of a package rejected by --reject-legacy-layout.
+func legacyLayoutError(pkgName string, normalized []Normalization) error {
+	var moves []string
+	for _, n := range normalized {
+		if n.Package == pkgName {
+			moves = append(moves, fmt.Sprintf("%s moved to %s", n.From, n.To))
+		}
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w of %s, migrate it to the src/{layer}/{type}/{component} layout:\n  %s", errLegacyLayout, pkgName, strings.Join(moves, "\n  "))
+}
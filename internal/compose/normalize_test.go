@@ -0,0 +1,41 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizedPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		isModern bool
+		to       string
+		moved    bool
+	}{
+		{"platform", false, "src/platform", true},
+		{"platform/services", false, "", false},
+		{"platform/services/roles", false, "src/platform/services", true},
+		{"platform/services/roles/nginx", false, "", false},
+		{"platform/services/roles/nginx/tasks/main.yaml", false, "", false},
+		{"platform/group_vars", false, "src/platform/variables", true},
+		{"platform/group_vars/all.yaml", false, "", false},
+		{"README.md", false, "", false},
+		{"src/platform/services/roles", true, "src/platform/services", true},
+		{"src/platform/services/nginx", true, "", false},
+	}
+
+	for _, tt := range tests {
+		to, moved := normalizedPath(tt.path, tt.isModern)
+		if to != tt.to || moved != tt.moved {
+			t.Errorf("%s: expected %q, %t, got %q, %t", tt.path, tt.to, tt.moved, to, moved)
+		}
+	}
+
+	normalized := []Normalization{{Package: "plasma-core", From: "platform", To: "src/platform"}}
+	if err := legacyLayoutError("plasma-core", normalized); !errors.Is(err, errLegacyLayout) {
+		t.Errorf("expected legacy layout error, got %v", err)
+	}
+	if err := legacyLayoutError("plasma-work", normalized); err != nil {
+		t.Errorf("expected no error for package without normalizations, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-model/internal/auth"
+	"github.com/plasmash/plasmactl-model/internal/release"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// OutdatedPackage stores remote changes of a dependency of the composition.
+// Changed is set when the downloaded checkout is behind its ref on remote: new commits of a branch
+// or a moved tag. Latest is the newest version tag above a version ref.
+type OutdatedPackage struct {
+	Name       string `json:"name"`
+	Ref        string `json:"ref"`
+	Type       string `json:"type"`
+	Downloaded bool   `json:"downloaded"`
+	Changed    bool   `json:"changed"`
+	Latest     string `json:"latest,omitempty"`
+	Frozen     bool   `json:"frozen,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IsOutdated reports if newer commits or tags are available.
+func (p OutdatedPackage) IsOutdated() bool {
+	return p.Changed || p.Latest != ""
+}
+
+// OutdatedChecker checks dependencies of compose.yaml against their remotes without composing.
+type OutdatedChecker struct {
+	action.WithLogger
+	action.WithTerm
+
+	// Keyring is used to authenticate when fetching remotes, optional.
+	Keyring keyring.Keyring
+	// Layout locates downloaded packages, empty paths are defaults.
+	Layout model.Layout
+}
+
+// Check returns the status of every dependency of compose.yaml in dir, refs resolved by compose.lock are checked.
+// Downloaded packages are compared with their ref on remote by Downloader.EnsureLatest, without updating the checkout.
+// Failed checks are stored in Error of the package, other packages are still checked.
+func (o *OutdatedChecker) Check(dir string) ([]OutdatedPackage, error) {
+	cfg, err := Lookup(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := LoadVersionLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	lock.Apply(cfg)
+
+	kw := &keyringWrapper{keyringService: o.Keyring, envHosts: compositionHosts(cfg)}
+	if kw.auth, err = auth.Load(dir); err != nil {
+		return nil, err
+	}
+	kw.SetLogger(o.Log())
+	kw.SetTerm(o.Term())
+
+	dm := CreateDownloadManager(kw, nil)
+	checkouts := LocateCheckouts(cfg, lock, filepath.Join(dir, o.Layout.WithDefaults().PackagesDir))
+
+	result := make([]OutdatedPackage, 0, len(cfg.Dependencies))
+	for _, d := range cfg.Dependencies {
+		pkg := d.ToPackage(d.Name)
+		status := OutdatedPackage{Name: pkg.GetName(), Ref: pkg.GetTarget(), Type: pkg.GetType(), Frozen: pkg.Frozen}
+
+		downloadPath := checkouts.CheckoutDir(pkg)
+		status.Downloaded = hasEntries(downloadPath)
+		if status.Downloaded {
+			downloader := dm.getDownloaderForPackage(pkg.GetType())
+			if gd, ok := downloader.(*gitDownloader); ok {
+				gd.checkOnly = true
+			}
+
+			isLatest, errCheck := downloader.EnsureLatest(pkg, downloadPath)
+			if errCheck != nil {
+				o.Log().Debug("failed to check package", "package", pkg.GetName(), "err", errCheck)
+				status.Error = errCheck.Error()
+			}
+			status.Changed = errCheck == nil && !isLatest
+		}
+
+		if status.Latest, err = dm.resolver.newerVersion(pkg); err != nil {
+			o.Log().Debug("failed to list versions", "package", pkg.GetName(), "err", err)
+			if status.Error == "" {
+				status.Error = err.Error()
+			}
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// newerVersion returns the newest version tag above the version ref of a git package, prereleases are considered
+// only if the ref is a prerelease. Empty string is returned for other refs.
+func (r *versionResolver) newerVersion(pkg *Package) (string, error) {
+	current, err := release.ParseVersion(pkg.GetRef())
+	if err != nil || pkg.GetType() != GitType {
+		return "", nil
+	}
+
+	tags, err := r.versionTags(pkg)
+	if err != nil {
+		return "", err
+	}
+
+	var newest string
+	best := current
+	for _, tag := range tags {
+		v, errParse := release.ParseVersion(tag)
+		if errParse != nil || (v.Prerelease != "" && current.Prerelease == "") {
+			continue
+		}
+		if v.Compare(best) > 0 {
+			newest, best = tag, v
+		}
+	}
+
+	return newest, nil
+}
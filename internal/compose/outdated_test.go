@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestOutdatedChecker(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	commit := func(content string, tags ...string) plumbing.Hash {
+		if err := os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add("file.txt"); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		hash, err := wt.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		for _, tag := range tags {
+			if _, err = repo.CreateTag(tag, hash, nil); err != nil {
+				t.Fatalf("failed to create tag: %v", err)
+			}
+		}
+		return hash
+	}
+	commit("v1", "v1.0.0")
+	commit("v1.1", "v1.1.0", "v2.0.0-rc.1")
+
+	dir := t.TempDir()
+	content := fmt.Sprintf("name: test\ndependencies:\n"+
+		"  - name: tagged\n    source:\n      type: git\n      url: %[1]s\n      ref: v1.0.0\n"+
+		"  - name: branch\n    source:\n      type: git\n      url: %[1]s\n      ref: master\n", repoDir)
+	if err = os.WriteFile(filepath.Join(dir, composeFile), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write compose.yaml: %v", err)
+	}
+
+	kw := &keyringWrapper{}
+	kw.SetTerm(launchr.Term())
+	g := &gitDownloader{k: kw}
+	pkg := &Package{Name: "branch", Source: Source{Type: GitType, URL: repoDir, Ref: "master"}}
+	if err = g.Download(context.Background(), pkg, filepath.Join(dir, model.PackagesDir, "branch", "master")); err != nil {
+		t.Fatalf("failed to download package: %v", err)
+	}
+
+	checker := &OutdatedChecker{}
+	checker.SetLogger(launchr.Log())
+	checker.SetTerm(launchr.Term())
+
+	check := func() map[string]OutdatedPackage {
+		packages, err := checker.Check(dir)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		result := make(map[string]OutdatedPackage)
+		for _, p := range packages {
+			result[p.Name] = p
+		}
+		return result
+	}
+
+	packages := check()
+	tagged := packages["tagged"]
+	if tagged.Latest != "v1.1.0" || tagged.Downloaded || tagged.Changed {
+		t.Errorf("expected newer stable tag of not downloaded package, got %+v", tagged)
+	}
+	if branch := packages["branch"]; branch.IsOutdated() || !branch.Downloaded || branch.Error != "" {
+		t.Errorf("expected downloaded branch to be up to date, got %+v", branch)
+	}
+
+	commit("v1.2")
+	if branch := check()["branch"]; !branch.Changed {
+		t.Errorf("expected new commits of the branch, got %+v", branch)
+	}
+
+	// The checkout isn't updated by the check.
+	data, err := os.ReadFile(filepath.Join(dir, model.PackagesDir, "branch", "master", "file.txt"))
+	if err != nil || string(data) != "v1.1" {
+		t.Errorf("expected checkout to be kept, got %q, %v", data, err)
+	}
+}
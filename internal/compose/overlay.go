@@ -0,0 +1,176 @@
+package compose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const overlayTemplateSuffix = ".tmpl"
+
+var errInvalidOverlay = errors.New("invalid overlay")
+
+// overlayTemplateData is passed to overlay templates.
+type overlayTemplateData struct {
+	Name string
+	Vars map[string]string
+	Env  map[string]string
+}
+
+// selectOverlays returns overlays to apply. All overlays are applied if names are empty,
+// otherwise only the named ones in the order of declaration.
+func selectOverlays(overlays []Overlay, names []string) ([]Overlay, error) {
+	declared := make(map[string]bool, len(overlays))
+	for _, o := range overlays {
+		if o.Path == "" || !filepath.IsLocal(o.Path) {
+			return nil, fmt.Errorf("%w: path %q must be relative to the model directory", errInvalidOverlay, o.Path)
+		}
+		declared[o.GetName()] = true
+	}
+
+	if len(names) == 0 {
+		return overlays, nil
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		if !declared[n] {
+			return nil, fmt.Errorf("%w: overlay %q is not declared in %s", errInvalidOverlay, n, composeFile)
+		}
+		wanted[n] = true
+	}
+
+	var selected []Overlay
+	for _, o := range overlays {
+		if wanted[o.GetName()] {
+			selected = append(selected, o)
+		}
+	}
+
+	return selected, nil
+}
+
+// isOverlayPath checks if path relative to the model directory is one of the overlay directories.
+func isOverlayPath(path string, overlays []Overlay) bool {
+	for _, o := range overlays {
+		if filepath.Clean(o.Path) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyOverlay copies overlay directory over targetDir replacing existing files.
+// It returns number of files written.
+func applyOverlay(baseDir, targetDir string, o Overlay, perms *permissionPolicy) (int, error) {
+	overlayDir := filepath.Join(baseDir, o.Path)
+	info, err := os.Stat(overlayDir)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", errInvalidOverlay, o.GetName(), err)
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("%w: %s is not a directory", errInvalidOverlay, o.Path)
+	}
+
+	data := overlayTemplateData{Name: o.GetName(), Vars: o.Vars, Env: environ()}
+
+	files := 0
+	err = filepath.WalkDir(overlayDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(overlayDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		destPath := filepath.Join(targetDir, rel)
+		switch finfo.Mode() & os.ModeType {
+		case os.ModeDir:
+			if err = createDir(destPath, dirPermissions); err != nil {
+				return err
+			}
+			return os.Chmod(destPath, perms.dirMode(filepath.ToSlash(rel)))
+		case os.ModeSymlink:
+			if err = os.RemoveAll(destPath); err != nil {
+				return err
+			}
+			files++
+			return lcopy(path, destPath)
+		}
+
+		if o.Template && strings.HasSuffix(rel, overlayTemplateSuffix) {
+			rel = strings.TrimSuffix(rel, overlayTemplateSuffix)
+			destPath = filepath.Join(targetDir, rel)
+			if err = os.RemoveAll(destPath); err != nil {
+				return err
+			}
+			if err = renderOverlayTemplate(path, destPath, perms.fileMode(filepath.ToSlash(rel), finfo.Mode()), data); err != nil {
+				return fmt.Errorf("overlay %s: %w", o.GetName(), err)
+			}
+			files++
+			return nil
+		}
+
+		if err = os.RemoveAll(destPath); err != nil {
+			return err
+		}
+		if _, err = fcopy(path, destPath); err != nil {
+			return err
+		}
+		files++
+
+		return os.Chmod(destPath, perms.fileMode(filepath.ToSlash(rel), finfo.Mode()))
+	})
+
+	return files, err
+}
+
+func renderOverlayTemplate(src, dst string, perm os.FileMode, data overlayTemplateData) error {
+	content, err := os.ReadFile(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+
+	tpl, err := template.New(filepath.Base(src)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(dst, buf.Bytes(), perm); err != nil {
+		return err
+	}
+
+	return os.Chmod(dst, perm)
+}
+
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,82 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectOverlays(t *testing.T) {
+	overlays := []Overlay{
+		{Path: "overlays/production"},
+		{Name: "stage", Path: "overlays/staging"},
+	}
+
+	all, err := selectOverlays(overlays, nil)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected all overlays, got %v, %v", all, err)
+	}
+
+	selected, err := selectOverlays(overlays, []string{"stage"})
+	if err != nil || len(selected) != 1 || selected[0].Path != "overlays/staging" {
+		t.Fatalf("expected staging overlay, got %v, %v", selected, err)
+	}
+
+	if _, err = selectOverlays(overlays, []string{"unknown"}); err == nil {
+		t.Error("expected error for undeclared overlay")
+	}
+
+	if _, err = selectOverlays([]Overlay{{Path: "../outside"}}, nil); err == nil {
+		t.Error("expected error for overlay outside of model directory")
+	}
+}
+
+func TestApplyOverlay(t *testing.T) {
+	baseDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	overlayDir := filepath.Join(baseDir, "overlays", "production")
+	writeFile(filepath.Join(overlayDir, "src", "app", "config.yaml"), "replicas: 3\n")
+	writeFile(filepath.Join(overlayDir, "src", "app", "env.yaml.tmpl"), "name: {{ .Name }}\nregion: {{ .Vars.region }}\n")
+	writeFile(filepath.Join(targetDir, "src", "app", "config.yaml"), "replicas: 1\n")
+	writeFile(filepath.Join(targetDir, "src", "app", "other.yaml"), "keep: true\n")
+
+	o := Overlay{Path: "overlays/production", Template: true, Vars: map[string]string{"region": "eu"}}
+	files, err := applyOverlay(baseDir, targetDir, o, &permissionPolicy{policy: PermissionsPreserve, dir: dirPermissions})
+	if err != nil {
+		t.Fatalf("applyOverlay failed: %v", err)
+	}
+	if files != 2 {
+		t.Errorf("expected 2 files written, got %d", files)
+	}
+
+	expected := map[string]string{
+		"src/app/config.yaml": "replicas: 3\n",
+		"src/app/env.yaml":    "name: production\nregion: eu\n",
+		"src/app/other.yaml":  "keep: true\n",
+	}
+	for path, content := range expected {
+		got, err := os.ReadFile(filepath.Join(targetDir, path))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected %q, got %q", path, content, got)
+		}
+	}
+
+	o.Vars = nil
+	if _, err = applyOverlay(baseDir, targetDir, o, &permissionPolicy{policy: PermissionsPreserve, dir: dirPermissions}); err == nil {
+		t.Error("expected error for missing template variable")
+	}
+}
@@ -0,0 +1,564 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Partial merges
+//
+// Every merge records in model.ComposeMergeFile how paths of its result were resolved: candidates offering a path
+// in merge order and the winner, along with states of packages and a fingerprint of the merge configuration.
+// If the configuration and the relative merge order of packages didn't change, the next compose merges again only
+// paths which changed packages offered before or offer now and local files added, removed or modified since.
+// Their candidates are replayed in merge order, candidates of unchanged packages come from the manifest.
+// Other paths keep their winner and are linked from the previous merge result instead of being copied.
+
+const mergeManifestVersion = 1
+
+// mergeSource is a candidate of a merged path. Local candidates keep the mode and modification time they were
+// merged with, changes of packages are tracked by their states.
+type mergeSource struct {
+	From    string      `json:"from"`
+	Source  string      `json:"source"`
+	Mode    fs.FileMode `json:"mode,omitempty"`
+	ModTime int64       `json:"mtime,omitempty"`
+}
+
+func newMergeSource(e *fsEntry) mergeSource {
+	s := mergeSource{From: e.From, Source: e.SrcPath}
+	if e.From == localOrigin {
+		s.Mode, s.ModTime = e.Entry.mode, e.Entry.modTime
+	}
+
+	return s
+}
+
+// mergeRecord is a merged path of the manifest.
+type mergeRecord struct {
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+	// Sources are the winner and files deep-merged into it by merge-yaml.
+	Sources []mergeSource `json:"sources"`
+	// Candidates are recorded once the path is merged, a candidate skipped before can't win the path
+	// whatever is merged before it.
+	Candidates []mergeSource `json:"candidates"`
+	ToLocal    int           `json:"to_local,omitempty"`
+	ToPackage  int           `json:"to_package,omitempty"`
+	Merged     int           `json:"merged,omitempty"`
+}
+
+// mergeManifest records the last merge, see model.ComposeMergeFile.
+type mergeManifest struct {
+	Version     int    `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+	// Order lists packages in merge order, States stores their declarations and content, see packageStates.
+	Order      []string          `json:"order"`
+	States     map[string]string `json:"states"`
+	Normalized []Normalization   `json:"normalized,omitempty"`
+	Records    []*mergeRecord    `json:"records"`
+}
+
+// loadMergeManifest reads the manifest of the last merge from baseDir, nil if there is none.
+func loadMergeManifest(baseDir string) (*mergeManifest, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, model.ComposeMergeFile)) //nolint:gosec // path is built from base dir
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m mergeManifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// saveMergeManifest writes the manifest of the merge result to baseDir.
+func saveMergeManifest(baseDir string, m *mergeManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(baseDir, model.ComposeMergeFile), data, os.FileMode(composePermissions))
+}
+
+// removeMergeManifest removes the manifest of the previous merge result before it is replaced.
+func removeMergeManifest(baseDir string) error {
+	err := os.Remove(filepath.Join(baseDir, model.ComposeMergeFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// mergeRecords collects records of merged paths while merging.
+type mergeRecords map[string]*mergeRecord
+
+// add records the candidate of path, it is called once the path is merged.
+func (r mergeRecords) add(path string, entry *fsEntry) {
+	rec, ok := r[path]
+	if !ok {
+		rec = &mergeRecord{Path: path}
+		r[path] = rec
+	}
+	rec.Candidates = append(rec.Candidates, newMergeSource(entry))
+}
+
+// resolved records how a conflict of path was resolved.
+func (r mergeRecords) resolved(path string, resolve mergeConflictResolve) {
+	rec, ok := r[path]
+	if !ok {
+		return
+	}
+
+	switch resolve {
+	case resolveToLocal:
+		rec.ToLocal++
+	case resolveToPackage:
+		rec.ToPackage++
+	case resolveMerged:
+		rec.Merged++
+	}
+}
+
+// list returns records of merged entries with their winners, records of reused entries are kept as they are.
+func (r mergeRecords) list(entries []*fsEntry) []*mergeRecord {
+	result := make([]*mergeRecord, 0, len(entries))
+	for _, e := range entries {
+		rec, ok := r[e.DstPath]
+		if !ok {
+			rec = &mergeRecord{Path: e.DstPath, Candidates: []mergeSource{newMergeSource(e)}}
+		}
+		if !e.Entry.reused {
+			rec.Mode = e.Entry.Mode()
+			rec.Sources = nil
+			for s := e; s != nil; s = s.mergedYAML {
+				rec.Sources = append(rec.Sources, newMergeSource(s))
+			}
+		}
+		result = append(result, rec)
+	}
+
+	return result
+}
+
+// mergeFingerprint returns the hash of the merge configuration: compose.yaml without dependencies, merge options,
+// the layout of the domain repo and overlays applied. Packages are merged partially only if it didn't change.
+func (b *Builder) mergeFingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %s\n", mergeManifestVersion, pluginVersion())
+	if b.compose != nil {
+		cfg := *b.compose
+		cfg.Dependencies = nil
+		data, _ := yaml.Marshal(&cfg)
+		h.Write(data)
+	}
+	fmt.Fprintf(h, "%t %t %t %t %s %v\n", b.skipNotVersioned, b.includeStaged, b.respectGitignore, b.rejectLegacy, b.symlinks, b.permissions)
+	fmt.Fprintf(h, "modern: %t\n", hasModernLayout(b.platformDir))
+
+	// Files removed from overlays would be left in reused paths.
+	for _, o := range b.applyOverlays {
+		fmt.Fprintf(h, "overlay %s\n", o.GetName())
+		overlayDir := filepath.Join(b.platformDir, o.Path)
+		_ = filepath.WalkDir(overlayDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				rel, _ := filepath.Rel(overlayDir, path)
+				fmt.Fprintf(h, "%s %s %d %d\n", filepath.ToSlash(rel), info.Mode(), info.ModTime().UnixNano(), info.Size())
+			}
+			return nil
+		})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// packageStates returns states of packages: their declaration, directory and the tree hash recorded in compose.lock.
+// Packages without a recorded hash have an empty state, they are always merged again.
+func (b *Builder) packageStates(order []string, packagesMap map[string]*Package, pkgPaths map[string]string) map[string]string {
+	states := make(map[string]string, len(order))
+	for _, name := range order {
+		pkg, ok := packagesMap[name]
+		if !ok {
+			states[name] = ""
+			continue
+		}
+
+		var hash string
+		for _, pi := range b.integrity {
+			if pi.Name == name && pi.Ref == pkg.GetTarget() {
+				hash = pi.Commit + " " + pi.Hash
+			}
+		}
+		if hash == "" {
+			states[name] = ""
+			continue
+		}
+
+		h := sha256.New()
+		data, _ := yaml.Marshal(pkg)
+		h.Write(data)
+		fmt.Fprintf(h, "declared_by: %s\npath: %s\nhash: %s\n", pkg.DeclaredBy, filepath.ToSlash(pkgPaths[name]), hash)
+		states[name] = treeHashPrefix + hex.EncodeToString(h.Sum(nil))
+	}
+
+	return states
+}
+
+// sameRelativeOrder reports if packages of both merge orders are merged in the same order relative to each other.
+func sameRelativeOrder(a, b []string) bool {
+	common := func(order, other []string) []string {
+		return slices.DeleteFunc(slices.Clone(order), func(name string) bool {
+			return !slices.Contains(other, name)
+		})
+	}
+
+	return slices.Equal(common(a, b), common(b, a))
+}
+
+// partialMerge is the plan of a merge of paths changed since the previous merge.
+type partialMerge struct {
+	previous *mergeManifest
+	// conflicts of the previous merge are kept for paths which aren't merged again.
+	conflicts *ConflictReport
+	order     []string
+	pkgPaths  map[string]string
+	// changed packages are walked again, packages removed since the previous merge included.
+	changed      map[string]bool
+	localChanged bool
+	// dirty paths are merged again, candidates holds their package candidates in merge order.
+	dirty      map[string]bool
+	candidates map[string][]*fsEntry
+	normalized []Normalization
+}
+
+// planPartialMerge returns the plan of a partial merge, nil if all paths have to be merged. Changed packages
+// are walked, candidates of unchanged ones are read from the manifest of the previous merge.
+func (b *Builder) planPartialMerge(fingerprint string, states map[string]string, order []string, packagesMap map[string]*Package, pkgPaths map[string]string, local map[string]*fsEntry) *partialMerge {
+	if b.fullMerge {
+		return nil
+	}
+
+	previous, err := loadMergeManifest(b.platformDir)
+	if err != nil {
+		b.Log().Warn("failed to read merge manifest, merging all packages", "error", err)
+		return nil
+	}
+	if previous == nil {
+		return nil
+	}
+
+	fullMerge := func(reason string) *partialMerge {
+		b.Term().Info().Printfln("Merging all packages, %s", reason)
+		return nil
+	}
+	switch {
+	case b.conflictUnit() == ConflictUnitComponent:
+		// Components are selected as a whole, paths can't be merged one by one.
+		return nil
+	case previous.Version != mergeManifestVersion || previous.Fingerprint != fingerprint:
+		return fullMerge("the merge configuration changed")
+	case !exists(b.previousDir):
+		return fullMerge("the previous merge result is missing")
+	case !sameRelativeOrder(previous.Order, order):
+		return fullMerge("the merge order of packages changed")
+	}
+
+	conflicts, err := LoadConflicts(b.platformDir)
+	if err != nil {
+		return fullMerge("conflicts of the previous merge can't be read")
+	}
+
+	p := &partialMerge{
+		previous:   previous,
+		conflicts:  conflicts,
+		order:      order,
+		pkgPaths:   pkgPaths,
+		changed:    make(map[string]bool),
+		dirty:      make(map[string]bool),
+		candidates: make(map[string][]*fsEntry),
+	}
+	for _, name := range order {
+		if state, ok := previous.States[name]; !ok || state == "" || state != states[name] {
+			p.changed[name] = true
+		}
+	}
+	for _, name := range previous.Order {
+		if _, ok := states[name]; !ok {
+			p.changed[name] = true
+		}
+	}
+
+	offered := make(map[string][]*fsEntry)
+	for _, name := range order {
+		if !p.changed[name] {
+			continue
+		}
+
+		var exclude []string
+		if pkg, ok := packagesMap[name]; ok {
+			exclude = pkg.Source.Exclude
+		}
+		err = b.walkPackage(name, pkgPaths[name], exclude, func(_ fs.FS, isModern bool, entry *fsEntry) (bool, error) {
+			if to, moved := normalizedPath(entry.SrcPath, isModern); moved {
+				p.normalized = append(p.normalized, Normalization{Package: name, From: entry.SrcPath, To: to})
+			}
+			offered[entry.DstPath] = append(offered[entry.DstPath], entry)
+			p.dirty[entry.DstPath] = true
+			return false, nil
+		})
+		if err != nil {
+			return fullMerge(fmt.Sprintf("%s can't be read: %v", name, err))
+		}
+	}
+
+	var globs []string
+	if b.compose != nil && b.compose.Substitution != nil {
+		globs = b.compose.Substitution.Paths
+	}
+
+	// Substituted files are written over, they are always merged again.
+	recorded := make(map[string]*mergeRecord, len(previous.Records))
+	for _, rec := range previous.Records {
+		recorded[rec.Path] = rec
+		if p.changedRecord(rec, local[rec.Path]) || matchAnyGlob(rec.Path, globs) {
+			p.dirty[rec.Path] = true
+		}
+	}
+	for path := range local {
+		if _, ok := recorded[path]; !ok {
+			p.dirty[path] = true
+			p.localChanged = true
+		}
+	}
+
+	index := make(map[string]int, len(order))
+	for i, name := range order {
+		index[name] = i
+	}
+	for path := range p.dirty {
+		var candidates []*fsEntry
+		if rec, ok := recorded[path]; ok {
+			for _, c := range rec.Candidates {
+				if c.From == localOrigin || p.changed[c.From] {
+					continue
+				}
+				info, err := os.Lstat(filepath.Join(pkgPaths[c.From], c.Source))
+				if err != nil {
+					return fullMerge(fmt.Sprintf("%s of %s can't be read: %v", c.Source, c.From, err))
+				}
+				candidates = append(candidates, &fsEntry{Prefix: pkgPaths[c.From], SrcPath: c.Source, DstPath: path, Entry: newFileMeta(info), From: c.From})
+			}
+		}
+		candidates = append(candidates, offered[path]...)
+		slices.SortStableFunc(candidates, func(a, b *fsEntry) int {
+			return index[a.From] - index[b.From]
+		})
+		p.candidates[path] = candidates
+	}
+
+	return p
+}
+
+// changedRecord reports if candidates of the recorded path changed: a package offering it changed, or the local
+// file was added, removed or modified since the previous merge.
+func (p *partialMerge) changedRecord(rec *mergeRecord, local *fsEntry) bool {
+	if len(rec.Sources) == 0 {
+		return true
+	}
+
+	hasLocal := false
+	for _, c := range rec.Candidates {
+		if c.From != localOrigin {
+			if p.changed[c.From] {
+				return true
+			}
+			continue
+		}
+
+		hasLocal = true
+		if local == nil || local.Entry.mode != c.Mode || local.Entry.modTime != c.ModTime {
+			p.localChanged = true
+			return true
+		}
+	}
+
+	if !hasLocal && local != nil {
+		p.localChanged = true
+		return true
+	}
+
+	return false
+}
+
+// mergeChanged merges dirty paths of the partial merge, other paths keep the winner of the previous merge.
+// It returns merged entries sorted by path and their records.
+func (b *Builder) mergeChanged(p *partialMerge, local map[string]*fsEntry, packagesMap map[string]*Package, ps map[string][]*mergeStrategy, cr *conflictResolver, conflicts *ConflictReport) ([]*fsEntry, mergeRecords, error) {
+	partial := &PartialMerge{Merged: len(p.dirty)}
+	for _, name := range p.order {
+		if p.changed[name] {
+			partial.Changed = append(partial.Changed, name)
+		}
+	}
+	if p.localChanged {
+		partial.Changed = append([]string{localOrigin}, partial.Changed...)
+	}
+	b.summary.Partial = partial
+	if len(partial.Changed) == 0 {
+		b.Term().Printfln("  Packages are unchanged since the previous merge")
+	} else {
+		b.Term().Info().Printfln("Merging %d paths of changed %s", len(p.dirty), strings.Join(partial.Changed, ", "))
+	}
+
+	records := make(mergeRecords)
+	var entriesTree []*fsEntry
+	for _, c := range p.conflicts.Conflicts {
+		if !p.dirty[c.Path] {
+			conflicts.keep(c)
+		}
+	}
+	for _, rec := range p.previous.Records {
+		if p.dirty[rec.Path] {
+			continue
+		}
+		records[rec.Path] = rec
+		entriesTree = append(entriesTree, b.reusedEntry(rec, p.pkgPaths))
+		b.summary.ConflictsToLocal += rec.ToLocal
+		b.summary.ConflictsToPackage += rec.ToPackage
+		b.summary.ConflictsMerged += rec.Merged
+	}
+
+	for _, n := range p.previous.Normalized {
+		if !p.changed[n.Package] {
+			b.summary.addNormalized(n)
+		}
+	}
+	for _, n := range p.normalized {
+		b.summary.addNormalized(n)
+	}
+	if b.rejectLegacy {
+		for _, name := range partial.Changed {
+			if err := legacyLayoutError(name, p.normalized); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(p.dirty)) {
+		entries := make(map[string]*fsEntry, 1)
+		if e, ok := local[path]; ok {
+			entries[path] = e
+			records.add(path, e)
+		}
+
+		for _, entry := range p.candidates[path] {
+			var previous string
+			if existing, found := entries[path]; found {
+				previous = existing.From
+			}
+
+			_, conflictReslv, ms := addStrategyEntries(ps[entry.From], nil, entries, entry, path, cr)
+			if _, merged := entries[path]; merged {
+				records.add(path, entry)
+			}
+
+			if !entry.Entry.IsDir() {
+				b.summary.addConflict(conflictReslv)
+				records.resolved(path, conflictReslv)
+				if conflictReslv != noConflict {
+					conflicts.add(path, previous, entry.From, entries[path], ms)
+				}
+				if b.logConflicts {
+					b.logConflictResolve(conflictReslv, path, entry.From, entries[path])
+				}
+			}
+		}
+
+		if e, ok := entries[path]; ok {
+			entriesTree = append(entriesTree, e)
+		}
+	}
+
+	for _, name := range partial.Changed {
+		if pkg, ok := packagesMap[name]; ok {
+			b.Term().Printfln("  %s %s", output.Get().Check, pkg.GetIdentifier())
+		}
+	}
+
+	// Parent directories are created before their content.
+	slices.SortFunc(entriesTree, func(a, b *fsEntry) int {
+		return strings.Compare(a.DstPath, b.DstPath)
+	})
+
+	return entriesTree, records, nil
+}
+
+// reusedEntry returns the entry of a path keeping the winner of the previous merge, files deep-merged into it
+// are chained like by merge-yaml.
+func (b *Builder) reusedEntry(rec *mergeRecord, pkgPaths map[string]string) *fsEntry {
+	var first, last *fsEntry
+	for _, s := range rec.Sources {
+		prefix := b.platformDir
+		if s.From != localOrigin {
+			prefix = pkgPaths[s.From]
+		}
+
+		e := &fsEntry{Prefix: prefix, SrcPath: s.Source, DstPath: rec.Path, From: s.From, Entry: fileMeta{mode: rec.Mode, reused: true}}
+		if first == nil {
+			first = e
+		} else {
+			last.mergedYAML = e
+		}
+		last = e
+	}
+
+	return first
+}
+
+// reuse links the path of the previous merge result into the merge, it reports false if the path has to be
+// copied from its sources, e.g. directories or paths removed from the previous result.
+func (b *Builder) reuse(item *fsEntry, destPath string) bool {
+	previous := filepath.Join(b.previousDir, item.DstPath)
+	info, err := os.Lstat(previous)
+	if err != nil || info.Mode().Type() != item.Entry.Mode().Type() {
+		return false
+	}
+
+	switch item.Entry.Mode().Type() {
+	case fs.ModeSymlink:
+		err = lcopy(previous, destPath)
+	case 0:
+		err = os.Link(previous, destPath)
+	default:
+		return false
+	}
+	if err != nil {
+		b.Log().Debug("failed to reuse merged path, copying it", "path", item.DstPath, "error", err)
+		return false
+	}
+	b.summary.Partial.Reused++
+
+	return true
+}
@@ -0,0 +1,175 @@
+package compose
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestPartialMerge(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, model.MergedDir, filepath.FromSlash(path)))
+		return string(data)
+	}
+
+	const services = "src/platform/services/"
+	pkgA := model.PackagesDir + "/a/v1/"
+	pkgB := model.PackagesDir + "/b/v1/"
+	write(services+"local/main.yaml", "local")
+	write(services+"shared/main.yaml", "local")
+	write(pkgA+services+"a/main.yaml", "a")
+	write(pkgA+services+"shared/main.yaml", "a")
+	write(pkgA+services+"ab/main.yaml", "a")
+	write(pkgB+services+"b/main.yaml", "b")
+	write(pkgB+services+"ab/main.yaml", "b")
+
+	packages := []*Package{
+		{Name: "a", Source: model.Source{Ref: "v1"}},
+		{Name: "b", Source: model.Source{Ref: "v1"}},
+	}
+	integrity := []PackageIntegrity{
+		{Name: "a", Ref: "v1", Hash: "sha256:a1"},
+		{Name: "b", Ref: "v1", Hash: "sha256:b1"},
+	}
+	perms, err := newPermissionPolicy("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compose := func(full bool) *Summary {
+		t.Helper()
+		b := &Builder{
+			platformDir: dir,
+			targetDir:   filepath.Join(dir, model.MergedStagingDir),
+			sourceDir:   filepath.Join(dir, model.PackagesDir),
+			packages:    packages,
+			summary:     &Summary{},
+			compose:     &Composition{},
+			permissions: perms,
+			fullMerge:   full,
+			previousDir: filepath.Join(dir, model.MergedDir),
+			integrity:   integrity,
+		}
+		b.SetLogger(launchr.Log())
+		b.SetTerm(launchr.Term())
+		if err := b.build(context.Background()); err != nil {
+			t.Fatalf("build failed: %v", err)
+		}
+		if err := os.RemoveAll(b.previousDir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(b.targetDir, b.previousDir); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveMergeManifest(dir, b.manifest); err != nil {
+			t.Fatal(err)
+		}
+		return b.summary
+	}
+	tree := func() []string {
+		var paths []string
+		root := filepath.Join(dir, model.MergedDir)
+		_ = filepath.WalkDir(root, func(path string, _ fs.DirEntry, _ error) error {
+			rel, _ := filepath.Rel(root, path)
+			paths = append(paths, filepath.ToSlash(rel)+"="+read(rel))
+			return nil
+		})
+		return paths
+	}
+	// Partial merges must end up with the result of a full merge.
+	assertFull := func(partial *Summary) {
+		t.Helper()
+		result := tree()
+		conflicts, _ := LoadConflicts(dir)
+		full := compose(true)
+		if !slices.Equal(result, tree()) {
+			t.Errorf("partial merge result differs from full merge:\n%v\n%v", result, tree())
+		}
+		if partial.ConflictsToLocal != full.ConflictsToLocal || partial.ConflictsToPackage != full.ConflictsToPackage {
+			t.Errorf("expected conflicts of full merge %+v, got %+v", full, partial)
+		}
+		if fullConflicts, _ := LoadConflicts(dir); len(conflicts.Conflicts) != len(fullConflicts.Conflicts) {
+			t.Errorf("expected conflicts report of full merge %+v, got %+v", fullConflicts, conflicts)
+		}
+	}
+
+	if s := compose(false); s.Partial != nil || read(services+"shared/main.yaml") != "local" || read(services+"ab/main.yaml") != "a" {
+		t.Fatalf("unexpected first merge %+v", s.Partial)
+	}
+
+	// Package b changed: its paths are merged again, a keeps ab/ merged before b.
+	if err = os.Remove(filepath.Join(dir, pkgB+services+"b/main.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	write(pkgB+services+"b/new.yaml", "b")
+	write(pkgB+services+"ab/main.yaml", "b2")
+	integrity[1].Hash = "sha256:b2"
+	s := compose(false)
+	if s.Partial == nil || !slices.Equal(s.Partial.Changed, []string{"b"}) || s.Partial.Reused == 0 {
+		t.Fatalf("expected partial merge of b, got %+v", s.Partial)
+	}
+	if read(services+"b/new.yaml") != "b" || read(services+"b/main.yaml") != "" || read(services+"ab/main.yaml") != "a" {
+		t.Errorf("unexpected partial merge result %v", tree())
+	}
+	assertFull(s)
+
+	// Package a doesn't offer ab/ anymore, b now wins it.
+	if err = os.RemoveAll(filepath.Join(dir, pkgA+services+"ab")); err != nil {
+		t.Fatal(err)
+	}
+	integrity[0].Hash = "sha256:a2"
+	if s = compose(false); s.Partial == nil || !slices.Equal(s.Partial.Changed, []string{"a"}) || read(services+"ab/main.yaml") != "b2" {
+		t.Errorf("expected b to win ab/ after partial merge of a, got %+v %v", s.Partial, tree())
+	}
+	assertFull(s)
+
+	// Local file modified, packages are unchanged.
+	write(services+"local/main.yaml", "local2")
+	if err = os.Chtimes(filepath.Join(dir, services+"local/main.yaml"), time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if s = compose(false); s.Partial == nil || !slices.Equal(s.Partial.Changed, []string{localOrigin}) || read(services+"local/main.yaml") != "local2" {
+		t.Errorf("expected partial merge of local file, got %+v", s.Partial)
+	}
+	assertFull(s)
+
+	// Configuration changes merge all paths.
+	packages = append([]*Package{{Name: "c", Source: model.Source{Ref: "v1"}}}, packages...)
+	write(model.PackagesDir+"/c/v1/"+services+"ab/main.yaml", "c")
+	integrity = append(integrity, PackageIntegrity{Name: "c", Ref: "v1", Hash: "sha256:c1"})
+	if s = compose(false); s.Partial == nil || !slices.Equal(s.Partial.Changed, []string{"c"}) || read(services+"ab/main.yaml") != "c" {
+		t.Errorf("expected added package c to win ab/, got %+v", s.Partial)
+	}
+	slices.Reverse(packages)
+	if s = compose(false); s.Partial != nil {
+		t.Errorf("expected full merge after merge order changed, got %+v", s.Partial)
+	}
+}
+
+func TestSameRelativeOrder(t *testing.T) {
+	if !sameRelativeOrder([]string{"a", "b", "c"}, []string{"a", "x", "c"}) {
+		t.Error("expected order of common packages to be the same")
+	}
+	if sameRelativeOrder([]string{"a", "b"}, []string{"b", "a"}) {
+		t.Error("expected swapped packages to change the order")
+	}
+}
@@ -0,0 +1,133 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// Permission policies applied to files of the merged result.
+const (
+	// PermissionsPreserve keeps file modes of packages as is.
+	PermissionsPreserve = "preserve"
+	// PermissionsSafe strips group and world writable bits.
+	PermissionsSafe = "safe"
+	// PermissionsNormalize sets 0755 for executables and directories, 0644 for other files.
+	PermissionsNormalize = "normalize"
+)
+
+var errInvalidPermissions = errors.New("invalid permissions policy")
+
+func validatePermissionsPolicy(policy string) error {
+	switch policy {
+	case "", PermissionsPreserve, PermissionsSafe, PermissionsNormalize:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s, %s", errInvalidPermissions, policy, PermissionsPreserve, PermissionsSafe, PermissionsNormalize)
+	}
+}
+
+// normalizeMode returns permission bits of a merged file or directory according to policy.
+func normalizeMode(mode fs.FileMode, policy string) fs.FileMode {
+	perm := mode.Perm()
+	switch policy {
+	case PermissionsSafe:
+		return perm &^ 0022
+	case PermissionsNormalize:
+		if mode.IsDir() || perm&0111 != 0 {
+			return 0755
+		}
+		return 0644
+	default:
+		return perm
+	}
+}
+
+type permissionRule struct {
+	pattern []string
+	file    fs.FileMode
+	dir     fs.FileMode
+}
+
+// permissionPolicy resolves modes of merged files and directories.
+// Explicit rules of compose.yaml take precedence over the policy and umask.
+type permissionPolicy struct {
+	policy string
+	dir    fs.FileMode
+	umask  fs.FileMode
+	rules  []permissionRule
+}
+
+func newPermissionPolicy(policy string, cfg *Permissions) (*permissionPolicy, error) {
+	if err := validatePermissionsPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	p := &permissionPolicy{policy: policy, dir: dirPermissions}
+	if cfg == nil {
+		return p, nil
+	}
+
+	var err error
+	if cfg.Dir != "" {
+		if p.dir, err = parseMode(cfg.Dir); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Umask {
+		p.umask = processUmask()
+	}
+
+	for _, r := range cfg.Rules {
+		if r.Path == "" {
+			return nil, fmt.Errorf("%w: rule path can't be empty", errInvalidPermissions)
+		}
+		rule := permissionRule{pattern: strings.Split(r.Path, "/")}
+		if r.File != "" {
+			if rule.file, err = parseMode(r.File); err != nil {
+				return nil, err
+			}
+		}
+		if r.Dir != "" {
+			if rule.dir, err = parseMode(r.Dir); err != nil {
+				return nil, err
+			}
+		}
+		p.rules = append(p.rules, rule)
+	}
+
+	return p, nil
+}
+
+// fileMode returns mode of a merged file, path is relative to the merged directory.
+func (p *permissionPolicy) fileMode(path string, mode fs.FileMode) fs.FileMode {
+	for _, r := range p.rules {
+		if r.file != 0 && matchGlob(r.pattern, strings.Split(path, "/")) {
+			return r.file
+		}
+	}
+
+	return normalizeMode(mode, p.policy) &^ p.umask
+}
+
+// dirMode returns mode of a merged directory, path is relative to the merged directory.
+func (p *permissionPolicy) dirMode(path string) fs.FileMode {
+	for _, r := range p.rules {
+		if r.dir != 0 && matchGlob(r.pattern, strings.Split(path, "/")) {
+			return r.dir
+		}
+	}
+
+	return p.dir &^ p.umask
+}
+
+func parseMode(s string) (fs.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("%w: mode %q, expected octal value like 0755", errInvalidPermissions, s)
+	}
+
+	return fs.FileMode(m), nil
+}
@@ -0,0 +1,68 @@
+package compose
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestNormalizeMode(t *testing.T) {
+	tests := []struct {
+		mode   fs.FileMode
+		policy string
+		want   fs.FileMode
+	}{
+		{0777, PermissionsPreserve, 0777},
+		{0600, "", 0600},
+		{0777, PermissionsSafe, 0755},
+		{0666, PermissionsSafe, 0644},
+		{0700, PermissionsNormalize, 0755},
+		{0600, PermissionsNormalize, 0644},
+		{0666, PermissionsNormalize, 0644},
+		{fs.ModeDir | 0700, PermissionsNormalize, 0755},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeMode(tt.mode, tt.policy); got != tt.want {
+			t.Errorf("normalizeMode(%v, %q) = %v, want %v", tt.mode, tt.policy, got, tt.want)
+		}
+	}
+
+	if err := validatePermissionsPolicy("strict"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestPermissionPolicy(t *testing.T) {
+	p, err := newPermissionPolicy(PermissionsSafe, &Permissions{
+		Dir: "0750",
+		Rules: []PermissionRule{
+			{Path: "src/**/secrets/*", File: "0600"},
+			{Path: "src/**/secrets", Dir: "0700"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newPermissionPolicy failed: %v", err)
+	}
+
+	if got := p.fileMode("src/app/secrets/key.pem", 0666); got != 0600 {
+		t.Errorf("expected rule file mode 0600, got %v", got)
+	}
+	if got := p.fileMode("src/app/main.yaml", 0666); got != 0644 {
+		t.Errorf("expected policy file mode 0644, got %v", got)
+	}
+	if got := p.dirMode("src/app/secrets"); got != 0700 {
+		t.Errorf("expected rule dir mode 0700, got %v", got)
+	}
+	if got := p.dirMode("src/app"); got != 0750 {
+		t.Errorf("expected configured dir mode 0750, got %v", got)
+	}
+
+	p.umask = 0027
+	if got := p.fileMode("src/app/main.yaml", 0666); got != 0640 {
+		t.Errorf("expected umask to be applied, got %v", got)
+	}
+
+	if _, err = newPermissionPolicy("", &Permissions{Dir: "rwx"}); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
@@ -0,0 +1,93 @@
+package compose
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// composeProgress stores packages downloaded by a compose run until the run completes.
+// A rerun after a failure trusts completed packages instead of checking their sources again
+// and resumes from the package which failed.
+type composeProgress struct {
+	path string
+	// Completed holds name=url@target of downloaded packages.
+	Completed []string `json:"completed"`
+	// resumed is the number of entries loaded from the previous run.
+	resumed int
+}
+
+func progressKey(pkg *Package) string {
+	return pkg.GetName() + "=" + sourceKey(pkg)
+}
+
+// loadProgress reads progress of the previous unfinished compose in baseDir, empty progress if there is none.
+func loadProgress(baseDir string) (*composeProgress, error) {
+	p := &composeProgress{path: filepath.Join(baseDir, model.ComposeProgressFile)}
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, p); err != nil {
+		// Progress is only an optimization, start over if it's unreadable.
+		p.Completed = nil
+	}
+	p.resumed = len(p.Completed)
+
+	return p, nil
+}
+
+// completed reports if pkg was downloaded by the previous run and its download is still present, nil-safe.
+func (p *composeProgress) completed(pkg *Package, downloadPath string) bool {
+	if p == nil || !slices.Contains(p.Completed[:p.resumed], progressKey(pkg)) {
+		return false
+	}
+
+	return hasEntries(downloadPath)
+}
+
+// complete records pkg as downloaded, so an interrupted run resumes after it, nil-safe.
+func (p *composeProgress) complete(pkg *Package) error {
+	if p == nil {
+		return nil
+	}
+
+	key := progressKey(pkg)
+	if slices.Contains(p.Completed, key) {
+		return nil
+	}
+	p.Completed = append(p.Completed, key)
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = EnsureDirExists(filepath.Dir(p.path)); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p.path, data, os.FileMode(composePermissions))
+}
+
+// finish removes progress once compose completed, nil-safe.
+func (p *composeProgress) finish() error {
+	if p == nil {
+		return nil
+	}
+
+	err := os.Remove(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
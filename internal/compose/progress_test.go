@@ -0,0 +1,66 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestComposeProgress(t *testing.T) {
+	baseDir := t.TempDir()
+	packagesDir := filepath.Join(baseDir, "packages")
+	pkg := &Package{Name: "core", Source: Source{Type: GitType, URL: "https://example.invalid/core.git", Ref: "v1.0.0"}}
+	downloadPath := filepath.Join(packagesDir, pkg.GetName(), pkg.GetTarget())
+
+	progress, err := loadProgress(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = progress.complete(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if progress.completed(pkg, downloadPath) {
+		t.Error("expected packages completed by the current run not to be resumed")
+	}
+
+	progress, err = loadProgress(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.completed(pkg, downloadPath) {
+		t.Error("expected missing download not to be resumed")
+	}
+
+	if err = os.MkdirAll(downloadPath, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(downloadPath, composeFile), []byte("name: core\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resumed packages aren't checked against the unreachable source.
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	summary := &Summary{}
+	dm := CreateDownloadManager(kw, summary)
+	dm.progress = progress
+	if err = dm.downloadPackage(context.Background(), pkg, packagesDir); err != nil {
+		t.Fatalf("expected completed package to be resumed, got %v", err)
+	}
+	if len(summary.Resumed) != 1 || len(summary.Cached) != 1 {
+		t.Errorf("expected resumed cached package, got %+v", summary)
+	}
+
+	if err = progress.finish(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(baseDir, model.ComposeProgressFile)); !os.IsNotExist(err) {
+		t.Errorf("expected progress to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package compose
+
+import (
+	"net/url"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// ComposedPackages returns identifiers of packages composed from cfg, recorded in bundle manifests.
+// Refs of dependencies are resolved by the lock, commits and tree hashes come from its integrity records.
+// Packages declared by nested compose.yaml files are listed after dependencies of cfg.
+func ComposedPackages(cfg *Composition, lock *VersionLock) []model.BundlePackage {
+	if cfg == nil {
+		return nil
+	}
+
+	var packages []model.BundlePackage
+	listed := make(map[string]bool)
+	for _, dep := range cfg.Dependencies {
+		pkg := dep.ToPackage(dep.Name)
+		pkg.Source.Ref = lock.Ref(dep)
+		bp := model.BundlePackage{
+			Name: dep.Name,
+			Type: pkg.GetType(),
+			URL:  StripCredentials(pkg.Source.URL),
+			Ref:  pkg.Source.Ref,
+		}
+		if pi, ok := lock.integrity(dep.Name, bp.Ref); ok {
+			bp.Commit, bp.Hash = pi.Commit, pi.Hash
+		}
+		listed[dep.Name] = true
+		packages = append(packages, bp)
+	}
+
+	if lock == nil {
+		return packages
+	}
+	for _, pi := range lock.Integrity {
+		if listed[pi.Name] {
+			continue
+		}
+		listed[pi.Name] = true
+		packages = append(packages, model.BundlePackage{Name: pi.Name, Ref: pi.Ref, Commit: pi.Commit, Hash: pi.Hash})
+	}
+
+	return packages
+}
+
+// integrity returns the integrity record of a package at ref, nil-safe.
+func (l *VersionLock) integrity(name, ref string) (PackageIntegrity, bool) {
+	if l == nil {
+		return PackageIntegrity{}, false
+	}
+
+	for _, pi := range l.Integrity {
+		if pi.Name == name && pi.Ref == ref {
+			return pi, true
+		}
+	}
+
+	return PackageIntegrity{}, false
+}
+
+// StripCredentials removes credentials embedded in a remote URL, unparsable URLs are kept as is.
+func StripCredentials(remoteURL string) string {
+	u, err := url.Parse(remoteURL)
+	if err != nil || u.User == nil {
+		return remoteURL
+	}
+	u.User = nil
+
+	return u.String()
+}
@@ -0,0 +1,120 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StalePackage stores a package directory which is not referenced by composition anymore.
+type StalePackage struct {
+	Name string `json:"name"`
+	Ref  string `json:"ref,omitempty"`
+	Path string `json:"path"`
+}
+
+// FindStalePackages returns directories of packagesDir not referenced by the composition
+// or by nested compositions of already downloaded packages. Refs resolved by lock are referenced instead of requested ones.
+func FindStalePackages(cfg *Composition, lock *VersionLock, packagesDir string) ([]StalePackage, error) {
+	if _, err := os.Stat(packagesDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	// Packages sharing a checkout reference the checkout of its owner.
+	referenced := make(map[string]bool)
+	for _, dir := range LocateCheckouts(cfg, lock, packagesDir).dirs {
+		referenced[dir] = true
+	}
+
+	var stale []StalePackage
+	err := findStaleDirs(packagesDir, "", referenced, &stale)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Path < stale[j].Path
+	})
+
+	return stale, nil
+}
+
+// PrunePackages removes stale package directories. Only directories located inside packagesDir are removed.
+func PrunePackages(stale []StalePackage, packagesDir string) error {
+	root, err := filepath.Abs(packagesDir)
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range stale {
+		path, err := filepath.Abs(sp.Path)
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to remove %s: outside of packages directory %s", sp.Path, packagesDir)
+		}
+
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+
+		// Remove package directory if the last ref of it was pruned.
+		pkgDir := filepath.Join(root, sp.Name)
+		if empty, errEmpty := IsEmptyDir(pkgDir); errEmpty == nil && empty {
+			_ = os.Remove(pkgDir)
+		}
+	}
+
+	return nil
+}
+
+// findStaleDirs walks packagesDir and collects directories which are neither referenced
+// nor parents of referenced directories (refs may contain slashes).
+func findStaleDirs(packagesDir, rel string, referenced map[string]bool, stale *[]StalePackage) error {
+	entries, err := os.ReadDir(filepath.Join(packagesDir, rel))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(rel, e.Name())
+		if referenced[path] {
+			continue
+		}
+
+		if isReferencedParent(path, referenced) {
+			if err = findStaleDirs(packagesDir, path, referenced, stale); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ref, _ := strings.Cut(path, string(filepath.Separator))
+		*stale = append(*stale, StalePackage{
+			Name: name,
+			Ref:  filepath.ToSlash(ref),
+			Path: filepath.Join(packagesDir, path),
+		})
+	}
+
+	return nil
+}
+
+func isReferencedParent(path string, referenced map[string]bool) bool {
+	prefix := path + string(filepath.Separator)
+	for r := range referenced {
+		if strings.HasPrefix(r, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindStalePackages(t *testing.T) {
+	packagesDir := t.TempDir()
+
+	dirs := []string{
+		"plasma-core/v1.0.0",
+		"plasma-core/v0.9.0",
+		"plasma-work/feature/x",
+		"plasma-work/feature/y",
+		"nested/latest",
+		"removed/latest",
+	}
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(packagesDir, d), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+	}
+
+	nestedCompose := []byte("name: core\ndependencies:\n  - name: nested\n    source:\n      url: https://example.com/nested.git\n")
+	if err := os.WriteFile(filepath.Join(packagesDir, "plasma-core/v1.0.0", composeFile), nestedCompose, 0600); err != nil {
+		t.Fatalf("failed to write compose file: %v", err)
+	}
+
+	cfg := &Composition{
+		Dependencies: []Dependency{
+			{Name: "plasma-core", Source: Source{URL: "https://example.com/core.git", Ref: "v1.0.0"}},
+			{Name: "plasma-work", Source: Source{URL: "https://example.com/work.git", Ref: "feature/x"}},
+		},
+	}
+
+	stale, err := FindStalePackages(cfg, nil, packagesDir)
+	if err != nil {
+		t.Fatalf("FindStalePackages failed: %v", err)
+	}
+
+	expected := []StalePackage{
+		{Name: "plasma-core", Ref: "v0.9.0", Path: filepath.Join(packagesDir, "plasma-core/v0.9.0")},
+		{Name: "plasma-work", Ref: "feature/y", Path: filepath.Join(packagesDir, "plasma-work/feature/y")},
+		{Name: "removed", Path: filepath.Join(packagesDir, "removed")},
+	}
+	if len(stale) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, stale)
+	}
+	for i := range expected {
+		if stale[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], stale[i])
+		}
+	}
+
+	if err = PrunePackages(stale, packagesDir); err != nil {
+		t.Fatalf("PrunePackages failed: %v", err)
+	}
+
+	for _, d := range []string{"plasma-core/v0.9.0", "plasma-work/feature/y", "removed"} {
+		if _, err = os.Stat(filepath.Join(packagesDir, d)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", d)
+		}
+	}
+	for _, d := range []string{"plasma-core/v1.0.0", "plasma-work/feature/x", "nested/latest"} {
+		if _, err = os.Stat(filepath.Join(packagesDir, d)); err != nil {
+			t.Errorf("expected %s to be kept: %v", d, err)
+		}
+	}
+}
+
+func TestPrunePackagesOutsideDir(t *testing.T) {
+	packagesDir := t.TempDir()
+	outside := t.TempDir()
+
+	err := PrunePackages([]StalePackage{{Name: "x", Path: outside}}, packagesDir)
+	if err == nil {
+		t.Fatal("expected error for path outside of packages directory")
+	}
+	if _, err = os.Stat(outside); err != nil {
+		t.Errorf("expected %s to be kept: %v", outside, err)
+	}
+}
@@ -0,0 +1,79 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/plasmash/plasmactl-model/internal/release"
+)
+
+// pluginModule is the module of the plugin, its version is read from build info of the binary.
+const pluginModule = "github.com/plasmash/plasmactl-model"
+
+// requiresPlugin is the tool name of the plugin in requires of compose.yaml.
+const requiresPlugin = "plasmactl-model"
+
+var (
+	errInvalidRequires  = errors.New("invalid requires")
+	errRequiresNotMet   = errors.New("plugin version doesn't meet requires")
+	rgxRequiresOperator = regexp.MustCompile(`([<>=^~]+)\s+`)
+)
+
+// pluginVersion returns version of the plugin built into the binary, empty for development builds.
+func pluginVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	version := info.Main.Version
+	if info.Main.Path != pluginModule {
+		version = ""
+		for _, dep := range info.Deps {
+			if dep.Path == pluginModule {
+				version = dep.Version
+				break
+			}
+		}
+	}
+	if version == "(devel)" {
+		return ""
+	}
+
+	return version
+}
+
+// checkRequires fails if the plugin version doesn't satisfy requires of compose.yaml declared by owner,
+// e.g. "plasmactl-model >= 1.4". Development builds of unknown version satisfy every requirement.
+func checkRequires(requires, owner, version string) error {
+	if requires == "" {
+		return nil
+	}
+
+	tool, expr, _ := strings.Cut(strings.TrimSpace(requires), " ")
+	if tool != requiresPlugin {
+		return fmt.Errorf("%w %q of %s, expected %s followed by a version constraint", errInvalidRequires, requires, owner, requiresPlugin)
+	}
+	constraint, ok, err := parseConstraint(rgxRequiresOperator.ReplaceAllString(strings.TrimSpace(expr), "$1"))
+	if err != nil || !ok {
+		return fmt.Errorf("%w %q of %s, expected %s followed by a version constraint", errInvalidRequires, requires, owner, requiresPlugin)
+	}
+
+	if version == "" {
+		return nil
+	}
+	v, err := release.ParseVersion(version)
+	if err != nil {
+		return nil //nolint:nilerr // versions which aren't semver satisfy every requirement
+	}
+	// Prereleases and pseudo-versions of a version are checked as the version itself.
+	v.Prerelease = ""
+	if !constraint.allows(v) {
+		return fmt.Errorf("%w: %s requires %s %s, the plugin is %s, upgrade plasmactl", errRequiresNotMet, owner, requiresPlugin, constraint, version)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRequires(t *testing.T) {
+	for _, tt := range []struct {
+		requires string
+		version  string
+		err      error
+	}{
+		{"", "v1.0.0", nil},
+		{"plasmactl-model >= 1.4", "v1.4.0", nil},
+		{"plasmactl-model >=1.4", "v1.5.2", nil},
+		{"plasmactl-model >= 1.4", "v1.3.9", errRequiresNotMet},
+		{"plasmactl-model ^1.4", "v2.0.0", errRequiresNotMet},
+		{"plasmactl-model >= 1.4", "v1.4.0-rc.1", nil},
+		{"plasmactl-model >= 1.4", "", nil},
+		{"plasmactl >= 1.4", "v1.4.0", errInvalidRequires},
+		{"plasmactl-model", "v1.4.0", errInvalidRequires},
+		{"plasmactl-model >= main", "", errInvalidRequires},
+	} {
+		if err := checkRequires(tt.requires, composeFile, tt.version); !errors.Is(err, tt.err) {
+			t.Errorf("%q with %q: expected %v, got %v", tt.requires, tt.version, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/internal/release"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// maxResolveRounds limits downloads repeated because resolved versions changed nested compositions.
+const maxResolveRounds = 10
+
+var errVersionConflict = errors.New("version conflict")
+
+// VersionRequest stores a ref of a package requested by a composition.
+type VersionRequest struct {
+	By  string `yaml:"by"`
+	Ref string `yaml:"ref"`
+}
+
+// LockedPackage stores the resolved ref of a package and refs requested by compositions.
+type LockedPackage struct {
+	Name      string           `yaml:"name"`
+	Ref       string           `yaml:"ref"`
+	Requested []VersionRequest `yaml:"requested"`
+}
+
+// VersionLock stores versions of packages resolved from version ranges or from refs
+// requested by several compositions, and tree hashes of downloaded packages.
+type VersionLock struct {
+	Packages  []LockedPackage    `yaml:"packages,omitempty"`
+	Integrity []PackageIntegrity `yaml:"integrity,omitempty"`
+}
+
+// LoadVersionLock reads the version lock of baseDir, missing lock is empty.
+func LoadVersionLock(baseDir string) (*VersionLock, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, model.VersionLockFile)) //nolint:gosec // path is built from base dir
+	if os.IsNotExist(err) {
+		return &VersionLock{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var l VersionLock
+	if err = yaml.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", model.VersionLockFile, err)
+	}
+
+	return &l, nil
+}
+
+// SaveVersionLock writes the version lock to baseDir, an empty lock removes the file.
+func SaveVersionLock(baseDir string, l *VersionLock) error {
+	path := filepath.Join(baseDir, model.VersionLockFile)
+	if l == nil || len(l.Packages) == 0 && len(l.Integrity) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	data = append([]byte("# Generated by model:compose, do not edit.\n"), data...)
+
+	return os.WriteFile(path, data, 0644) //nolint:gosec // lock is committed with compose.yaml
+}
+
+// Ref returns ref of dependency d. The locked ref is used if it satisfies the ref requested by d.
+func (l *VersionLock) Ref(d Dependency) string {
+	if l == nil {
+		return d.Source.Ref
+	}
+
+	for _, lp := range l.Packages {
+		if lp.Name == d.Name && satisfiesRef(lp.Ref, d.Source.Ref) {
+			return lp.Ref
+		}
+	}
+
+	return d.Source.Ref
+}
+
+// Apply replaces refs of cfg dependencies with locked refs.
+func (l *VersionLock) Apply(cfg *Composition) {
+	for i := range cfg.Dependencies {
+		cfg.Dependencies[i].Source.Ref = l.Ref(cfg.Dependencies[i])
+	}
+}
+
+// satisfiesRef reports if a concrete ref satisfies a requested ref.
+func satisfiesRef(ref, requested string) bool {
+	if ref == requested {
+		return true
+	}
+
+	c, ok, err := parseConstraint(requested)
+	if !ok || err != nil {
+		return false
+	}
+	v, err := release.ParseVersion(ref)
+
+	return err == nil && c.allows(v)
+}
+
+type resolvedRequest struct {
+	VersionRequest
+	used string
+}
+
+// versionResolver chooses a single version of every package requested by the composition and nested
+// compositions. Version ranges and refs requested by several compositions are resolved to the highest
+// version satisfying all of them, a conflict error is returned if there is no such version.
+type versionResolver struct {
+	kw   *keyringWrapper
+	lock *VersionLock
+	// pins stores versions resolved in previous rounds.
+	pins map[string]string
+	// tags caches version tags per package URL.
+	tags map[string][]string
+	// requests and packages are collected in the current round.
+	requests map[string][]resolvedRequest
+	packages map[string]*Package
+}
+
+func newVersionResolver(kw *keyringWrapper) *versionResolver {
+	return &versionResolver{
+		kw:   kw,
+		pins: make(map[string]string),
+		tags: make(map[string][]string),
+	}
+}
+
+func (r *versionResolver) reset() {
+	r.requests = make(map[string][]resolvedRequest)
+	r.packages = make(map[string]*Package)
+}
+
+// ref records the ref of pkg requested by a composition and returns the ref to download.
+func (r *versionResolver) ref(pkg *Package, by string) (string, error) {
+	requested := pkg.GetRef()
+	used := requested
+
+	if pin, ok := r.pins[pkg.GetName()]; ok {
+		used = pin
+	} else if locked := r.lock.Ref(Dependency{Name: pkg.GetName(), Source: pkg.Source}); locked != requested {
+		used = locked
+	} else if isVersionRange(requested) {
+		c, _, err := parseConstraint(requested)
+		if err != nil {
+			return "", err
+		}
+		if used, err = r.highest(pkg, []*versionConstraint{c}, nil); err != nil {
+			return "", err
+		}
+	}
+
+	if r.requests == nil {
+		r.reset()
+	}
+	r.requests[pkg.GetName()] = append(r.requests[pkg.GetName()], resolvedRequest{VersionRequest{By: by, Ref: requested}, used})
+	if _, ok := r.packages[pkg.GetName()]; !ok {
+		r.packages[pkg.GetName()] = pkg
+	}
+
+	return used, nil
+}
+
+// resolve chooses versions of packages requested in the current round.
+// It returns true if any package has to be downloaded again with a resolved version.
+func (r *versionResolver) resolve() (bool, error) {
+	changed := false
+	for _, name := range slices.Sorted(maps.Keys(r.requests)) {
+		reqs := r.requests[name]
+		if !needsResolution(reqs) {
+			continue
+		}
+
+		var constraints []*versionConstraint
+		var exact []string
+		for _, req := range reqs {
+			c, ok, err := parseConstraint(req.Ref)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, conflictError(name, reqs)
+			}
+			constraints = append(constraints, c)
+			if !isVersionRange(req.Ref) {
+				exact = append(exact, req.Ref)
+			}
+		}
+
+		chosen := reqs[0].used
+		if !allUsed(reqs, chosen) || !satisfiesAll(chosen, constraints) {
+			var err error
+			chosen, err = r.highest(r.packages[name], constraints, exact)
+			if errors.Is(err, errVersionConflict) {
+				return false, conflictError(name, reqs)
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+
+		for _, req := range reqs {
+			if req.used != chosen {
+				changed = true
+			}
+		}
+		r.pins[name] = chosen
+	}
+
+	return changed, nil
+}
+
+// versionLock returns resolutions of the current round.
+func (r *versionResolver) versionLock() *VersionLock {
+	l := &VersionLock{}
+	for _, name := range slices.Sorted(maps.Keys(r.requests)) {
+		reqs := r.requests[name]
+		if !needsResolution(reqs) {
+			continue
+		}
+
+		lp := LockedPackage{Name: name, Ref: reqs[0].used}
+		for _, req := range reqs {
+			if !slices.Contains(lp.Requested, req.VersionRequest) {
+				lp.Requested = append(lp.Requested, req.VersionRequest)
+			}
+		}
+		l.Packages = append(l.Packages, lp)
+	}
+
+	return l
+}
+
+// highest returns the highest version tag satisfying all constraints, exact versions are candidates too.
+func (r *versionResolver) highest(pkg *Package, constraints []*versionConstraint, exact []string) (string, error) {
+	candidates := slices.Clone(exact)
+	if slices.ContainsFunc(constraints, func(c *versionConstraint) bool { return isVersionRange(c.raw) }) {
+		tags, err := r.versionTags(pkg)
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, tags...)
+	}
+
+	var best string
+	var bestVersion *release.Version
+	for _, candidate := range candidates {
+		v, err := release.ParseVersion(candidate)
+		if err != nil || !satisfiesAll(candidate, constraints) {
+			continue
+		}
+		if bestVersion == nil || v.Compare(bestVersion) > 0 {
+			best, bestVersion = candidate, v
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("%w: no version of %s satisfies %s", errVersionConflict, pkg.GetName(), joinConstraints(constraints))
+	}
+
+	return best, nil
+}
+
+// versionTags lists semver tags of a git package, or of the repository of a release package.
+func (r *versionResolver) versionTags(pkg *Package) ([]string, error) {
+	if t := pkg.GetType(); t != GitType && t != ReleaseType {
+		return nil, fmt.Errorf("%w: version ranges are supported for git and release packages only, %s is %s", errVersionConflict, pkg.GetName(), pkg.GetType())
+	}
+
+	if tags, ok := r.tags[pkg.GetURL()]; ok {
+		return tags, nil
+	}
+
+	refs, err := listRemoteRefs(r.kw, pkg.GetURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", pkg.GetName(), err)
+	}
+
+	var tags []string
+	for _, ref := range refs {
+		if _, errParse := release.ParseVersion(ref.Name); ref.IsTag && errParse == nil {
+			tags = append(tags, ref.Name)
+		}
+	}
+	r.tags[pkg.GetURL()] = tags
+
+	return tags, nil
+}
+
+// needsResolution reports if a package is requested with a version range or with different refs.
+func needsResolution(reqs []resolvedRequest) bool {
+	for _, req := range reqs {
+		if isVersionRange(req.Ref) || req.Ref != reqs[0].Ref {
+			return true
+		}
+	}
+
+	return false
+}
+
+func allUsed(reqs []resolvedRequest, ref string) bool {
+	for _, req := range reqs {
+		if req.used != ref {
+			return false
+		}
+	}
+
+	return true
+}
+
+func satisfiesAll(ref string, constraints []*versionConstraint) bool {
+	v, err := release.ParseVersion(ref)
+	if err != nil {
+		return false
+	}
+
+	for _, c := range constraints {
+		if !c.allows(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func joinConstraints(constraints []*versionConstraint) string {
+	var raw []string
+	for _, c := range constraints {
+		raw = append(raw, c.raw)
+	}
+
+	return strings.Join(raw, ", ")
+}
+
+func conflictError(name string, reqs []resolvedRequest) error {
+	var requested []string
+	for _, req := range reqs {
+		requested = append(requested, fmt.Sprintf("%s requires %s", req.By, req.Ref))
+	}
+	sort.Strings(requested)
+
+	return fmt.Errorf("%w: package %s: %s", errVersionConflict, name, strings.Join(slices.Compact(requested), "; "))
+}
@@ -0,0 +1,112 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/internal/release"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		allowed    bool
+	}{
+		{"v1.2.3", "1.2.3", true},
+		{"v1.2.3", "v1.2.4", false},
+		{"^1.2.0", "v1.9.0", true},
+		{"^1.2.0", "v2.0.0", false},
+		{"^1.2.0", "v1.1.9", false},
+		{"^0.2.3", "v0.2.9", true},
+		{"^0.2.3", "v0.3.0", false},
+		{"^0.0.3", "v0.0.4", false},
+		{"~1.2.3", "v1.2.9", true},
+		{"~1.2.3", "v1.3.0", false},
+		{"~1", "v1.9.0", true},
+		{">=1.0.0 <1.5.0", "v1.4.9", true},
+		{">=1.0.0, <1.5.0", "v1.5.0", false},
+		{"^1.2.0", "v1.3.0-rc.1", false},
+		{">=v1.3.0-rc.1", "v1.3.0-rc.1", true},
+		{">=1.2 <2.0", "v1.2.0", true},
+		{">=1.2 <2.0", "v2.0.0", false},
+		{"^1.4", "v1.9.3", true},
+		{"^1.4", "v1.3.9", false},
+	}
+
+	for _, tt := range tests {
+		c, ok, err := parseConstraint(tt.constraint)
+		if err != nil || !ok {
+			t.Fatalf("failed to parse %q: %v", tt.constraint, err)
+		}
+		v, err := release.ParseVersion(tt.version)
+		if err != nil {
+			t.Fatalf("failed to parse version %q: %v", tt.version, err)
+		}
+		if c.allows(v) != tt.allowed {
+			t.Errorf("%s allows %s: expected %t", tt.constraint, tt.version, tt.allowed)
+		}
+	}
+
+	if _, ok, _ := parseConstraint("main"); ok {
+		t.Error("expected branch not to be a version constraint")
+	}
+	if _, _, err := parseConstraint("^one"); err == nil {
+		t.Error("expected error for invalid range")
+	}
+}
+
+func TestVersionResolver(t *testing.T) {
+	const url = "https://example.com/core.git"
+	r := newVersionResolver(nil)
+	r.tags[url] = []string{"v1.2.0", "v1.3.1", "v1.4.0", "v2.0.0"}
+
+	request := func(by, ref string) string {
+		t.Helper()
+		used, err := r.ref(&Package{Name: "core", Source: Source{Type: GitType, URL: url, Ref: ref}}, by)
+		if err != nil {
+			t.Fatalf("ref failed: %v", err)
+		}
+		return used
+	}
+
+	r.reset()
+	if used := request(localOrigin, "^1.2.0"); used != "v1.4.0" {
+		t.Errorf("expected highest version of range, got %s", used)
+	}
+	request("work", "~1.3.0")
+
+	changed, err := r.resolve()
+	if err != nil || !changed {
+		t.Fatalf("expected versions to change, got %t, %v", changed, err)
+	}
+
+	r.reset()
+	request(localOrigin, "^1.2.0")
+	if used := request("work", "~1.3.0"); used != "v1.3.1" {
+		t.Errorf("expected resolved version, got %s", used)
+	}
+	if changed, err = r.resolve(); err != nil || changed {
+		t.Fatalf("expected versions to settle, got %t, %v", changed, err)
+	}
+
+	lock := r.versionLock()
+	if len(lock.Packages) != 1 || lock.Packages[0].Ref != "v1.3.1" || len(lock.Packages[0].Requested) != 2 {
+		t.Errorf("unexpected version lock: %+v", lock)
+	}
+	if ref := lock.Ref(Dependency{Name: "core", Source: Source{Ref: "^1.0.0"}}); ref != "v1.3.1" {
+		t.Errorf("expected locked ref, got %s", ref)
+	}
+	if ref := lock.Ref(Dependency{Name: "core", Source: Source{Ref: "v2.0.0"}}); ref != "v2.0.0" {
+		t.Errorf("expected requested ref not satisfied by lock, got %s", ref)
+	}
+
+	r = newVersionResolver(nil)
+	r.tags[url] = []string{"v1.2.0", "v2.0.0"}
+	r.reset()
+	request(localOrigin, "^1.2.0")
+	request("work", "v2.0.0")
+	if _, err = r.resolve(); !errors.Is(err, errVersionConflict) {
+		t.Errorf("expected version conflict, got %v", err)
+	}
+}
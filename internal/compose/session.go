@@ -0,0 +1,65 @@
+package compose
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/launchrctl/keyring"
+)
+
+// authSession stores the authentication mode and credentials which succeeded for a host during a compose.
+type authSession struct {
+	mode authenticationMode
+	ci   keyring.CredentialsItem
+}
+
+func (s authSession) gitAuth() *http.BasicAuth {
+	return &http.BasicAuth{Username: s.ci.Username, Password: s.ci.Password}
+}
+
+// session returns credentials which succeeded for the host of url or its path scope.
+func (kw *keyringWrapper) session(url string) (authSession, bool) {
+	key, ok := kw.sessionKey(url)
+	if !ok {
+		return authSession{}, false
+	}
+
+	s, ok := kw.sessions[key]
+	return s, ok
+}
+
+// sessionKey returns the host of url, or the keyring item URL if credentials of the host are scoped by path.
+func (kw *keyringWrapper) sessionKey(url string) (string, bool) {
+	host, err := baseURL(url)
+	if err != nil {
+		return "", false
+	}
+
+	if kw.keyringAvailable() {
+		scoped := kw.keyringURL(url, host)
+		if _, path, ok := splitCredentialsURL(scoped); ok && path != "" {
+			return scoped, true
+		}
+	}
+
+	return host, true
+}
+
+// saveSession stores credentials which succeeded for url, so other packages of the host reuse them
+// without probing authentication modes and prompting again.
+func (kw *keyringWrapper) saveSession(url string, mode authenticationMode, ci keyring.CredentialsItem) {
+	key, ok := kw.sessionKey(url)
+	if !ok {
+		return
+	}
+
+	if kw.sessions == nil {
+		kw.sessions = make(map[string]authSession)
+	}
+	kw.sessions[key] = authSession{mode: mode, ci: ci}
+}
+
+// dropSession forgets credentials of the host of url, e.g. when they are rejected for another repository.
+func (kw *keyringWrapper) dropSession(url string) {
+	if key, ok := kw.sessionKey(url); ok {
+		delete(kw.sessions, key)
+	}
+}
@@ -0,0 +1,60 @@
+package compose
+
+import (
+	"archive/tar"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+)
+
+func TestSessionReusedPerHost(t *testing.T) {
+	archive := writeTarGz(t, []tarEntry{
+		{name: "pkg/", typeflag: tar.TypeDir},
+		{name: "pkg/main.yaml", typeflag: tar.TypeReg, content: "key: value\n"},
+	})
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if user, pass, ok := r.BasicAuth(); !ok || user != "deploy" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, archive)
+	}))
+	defer srv.Close()
+
+	kw := &keyringWrapper{}
+	kw.SetLogger(launchr.Log())
+	kw.SetTerm(launchr.Term())
+	kw.saveSession(srv.URL+"/a.tar.gz", authenticationModeManual, keyring.CredentialsItem{Username: "deploy", Password: "secret"})
+
+	for _, name := range []string{"a", "b"} {
+		h := newHTTP(kw, "").(*httpDownloader)
+		pkg := &Package{Name: name, Source: Source{Type: HTTPType, URL: srv.URL + "/" + name + ".tar.gz"}}
+		targetDir := filepath.Join(t.TempDir(), name)
+		if err := h.Download(context.Background(), pkg, targetDir); err != nil {
+			t.Fatalf("download of %s failed: %v", name, err)
+		}
+		if h.Stats().auth != authenticationModeManual {
+			t.Errorf("expected session auth mode for %s, got %s", name, h.Stats().auth)
+		}
+		if _, err := os.Stat(filepath.Join(targetDir, TargetLatest, "main.yaml")); err != nil {
+			t.Errorf("expected package %s to be extracted: %v", name, err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected one request per package without probing, got %d", requests)
+	}
+
+	kw.dropSession(srv.URL + "/b.tar.gz")
+	if _, ok := kw.session(srv.URL + "/a.tar.gz"); ok {
+		t.Error("expected session of the host to be dropped")
+	}
+}
@@ -0,0 +1,82 @@
+package compose
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// PackageStats stores size statistics of a downloaded package.
+type PackageStats struct {
+	Components  int   `json:"components"`
+	Files       int   `json:"files"`
+	Size        int64 `json:"size"`
+	MergedFiles int   `json:"merged_files"`
+}
+
+// SaveSummary writes summary of a compose run to baseDir.
+func SaveSummary(baseDir string, s *Summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(baseDir, model.ComposeSummaryFile), data, os.FileMode(composePermissions))
+}
+
+// LoadSummary reads summary of the last compose run from baseDir.
+func LoadSummary(baseDir string) (*Summary, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, model.ComposeSummaryFile)) //nolint:gosec // path is built from base dir
+	if err != nil {
+		return nil, err
+	}
+
+	var s Summary
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// MergedFiles returns number of merged files per package, nil summary gives an empty map.
+func (s *Summary) MergedFiles() map[string]int {
+	files := make(map[string]int)
+	if s == nil {
+		return files
+	}
+	for _, pf := range s.Files {
+		files[pf.Name] = pf.Files
+	}
+
+	return files
+}
+
+// CollectPackageStats returns files count and size of a downloaded package located by checkouts,
+// merged is a number of merged files per package of the last compose run.
+func CollectPackageStats(checkouts *Checkouts, dep Dependency, merged map[string]int) PackageStats {
+	pkg := dep.ToPackage(dep.Name)
+	stats := PackageStats{MergedFiles: merged[pkg.GetName()]}
+
+	pkgDir := checkouts.PackageDir(pkg)
+	_ = filepath.WalkDir(pkgDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == gitPrefix {
+			return fs.SkipDir
+		}
+		if d.Type().IsRegular() {
+			stats.Files++
+			if info, errInfo := d.Info(); errInfo == nil {
+				stats.Size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return stats
+}
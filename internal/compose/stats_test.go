@@ -0,0 +1,77 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestCollectPackageStats(t *testing.T) {
+	baseDir := t.TempDir()
+	pkgDir := filepath.Join(baseDir, model.PackagesDir, "core", "v1.0.0")
+	for path, content := range map[string]string{
+		"src/app/main.yaml": "abcd",
+		"README.md":         "ab",
+		".git/HEAD":         "ref: refs/heads/main\n",
+	} {
+		p := filepath.Join(pkgDir, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(baseDir, model.ComposeDir), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSummary(baseDir, &Summary{Files: []PackageFiles{{Name: "core", Files: 1}}}); err != nil {
+		t.Fatalf("SaveSummary failed: %v", err)
+	}
+	summary, err := LoadSummary(baseDir)
+	if err != nil {
+		t.Fatalf("LoadSummary failed: %v", err)
+	}
+
+	dep := Dependency{Name: "core", Source: Source{Ref: "v1.0.0"}}
+	checkouts := LocateCheckouts(&Composition{Dependencies: []Dependency{dep}}, nil, filepath.Join(baseDir, model.PackagesDir))
+	stats := CollectPackageStats(checkouts, dep, summary.MergedFiles())
+	want := PackageStats{Files: 2, Size: 6, MergedFiles: 1}
+	if stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+
+	var missing *Summary
+	if len(missing.MergedFiles()) != 0 {
+		t.Error("expected empty merged files of nil summary")
+	}
+}
+
+func TestCollectPackageStatsSubpath(t *testing.T) {
+	packagesDir := t.TempDir()
+	checkoutDir := filepath.Join(packagesDir, "networking", "v1.0.0")
+	for path, content := range map[string]string{
+		"packages/networking/src/app/main.yaml": "abcd",
+		"packages/storage/src/app/main.yaml":    "abcdef",
+		"README.md":                             "ab",
+	} {
+		p := filepath.Join(checkoutDir, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dep := Dependency{Name: "networking", Source: Source{Ref: "v1.0.0", Subpath: "packages/networking"}}
+	checkouts := LocateCheckouts(&Composition{Dependencies: []Dependency{dep}}, nil, packagesDir)
+	stats := CollectPackageStats(checkouts, dep, nil)
+	want := PackageStats{Files: 1, Size: 4}
+	if stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+}
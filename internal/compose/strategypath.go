@@ -0,0 +1,158 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var errInvalidExclude = errors.New("invalid exclude")
+
+// regexPathPrefix marks strategy paths given as a regular expression matched against the whole merged path.
+const regexPathPrefix = "regex:"
+
+// strategyPattern is a compiled glob or regex path of a strategy.
+type strategyPattern struct {
+	glob []string
+	re   *regexp.Regexp
+	// literal is the path every match of the regex starts with.
+	literal string
+}
+
+// strategyPatterns caches compiled patterns, they are matched against every merged path.
+var strategyPatterns sync.Map
+
+// isStrategyPattern reports if a strategy path is a glob or a regex rather than a literal path prefix.
+func isStrategyPattern(p string) bool {
+	return strings.HasPrefix(p, regexPathPrefix) || strings.ContainsAny(p, "*?[")
+}
+
+// compileStrategyPattern returns the compiled glob or regex of a strategy path.
+func compileStrategyPattern(p string) (*strategyPattern, error) {
+	if cached, ok := strategyPatterns.Load(p); ok {
+		return cached.(*strategyPattern), nil
+	}
+
+	sp := &strategyPattern{}
+	if expr, ok := strings.CutPrefix(p, regexPathPrefix); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %w", p, err)
+		}
+		sp.re = re
+		// Matches are anchored, the literal prefix of the expression is a prefix of matched paths.
+		if unanchored, errPrefix := regexp.Compile(strings.TrimPrefix(expr, "^")); errPrefix == nil {
+			sp.literal, _ = unanchored.LiteralPrefix()
+		}
+	} else {
+		sp.glob = strings.Split(strings.Trim(p, "/"), "/")
+		for _, segment := range sp.glob {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("path %q: %w", p, err)
+			}
+		}
+	}
+
+	strategyPatterns.Store(p, sp)
+	return sp, nil
+}
+
+// match reports if the pattern matches the path or one of its parent directories.
+func (sp *strategyPattern) match(p string) bool {
+	segments := strings.Split(p, "/")
+	for i := len(segments); i > 0; i-- {
+		if sp.matchWhole(segments[:i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sp *strategyPattern) matchWhole(segments []string) bool {
+	if sp.re != nil {
+		return sp.re.MatchString(strings.Join(segments, "/"))
+	}
+
+	return matchGlob(sp.glob, segments)
+}
+
+// leadsTo reports if paths inside of dir may match the pattern. Regexes can't be matched partially,
+// directories on the way to and inside of their literal prefix are reported.
+func (sp *strategyPattern) leadsTo(dir string) bool {
+	if sp.re != nil {
+		return strings.HasPrefix(sp.literal, dir+"/") || strings.HasPrefix(dir+"/", sp.literal)
+	}
+
+	return globLeadsTo(sp.glob, strings.Split(dir, "/"))
+}
+
+// globLeadsTo reports if segments of a directory match the beginning of the glob.
+func globLeadsTo(pattern, dir []string) bool {
+	for len(dir) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], dir[0]); !ok {
+			return false
+		}
+		pattern, dir = pattern[1:], dir[1:]
+	}
+
+	return true
+}
+
+// validateStrategyPaths checks globs and regexes of strategy paths.
+func validateStrategyPaths(item Strategy) error {
+	for _, p := range item.Paths {
+		if !isStrategyPattern(p) {
+			continue
+		}
+		if _, err := compileStrategyPattern(p); err != nil {
+			return fmt.Errorf("%w: %w", errInvalidStrategy, err)
+		}
+	}
+
+	return nil
+}
+
+// excludedPath returns the exclude pattern matching a path of a package or one of its parent directories.
+// Literal patterns match whole path segments, unlike prefixes of strategy paths.
+func excludedPath(p string, exclude []string) (string, bool) {
+	for _, e := range exclude {
+		if !isStrategyPattern(e) {
+			literal := strings.Trim(e, "/")
+			if p == literal || strings.HasPrefix(p, literal+"/") {
+				return e, true
+			}
+			continue
+		}
+		if pattern, err := compileStrategyPattern(e); err == nil && pattern.match(p) {
+			return e, true
+		}
+	}
+
+	return "", false
+}
+
+// validateExcludes checks globs and regexes of exclude lists of dependencies and excluded names.
+func validateExcludes(cfg *Composition) error {
+	for _, d := range cfg.Dependencies {
+		for _, e := range d.Source.Exclude {
+			if !isStrategyPattern(e) {
+				continue
+			}
+			if _, err := compileStrategyPattern(e); err != nil {
+				return fmt.Errorf("%w of %s: %w", errInvalidExclude, d.Name, err)
+			}
+		}
+	}
+
+	return validateExclusions(cfg)
+}
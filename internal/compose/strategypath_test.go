@@ -0,0 +1,67 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStrategyPathPatterns(t *testing.T) {
+	paths := cleanStrategyPaths([]string{"src/*/group_vars/**", `regex:src/platform/.*\.ya?ml`, "src/foundation"})
+
+	for path, expected := range map[string]bool{
+		"src/platform/group_vars/all/main.yaml":          true,
+		"src/interaction/group_vars/vars.yaml":           true,
+		"src/platform/services/nginx/defaults/main.yaml": true,
+		"src/platform/services/nginx/files/nginx.conf":   false,
+		"src/cognition/skills/search/tasks/main.yaml":    false,
+		"src/foundation/applications/app/main.yaml":      true,
+		"src/foundationx/main.yaml":                      false,
+	} {
+		if got := ensureStrategyPrefixPath(path, paths); got != expected {
+			t.Errorf("%s: expected match %v, got %v", path, expected, got)
+		}
+	}
+
+	// Directories on the way to matches are merged by filter-package-files.
+	for dir, expected := range map[string]bool{
+		"src":                         true,
+		"src/cognition":               true,
+		"src/cognition/group_vars":    true,
+		"src/cognition/skills":        false,
+		"src/platform/services/nginx": true,
+		"docs":                        false,
+	} {
+		if got := ensureStrategyContainsPath(dir, paths); got != expected {
+			t.Errorf("%s: expected directory to lead to matches %v, got %v", dir, expected, got)
+		}
+	}
+
+	for _, invalid := range []string{"src/[platform/*", "regex:src/(platform"} {
+		cfg := &Composition{Strategies: []Strategy{{Name: StrategyOverwriteLocal, Paths: []string{invalid}}}}
+		if err := validateStrategies(cfg); !errors.Is(err, errInvalidStrategy) {
+			t.Errorf("%s: expected invalid strategy path, got %v", invalid, err)
+		}
+	}
+}
+
+func TestExcludedPath(t *testing.T) {
+	exclude := []string{"docs", "src/*/tests/**", "regex:.*\\.md"}
+	for path, expected := range map[string]bool{
+		"docs":                           true,
+		"docs/index.html":                true,
+		"docs-extra/index.html":          false,
+		"src/platform/tests/main.yaml":   true,
+		"src/platform/services/main.yml": false,
+		"README.md":                      true,
+		"src/README.md.j2":               false,
+	} {
+		if _, got := excludedPath(path, exclude); got != expected {
+			t.Errorf("%s: expected excluded %v, got %v", path, expected, got)
+		}
+	}
+
+	cfg := &Composition{Dependencies: []Dependency{{Name: "core", Source: Source{Exclude: []string{"regex:docs/(x"}}}}}
+	if err := validateExcludes(cfg); !errors.Is(err, errInvalidExclude) {
+		t.Errorf("expected invalid exclude, got %v", err)
+	}
+}
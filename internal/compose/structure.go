@@ -0,0 +1,56 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidStructure = errors.New("invalid model structure")
+
+// structureProblems checks merged paths of src/ follow the {layer}/{type}/{component} convention:
+// layers must be known, files can't lie directly in type directories and roles directories left
+// by layouts not normalized by stripRolesFromPath are reported. Actions, docs and variables of layers
+// have their own layouts. It returns a list of problems found.
+func structureProblems(entriesTree []*fsEntry) []string {
+	var problems []string
+	for _, entry := range entriesTree {
+		parts := strings.Split(entry.DstPath, "/")
+		if len(parts) < 2 || parts[0] != "src" {
+			continue
+		}
+
+		switch {
+		case len(parts) == 2 && entry.Entry.IsDir() && !layerNames[parts[1]]:
+			problems = append(problems, fmt.Sprintf("%s: unknown layer %s of %s", entry.DstPath, parts[1], entry.From))
+		case len(parts) < 4 || !layerNames[parts[1]]:
+			continue
+		case parts[2] == "actions" || parts[2] == "docs" || parts[2] == "variables":
+			continue
+		case len(parts) == 4 && !entry.Entry.IsDir():
+			problems = append(problems, fmt.Sprintf("%s: file of %s directly in type directory %s, expected in a component", entry.DstPath, entry.From, strings.Join(parts[:3], "/")))
+		case parts[len(parts)-1] == "roles" && entry.Entry.IsDir():
+			problems = append(problems, fmt.Sprintf("%s: nested roles directory of %s, expected %s/{component}", entry.DstPath, entry.From, strings.Join(parts[:3], "/")))
+		}
+	}
+
+	return problems
+}
+
+// verifyStructure reports problems of the merged structure, they fail the build in strict mode.
+func (b *Builder) verifyStructure(entriesTree []*fsEntry) error {
+	problems := structureProblems(entriesTree)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if b.strict {
+		return fmt.Errorf("%w:\n  %s", errInvalidStructure, strings.Join(problems, "\n  "))
+	}
+
+	for _, p := range problems {
+		b.Term().Warning().Printfln("%s", p)
+	}
+
+	return nil
+}
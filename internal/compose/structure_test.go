@@ -0,0 +1,38 @@
+package compose
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestStructureProblems(t *testing.T) {
+	dir, file := fileMeta{mode: fs.ModeDir | 0755}, fileMeta{mode: 0644}
+	tree := []*fsEntry{
+		{DstPath: "src", Entry: dir, From: localOrigin},
+		{DstPath: "src/platform", Entry: dir, From: localOrigin},
+		{DstPath: "src/platform/services/nginx/tasks/main.yaml", Entry: file, From: localOrigin},
+		{DstPath: "src/platform/variables/all.yaml", Entry: file, From: localOrigin},
+		{DstPath: "src/platform/actions/deploy/action.yaml", Entry: file, From: localOrigin},
+		{DstPath: "src/platfrom", Entry: dir, From: "plasma-core"},
+		{DstPath: "src/platfrom/services/nginx/tasks/main.yaml", Entry: file, From: "plasma-core"},
+		{DstPath: "src/platform/services/main.yaml", Entry: file, From: "plasma-core"},
+		{DstPath: "src/platform/services/nginx/roles", Entry: dir, From: "plasma-work"},
+		{DstPath: "README.md", Entry: file, From: localOrigin},
+	}
+
+	problems := structureProblems(tree)
+	expected := []string{
+		"src/platfrom: unknown layer platfrom of plasma-core",
+		"src/platform/services/main.yaml: file of plasma-core directly in type directory src/platform/services",
+		"src/platform/services/nginx/roles: nested roles directory of plasma-work",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %q", len(expected), problems)
+	}
+	for i := range expected {
+		if !strings.HasPrefix(problems[i], expected[i]) {
+			t.Errorf("expected %q, got %q", expected[i], problems[i])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package compose
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Built-in substitution variables.
+const (
+	VarModelName     = "plasma_model_name"
+	VarModelVersion  = "plasma_model_version"
+	varPackagePrefix = "plasma_package_"
+	varRefSuffix     = "_ref"
+)
+
+var (
+	rgxPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	rgxVarName     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// packageRefVar returns name of a variable holding ref of a package, e.g. plasma_package_plasma_core_ref.
+func packageRefVar(name string) string {
+	return varPackagePrefix + strings.Trim(rgxVarName.ReplaceAllString(strings.ToLower(name), "_"), "_") + varRefSuffix
+}
+
+// substitutionVars returns built-in variables merged with the ones declared in compose.yaml.
+// Declared variables take precedence.
+func substitutionVars(cfg *Composition, platformDir string, packages []*Package) map[string]string {
+	vars := map[string]string{
+		VarModelName: cfg.Name,
+	}
+	if version := modelVersion(platformDir); version != "" {
+		vars[VarModelVersion] = version
+	}
+	for _, p := range packages {
+		vars[packageRefVar(p.GetName())] = p.GetTarget()
+	}
+	if cfg.Substitution != nil {
+		for k, v := range cfg.Substitution.Vars {
+			vars[k] = v
+		}
+	}
+
+	return vars
+}
+
+// modelVersion returns the closest tag reachable from HEAD of the model repository,
+// or the short HEAD hash if there is no tag. Empty string is returned outside of git repository.
+func modelVersion(dir string) string {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return ""
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return ""
+	}
+
+	tags := make(map[plumbing.Hash]string)
+	iter, err := r.Tags()
+	if err == nil {
+		_ = iter.ForEach(func(ref *plumbing.Reference) error {
+			hash := ref.Hash()
+			if tag, errTag := r.TagObject(hash); errTag == nil {
+				hash = tag.Target
+			}
+			tags[hash] = ref.Name().Short()
+			return nil
+		})
+	}
+
+	version := head.Hash().String()[:7]
+	commits, err := r.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return version
+	}
+	_ = commits.ForEach(func(c *object.Commit) error {
+		if tag, ok := tags[c.Hash]; ok {
+			version = tag
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	return version
+}
+
+// substituteFiles replaces known placeholders in files of dir matching globs.
+// Unknown placeholders are kept as is, so templates of other tools are not affected.
+// It returns number of changed files.
+func substituteFiles(dir string, globs []string, vars map[string]string) (int, error) {
+	changed := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == gitPrefix {
+			return fs.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if !matchAnyGlob(filepath.ToSlash(rel), globs) {
+			return nil
+		}
+
+		content, err := os.ReadFile(filepath.Clean(p))
+		if err != nil {
+			return err
+		}
+		// Skip binary files.
+		if bytes.IndexByte(content[:min(len(content), 8000)], 0) != -1 {
+			return nil
+		}
+
+		replaced := rgxPlaceholder.ReplaceAllFunc(content, func(m []byte) []byte {
+			name := rgxPlaceholder.FindSubmatch(m)[1]
+			if v, ok := vars[string(name)]; ok {
+				return []byte(v)
+			}
+			return m
+		})
+		if bytes.Equal(content, replaced) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(p, replaced, info.Mode().Perm()); err != nil {
+			return err
+		}
+		changed++
+
+		return nil
+	})
+
+	return changed, err
+}
+
+func matchAnyGlob(name string, globs []string) bool {
+	for _, g := range globs {
+		if matchGlob(strings.Split(g, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlob matches path segments against pattern segments, "**" matches any number of segments.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,73 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchAnyGlob(t *testing.T) {
+	tests := []struct {
+		name  string
+		globs []string
+		want  bool
+	}{
+		{"src/app/meta.yaml", []string{"src/**/meta.yaml"}, true},
+		{"src/meta.yaml", []string{"src/**/meta.yaml"}, true},
+		{"src/app/roles/x/meta.yaml", []string{"src/*/meta.yaml"}, false},
+		{"VERSION", []string{"src/**", "VERSION"}, true},
+		{"src/app/main.yaml", []string{"**/*.yaml"}, true},
+		{"src/app/main.yml", []string{"**/*.yaml"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchAnyGlob(tt.name, tt.globs); got != tt.want {
+			t.Errorf("matchAnyGlob(%q, %v) = %v, want %v", tt.name, tt.globs, got, tt.want)
+		}
+	}
+}
+
+func TestSubstituteFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"VERSION":          "{{ plasma_model_version }}\n",
+		"src/app/meta.yml": "core: {{plasma_package_plasma_core_ref}}\nhost: {{ inventory_hostname }}\n",
+		"src/app/skip.yml": "{{ plasma_model_version }}\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vars := map[string]string{
+		VarModelVersion:              "v1.2.0",
+		packageRefVar("plasma-core"): "1.4.1",
+	}
+	changed, err := substituteFiles(dir, []string{"VERSION", "src/**/meta.yml"}, vars)
+	if err != nil {
+		t.Fatalf("substituteFiles failed: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("expected 2 changed files, got %d", changed)
+	}
+
+	expected := map[string]string{
+		"VERSION":          "v1.2.0\n",
+		"src/app/meta.yml": "core: 1.4.1\nhost: {{ inventory_hostname }}\n",
+		"src/app/skip.yml": "{{ plasma_model_version }}\n",
+	}
+	for name, content := range expected {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, got)
+		}
+	}
+}
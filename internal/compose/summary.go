@@ -0,0 +1,207 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Compose phases tracked in Summary.
+const (
+	PhaseFetch      = model.PhaseFetch
+	PhaseVerify     = model.PhaseVerify
+	PhaseMerge      = model.PhaseMerge
+	PhaseCopy       = model.PhaseCopy
+	PhaseOverlay    = model.PhaseOverlay
+	PhaseSubstitute = model.PhaseSubstitute
+)
+
+// Package cache states reported in PackageMetrics.
+const (
+	CacheHit  = model.CacheHit
+	CacheMiss = model.CacheMiss
+)
+
+// Summary collects statistics of a compose run, see [model.ComposeSummary].
+type Summary model.ComposeSummary
+
+func (s *Summary) addFetched(identifier string) {
+	s.Fetched = append(s.Fetched, identifier)
+}
+
+func (s *Summary) addCached(identifier string) {
+	s.Cached = append(s.Cached, identifier)
+}
+
+func (s *Summary) addResumed(identifier string) {
+	s.Resumed = append(s.Resumed, identifier)
+}
+
+func (s *Summary) addSkipped(name string) {
+	if !slices.Contains(s.Skipped, name) {
+		s.Skipped = append(s.Skipped, name)
+	}
+}
+
+// resetDownloads drops download statistics before packages are downloaded again.
+func (s *Summary) resetDownloads() {
+	s.Fetched, s.Cached, s.Resumed, s.Packages = nil, nil, nil, nil
+}
+
+func (s *Summary) addNormalized(n Normalization) {
+	s.Normalized = append(s.Normalized, n)
+}
+
+func (s *Summary) addPackageMetrics(pm PackageMetrics) {
+	s.Packages = append(s.Packages, pm)
+}
+
+func (s *Summary) addConflict(resolve mergeConflictResolve) {
+	switch resolve {
+	case resolveToLocal:
+		s.ConflictsToLocal++
+	case resolveToPackage:
+		s.ConflictsToPackage++
+	case resolveMerged:
+		s.ConflictsMerged++
+	}
+}
+
+func (s *Summary) addPhase(name string, start time.Time) {
+	s.Phases = append(s.Phases, PhaseTiming{Name: name, Elapsed: time.Since(start)})
+}
+
+// Conflicts returns total number of resolved conflicts.
+func (s *Summary) Conflicts() int {
+	return s.ConflictsToLocal + s.ConflictsToPackage + s.ConflictsMerged
+}
+
+// Lines returns human-readable summary lines.
+func (s *Summary) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("Packages: %d fetched, %d cached", len(s.Fetched), len(s.Cached)),
+	}
+
+	if len(s.Resumed) > 0 {
+		lines = append(lines, fmt.Sprintf("Resumed: %d packages downloaded by previous run", len(s.Resumed)))
+	}
+
+	if len(s.Skipped) > 0 {
+		lines = append(lines, fmt.Sprintf("Skipped: %s", strings.Join(s.Skipped, ", ")))
+	}
+
+	if len(s.NestedStrategies) > 0 {
+		lines = append(lines, "Nested strategies:")
+		for _, ns := range s.NestedStrategies {
+			line := fmt.Sprintf("  %s\t%s\tdeclared by %s", ns.Package, ns.Strategy, ns.DeclaredBy)
+			if ns.Ignored {
+				line += "\tignored"
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(s.Packages) > 0 {
+		lines = append(lines, "Downloads:")
+		for _, pm := range s.Packages {
+			size := FormatBytes(pm.Bytes)
+			if pm.DiskBytes > 0 {
+				size = FormatBytes(pm.DiskBytes) + " on disk"
+			}
+			line := fmt.Sprintf("  %s\t%s\t%s\t%s", pm.Name, pm.Cache, size, pm.Duration.Round(time.Millisecond))
+			if pm.Auth != "" {
+				line += "\tauth: " + pm.Auth
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(s.Files) > 0 {
+		lines = append(lines, "Files merged:")
+		for _, pf := range s.Files {
+			lines = append(lines, fmt.Sprintf("  %s\t%d", pf.Name, pf.Files))
+		}
+	}
+
+	if len(s.Components) > 0 {
+		lines = append(lines, "Components merged:")
+		for _, c := range s.Components {
+			var parts []string
+			for _, pf := range c.Files {
+				parts = append(parts, fmt.Sprintf("%d files from %s", pf.Files, pf.Name))
+			}
+			if c.OverriddenLocally > 0 {
+				parts = append(parts, fmt.Sprintf("%d overridden locally", c.OverriddenLocally))
+			}
+			if c.OverriddenByPackage > 0 {
+				parts = append(parts, fmt.Sprintf("%d overridden by packages", c.OverriddenByPackage))
+			}
+			lines = append(lines, fmt.Sprintf("  %s: %s", c.Name, strings.Join(parts, ", ")))
+		}
+	}
+
+	if len(s.Normalized) > 0 {
+		lines = append(lines, "Normalized legacy layouts:")
+		for _, n := range s.Normalized {
+			lines = append(lines, fmt.Sprintf("  %s\t%s\tmoved to %s", n.Package, n.From, n.To))
+		}
+	}
+
+	if len(s.Overlays) > 0 {
+		lines = append(lines, "Overlays applied:")
+		for _, o := range s.Overlays {
+			lines = append(lines, fmt.Sprintf("  %s\t%d", o.Name, o.Files))
+		}
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("Conflicts: %d (%d resolved to local, %d resolved to package)", s.Conflicts(), s.ConflictsToLocal, s.ConflictsToPackage),
+	)
+
+	if s.ConflictsMerged > 0 {
+		lines = append(lines, fmt.Sprintf("Merged: %d YAML files", s.ConflictsMerged))
+	}
+
+	lines = append(lines, fmt.Sprintf("Copied: %s", FormatBytes(s.BytesCopied)))
+
+	if s.Partial != nil {
+		changed := "none"
+		if len(s.Partial.Changed) > 0 {
+			changed = strings.Join(s.Partial.Changed, ", ")
+		}
+		lines = append(lines, fmt.Sprintf("Partial merge: %d paths merged again, %d reused (changed: %s)", s.Partial.Merged, s.Partial.Reused, changed))
+	}
+
+	if s.Substituted > 0 {
+		lines = append(lines, fmt.Sprintf("Substituted: %d files", s.Substituted))
+	}
+
+	if len(s.Phases) > 0 {
+		var phases []string
+		for _, p := range s.Phases {
+			phases = append(phases, fmt.Sprintf("%s %s", p.Name, p.Elapsed.Round(time.Millisecond)))
+		}
+		lines = append(lines, fmt.Sprintf("Elapsed: %s", strings.Join(phases, ", ")))
+	}
+
+	return lines
+}
+
+// FormatBytes returns human-readable size, e.g. 1.5 MiB.
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,116 @@
+package compose
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		512:         "512 B",
+		1024:        "1.0 KiB",
+		1536:        "1.5 KiB",
+		5 * 1 << 20: "5.0 MiB",
+	}
+
+	for in, expected := range tests {
+		if got := FormatBytes(in); got != expected {
+			t.Errorf("FormatBytes(%d) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+func TestCountMergedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	fileInfo, err := os.Stat(filepath.Join(dir, "file"))
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("failed to stat dir: %v", err)
+	}
+
+	entry := func(info fs.FileInfo, from string) *fsEntry {
+		return &fsEntry{Entry: newFileMeta(info), From: from}
+	}
+
+	tree := []*fsEntry{
+		entry(dirInfo, "pkg-b"),
+		entry(fileInfo, "pkg-b"),
+		entry(fileInfo, "pkg-a"),
+		entry(fileInfo, localOrigin),
+		entry(fileInfo, "pkg-b"),
+	}
+
+	files := countMergedFiles(tree, []string{DependencyRoot, "pkg-b", "pkg-a"})
+	expected := []PackageFiles{{Name: localOrigin, Files: 1}, {Name: "pkg-b", Files: 2}, {Name: "pkg-a", Files: 1}}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("expected %v at %d, got %v", expected[i], i, files[i])
+		}
+	}
+}
+
+func TestSummaryPackageMetricsLines(t *testing.T) {
+	s := &Summary{}
+	s.addPackageMetrics(PackageMetrics{Name: "pkg-a", Cache: CacheMiss, Bytes: 2048, Duration: 1500 * time.Millisecond, Auth: "keyring"})
+	s.addPackageMetrics(PackageMetrics{Name: "pkg-b", Cache: CacheHit, Duration: 20 * time.Millisecond})
+	s.addPackageMetrics(PackageMetrics{Name: "pkg-c", Cache: CacheMiss, DiskBytes: 4096, Duration: 20 * time.Millisecond})
+
+	lines := strings.Join(s.Lines(), "\n")
+	for _, expected := range []string{
+		"Downloads:",
+		"  pkg-a\tmiss\t2.0 KiB\t1.5s\tauth: keyring",
+		"  pkg-b\thit\t0 B\t20ms\n",
+		"  pkg-c\tmiss\t4.0 KiB on disk\t20ms",
+	} {
+		if !strings.Contains(lines, expected) {
+			t.Errorf("expected %q in summary:\n%s", expected, lines)
+		}
+	}
+}
+
+func TestCountMergedComponents(t *testing.T) {
+	file := fileMeta{mode: 0644}
+	tree := []*fsEntry{
+		{DstPath: "src/interaction/applications/connect", Entry: fileMeta{mode: fs.ModeDir | 0755}, From: "plasma-core"},
+		{DstPath: "src/interaction/applications/connect/tasks/main.yaml", Entry: file, From: "plasma-core"},
+		{DstPath: "src/interaction/applications/connect/defaults/main.yaml", Entry: file, From: localOrigin},
+		{DstPath: "src/interaction/applications/connect/meta/main.yaml", Entry: file, From: "plasma-core"},
+		{DstPath: "src/platform/services/nginx/tasks/main.yaml", Entry: file, From: "plasma-work"},
+		{DstPath: "src/platform/variables/all.yaml", Entry: file, From: "plasma-work"},
+		{DstPath: "README.md", Entry: file, From: localOrigin},
+	}
+	conflicts := newConflictReport("")
+	conflicts.add("src/interaction/applications/connect/defaults/main.yaml", localOrigin, "plasma-core", tree[2], nil)
+	conflicts.add("src/platform/services/nginx/tasks/main.yaml", "plasma-core", "plasma-work", tree[4], nil)
+
+	components := countMergedComponents(tree, []string{DependencyRoot, "plasma-core", "plasma-work"}, conflicts)
+	expected := []ComponentFiles{
+		{Name: "interaction.applications.connect", Files: []PackageFiles{{Name: localOrigin, Files: 1}, {Name: "plasma-core", Files: 2}}, OverriddenLocally: 1},
+		{Name: "platform.services.nginx", Files: []PackageFiles{{Name: "plasma-work", Files: 1}}, OverriddenByPackage: 1},
+	}
+	if !reflect.DeepEqual(components, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, components)
+	}
+
+	lines := strings.Join((&Summary{Components: components}).Lines(), "\n")
+	expectedLine := "  interaction.applications.connect: 1 files from domain repo, 2 files from plasma-core, 1 overridden locally"
+	if !strings.Contains(lines, expectedLine) {
+		t.Errorf("expected %q in summary:\n%s", expectedLine, lines)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package compose
+
+import "os"
+
+// createSymlink creates a symlink at dest pointing to a slash separated target.
+func createSymlink(target, dest string) error {
+	return os.Symlink(target, dest)
+}
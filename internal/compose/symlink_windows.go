@@ -0,0 +1,25 @@
+//go:build windows
+
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+var errSymlinkUnsupported = errors.New("symlinks can't be created, enable Developer Mode or use --symlinks materialize or skip")
+
+// createSymlink creates a symlink at dest pointing to a slash separated target.
+// Windows resolves only backslash separated targets, creating symlinks requires Developer Mode or administrator rights.
+func createSymlink(target, dest string) error {
+	err := os.Symlink(filepath.FromSlash(target), dest)
+	if errors.Is(err, windows.ERROR_PRIVILEGE_NOT_HELD) {
+		return fmt.Errorf("%w: %w", errSymlinkUnsupported, err)
+	}
+
+	return err
+}
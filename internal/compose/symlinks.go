@@ -0,0 +1,155 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Policies of symlinks merged from packages and the domain repository.
+const (
+	// SymlinksKeep recreates symlinks with their original targets.
+	SymlinksKeep = "keep"
+	// SymlinksRewriteRelative recreates symlinks pointing inside of their package as relative links
+	// to the merged location of the target and skips others.
+	SymlinksRewriteRelative = "rewrite-relative"
+	// SymlinksMaterialize replaces symlinks pointing inside of their package by a copy of the target
+	// and skips others.
+	SymlinksMaterialize = "materialize"
+	// SymlinksSkip leaves symlinks out of the merged output.
+	SymlinksSkip = "skip"
+)
+
+var errInvalidSymlinksPolicy = errors.New("invalid symlinks policy")
+
+func validateSymlinksPolicy(policy string) error {
+	switch policy {
+	case "", SymlinksKeep, SymlinksRewriteRelative, SymlinksMaterialize, SymlinksSkip:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s, %s, %s", errInvalidSymlinksPolicy, policy, SymlinksKeep, SymlinksRewriteRelative, SymlinksMaterialize, SymlinksSkip)
+	}
+}
+
+// copySymlink creates the merged entry of a symlink according to the symlinks policy.
+func (b *Builder) copySymlink(item *fsEntry, sourcePath, destPath string) error {
+	switch b.symlinks {
+	case SymlinksSkip:
+		b.Log().Debug("skipping symlink", "path", item.DstPath, "from", item.From)
+		return nil
+	case SymlinksRewriteRelative:
+		return b.rewriteSymlink(item, sourcePath, destPath)
+	case SymlinksMaterialize:
+		return b.materializeSymlink(item, sourcePath, destPath)
+	default:
+		return lcopy(sourcePath, destPath)
+	}
+}
+
+// symlinkTarget returns the package relative path of the symlink target, false if it points outside of the package.
+func symlinkTarget(item *fsEntry, sourcePath string) (string, bool, error) {
+	target, err := os.Readlink(sourcePath)
+	if err != nil {
+		return "", false, err
+	}
+
+	root := filepath.Clean(item.Prefix)
+	resolved := target
+	if !filepath.IsAbs(target) {
+		resolved = filepath.Join(root, filepath.Dir(item.SrcPath), target)
+	}
+	if !withinDir(root, resolved) {
+		return target, false, nil
+	}
+
+	rel, err := filepath.Rel(root, filepath.Clean(resolved))
+	return rel, err == nil, err
+}
+
+// rewriteSymlink links the merged entry to the merged location of the target.
+func (b *Builder) rewriteSymlink(item *fsEntry, sourcePath, destPath string) error {
+	target, ok, err := symlinkTarget(item, sourcePath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		b.Term().Warning().Printfln("Skipping symlink %s of %s, %s points outside of the package", item.DstPath, item.From, target)
+		return nil
+	}
+
+	dstTarget := filepath.ToSlash(target)
+	if item.From != localOrigin {
+		dstTarget = adjustDestinationPath(dstTarget, hasModernLayout(item.Prefix))
+	}
+
+	link, err := filepath.Rel(filepath.Dir(item.DstPath), dstTarget)
+	if err != nil {
+		return err
+	}
+
+	return createSymlink(filepath.ToSlash(link), destPath)
+}
+
+// materializeSymlink copies the target of the symlink to the merged entry.
+// Symlinks inside of a materialized directory are kept as they are.
+func (b *Builder) materializeSymlink(item *fsEntry, sourcePath, destPath string) error {
+	target, ok, err := symlinkTarget(item, sourcePath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		b.Term().Warning().Printfln("Skipping symlink %s of %s, %s points outside of the package", item.DstPath, item.From, target)
+		return nil
+	}
+
+	// Resolve chains of links, the final target must stay inside of the package too.
+	root, err := filepath.EvalSymlinks(item.Prefix)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(sourcePath)
+	if err != nil || !withinDir(root, resolved) {
+		b.Term().Warning().Printfln("Skipping symlink %s of %s, %s is broken or resolves outside of the package", item.DstPath, item.From, target)
+		return nil
+	}
+
+	return filepath.WalkDir(resolved, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(destPath, rel)
+		mergedPath := filepath.ToSlash(filepath.Join(item.DstPath, rel))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if d.Name() == gitPrefix {
+				return fs.SkipDir
+			}
+			if err = createDir(dst, info.Mode()); err != nil {
+				return err
+			}
+			return os.Chmod(dst, b.permissions.dirMode(mergedPath))
+		case d.Type()&fs.ModeSymlink != 0:
+			return lcopy(path, dst)
+		default:
+			written, err := fcopy(path, dst)
+			if err != nil {
+				return err
+			}
+			b.summary.BytesCopied += written
+			return os.Chmod(dst, b.permissions.fileMode(mergedPath, info.Mode()))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestSymlinksPolicy(t *testing.T) {
+	pkgDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(pkgDir, "platform", "services", "a"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "platform", "services", "a", "main.yaml"), []byte("key: value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"platform/services/abs":    filepath.Join(pkgDir, "platform", "services", "a"),
+		"platform/services/escape": "../../../outside",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(pkgDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	perms, err := newPermissionPolicy("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merge := func(policy, name string) string {
+		targetDir := t.TempDir()
+		b := &Builder{summary: &Summary{}, permissions: perms, symlinks: policy}
+		b.SetLogger(launchr.Log())
+		b.SetTerm(launchr.Term())
+
+		item := &fsEntry{Prefix: pkgDir, SrcPath: name, DstPath: adjustDestinationPath(name, false), From: "pkg"}
+		destPath := filepath.Join(targetDir, item.DstPath)
+		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.copySymlink(item, filepath.Join(pkgDir, name), destPath); err != nil {
+			t.Fatalf("%s of %s failed: %v", policy, name, err)
+		}
+		return destPath
+	}
+
+	if target, _ := os.Readlink(merge(SymlinksKeep, "platform/services/abs")); target != links["platform/services/abs"] {
+		t.Errorf("expected original target to be kept, got %s", target)
+	}
+	if target, _ := os.Readlink(merge(SymlinksRewriteRelative, "platform/services/abs")); target != "a" {
+		t.Errorf("expected relative target, got %s", target)
+	}
+	if _, err = os.Lstat(merge(SymlinksRewriteRelative, "platform/services/escape")); !os.IsNotExist(err) {
+		t.Errorf("expected escaping symlink to be skipped, got %v", err)
+	}
+
+	materialized := merge(SymlinksMaterialize, "platform/services/abs")
+	if info, errStat := os.Lstat(materialized); errStat != nil || !info.IsDir() {
+		t.Fatalf("expected materialized directory, got %v", errStat)
+	}
+	if _, err = os.Stat(filepath.Join(materialized, "main.yaml")); err != nil {
+		t.Errorf("expected target content to be copied: %v", err)
+	}
+
+	if _, err = os.Lstat(merge(SymlinksSkip, "platform/services/abs")); !os.IsNotExist(err) {
+		t.Errorf("expected symlink to be skipped, got %v", err)
+	}
+
+	if err = validateSymlinksPolicy("follow"); err == nil {
+		t.Error("expected error for unknown symlinks policy")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package compose
+
+import "io/fs"
+
+// processUmask returns umask of the current process, there is no umask on this platform.
+func processUmask() fs.FileMode {
+	return 0
+}
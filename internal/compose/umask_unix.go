@@ -0,0 +1,17 @@
+//go:build unix
+
+package compose
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// processUmask returns umask of the current process.
+func processUmask() fs.FileMode {
+	// Umask can only be read by setting it, restore the previous value right away.
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+
+	return fs.FileMode(mask) //nolint:gosec // umask is within permission bits
+}
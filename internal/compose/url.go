@@ -0,0 +1,108 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	errInvalidURL = errors.New("invalid URL")
+
+	// rgxScpURL matches scp-like git URLs, e.g. git@github.com:org/repo.git
+	rgxScpURL = regexp.MustCompile(`^(?:([\w.+-]+)@)?([\w.-]+):([^/\\][^:]*)$`)
+)
+
+// normalizeSourceURL validates package source URL and returns its canonical representation.
+// For git sources scp-like URLs are converted to ssh:// form, trailing slashes are trimmed
+// and .git suffix is appended. For http and release sources only the format is validated
+// and trailing slashes are trimmed.
+func normalizeSourceURL(sourceType, rawURL string) (string, error) {
+	raw := strings.TrimSpace(rawURL)
+	if raw == "" {
+		return "", fmt.Errorf("%w: URL can't be empty", errInvalidURL)
+	}
+
+	if t := strings.ToLower(sourceType); t == HTTPType || t == ReleaseType {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("%w: %q, expected http(s)://host/path", errInvalidURL, raw)
+		}
+
+		return strings.TrimRight(raw, "/"), nil
+	}
+
+	if !strings.Contains(raw, "://") {
+		m := rgxScpURL.FindStringSubmatch(raw)
+		if m == nil {
+			return "", fmt.Errorf("%w: %q, expected scheme://host/path or user@host:path", errInvalidURL, raw)
+		}
+
+		user := m[1]
+		if user != "" {
+			user += "@"
+		}
+		raw = "ssh://" + user + m[2] + "/" + m[3]
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidURL, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+		if u.Host == "" {
+			return "", fmt.Errorf("%w: %q has no host", errInvalidURL, raw)
+		}
+	case "file":
+	default:
+		return "", fmt.Errorf("%w: unsupported scheme %q", errInvalidURL, u.Scheme)
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	if u.Path == "" {
+		return "", fmt.Errorf("%w: %q has no repository path", errInvalidURL, raw)
+	}
+	if !strings.HasSuffix(u.Path, ".git") {
+		u.Path += ".git"
+	}
+	u.RawPath = ""
+
+	return u.String(), nil
+}
+
+// sameSourceURL checks if two package URLs point to the same source.
+func sameSourceURL(sourceType, a, b string) bool {
+	na, errA := normalizeSourceURL(sourceType, a)
+	nb, errB := normalizeSourceURL(sourceType, b)
+	if errA != nil || errB != nil {
+		return strings.TrimSpace(a) == strings.TrimSpace(b)
+	}
+
+	return na == nb
+}
+
+// sameSource checks if two dependencies point to the same package, packages of a monorepo differ by subpath.
+func sameSource(a, b Dependency) bool {
+	return sameSourceURL(b.Source.Type, a.Source.URL, b.Source.URL) &&
+		a.ToPackage(a.Name).GetSubpath() == b.ToPackage(b.Name).GetSubpath()
+}
+
+// urlHost returns the host of a package URL, scp-like git URLs included, or the URL itself if it can't be parsed.
+func urlHost(rawURL string) string {
+	raw := strings.TrimSpace(rawURL)
+	if m := rgxScpURL.FindStringSubmatch(raw); m != nil && !strings.Contains(raw, "://") {
+		return strings.ToLower(m[2])
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	return strings.ToLower(u.Hostname())
+}
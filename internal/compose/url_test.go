@@ -0,0 +1,82 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeSourceURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceType string
+		url        string
+		expected   string
+		wantErr    bool
+	}{
+		{"https", GitType, "https://github.com/org/repo.git", "https://github.com/org/repo.git", false},
+		{"append .git", GitType, "https://github.com/org/repo", "https://github.com/org/repo.git", false},
+		{"trailing slash", GitType, "https://github.com/org/repo/", "https://github.com/org/repo.git", false},
+		{"host case", GitType, "https://GitHub.com/org/repo", "https://github.com/org/repo.git", false},
+		{"scp-like", GitType, "git@github.com:org/repo.git", "ssh://git@github.com/org/repo.git", false},
+		{"scp-like without user", GitType, "github.com:org/repo", "ssh://github.com/org/repo.git", false},
+		{"ssh", GitType, "ssh://git@github.com:22/org/repo", "ssh://git@github.com:22/org/repo.git", false},
+		{"spaces", GitType, "  https://github.com/org/repo  ", "https://github.com/org/repo.git", false},
+		{"empty", GitType, "", "", true},
+		{"no scheme", GitType, "github.com/org/repo", "", true},
+		{"unsupported scheme", GitType, "ftp://github.com/org/repo", "", true},
+		{"no path", GitType, "https://github.com", "", true},
+		{"http archive", HTTPType, "https://example.com/a.tar.gz/", "https://example.com/a.tar.gz", false},
+		{"http invalid", HTTPType, "git@github.com:org/repo.git", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSourceURL(tt.sourceType, tt.url)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidURL) {
+					t.Fatalf("expected invalid URL error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSameSourceURL(t *testing.T) {
+	if !sameSourceURL(GitType, "git@github.com:org/repo", "ssh://git@github.com/org/repo.git/") {
+		t.Error("expected scp-like and ssh URLs to match")
+	}
+	if sameSourceURL(GitType, "https://github.com/org/repo", "https://github.com/org/other") {
+		t.Error("expected different repositories not to match")
+	}
+}
+
+func TestTemplatedHTTPURL(t *testing.T) {
+	pkg := &Package{Name: "archive", Source: Source{Type: HTTPType, Ref: "1.2.0", URL: "https://example.com/pkg/{ref}/pkg-{ref}.tar.gz"}}
+	if got := pkg.GetURL(); got != "https://example.com/pkg/1.2.0/pkg-1.2.0.tar.gz" {
+		t.Errorf("expected ref to be substituted, got %s", got)
+	}
+	if got := pkg.GetIdentifier(); got != "example.com/pkg/{ref}/pkg-{ref}.tar.gz@1.2.0" {
+		t.Errorf("expected identifier to keep the template, got %s", got)
+	}
+	if _, err := normalizeSourceURL(HTTPType, pkg.Source.URL); err != nil {
+		t.Errorf("expected templated URL to be valid, got %v", err)
+	}
+
+	gitPkg := &Package{Name: "repo", Source: Source{Type: GitType, Ref: "main", URL: "https://example.com/{ref}.git"}}
+	if got := gitPkg.GetURL(); got != gitPkg.Source.URL {
+		t.Errorf("expected git URL to be kept, got %s", got)
+	}
+
+	pkg.Source.Ref = ""
+	if err := newHTTP(&keyringWrapper{}, "").Download(context.Background(), pkg, t.TempDir()); !errors.Is(err, errNoURL) {
+		t.Errorf("expected templated URL without ref to fail, got %v", err)
+	}
+}
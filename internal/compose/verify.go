@@ -0,0 +1,71 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errUnsafeOutput = errors.New("unsafe merged output")
+
+// verifyMergedOutput checks that symlinks of dir resolve to existing entries inside dir.
+// It returns a list of problems found.
+func verifyMergedOutput(dir string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == gitPrefix {
+			return fs.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		if filepath.IsAbs(target) {
+			problems = append(problems, fmt.Sprintf("%s: symlink to absolute path %s", rel, target))
+			return nil
+		}
+		if !withinDir(root, filepath.Join(root, filepath.Dir(rel), target)) {
+			problems = append(problems, fmt.Sprintf("%s: symlink %s points outside of merged directory", rel, target))
+			return nil
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: broken symlink %s", rel, target))
+			return nil
+		}
+		if !withinDir(root, resolved) {
+			problems = append(problems, fmt.Sprintf("%s: symlink %s resolves outside of merged directory", rel, target))
+		}
+
+		return nil
+	})
+
+	return problems, err
+}
+
+func withinDir(root, path string) bool {
+	path = filepath.Clean(path)
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
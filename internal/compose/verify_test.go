@@ -0,0 +1,46 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyMergedOutput(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src", "app"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "app", "main.yaml"), []byte("a: b\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"src/app/ok.yaml":     "main.yaml",
+		"src/app/broken.yaml": "missing.yaml",
+		"src/app/escape.yaml": "../../../etc/passwd",
+		"src/app/absolute":    "/etc/passwd",
+		"src/app/parent-ok":   "../app",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := verifyMergedOutput(dir)
+	if err != nil {
+		t.Fatalf("verifyMergedOutput failed: %v", err)
+	}
+
+	joined := strings.Join(problems, "\n")
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %d:\n%s", len(problems), joined)
+	}
+	for _, name := range []string{"broken.yaml", "escape.yaml", "absolute"} {
+		if !strings.Contains(joined, name) {
+			t.Errorf("expected problem for %s, got:\n%s", name, joined)
+		}
+	}
+}
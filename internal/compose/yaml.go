@@ -13,16 +13,36 @@ var composePermissions uint32 = 0644
 // Re-export for internal use
 var (
 	Lookup       = model.Lookup
+	LookupStrict = model.LookupStrict
 	TargetLatest = model.TargetLatest
+
+	ErrConflictPolicy = model.ErrConflictPolicy
+	ErrLockOutOfDate  = model.ErrLockOutOfDate
 )
 
 // Type aliases for internal use
 type (
-	Composition = model.Composition
-	Package     = model.Package
-	Dependency  = model.Dependency
-	Strategy    = model.Strategy
-	Source      = model.Source
+	Composition    = model.Composition
+	Package        = model.Package
+	Dependency     = model.Dependency
+	Strategy       = model.Strategy
+	Layers         = model.Layers
+	Source         = model.Source
+	Overlay        = model.Overlay
+	Substitution   = model.Substitution
+	Permissions    = model.Permissions
+	PermissionRule = model.PermissionRule
+	Metadata       = model.Metadata
+	Excluded       = model.Excluded
+	ErrAuthFailed  = model.ErrAuthFailed
+	ErrRefNotFound = model.ErrRefNotFound
+	NestedStrategy = model.NestedStrategy
+	Normalization  = model.Normalization
+	PackageFiles   = model.PackageFiles
+	ComponentFiles = model.ComponentFiles
+	PackageMetrics = model.PackageMetrics
+	PhaseTiming    = model.PhaseTiming
+	PartialMerge   = model.PartialMerge
 )
 
 func writeComposeYaml(cfg *Composition) error {
@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var errMergeYAML = errors.New("can't merge YAML")
+
+// isYAMLPath reports if the merged path is a YAML file, merge-yaml only applies to them.
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// deepMergeYAML deep-merges YAML mappings of files in order: nested mappings are merged key by key,
+// other values of later files replace earlier ones, lists included. Key order and comments of earlier files are kept.
+func deepMergeYAML(paths []string) ([]byte, error) {
+	var merged *yaml.Node
+	for _, p := range paths {
+		doc, err := readYAMLMapping(p)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		if merged == nil {
+			merged = doc
+			continue
+		}
+		mergeYAMLNode(merged.Content[0], doc.Content[0])
+	}
+
+	if merged == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(merged); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readYAMLMapping returns the document of a YAML file holding a single mapping, nil for empty files.
+func readYAMLMapping(path string) (*yaml.Node, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	var doc yaml.Node
+	if err = dec.Decode(&doc); errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errMergeYAML, path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: %s isn't a YAML mapping", errMergeYAML, path)
+	}
+	if err = dec.Decode(&yaml.Node{}); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %s has more than one YAML document", errMergeYAML, path)
+	}
+
+	return &doc, nil
+}
+
+func mergeYAMLNode(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		j := -1
+		for k := 0; k+1 < len(dst.Content); k += 2 {
+			if dst.Content[k].Value == key.Value {
+				j = k
+				break
+			}
+		}
+
+		switch {
+		case j < 0:
+			dst.Content = append(dst.Content, key, value)
+		case dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeYAMLNode(dst.Content[j+1], value)
+		default:
+			dst.Content[j+1] = value
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeYAMLFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	local := write("local.yaml", "# kept\nname: local\nnested:\n  a: 1\n  list: [1, 2]\n")
+	pkg := write("pkg.yaml", "nested:\n  b: 2\n  list: [3]\nextra: true\n")
+
+	content, err := deepMergeYAML([]string{local, pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# kept\nname: local\nnested:\n  a: 1\n  list: [3]\n  b: 2\nextra: true\n"
+	if string(content) != expected {
+		t.Errorf("unexpected merge result:\n%s", content)
+	}
+
+	scalar := write("scalar.yml", "- item\n")
+	if _, err = deepMergeYAML([]string{local, scalar}); err == nil || !strings.Contains(err.Error(), "isn't a YAML mapping") {
+		t.Errorf("expected error for a non mapping file, got %v", err)
+	}
+
+	entries := map[string]*fsEntry{}
+	for _, name := range []string{"local.yaml", "pkg.yaml", "scalar.yml"} {
+		finfo, err := os.Lstat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[name] = &fsEntry{Prefix: dir, SrcPath: name, Entry: newFileMeta(finfo), From: name}
+	}
+	strategies := []*mergeStrategy{{s: mergeYAMLFiles, t: packageStrategy, paths: []string{"src/"}}}
+	entriesMap := map[string]*fsEntry{"src/vars.yaml": entries["local.yaml"]}
+	if _, resolve, _ := addStrategyEntries(strategies, nil, entriesMap, entries["pkg.yaml"], "src/vars.yaml", newConflictResolver("")); resolve != resolveMerged {
+		t.Errorf("expected YAML conflict to be merged, got %d", resolve)
+	}
+	if entriesMap["src/vars.yaml"].mergedYAML != entries["pkg.yaml"] {
+		t.Error("expected package file to be chained for merge")
+	}
+	if action, _ := decideEntry(strategies, entries["local.yaml"], entries["pkg.yaml"], "src/vars.txt", newConflictResolver("")); action != skipEntry {
+		t.Errorf("expected non YAML paths to resolve as usual, got %d", action)
+	}
+}
@@ -0,0 +1,93 @@
+// Package fsutil provides file copying shared by the copy phases of compose, prepare and bundle.
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// bufferSize is the size of pooled copy buffers, larger than 32 KiB of io.Copy to cut syscalls on big files.
+const bufferSize = 256 << 10
+
+var errNotRegular = errors.New("not a regular file")
+
+var buffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, bufferSize)
+		return &b
+	},
+}
+
+// Copy copies src to dst with a pooled buffer.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := buffers.Get().(*[]byte)
+	defer buffers.Put(buf)
+
+	// Hide ReaderFrom and WriterTo, their generic fallbacks allocate a buffer per call.
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+// CopyFile copies the regular file src to dst and returns the number of bytes copied.
+// Missing dst is created with perm before umask, clones on macOS keep the mode of src. Existing dst is
+// truncated and keeps its mode.
+// Copy-on-write clones are made where the platform and the filesystem support them: clonefile on macOS
+// and reflinks on Linux. Otherwise dst is preallocated and filled by copy_file_range on Linux
+// or through a pooled buffer.
+func CopyFile(src, dst string, perm fs.FileMode) (int64, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%s is %w", src, errNotRegular)
+	}
+
+	if clonePath(src, dst) {
+		return info.Size(), nil
+	}
+
+	source, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(filepath.Clean(dst), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := copyContents(destination, source, info.Size())
+	if err != nil {
+		destination.Close()
+		return written, err
+	}
+
+	return written, destination.Close()
+}
+
+func copyContents(dst, src *os.File, size int64) (int64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+	if cloneFile(dst, src) {
+		return size, nil
+	}
+
+	preallocate(dst, size)
+	written, err := kernelCopy(dst, src)
+	if err != nil {
+		return written, err
+	}
+	if written < size {
+		// The source shrank while copying, drop the preallocated tail.
+		err = dst.Truncate(written)
+	}
+
+	return written, err
+}
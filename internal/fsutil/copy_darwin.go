@@ -0,0 +1,29 @@
+//go:build darwin
+
+package fsutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// clonePath makes dst a copy-on-write clone of src on APFS. Clones keep the mode of src,
+// existing dst isn't replaced, it is copied over instead.
+func clonePath(src, dst string) bool {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW) == nil
+}
+
+// cloneFile is not used on macOS, clones are made from paths by clonePath.
+func cloneFile(_, _ *os.File) bool {
+	return false
+}
+
+// preallocate extends dst to its final size before it is written.
+func preallocate(f *os.File, size int64) {
+	_ = f.Truncate(size)
+}
+
+func kernelCopy(dst, src *os.File) (int64, error) {
+	return Copy(dst, src)
+}
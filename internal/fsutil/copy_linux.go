@@ -0,0 +1,29 @@
+//go:build linux
+
+package fsutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// clonePath is not used on Linux, reflinks are made between open files by cloneFile.
+func clonePath(_, _ string) bool {
+	return false
+}
+
+// cloneFile makes dst a reflink of src on filesystems sharing extents, e.g. btrfs and XFS.
+func cloneFile(dst, src *os.File) bool {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())) == nil //nolint:gosec // file descriptors fit int
+}
+
+// preallocate reserves blocks of dst, filesystems without fallocate are left as they are.
+func preallocate(f *os.File, size int64) {
+	_ = unix.Fallocate(int(f.Fd()), 0, 0, size) //nolint:gosec // file descriptors fit int
+}
+
+// kernelCopy copies by copy_file_range or sendfile of os.File.ReadFrom, contents don't pass user space.
+func kernelCopy(dst, src *os.File) (int64, error) {
+	return dst.ReadFrom(src)
+}
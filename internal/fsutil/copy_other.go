@@ -0,0 +1,22 @@
+//go:build !linux && !darwin
+
+package fsutil
+
+import "os"
+
+func clonePath(_, _ string) bool {
+	return false
+}
+
+func cloneFile(_, _ *os.File) bool {
+	return false
+}
+
+// preallocate extends dst to its final size before it is written.
+func preallocate(f *os.File, size int64) {
+	_ = f.Truncate(size)
+}
+
+func kernelCopy(dst, src *os.File) (int64, error) {
+	return Copy(dst, src)
+}
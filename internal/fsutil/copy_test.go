@@ -0,0 +1,56 @@
+package fsutil
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	content := bytes.Repeat([]byte("0123456789"), bufferSize/5)
+	if err := os.WriteFile(src, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := os.WriteFile(dst, bytes.Repeat([]byte("x"), 3*len(content)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := CopyFile(src, dst, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(len(content)) || !bytes.Equal(got, content) {
+		t.Errorf("expected %d bytes copied over the longer destination, got %d written and %d bytes", len(content), written, len(got))
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err = os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if written, err = CopyFile(empty, filepath.Join(dir, "empty-copy"), 0600); err != nil || written != 0 {
+		t.Errorf("expected empty file to be copied, got %d, %v", written, err)
+	}
+
+	if _, err = CopyFile(dir, filepath.Join(dir, "dir-copy"), 0600); !errors.Is(err, errNotRegular) {
+		t.Errorf("expected directory to be rejected, got %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	var dst bytes.Buffer
+	content := bytes.Repeat([]byte("a"), 3*bufferSize+1)
+	written, err := Copy(&dst, bytes.NewReader(content))
+	if err != nil || written != int64(len(content)) || !bytes.Equal(dst.Bytes(), content) {
+		t.Errorf("expected %d bytes copied, got %d, %v", len(content), written, err)
+	}
+}
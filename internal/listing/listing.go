@@ -0,0 +1,138 @@
+// Package listing provides sorting and pagination of listed packages.
+package listing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+	"github.com/plasmash/plasmactl-platform/pkg/graph"
+)
+
+// Sort keys of listed packages.
+const (
+	SortName       = "name"
+	SortRef        = "ref"
+	SortComponents = "components"
+	SortSize       = "size"
+)
+
+var errInvalidListing = errors.New("invalid listing option")
+
+// Entry stores sort keys of a listed package.
+type Entry struct {
+	Name       string
+	Ref        string
+	Components int
+	Size       int64
+}
+
+// Options stores sorting and pagination of a listing.
+type Options struct {
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// Validate checks sort key and pagination bounds.
+func (o Options) Validate() error {
+	switch o.Sort {
+	case "", SortName, SortRef, SortComponents, SortSize:
+	default:
+		return fmt.Errorf("%w: sort %q, expected one of: %s, %s, %s, %s", errInvalidListing, o.Sort, SortName, SortRef, SortComponents, SortSize)
+	}
+
+	if o.Limit < 0 || o.Offset < 0 {
+		return fmt.Errorf("%w: limit and offset can't be negative", errInvalidListing)
+	}
+
+	return nil
+}
+
+// Apply returns indexes of entries in the requested order and page.
+// Without sort key the original order is kept. Components and size are sorted in descending order,
+// ties are ordered by name.
+func (o Options) Apply(entries []Entry) []int {
+	idx := make([]int, len(entries))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	if o.Sort != "" {
+		sort.SliceStable(idx, func(i, j int) bool {
+			a, b := entries[idx[i]], entries[idx[j]]
+			switch o.Sort {
+			case SortRef:
+				if a.Ref != b.Ref {
+					return a.Ref < b.Ref
+				}
+			case SortComponents:
+				if a.Components != b.Components {
+					return a.Components > b.Components
+				}
+			case SortSize:
+				if a.Size != b.Size {
+					return a.Size > b.Size
+				}
+			}
+			return a.Name < b.Name
+		})
+	}
+
+	start := min(o.Offset, len(idx))
+	end := len(idx)
+	if o.Limit > 0 {
+		end = min(start+o.Limit, end)
+	}
+
+	return idx[start:end]
+}
+
+// NeedsComponents checks if component counts are required for sorting.
+func (o Options) NeedsComponents() bool {
+	return o.Sort == SortComponents
+}
+
+// NeedsSize checks if package sizes are required for sorting.
+func (o Options) NeedsSize() bool {
+	return o.Sort == SortSize
+}
+
+// Dependencies returns the requested page of dependencies in the requested order.
+// Components counts packages components, it's only called when sorting by components.
+// Size is measured on the downloaded packages located by checkouts.
+func (o Options) Dependencies(deps []model.Dependency, checkouts *compose.Checkouts, components func(name string) int) []model.Dependency {
+	entries := make([]Entry, len(deps))
+	for i, dep := range deps {
+		pkg := dep.ToPackage(dep.Name)
+		entries[i] = Entry{Name: pkg.GetName(), Ref: pkg.GetTarget()}
+		if o.NeedsComponents() && components != nil {
+			entries[i].Components = components(dep.Name)
+		}
+		if o.NeedsSize() {
+			entries[i].Size = compose.DirSize(checkouts.PackageDir(pkg))
+		}
+	}
+
+	var result []model.Dependency
+	for _, i := range o.Apply(entries) {
+		result = append(result, deps[i])
+	}
+
+	return result
+}
+
+// ComponentCounter returns a function counting components contained by a package in the graph.
+func ComponentCounter(g *graph.PlatformGraph) func(name string) int {
+	return func(name string) int {
+		var count int
+		for _, e := range g.EdgesFrom(name, "contains") {
+			if e.To().Type == "component" {
+				count++
+			}
+		}
+		return count
+	}
+}
@@ -0,0 +1,41 @@
+package listing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	entries := []Entry{
+		{Name: "work", Ref: "v2.0.0", Components: 3, Size: 100},
+		{Name: "core", Ref: "v1.0.0", Components: 10, Size: 50},
+		{Name: "addons", Ref: "v1.0.0", Components: 3, Size: 300},
+	}
+
+	tests := []struct {
+		opts Options
+		want []int
+	}{
+		{Options{}, []int{0, 1, 2}},
+		{Options{Sort: SortName}, []int{2, 1, 0}},
+		{Options{Sort: SortRef}, []int{2, 1, 0}},
+		{Options{Sort: SortComponents}, []int{1, 2, 0}},
+		{Options{Sort: SortSize}, []int{2, 0, 1}},
+		{Options{Sort: SortName, Limit: 2}, []int{2, 1}},
+		{Options{Sort: SortName, Offset: 1, Limit: 1}, []int{1}},
+		{Options{Offset: 5}, []int{}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.opts.Apply(entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Apply(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+
+	if err := (Options{Sort: "date"}).Validate(); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+	if err := (Options{Limit: -1}).Validate(); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
@@ -0,0 +1,83 @@
+// Package output provides markers of terminal output with plain ASCII fallback.
+package output
+
+import "os"
+
+// Markers stores symbols used in terminal output.
+type Markers struct {
+	Check      string
+	Package    string
+	Component  string
+	Zone       string
+	Node       string
+	Branch     string
+	LastBranch string
+	Indent     string
+	LastIndent string
+	Arrow      string
+}
+
+var (
+	fancyMarkers = Markers{
+		Check:      "✓",
+		Package:    "📦 ",
+		Component:  "🧩 ",
+		Zone:       "📍 ",
+		Node:       "🖥  ",
+		Branch:     "├── ",
+		LastBranch: "└── ",
+		Indent:     "│   ",
+		LastIndent: "    ",
+		Arrow:      "→",
+	}
+
+	plainMarkers = Markers{
+		Check:      "+",
+		Package:    "[package] ",
+		Component:  "[component] ",
+		Zone:       "[zone] ",
+		Node:       "[node] ",
+		Branch:     "|-- ",
+		LastBranch: "`-- ",
+		Indent:     "|   ",
+		LastIndent: "    ",
+		Arrow:      "->",
+	}
+
+	plain bool
+)
+
+// SetPlain forces ASCII markers, the returned function restores the previous mode,
+// so actions run in-process by other plugins don't change output of their caller.
+func SetPlain(v bool) (restore func()) {
+	previous := plain
+	plain = v
+
+	return func() {
+		plain = previous
+	}
+}
+
+// IsPlain checks if ASCII markers must be used: forced with SetPlain,
+// NO_COLOR environment variable is set or the terminal is dumb.
+func IsPlain() bool {
+	return plain || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
+// Get returns markers for the current output mode.
+func Get() Markers {
+	if IsPlain() {
+		return plainMarkers
+	}
+
+	return fancyMarkers
+}
+
+// Tree returns tree branch of an item and indentation of its children.
+func (m Markers) Tree(last bool) (branch, indent string) {
+	if last {
+		return m.LastBranch, m.LastIndent
+	}
+
+	return m.Branch, m.Indent
+}
@@ -0,0 +1,47 @@
+package output
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	SetPlain(false)
+	if Get().Check != "✓" {
+		t.Errorf("expected unicode markers by default")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if Get() != plainMarkers {
+		t.Errorf("expected plain markers with NO_COLOR")
+	}
+
+	t.Setenv("NO_COLOR", "")
+	defer SetPlain(true)()
+	branch, indent := Get().Tree(true)
+	if branch != "`-- " || indent != "    " {
+		t.Errorf("unexpected plain tree markers %q %q", branch, indent)
+	}
+	if Get().Arrow != "->" {
+		t.Errorf("expected ASCII arrow, got %q", Get().Arrow)
+	}
+}
+
+func TestSetPlainRestore(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	restore := SetPlain(true)
+	restoreNested := SetPlain(false)
+	if IsPlain() {
+		t.Errorf("expected fancy markers of nested mode")
+	}
+	restoreNested()
+	if !IsPlain() {
+		t.Errorf("expected plain markers restored")
+	}
+	restore()
+	if IsPlain() {
+		t.Errorf("expected fancy markers restored")
+	}
+}
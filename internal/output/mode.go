@@ -0,0 +1,41 @@
+package output
+
+import (
+	"errors"
+	"strings"
+)
+
+var errConflictingModes = errors.New("--quiet and --porcelain can't be used together")
+
+// Mode selects terminal output of listing actions. Structured results are returned in every mode.
+// Quiet prints nothing but errors, porcelain prints records of tab separated fields which
+// don't change between versions, unlike human-readable output.
+type Mode struct {
+	Quiet     bool
+	Porcelain bool
+}
+
+// Validate checks the modes are not combined.
+func (m Mode) Validate() error {
+	if m.Quiet && m.Porcelain {
+		return errConflictingModes
+	}
+
+	return nil
+}
+
+// Human checks if human-readable output must be printed.
+func (m Mode) Human() bool {
+	return !m.Quiet && !m.Porcelain
+}
+
+var fieldReplacer = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+// Fields formats a porcelain record. Tabs and line breaks of values are replaced by spaces.
+func Fields(fields ...string) string {
+	for i, f := range fields {
+		fields[i] = fieldReplacer.Replace(f)
+	}
+
+	return strings.Join(fields, "\t")
+}
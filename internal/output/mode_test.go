@@ -0,0 +1,13 @@
+package output
+
+import "testing"
+
+func TestFields(t *testing.T) {
+	if line := Fields("core", "v1.0.0", "multi\nline\tvalue", ""); line != "core\tv1.0.0\tmulti line value\t" {
+		t.Errorf("unexpected porcelain record %q", line)
+	}
+
+	if err := (Mode{Quiet: true, Porcelain: true}).Validate(); err == nil {
+		t.Error("expected error combining quiet and porcelain")
+	}
+}
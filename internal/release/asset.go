@@ -0,0 +1,58 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shortDigestLength is the number of hex characters of the digest embedded in asset names.
+const shortDigestLength = 8
+
+// AssetDigest stores name of an uploaded asset and SHA-256 digest of its content
+type AssetDigest struct {
+	Name   string
+	SHA256 string
+}
+
+// DigestFile returns hex encoded SHA-256 digest of the file
+func DigestFile(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DigestAssetName embeds the short digest in the asset name before its extension,
+// e.g. model-1.4.0.pm becomes model-1.4.0-ab12cd34.pm
+func DigestAssetName(name, digest string) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), digest[:min(shortDigestLength, len(digest))], ext)
+}
+
+// FormatAssetDigests formats digests of assets into a markdown release body section
+func FormatAssetDigests(assets []AssetDigest) string {
+	if len(assets) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### Assets\n\n")
+	for _, a := range assets {
+		fmt.Fprintf(&sb, "- `%s` sha256:%s\n", a.Name, a.SHA256)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigestAssetName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model-1.4.0.pm")
+	if err := os.WriteFile(path, []byte("bundle"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	digest, err := DigestFile(path)
+	if err != nil {
+		t.Fatalf("DigestFile failed: %v", err)
+	}
+	if len(digest) != 64 {
+		t.Fatalf("expected hex SHA-256 digest, got %q", digest)
+	}
+
+	if name := DigestAssetName("model-1.4.0.pm", digest); name != "model-1.4.0-"+digest[:8]+".pm" {
+		t.Errorf("unexpected asset name %q", name)
+	}
+
+	body := FormatAssetDigests([]AssetDigest{{Name: "model-1.4.0-" + digest[:8] + ".pm", SHA256: digest}})
+	expected := "### Assets\n\n- `model-1.4.0-" + digest[:8] + ".pm` sha256:" + digest + "\n"
+	if body != expected {
+		t.Errorf("unexpected release body section %q", body)
+	}
+}
@@ -0,0 +1,273 @@
+package release
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// Bitbucket Cloud implementation
+//
+// Bitbucket has no release objects, assets are published as repository downloads. A release of a tag is
+// its notes uploaded as the download bitbucketNotesName(tag) and the assets uploaded along with them.
+
+// errBitbucketDataCenter is returned by detection of self-hosted Bitbucket, it has no downloads API.
+var errBitbucketDataCenter = fmt.Errorf("%w: Bitbucket Data Center has no downloads API, only Bitbucket Cloud is supported", errInvalidForgeType)
+
+// bitbucketNotesName returns the name of the download storing notes of the release of tag.
+func bitbucketNotesName(tag string) string {
+	return "release-notes-" + tag + ".md"
+}
+
+// bitbucketAPIURL returns Bitbucket API base URL, Bitbucket Cloud serves it on a separate host
+func (f *Forge) bitbucketAPIURL() string {
+	if f.host == "bitbucket.org" {
+		return "https://api.bitbucket.org/2.0"
+	}
+
+	return "https://" + f.host + "/2.0"
+}
+
+// bitbucketAuthorization returns Authorization header of Bitbucket requests. Access tokens are sent as Bearer,
+// app passwords given as username:app_password use Basic authentication.
+func (f *Forge) bitbucketAuthorization() string {
+	if strings.Contains(f.token, ":") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(f.token))
+	}
+
+	return "Bearer " + f.token
+}
+
+// createBitbucketRelease uploads notes of the release, drafts aren't supported and the release is visible at once
+func (f *Forge) createBitbucketRelease(tag, changelog string) (*ReleaseInfo, error) {
+	name := bitbucketNotesName(tag)
+	if err := f.uploadBitbucketDownload(name, strings.NewReader(changelog)); err != nil {
+		return nil, err
+	}
+	f.trackBitbucketDownload(tag, name)
+
+	return &ReleaseInfo{ID: tag, URL: "https://" + f.host + "/" + f.repo + "/downloads/"}, nil // Bitbucket uses tag as release ID
+}
+
+// uploadBitbucketAsset uploads the file as a download of the repository and returns its download URL
+func (f *Forge) uploadBitbucketAsset(tag, filePath, fileName string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err = f.uploadBitbucketDownload(fileName, file); err != nil {
+		return "", err
+	}
+	f.trackBitbucketDownload(tag, fileName)
+
+	return "https://" + f.host + "/" + f.repo + "/downloads/" + url.PathEscape(fileName), nil
+}
+
+// uploadBitbucketDownload uploads content as a download named name, an existing download of the name is replaced
+func (f *Forge) uploadBitbucketDownload(name string, content io.Reader) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("files", name)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, content); err != nil {
+		return err
+	}
+	if err = mw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", f.bitbucketAPIURL()+"/repositories/"+f.repo+"/downloads", &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", f.bitbucketAuthorization())
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return f.responseError(resp, "upload download "+name, body)
+	}
+
+	return nil
+}
+
+// trackBitbucketDownload records downloads uploaded for the release of tag, they are deleted with the release
+func (f *Forge) trackBitbucketDownload(tag, name string) {
+	if f.bitbucketDownloads == nil {
+		f.bitbucketDownloads = make(map[string][]string)
+	}
+	f.bitbucketDownloads[tag] = append(f.bitbucketDownloads[tag], name)
+}
+
+// deleteBitbucketRelease deletes downloads uploaded for the release of tag, its notes if none were uploaded
+// by this client. Downloads already deleted are skipped.
+func (f *Forge) deleteBitbucketRelease(tag string) error {
+	names := f.bitbucketDownloads[tag]
+	if len(names) == 0 {
+		names = []string{bitbucketNotesName(tag)}
+	}
+
+	for _, name := range names {
+		req, err := http.NewRequest("DELETE", f.bitbucketAPIURL()+"/repositories/"+f.repo+"/downloads/"+url.PathEscape(name), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", f.bitbucketAuthorization())
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return f.responseError(resp, "delete download "+name, body)
+		}
+	}
+	delete(f.bitbucketDownloads, tag)
+
+	return nil
+}
+
+// listBitbucketDownloads lists downloads of the repository, following pages
+func (f *Forge) listBitbucketDownloads() ([]releaseAsset, error) {
+	var assets []releaseAsset
+	next := f.bitbucketAPIURL() + "/repositories/" + f.repo + "/downloads?pagelen=100"
+	for next != "" {
+		req, err := http.NewRequest("GET", next, nil)
+		if err != nil {
+			return nil, err
+		}
+		f.authorize(req)
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, f.responseError(resp, "list downloads", body)
+		}
+
+		var page struct {
+			Values []struct {
+				Name  string `json:"name"`
+				Links struct {
+					Self struct {
+						Href string `json:"href"`
+					} `json:"self"`
+				} `json:"links"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		for _, v := range page.Values {
+			assets = append(assets, releaseAsset{name: v.Name, url: v.Links.Self.Href})
+		}
+		next = page.Next
+	}
+
+	return assets, nil
+}
+
+// bitbucketTagAsset reports if the download name belongs to the release of tag. Names contain the tag as
+// a segment like bundles named {name}-{tag}.pm by model:bundle, optionally followed by the short digest of
+// --digest-names, so tag v1.2 doesn't match downloads of v1.2.3 or v1.2-rc1.
+func bitbucketTagAsset(name, tag string) bool {
+	re := regexp.MustCompile(`-` + regexp.QuoteMeta(tag) + `(-[0-9a-f]{` + strconv.Itoa(shortDigestLength) + `})?(\.[^0-9]|$)`)
+	return re.MatchString(name)
+}
+
+// bitbucketReleaseAssets lists downloads of the release of tag. Downloads uploaded by this client are
+// recorded, others are matched by the tag segment of their names.
+func (f *Forge) bitbucketReleaseAssets(tag string) ([]releaseAsset, error) {
+	downloads, err := f.listBitbucketDownloads()
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded := f.bitbucketDownloads[tag]
+	var assets []releaseAsset
+	for _, a := range downloads {
+		if a.name == bitbucketNotesName(tag) {
+			continue
+		}
+		if slices.Contains(uploaded, a.name) || len(uploaded) == 0 && bitbucketTagAsset(a.name, tag) {
+			assets = append(assets, a)
+		}
+	}
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("%w: no download of release %s of %s", errAssetNotFound, tag, f.repo)
+	}
+
+	return assets, nil
+}
+
+// checkBitbucketPermissions verifies the repository is visible with the token. Bitbucket doesn't disclose
+// permissions of access tokens, missing write access is reported by the first upload.
+func (f *Forge) checkBitbucketPermissions() error {
+	req, err := http.NewRequest("GET", f.bitbucketAPIURL()+"/repositories/"+f.repo, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", f.bitbucketAuthorization())
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return &model.ErrAuthFailed{Host: f.host, Err: fmt.Errorf("%w: repository %s isn't visible with the token", errTokenPermissions, f.repo)}
+	default:
+		return f.responseError(resp, "check token permissions", body)
+	}
+}
+
+// bitbucketReleaseExists reports if notes of the release of tag were uploaded
+func (f *Forge) bitbucketReleaseExists(tag string) (bool, error) {
+	downloads, err := f.listBitbucketDownloads()
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range downloads {
+		if a.name == bitbucketNotesName(tag) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,146 @@
+package release
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBitbucketRelease(t *testing.T) {
+	var mu sync.Mutex
+	downloads := make(map[string]string)
+	var authorizations []string
+	var srvURL string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+
+		const prefix = "/2.0/repositories/workspace/repo/downloads"
+		switch {
+		case r.Method == "POST" && r.URL.Path == prefix:
+			file, header, err := r.FormFile("files")
+			if err != nil {
+				t.Errorf("expected files field: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, _ := io.ReadAll(file)
+			downloads[header.Filename] = string(content)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "GET" && r.URL.Path == prefix:
+			var values []string
+			for _, name := range slices.Sorted(maps.Keys(downloads)) {
+				values = append(values, `{"name": "`+name+`", "links": {"self": {"href": "`+srvURL+prefix+"/"+name+`"}}}`)
+			}
+			_, _ = w.Write([]byte(`{"values": [` + strings.Join(values, ",") + `]}`))
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, prefix+"/"):
+			content, ok := downloads[strings.TrimPrefix(r.URL.Path, prefix+"/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(content))
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, prefix+"/"):
+			delete(downloads, strings.TrimPrefix(r.URL.Path, prefix+"/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "workspace/repo", "user:app-password")
+	f.client = srv.Client()
+	if err := f.SetType(ForgeBitbucket); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := f.CreateRelease("v1.0.0", "changelog", true)
+	if err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	if info.ID != "v1.0.0" || !strings.HasSuffix(info.URL, "/workspace/repo/downloads/") {
+		t.Errorf("unexpected release info: %+v", info)
+	}
+	if downloads[bitbucketNotesName("v1.0.0")] != "changelog" {
+		t.Errorf("expected notes to be uploaded, got %v", downloads)
+	}
+
+	asset := filepath.Join(t.TempDir(), "platform-v1.0.0.pm")
+	if err = os.WriteFile(asset, []byte("pm"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assetURL, err := f.UploadAsset(info.ID, asset)
+	if err != nil {
+		t.Fatalf("failed to upload asset: %v", err)
+	}
+	if !strings.HasSuffix(assetURL, "/workspace/repo/downloads/platform-v1.0.0.pm") {
+		t.Errorf("unexpected asset URL: %s", assetURL)
+	}
+	if !strings.HasPrefix(authorizations[0], "Basic ") {
+		t.Errorf("expected app password to use Basic authentication, got %s", authorizations[0])
+	}
+
+	if exists, err := f.ReleaseExists("v1.0.0"); err != nil || !exists {
+		t.Errorf("expected release v1.0.0 to exist, got %v, %v", exists, err)
+	}
+	if exists, err := f.ReleaseExists("v1.1.0"); err != nil || exists {
+		t.Errorf("expected release v1.1.0 not to exist, got %v, %v", exists, err)
+	}
+
+	fpath, size, err := f.DownloadAsset("v1.0.0", "platform-*.pm", t.TempDir())
+	if err != nil || size != 2 || filepath.Base(fpath) != "platform-v1.0.0.pm" {
+		t.Errorf("unexpected download %s of %d bytes: %v", fpath, size, err)
+	}
+
+	if err = f.DeleteRelease(info.ID); err != nil {
+		t.Fatalf("failed to delete release: %v", err)
+	}
+	if len(downloads) != 0 {
+		t.Errorf("expected downloads of the release to be deleted, got %v", downloads)
+	}
+}
+
+func TestBitbucketTagAsset(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"platform-v1.2.pm":          true,
+		"platform-v1.2.pm.sig":      true,
+		"platform-v1.2-ab12cd34.pm": true,
+		"platform-v1.2":             true,
+		"platform-v1.2.3.pm":        false,
+		"platform-v1.2.10.pm":       false,
+		"platform-v1.2-rc1.pm":      false,
+		"platformv1.2.pm":           false,
+	} {
+		if got := bitbucketTagAsset(name, "v1.2"); got != expected {
+			t.Errorf("expected %s to match v1.2 %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestBitbucketToken(t *testing.T) {
+	f := NewForge("bitbucket.org", "workspace/repo", "access-token")
+	if f.bitbucketAPIURL() != "https://api.bitbucket.org/2.0" {
+		t.Errorf("unexpected API URL %s", f.bitbucketAPIURL())
+	}
+	if f.bitbucketAuthorization() != "Bearer access-token" {
+		t.Errorf("expected access token to be sent as Bearer, got %s", f.bitbucketAuthorization())
+	}
+	if forgeType, err := f.DetectType(); err != nil || forgeType != ForgeBitbucket {
+		t.Errorf("expected bitbucket.org to be detected, got %s, %v", forgeType, err)
+	}
+
+	t.Setenv("BITBUCKET_TOKEN", "env-token")
+	if token := ResolveToken("", ForgeBitbucket, nil); token != "env-token" {
+		t.Errorf("expected BITBUCKET_TOKEN, got %q", token)
+	}
+}
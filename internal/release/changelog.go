@@ -1,15 +1,19 @@
 package release
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	conventionalcommits "github.com/leodido/go-conventionalcommits"
 	"github.com/leodido/go-conventionalcommits/parser"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
 // CommitTypeInfo contains display information for a commit type
@@ -40,10 +44,17 @@ type ParsedCommit struct {
 	Hash        string
 }
 
+var errInvalidSince = errors.New("invalid changelog cut-off")
+
 // ChangelogGenerator generates changelogs from git history
+// sinceTime and sinceHash cut off older history, truncated is set when the history of a shallow clone ends
+// before the previous release.
 type ChangelogGenerator struct {
-	repo   *git.Repository
-	parser conventionalcommits.Machine
+	repo      *git.Repository
+	parser    conventionalcommits.Machine
+	sinceTime *time.Time
+	sinceHash plumbing.Hash
+	truncated bool
 }
 
 // NewChangelogGenerator creates a new ChangelogGenerator
@@ -63,6 +74,35 @@ func NewChangelogGenerator(workDir string) (*ChangelogGenerator, error) {
 	return &ChangelogGenerator{repo: repo, parser: p}, nil
 }
 
+// SetSince limits the changelog to commits after since: a date (YYYY-MM-DD or RFC 3339) or a commit,
+// the commit itself isn't included. Empty since includes the whole history.
+func (c *ChangelogGenerator) SetSince(since string) error {
+	c.sinceTime, c.sinceHash = nil, plumbing.ZeroHash
+	if since == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, since); err == nil {
+			c.sinceTime = &t
+			return nil
+		}
+	}
+
+	hash, err := c.repo.ResolveRevision(plumbing.Revision(since))
+	if err != nil {
+		return fmt.Errorf("%w %q, expected a date (YYYY-MM-DD) or a commit: %w", errInvalidSince, since, err)
+	}
+	c.sinceHash = *hash
+
+	return nil
+}
+
+// Truncated reports if the last generated changelog stopped at the end of a shallow clone history.
+func (c *ChangelogGenerator) Truncated() bool {
+	return c.truncated
+}
+
 // parseCommit parses a commit message using go-conventionalcommits
 func (c *ChangelogGenerator) parseCommit(message, hash string) *ParsedCommit {
 	// Parse first line only
@@ -102,7 +142,7 @@ func (c *ChangelogGenerator) Generate(fromTag string) (string, error) {
 		return "", fmt.Errorf("failed to get HEAD: %w", err)
 	}
 
-	commitIter, err := c.repo.Log(&git.LogOptions{From: head.Hash()})
+	commitIter, err := c.repo.Log(&git.LogOptions{From: head.Hash(), Since: c.sinceTime})
 	if err != nil {
 		return "", fmt.Errorf("failed to get commit log: %w", err)
 	}
@@ -121,7 +161,7 @@ func (c *ChangelogGenerator) Generate(fromTag string) (string, error) {
 	var breakingChanges []*ParsedCommit
 
 	err = commitIter.ForEach(func(commit *object.Commit) error {
-		if stopHash != plumbing.ZeroHash && commit.Hash == stopHash {
+		if (stopHash != plumbing.ZeroHash && commit.Hash == stopHash) || commit.Hash == c.sinceHash {
 			return errStop
 		}
 
@@ -135,11 +175,28 @@ func (c *ChangelogGenerator) Generate(fromTag string) (string, error) {
 		return nil
 	})
 
-	if err != nil && err != errStop {
+	// Shallow clones miss commits beyond their depth, the changelog starts at the oldest fetched commit.
+	c.truncated = errors.Is(err, plumbing.ErrObjectNotFound)
+	if err != nil && err != errStop && !c.truncated {
 		return "", err
 	}
 
-	return c.formatChangelog(commitsByType, breakingChanges), nil
+	// No commits means no package changes either
+	if len(commitsByType) == 0 {
+		return "", nil
+	}
+
+	packageChanges, err := c.PackageChanges(fromTag)
+	if err != nil {
+		return "", err
+	}
+
+	composition, err := c.compositionAt(head.Hash())
+	if err != nil {
+		return "", err
+	}
+
+	return c.formatChangelog(composition.Metadata(), commitsByType, breakingChanges, packageChanges), nil
 }
 
 var errStop = fmt.Errorf("stop")
@@ -164,9 +221,15 @@ func (c *ChangelogGenerator) resolveTag(tagName string) (plumbing.Hash, error) {
 }
 
 // formatChangelog formats the collected commits into a markdown changelog
-func (c *ChangelogGenerator) formatChangelog(commitsByType map[string][]*ParsedCommit, breakingChanges []*ParsedCommit) string {
+func (c *ChangelogGenerator) formatChangelog(meta model.Metadata, commitsByType map[string][]*ParsedCommit, breakingChanges []*ParsedCommit, packageChanges *PackageChanges) string {
 	var sb strings.Builder
 
+	// Composition metadata from compose.yaml
+	if section := formatMetadata(meta); section != "" {
+		sb.WriteString(section)
+		sb.WriteString("\n")
+	}
+
 	// Breaking changes first
 	if len(breakingChanges) > 0 {
 		sb.WriteString("### ⚠ Breaking Changes\n\n")
@@ -176,6 +239,12 @@ func (c *ChangelogGenerator) formatChangelog(commitsByType map[string][]*ParsedC
 		sb.WriteString("\n")
 	}
 
+	// Package changes derived from compose.yaml
+	if section := formatPackageChanges(packageChanges); section != "" {
+		sb.WriteString(section)
+		sb.WriteString("\n")
+	}
+
 	// Sort types by order
 	var types []string
 	for t := range commitsByType {
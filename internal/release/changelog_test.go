@@ -0,0 +1,80 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestChangelogSince(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hashes []plumbing.Hash
+	messages := []string{"feat: legacy import", "fix: migration", "feat: first release"}
+	for i, msg := range messages {
+		if err = os.WriteFile(filepath.Join(dir, "file.txt"), []byte(msg), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Add("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "dev", Email: "dev@example.com", When: time.Date(2020+i, 1, 1, 0, 0, 0, 0, time.UTC)}
+		hash, errCommit := w.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+		if errCommit != nil {
+			t.Fatal(errCommit)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	c, err := NewChangelogGenerator(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, since := range []string{hashes[0].String()[:7], "2021-01-01"} {
+		if err = c.SetSince(since); err != nil {
+			t.Fatalf("unexpected error for %s: %v", since, err)
+		}
+		changelog, errGen := c.Generate("")
+		if errGen != nil {
+			t.Fatal(errGen)
+		}
+		if strings.Contains(changelog, "legacy import") || !strings.Contains(changelog, "migration") || !strings.Contains(changelog, "first release") {
+			t.Errorf("expected changelog since %s to exclude older commits, got:\n%s", since, changelog)
+		}
+	}
+
+	if err = c.SetSince("not-a-commit"); err == nil {
+		t.Error("expected invalid since to fail")
+	}
+
+	// A shallow clone misses the objects of older commits.
+	if err = c.SetSince(""); err != nil {
+		t.Fatal(err)
+	}
+	first := hashes[0].String()
+	if err = os.Remove(filepath.Join(dir, ".git", "objects", first[:2], first[2:])); err != nil {
+		t.Fatal(err)
+	}
+	changelog, err := c.Generate("")
+	if err != nil {
+		t.Fatalf("expected missing history to be tolerated, got %v", err)
+	}
+	if !c.Truncated() || !strings.Contains(changelog, "migration") {
+		t.Errorf("expected truncated changelog, got:\n%s", changelog)
+	}
+}
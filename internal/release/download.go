@@ -0,0 +1,186 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var errAssetNotFound = errors.New("release asset not found")
+
+// releaseAsset is a downloadable asset of a release
+type releaseAsset struct {
+	name string
+	url  string
+}
+
+// DownloadAsset downloads the first asset of the release of tag with a name matching the pattern into dir
+// and returns the path of the downloaded file and its size. Patterns have the syntax of path.Match,
+// e.g. model-*.pm matches assets with digests embedded by DigestAssetName. Without a token only
+// public releases are visible.
+func (f *Forge) DownloadAsset(tag, pattern, dir string) (string, int64, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", 0, fmt.Errorf("invalid asset pattern %q: %w", pattern, err)
+	}
+
+	assets, err := f.releaseAssets(tag)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, a := range assets {
+		if ok, _ := path.Match(pattern, a.name); ok {
+			return f.downloadAsset(a, dir)
+		}
+	}
+
+	return "", 0, fmt.Errorf("%w: no asset of release %s of %s matches %s", errAssetNotFound, tag, f.repo, pattern)
+}
+
+// releaseAssets lists assets of the release of tag
+func (f *Forge) releaseAssets(tag string) ([]releaseAsset, error) {
+	var releaseURL string
+	switch f.forgeType {
+	case ForgeGitHub:
+		releaseURL = f.githubAPIURL() + "/repos/" + f.repo + "/releases/tags/" + url.PathEscape(tag)
+	case ForgeGitLab:
+		releaseURL = "https://" + f.host + "/api/v4/projects/" + f.gitLabProject() + "/releases/" + url.PathEscape(tag)
+	case ForgeGitea, ForgeForgejo:
+		releaseURL = "https://" + f.host + "/api/v1/repos/" + f.repo + "/releases/tags/" + url.PathEscape(tag)
+	case ForgeBitbucket:
+		return f.bitbucketReleaseAssets(tag)
+	default:
+		return nil, fmt.Errorf("unsupported forge type: %s", f.forgeType)
+	}
+
+	req, err := http.NewRequest("GET", releaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.authorize(req)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: release %s of %s doesn't exist", errAssetNotFound, tag, f.repo)
+	default:
+		return nil, f.responseError(resp, "get release", body)
+	}
+
+	var result struct {
+		// Assets of GitHub and Gitea releases, GitLab lists links of assets
+		Assets json.RawMessage `json:"assets"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	var assets []releaseAsset
+	if f.forgeType == ForgeGitLab {
+		var links struct {
+			Links []struct {
+				Name string `json:"name"`
+				URL  string `json:"url"`
+			} `json:"links"`
+		}
+		if err = json.Unmarshal(result.Assets, &links); err != nil {
+			return nil, err
+		}
+		for _, l := range links.Links {
+			assets = append(assets, releaseAsset{name: l.Name, url: l.URL})
+		}
+		return assets, nil
+	}
+
+	var list []struct {
+		Name               string `json:"name"`
+		URL                string `json:"url"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+	if err = json.Unmarshal(result.Assets, &list); err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		// GitHub API URL serves content of private assets, browser URLs require a session
+		assetURL := a.BrowserDownloadURL
+		if f.forgeType == ForgeGitHub && a.URL != "" {
+			assetURL = a.URL
+		}
+		assets = append(assets, releaseAsset{name: a.Name, url: assetURL})
+	}
+
+	return assets, nil
+}
+
+// downloadAsset writes content of the asset into dir, the download isn't limited by the client timeout
+func (f *Forge) downloadAsset(a releaseAsset, dir string) (string, int64, error) {
+	req, err := http.NewRequest("GET", a.url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	f.authorize(req)
+	if f.forgeType == ForgeGitHub {
+		req.Header.Set("Accept", "application/octet-stream")
+	}
+
+	client := *f.client
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", 0, f.responseError(resp, "download asset "+a.name, body)
+	}
+
+	if err = os.MkdirAll(dir, 0750); err != nil {
+		return "", 0, err
+	}
+	fpath := filepath.Join(dir, filepath.Base(filepath.FromSlash(a.name)))
+	out, err := os.Create(filepath.Clean(fpath))
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Close()
+
+	written, err := io.Copy(out, resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fpath, written, out.Close()
+}
+
+// authorize sets the token of read requests, requests without token are anonymous
+func (f *Forge) authorize(req *http.Request) {
+	if f.token == "" {
+		return
+	}
+
+	switch f.forgeType {
+	case ForgeGitHub:
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	case ForgeGitLab:
+		req.Header.Set("PRIVATE-TOKEN", f.token)
+	case ForgeGitea, ForgeForgejo:
+		req.Header.Set("Authorization", f.giteaAuthorization())
+	case ForgeBitbucket:
+		req.Header.Set("Authorization", f.bitbucketAuthorization())
+	}
+}
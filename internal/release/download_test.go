@@ -0,0 +1,66 @@
+package release
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDownloadAsset(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/org/repo/releases/tags/v1.0.0":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"assets": [
+				{"name": "notes.md", "url": "` + srvURL + `/api/v3/assets/1"},
+				{"name": "model-1.0.0-ab12cd34.pm", "url": "` + srvURL + `/api/v3/assets/2", "browser_download_url": "` + srvURL + `/download/model.pm"}
+			]}`))
+		case "/api/v3/assets/2":
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				t.Errorf("expected asset content to be requested, got Accept %q", r.Header.Get("Accept"))
+			}
+			_, _ = w.Write([]byte("pm"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	f := NewForge(host, "org/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitHub
+
+	dir := t.TempDir()
+	fpath, size, err := f.DownloadAsset("v1.0.0", "model-*.pm", dir)
+	if err != nil {
+		t.Fatalf("failed to download asset: %v", err)
+	}
+	content, err := os.ReadFile(fpath)
+	if err != nil || string(content) != "pm" || size != 2 || !strings.HasSuffix(fpath, "model-1.0.0-ab12cd34.pm") {
+		t.Errorf("unexpected asset %s of %d bytes: %q, %v", fpath, size, content, err)
+	}
+
+	if _, _, err = f.DownloadAsset("v1.0.0", "*.zip", dir); !errors.Is(err, errAssetNotFound) {
+		t.Errorf("expected no matching asset, got %v", err)
+	}
+	if _, _, err = f.DownloadAsset("v2.0.0", "*.pm", dir); !errors.Is(err, errAssetNotFound) {
+		t.Errorf("expected missing release, got %v", err)
+	}
+
+	// Private releases aren't visible without token.
+	f = NewForge(host, "org/repo", "")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitHub
+	if _, _, err = f.DownloadAsset("v1.0.0", "*.pm", dir); !errors.Is(err, errAssetNotFound) {
+		t.Errorf("expected anonymous request to miss the release, got %v", err)
+	}
+}
@@ -3,25 +3,33 @@ package release
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/plasmash/plasmactl-model/internal/auth"
+	"github.com/plasmash/plasmactl-model/pkg/model"
 )
 
 // ForgeType represents a git forge type
 type ForgeType string
 
 const (
-	ForgeGitHub  ForgeType = "github"
-	ForgeGitLab  ForgeType = "gitlab"
-	ForgeGitea   ForgeType = "gitea"
-	ForgeForgejo ForgeType = "forgejo"
-	ForgeUnknown ForgeType = "unknown"
+	ForgeGitHub    ForgeType = "github"
+	ForgeGitLab    ForgeType = "gitlab"
+	ForgeGitea     ForgeType = "gitea"
+	ForgeForgejo   ForgeType = "forgejo"
+	ForgeBitbucket ForgeType = "bitbucket"
+	ForgeUnknown   ForgeType = "unknown"
 )
 
 // Forge provides release operations for git forges
@@ -31,6 +39,49 @@ type Forge struct {
 	forgeType ForgeType
 	token     string
 	client    *http.Client
+	projectID string // resolved GitLab project ID
+
+	gitLabAssets string
+	giteaScheme  string // authorization scheme accepted by Gitea/Forgejo
+
+	enterpriseVersion string            // version of GitHub Enterprise Server
+	uploadURLs        map[string]string // GitHub upload URLs of releases
+
+	bitbucketDownloads map[string][]string     // Bitbucket downloads uploaded per release tag
+	gitLabDrafts       map[string]*gitLabDraft // GitLab releases created by PublishRelease
+}
+
+// gitLabDraft is a GitLab release not created yet. GitLab has no drafts, the release is created with links
+// of its uploaded assets by PublishRelease, so it is never public without them.
+type gitLabDraft struct {
+	changelog string
+	links     []map[string]interface{}
+}
+
+// Storages of release assets on GitLab.
+const (
+	// GitLabAssetsPackage uploads assets to the Generic Package Registry.
+	GitLabAssetsPackage = "package"
+	// GitLabAssetsUploads uploads assets to project uploads, for instances with the package registry disabled.
+	GitLabAssetsUploads = "uploads"
+)
+
+var (
+	errInvalidGitLabAssets = errors.New("invalid GitLab assets storage")
+	errInvalidForgeType    = errors.New("invalid forge type")
+	errTokenPermissions    = errors.New("API token lacks release permissions")
+)
+
+// githubEnterpriseHeader is returned by GitHub Enterprise Server API, including responses requiring authentication.
+const githubEnterpriseHeader = "X-GitHub-Enterprise-Version"
+
+// ReleaseInfo contains identifiers of a created release
+type ReleaseInfo struct {
+	ID  string
+	URL string
+	// Assets are download URLs of assets by name, set by PublishRelease. GitHub download URLs of draft
+	// releases point at an untagged release and stop working once it is published.
+	Assets map[string]string
 }
 
 // NewForge creates a new Forge instance
@@ -45,6 +96,40 @@ func NewForge(host, repo, token string) *Forge {
 	}
 }
 
+// SetGitLabAssets sets storage of assets uploaded to GitLab releases, package registry by default
+func (f *Forge) SetGitLabAssets(storage string) error {
+	switch storage {
+	case "", GitLabAssetsPackage, GitLabAssetsUploads:
+		f.gitLabAssets = storage
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s", errInvalidGitLabAssets, storage, GitLabAssetsPackage, GitLabAssetsUploads)
+	}
+}
+
+// SetType sets the forge type, skipping detection. It's required when probing is blocked,
+// e.g. by SSO redirects of the host.
+func (f *Forge) SetType(forgeType ForgeType) error {
+	switch forgeType {
+	case ForgeGitHub, ForgeGitLab, ForgeGitea, ForgeForgejo, ForgeBitbucket:
+		f.forgeType = forgeType
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s, %s, %s, %s", errInvalidForgeType, forgeType, ForgeGitHub, ForgeGitLab, ForgeGitea, ForgeForgejo, ForgeBitbucket)
+	}
+}
+
+// Type returns the detected or set forge type
+func (f *Forge) Type() ForgeType {
+	return f.forgeType
+}
+
+// EnterpriseVersion returns version of GitHub Enterprise Server, empty for other forges
+// or if the server doesn't disclose it
+func (f *Forge) EnterpriseVersion() string {
+	return f.enterpriseVersion
+}
+
 // DetectType detects the forge type from the host
 func (f *Forge) DetectType() (ForgeType, error) {
 	// Known hosts
@@ -61,9 +146,20 @@ func (f *Forge) DetectType() (ForgeType, error) {
 	case "gitea.com":
 		f.forgeType = ForgeGitea
 		return f.forgeType, nil
+	case "bitbucket.org":
+		f.forgeType = ForgeBitbucket
+		return f.forgeType, nil
+	}
+
+	// Probe APIs for unknown hosts.
+	// GitHub Enterprise Server identifies itself by a header, even if the API requires authentication.
+	status, header := f.probe("/api/v3/meta")
+	if v := header.Get(githubEnterpriseHeader); v != "" || status == http.StatusOK {
+		f.enterpriseVersion = v
+		f.forgeType = ForgeGitHub
+		return f.forgeType, nil
 	}
 
-	// Probe APIs for unknown hosts
 	if f.probeAPI("/api/v4/version") {
 		f.forgeType = ForgeGitLab
 		return f.forgeType, nil
@@ -79,32 +175,43 @@ func (f *Forge) DetectType() (ForgeType, error) {
 		return f.forgeType, nil
 	}
 
-	if f.probeAPI("/api/v3/meta") {
-		f.forgeType = ForgeGitHub
-		return f.forgeType, nil
+	f.forgeType = ForgeUnknown
+	if f.probeAPI("/rest/api/1.0/application-properties") {
+		return f.forgeType, fmt.Errorf("%s: %w", f.host, errBitbucketDataCenter)
 	}
 
-	f.forgeType = ForgeUnknown
-	return f.forgeType, fmt.Errorf("could not detect forge type for %s", f.host)
+	return f.forgeType, fmt.Errorf("could not detect forge type for %s, set it explicitly with --forge", f.host)
 }
 
 func (f *Forge) probeAPI(path string) bool {
+	status, _ := f.probe(path)
+	return status == http.StatusOK
+}
+
+// probe requests the API path and returns response status and headers. Redirects aren't followed,
+// a login page of SSO in front of the host must not be taken for an API response.
+func (f *Forge) probe(path string) (int, http.Header) {
 	req, err := http.NewRequest("GET", "https://"+f.host+path, nil)
 	if err != nil {
-		return false
+		return 0, http.Header{}
 	}
 
 	if f.token != "" {
 		req.Header.Set("Authorization", "Bearer "+f.token)
 	}
 
-	resp, err := f.client.Do(req)
+	client := *f.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return false
+		return 0, http.Header{}
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK
+	return resp.StatusCode, resp.Header
 }
 
 func (f *Forge) isForgejo() bool {
@@ -123,53 +230,348 @@ func (f *Forge) isForgejo() bool {
 	return strings.Contains(strings.ToLower(string(body)), "forgejo")
 }
 
-// CreateRelease creates a release on the forge
-func (f *Forge) CreateRelease(tag, changelog string) (string, error) {
+// CreateRelease creates a release on the forge and returns its ID and web URL
+// Draft releases are not visible until published. GitLab drafts are created by PublishRelease along with
+// links of their assets, Bitbucket doesn't support drafts and ignores it
+func (f *Forge) CreateRelease(tag, changelog string, draft bool) (*ReleaseInfo, error) {
 	switch f.forgeType {
 	case ForgeGitHub:
-		return f.createGitHubRelease(tag, changelog)
+		return f.createGitHubRelease(tag, changelog, draft)
 	case ForgeGitLab:
-		return f.createGitLabRelease(tag, changelog)
+		if draft {
+			return f.draftGitLabRelease(tag, changelog), nil
+		}
+		return f.createGitLabRelease(tag, changelog, nil)
+	case ForgeBitbucket:
+		return f.createBitbucketRelease(tag, changelog)
 	case ForgeGitea, ForgeForgejo:
-		return f.createGiteaRelease(tag, changelog)
+		return f.createGiteaRelease(tag, changelog, draft)
 	default:
-		return "", fmt.Errorf("unsupported forge type: %s", f.forgeType)
+		return nil, fmt.Errorf("unsupported forge type: %s", f.forgeType)
+	}
+}
+
+// PublishRelease publishes a draft release and returns its updated web URL and download URLs of its assets
+func (f *Forge) PublishRelease(releaseID string) (*ReleaseInfo, error) {
+	switch f.forgeType {
+	case ForgeGitHub:
+		apiURL := f.githubAPIURL()
+		return f.publishRelease(apiURL+"/repos/"+f.repo+"/releases/"+releaseID, "Bearer "+f.token, releaseID)
+	case ForgeGitLab:
+		draft, ok := f.gitLabDrafts[releaseID]
+		if !ok {
+			return &ReleaseInfo{ID: releaseID}, nil
+		}
+		info, err := f.createGitLabRelease(releaseID, draft.changelog, draft.links)
+		if err != nil {
+			return nil, err
+		}
+		delete(f.gitLabDrafts, releaseID)
+		return info, nil
+	case ForgeBitbucket:
+		// Bitbucket releases are never drafts
+		return &ReleaseInfo{ID: releaseID}, nil
+	case ForgeGitea, ForgeForgejo:
+		apiURL := "https://" + f.host + "/api/v1"
+		return f.publishRelease(apiURL+"/repos/"+f.repo+"/releases/"+releaseID, f.giteaAuthorization(), releaseID)
+	default:
+		return nil, fmt.Errorf("unsupported forge type: %s", f.forgeType)
 	}
 }
 
-// UploadAsset uploads an asset to the release
-func (f *Forge) UploadAsset(releaseID, filePath string) error {
+// DeleteRelease deletes a release, the tag is kept
+func (f *Forge) DeleteRelease(releaseID string) error {
+	var req *http.Request
+	var err error
+
 	switch f.forgeType {
+	case ForgeBitbucket:
+		return f.deleteBitbucketRelease(releaseID)
 	case ForgeGitHub:
-		return f.uploadGitHubAsset(releaseID, filePath)
+		apiURL := f.githubAPIURL()
+		req, err = http.NewRequest("DELETE", apiURL+"/repos/"+f.repo+"/releases/"+releaseID, nil)
+		if err == nil {
+			req.Header.Set("Authorization", "Bearer "+f.token)
+		}
 	case ForgeGitLab:
-		return f.uploadGitLabAsset(releaseID, filePath)
+		if _, ok := f.gitLabDrafts[releaseID]; ok {
+			// The release wasn't created, uploaded files stay in the package registry or project uploads
+			delete(f.gitLabDrafts, releaseID)
+			return nil
+		}
+		apiURL := "https://" + f.host + "/api/v4"
+		req, err = http.NewRequest("DELETE", apiURL+"/projects/"+f.gitLabProject()+"/releases/"+url.PathEscape(releaseID), nil)
+		if err == nil {
+			req.Header.Set("PRIVATE-TOKEN", f.token)
+		}
 	case ForgeGitea, ForgeForgejo:
-		return f.uploadGiteaAsset(releaseID, filePath)
+		apiURL := "https://" + f.host + "/api/v1"
+		req, err = http.NewRequest("DELETE", apiURL+"/repos/"+f.repo+"/releases/"+releaseID, nil)
+		if err == nil {
+			req.Header.Set("Authorization", f.giteaAuthorization())
+		}
 	default:
 		return fmt.Errorf("unsupported forge type: %s", f.forgeType)
 	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return f.responseError(resp, "delete release", body)
+	}
+
+	return nil
 }
 
-// GitHub implementation
-func (f *Forge) createGitHubRelease(tag, changelog string) (string, error) {
-	apiURL := "https://api.github.com"
-	if f.host != "github.com" {
-		apiURL = "https://" + f.host + "/api/v3"
+// ReleaseExists reports if a release of the tag exists, it only reads from the forge
+func (f *Forge) ReleaseExists(tag string) (bool, error) {
+	var req *http.Request
+	var err error
+
+	switch f.forgeType {
+	case ForgeBitbucket:
+		return f.bitbucketReleaseExists(tag)
+	case ForgeGitHub:
+		req, err = http.NewRequest("GET", f.githubAPIURL()+"/repos/"+f.repo+"/releases/tags/"+url.PathEscape(tag), nil)
+		if err == nil {
+			req.Header.Set("Authorization", "Bearer "+f.token)
+		}
+	case ForgeGitLab:
+		apiURL := "https://" + f.host + "/api/v4"
+		req, err = http.NewRequest("GET", apiURL+"/projects/"+f.gitLabProject()+"/releases/"+url.PathEscape(tag), nil)
+		if err == nil {
+			req.Header.Set("PRIVATE-TOKEN", f.token)
+		}
+	case ForgeGitea, ForgeForgejo:
+		apiURL := "https://" + f.host + "/api/v1"
+		req, err = http.NewRequest("GET", apiURL+"/repos/"+f.repo+"/releases/tags/"+url.PathEscape(tag), nil)
+		if err == nil {
+			req.Header.Set("Authorization", f.giteaAuthorization())
+		}
+	default:
+		return false, fmt.Errorf("unsupported forge type: %s", f.forgeType)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, f.responseError(resp, "check release", body)
+	}
+}
+
+// responseError returns an error of a failed forge request, rejected credentials are reported as model.ErrAuthFailed.
+// Messages of missing token scopes are reported as errTokenPermissions instead of the response body.
+func (f *Forge) responseError(resp *http.Response, action string, body []byte) error {
+	err := fmt.Errorf("failed to %s: %s", action, string(body))
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return err
+	}
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		err = fmt.Errorf("failed to %s: %s", action, apiErr.Message)
+		if strings.Contains(strings.ToLower(apiErr.Message), "scope") {
+			err = fmt.Errorf("%w, failed to %s: %s", errTokenPermissions, action, apiErr.Message)
+		}
+	}
+
+	return &model.ErrAuthFailed{Host: f.host, Err: err}
+}
+
+// giteaAuthorization returns Authorization header of Gitea/Forgejo requests, the token scheme
+// unless CheckPermissions found only Bearer is accepted
+func (f *Forge) giteaAuthorization() string {
+	if f.giteaScheme == "" {
+		return "token " + f.token
+	}
+
+	return f.giteaScheme + " " + f.token
+}
+
+// githubAPIURL returns GitHub API base URL, Enterprise Server serves it under /api/v3
+func (f *Forge) githubAPIURL() string {
+	if f.host == "github.com" {
+		return "https://api.github.com"
+	}
+
+	return "https://" + f.host + "/api/v3"
+}
+
+// CheckPermissions verifies the token can create releases of the repository before anything is created.
+// GitHub classic tokens must have repo or public_repo scope, Gitea and Forgejo tokens are tried with
+// both token and Bearer authorization schemes. The token owner must have write access to the repository.
+func (f *Forge) CheckPermissions() error {
+	switch f.forgeType {
+	case ForgeGitHub:
+		return f.checkRepoPermissions(f.githubAPIURL(), []string{"Bearer"})
+	case ForgeGitea, ForgeForgejo:
+		return f.checkRepoPermissions("https://"+f.host+"/api/v1", []string{"token", "Bearer"})
+	case ForgeBitbucket:
+		return f.checkBitbucketPermissions()
+	default:
+		// GitLab doesn't disclose token scopes and permissions in a single request, errors are reported on use
+		return nil
+	}
+}
+
+func (f *Forge) checkRepoPermissions(apiURL string, schemes []string) error {
+	var resp *http.Response
+	var body []byte
+	for _, scheme := range schemes {
+		req, err := http.NewRequest("GET", apiURL+"/repos/"+f.repo, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", scheme+" "+f.token)
+
+		resp, err = f.client.Do(req)
+		if err != nil {
+			return err
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			if f.forgeType != ForgeGitHub {
+				f.giteaScheme = scheme
+			}
+			break
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &model.ErrAuthFailed{Host: f.host, Err: fmt.Errorf("%w: repository %s isn't visible with the token", errTokenPermissions, f.repo)}
+	default:
+		return f.responseError(resp, "check token permissions", body)
+	}
+
+	// Classic GitHub tokens list their scopes, fine-grained tokens don't
+	if scopes := resp.Header.Get("X-OAuth-Scopes"); scopes != "" {
+		granted := strings.Split(strings.ReplaceAll(scopes, " ", ""), ",")
+		if !slices.Contains(granted, "repo") && !slices.Contains(granted, "public_repo") {
+			return &model.ErrAuthFailed{Host: f.host, Err: fmt.Errorf("%w: token has scopes %s, releases require repo or public_repo", errTokenPermissions, scopes)}
+		}
+	}
+
+	var repo struct {
+		Permissions struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	if err := json.Unmarshal(body, &repo); err != nil {
+		return err
+	}
+	if !repo.Permissions.Push {
+		return &model.ErrAuthFailed{Host: f.host, Err: fmt.Errorf("%w: token owner has no write access to %s", errTokenPermissions, f.repo)}
+	}
+
+	return nil
+}
+
+// publishRelease unsets draft flag of GitHub and Gitea releases, they share the API
+func (f *Forge) publishRelease(releaseURL, authorization, releaseID string) (*ReleaseInfo, error) {
+	body, _ := json.Marshal(map[string]interface{}{"draft": false})
+	req, err := http.NewRequest("PATCH", releaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, f.responseError(resp, "publish release", respBody)
+	}
+
+	var result struct {
+		HTMLURL string `json:"html_url"`
+		Assets  []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	info := &ReleaseInfo{ID: releaseID, URL: result.HTMLURL, Assets: make(map[string]string)}
+	for _, a := range result.Assets {
+		info.Assets[a.Name] = a.BrowserDownloadURL
+	}
+
+	return info, nil
+}
+
+// UploadAsset uploads an asset to the release and returns its download URL
+func (f *Forge) UploadAsset(releaseID, filePath string) (string, error) {
+	return f.UploadAssetAs(releaseID, filePath, filepath.Base(filePath))
+}
+
+// UploadAssetAs uploads a file to a release under the given asset name
+func (f *Forge) UploadAssetAs(releaseID, filePath, fileName string) (string, error) {
+	switch f.forgeType {
+	case ForgeGitHub:
+		return f.uploadGitHubAsset(releaseID, filePath, fileName)
+	case ForgeGitLab:
+		return f.uploadGitLabAsset(releaseID, filePath, fileName)
+	case ForgeGitea, ForgeForgejo:
+		return f.uploadGiteaAsset(releaseID, filePath, fileName)
+	case ForgeBitbucket:
+		return f.uploadBitbucketAsset(releaseID, filePath, fileName)
+	default:
+		return "", fmt.Errorf("unsupported forge type: %s", f.forgeType)
 	}
+}
+
+// GitHub implementation
+func (f *Forge) createGitHubRelease(tag, changelog string, draft bool) (*ReleaseInfo, error) {
+	apiURL := f.githubAPIURL()
 
 	payload := map[string]interface{}{
 		"tag_name":   tag,
 		"name":       tag,
 		"body":       changelog,
-		"draft":      false,
+		"draft":      draft,
 		"prerelease": false,
 	}
 
 	body, _ := json.Marshal(payload)
 	req, err := http.NewRequest("POST", apiURL+"/repos/"+f.repo+"/releases", bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+f.token)
@@ -178,44 +580,57 @@ func (f *Forge) createGitHubRelease(tag, changelog string) (string, error) {
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("failed to create release: %s", string(respBody))
+		return nil, f.responseError(resp, "create release", respBody)
 	}
 
 	var result struct {
-		ID int `json:"id"`
+		ID        int    `json:"id"`
+		HTMLURL   string `json:"html_url"`
+		UploadURL string `json:"upload_url"`
 	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return fmt.Sprintf("%d", result.ID), nil
-}
+	// Upload host differs between github.com and Enterprise Server, the release tells where to upload
+	if result.UploadURL != "" {
+		if f.uploadURLs == nil {
+			f.uploadURLs = make(map[string]string)
+		}
+		uploadURL, _, _ := strings.Cut(result.UploadURL, "{")
+		f.uploadURLs[strconv.Itoa(result.ID)] = uploadURL
+	}
 
-func (f *Forge) uploadGitHubAsset(releaseID, filePath string) error {
-	fileName := filepath.Base(filePath)
+	return &ReleaseInfo{ID: fmt.Sprintf("%d", result.ID), URL: result.HTMLURL}, nil
+}
 
-	uploadURL := "https://uploads.github.com"
-	if f.host != "github.com" {
-		uploadURL = "https://" + f.host + "/api/uploads"
+func (f *Forge) uploadGitHubAsset(releaseID, filePath, fileName string) (string, error) {
+	uploadURL, ok := f.uploadURLs[releaseID]
+	if !ok {
+		uploadURL = "https://uploads.github.com"
+		if f.host != "github.com" {
+			uploadURL = "https://" + f.host + "/api/uploads"
+		}
+		uploadURL += fmt.Sprintf("/repos/%s/releases/%s/assets", f.repo, releaseID)
 	}
-	uploadURL += fmt.Sprintf("/repos/%s/releases/%s/assets?name=%s", f.repo, releaseID, url.QueryEscape(fileName))
+	uploadURL += "?name=" + url.QueryEscape(fileName)
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
 
 	req, err := http.NewRequest("POST", uploadURL, file)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+f.token)
@@ -223,33 +638,98 @@ func (f *Forge) uploadGitHubAsset(releaseID, filePath string) error {
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
+	respBody, _ := io.ReadAll(resp.Body)
+
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload asset: %s", string(body))
+		return "", f.responseError(resp, "upload asset", respBody)
 	}
 
-	return nil
+	var result struct {
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", err
+	}
+
+	return result.BrowserDownloadURL, nil
 }
 
 // GitLab implementation
-func (f *Forge) createGitLabRelease(tag, changelog string) (string, error) {
-	apiURL := "https://" + f.host + "/api/v4"
+
+// gitLabProject returns the project reference for GitLab API paths.
+// The numeric project ID is resolved once, otherwise the URL-encoded full path
+// is used (group/sub/repo becomes group%2Fsub%2Frepo), so nested subgroups of any depth work.
+func (f *Forge) gitLabProject() string {
+	if f.projectID != "" {
+		return f.projectID
+	}
+
 	encodedRepo := url.PathEscape(f.repo)
+	req, err := http.NewRequest("GET", "https://"+f.host+"/api/v4/projects/"+encodedRepo, nil)
+	if err != nil {
+		return encodedRepo
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", f.token)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return encodedRepo
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return encodedRepo
+	}
+
+	var project struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil || project.ID == 0 {
+		return encodedRepo
+	}
+
+	f.projectID = strconv.Itoa(project.ID)
+	return f.projectID
+}
+
+// draftGitLabRelease records the release of tag to be created by PublishRelease
+func (f *Forge) draftGitLabRelease(tag, changelog string) *ReleaseInfo {
+	if f.gitLabDrafts == nil {
+		f.gitLabDrafts = make(map[string]*gitLabDraft)
+	}
+	f.gitLabDrafts[tag] = &gitLabDraft{changelog: changelog}
+
+	return &ReleaseInfo{ID: tag, URL: f.gitLabReleaseURL(tag)}
+}
+
+// gitLabReleaseURL returns web URL of the release of tag
+func (f *Forge) gitLabReleaseURL(tag string) string {
+	return fmt.Sprintf("https://%s/%s/-/releases/%s", f.host, f.repo, url.PathEscape(tag))
+}
+
+// createGitLabRelease creates the release of tag with asset links
+func (f *Forge) createGitLabRelease(tag, changelog string, links []map[string]interface{}) (*ReleaseInfo, error) {
+	apiURL := "https://" + f.host + "/api/v4"
+	project := f.gitLabProject()
 
 	payload := map[string]interface{}{
 		"tag_name":    tag,
 		"name":        tag,
 		"description": changelog,
 	}
+	if len(links) > 0 {
+		payload["assets"] = map[string]interface{}{"links": links}
+	}
 
 	body, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", apiURL+"/projects/"+encodedRepo+"/releases", bytes.NewReader(body))
+	req, err := http.NewRequest("POST", apiURL+"/projects/"+project+"/releases", bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("PRIVATE-TOKEN", f.token)
@@ -257,132 +737,242 @@ func (f *Forge) createGitLabRelease(tag, changelog string) (string, error) {
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("failed to create release: %s", string(respBody))
+		return nil, f.responseError(resp, "create release", respBody)
 	}
 
-	return tag, nil // GitLab uses tag as release ID
+	var result struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+		Assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	releaseURL := result.Links.Self
+	if releaseURL == "" {
+		releaseURL = f.gitLabReleaseURL(tag)
+	}
+
+	info := &ReleaseInfo{ID: tag, URL: releaseURL, Assets: make(map[string]string)} // GitLab uses tag as release ID
+	for _, l := range result.Assets.Links {
+		info.Assets[l.Name] = l.DirectAssetURL
+	}
+
+	return info, nil
 }
 
-func (f *Forge) uploadGitLabAsset(tag, filePath string) error {
+func (f *Forge) uploadGitLabAsset(tag, filePath, fileName string) (string, error) {
 	apiURL := "https://" + f.host + "/api/v4"
-	encodedRepo := url.PathEscape(f.repo)
-	fileName := filepath.Base(filePath)
+	project := f.gitLabProject()
+	encodedTag := url.PathEscape(tag)
+
+	var downloadURL, linkType string
+	var err error
+	switch f.gitLabAssets {
+	case GitLabAssetsUploads:
+		downloadURL, err = f.uploadGitLabProjectFile(apiURL, project, filePath, fileName)
+		linkType = "other"
+	default:
+		downloadURL, err = f.uploadGitLabPackage(apiURL, project, encodedTag, filePath, fileName)
+		linkType = "package"
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Link asset to release, direct asset path gives it a permanent URL under the release
+	linkPayload := map[string]interface{}{
+		"name":              fileName,
+		"url":               downloadURL,
+		"direct_asset_path": "/" + fileName,
+		"link_type":         linkType,
+	}
+	if draft, ok := f.gitLabDrafts[tag]; ok {
+		draft.links = append(draft.links, linkPayload)
+		return f.gitLabReleaseURL(tag) + "/downloads/" + url.PathEscape(fileName), nil
+	}
+
+	linkBody, _ := json.Marshal(linkPayload)
+	linkReq, err := http.NewRequest("POST",
+		fmt.Sprintf("%s/projects/%s/releases/%s/assets/links", apiURL, project, encodedTag),
+		bytes.NewReader(linkBody))
+	if err != nil {
+		return "", err
+	}
+
+	linkReq.Header.Set("PRIVATE-TOKEN", f.token)
+	linkReq.Header.Set("Content-Type", "application/json")
+
+	linkResp, err := f.client.Do(linkReq)
+	if err != nil {
+		return "", err
+	}
+	defer linkResp.Body.Close()
+
+	var link struct {
+		DirectAssetURL string `json:"direct_asset_url"`
+	}
+	linkRespBody, _ := io.ReadAll(linkResp.Body)
+	if linkResp.StatusCode == http.StatusCreated && json.Unmarshal(linkRespBody, &link) == nil && link.DirectAssetURL != "" {
+		return link.DirectAssetURL, nil
+	}
+
+	return downloadURL, nil
+}
 
-	// Upload to Generic Package Registry
+// uploadGitLabPackage uploads the file to the Generic Package Registry and returns its download URL
+func (f *Forge) uploadGitLabPackage(apiURL, project, encodedTag, filePath, fileName string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
 
 	uploadURL := fmt.Sprintf("%s/projects/%s/packages/generic/plasma-release/%s/%s",
-		apiURL, encodedRepo, tag, url.PathEscape(fileName))
+		apiURL, project, encodedTag, url.PathEscape(fileName))
 
 	req, err := http.NewRequest("PUT", uploadURL, file)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("PRIVATE-TOKEN", f.token)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload asset: %s", string(body))
+		return "", f.responseError(resp, "upload asset", body)
 	}
 
-	// Link asset to release
-	downloadURL := fmt.Sprintf("%s/projects/%s/packages/generic/plasma-release/%s/%s",
-		apiURL, encodedRepo, tag, url.PathEscape(fileName))
+	return uploadURL, nil
+}
 
-	linkPayload := map[string]interface{}{
-		"name":      fileName,
-		"url":       downloadURL,
-		"link_type": "package",
+// uploadGitLabProjectFile uploads the file to project uploads, used where the package registry is disabled,
+// and returns its download URL
+func (f *Forge) uploadGitLabProjectFile(apiURL, project, filePath, fileName string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
 	}
+	defer file.Close()
 
-	linkBody, _ := json.Marshal(linkPayload)
-	linkReq, err := http.NewRequest("POST",
-		fmt.Sprintf("%s/projects/%s/releases/%s/assets/links", apiURL, encodedRepo, tag),
-		bytes.NewReader(linkBody))
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", fileName)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err = mw.Close(); err != nil {
+		return "", err
 	}
 
-	linkReq.Header.Set("PRIVATE-TOKEN", f.token)
-	linkReq.Header.Set("Content-Type", "application/json")
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/projects/%s/uploads", apiURL, project), &buf)
+	if err != nil {
+		return "", err
+	}
 
-	linkResp, err := f.client.Do(linkReq)
+	req.Header.Set("PRIVATE-TOKEN", f.token)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := f.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer linkResp.Body.Close()
+	defer resp.Body.Close()
 
-	return nil
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return "", f.responseError(resp, "upload asset", respBody)
+	}
+
+	var result struct {
+		URL      string `json:"url"`
+		FullPath string `json:"full_path"`
+	}
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return "", err
+	}
+
+	// full_path is absolute on the instance, older versions only return url relative to the project
+	if result.FullPath != "" {
+		return "https://" + f.host + result.FullPath, nil
+	}
+
+	return "https://" + f.host + "/" + f.repo + result.URL, nil
 }
 
 // Gitea/Forgejo implementation
-func (f *Forge) createGiteaRelease(tag, changelog string) (string, error) {
+func (f *Forge) createGiteaRelease(tag, changelog string, draft bool) (*ReleaseInfo, error) {
 	apiURL := "https://" + f.host + "/api/v1"
 
 	payload := map[string]interface{}{
 		"tag_name":   tag,
 		"name":       tag,
 		"body":       changelog,
-		"draft":      false,
+		"draft":      draft,
 		"prerelease": false,
 	}
 
 	body, _ := json.Marshal(payload)
 	req, err := http.NewRequest("POST", apiURL+"/repos/"+f.repo+"/releases", bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "token "+f.token)
+	req.Header.Set("Authorization", f.giteaAuthorization())
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("failed to create release: %s", string(respBody))
+		return nil, f.responseError(resp, "create release", respBody)
 	}
 
 	var result struct {
-		ID int `json:"id"`
+		ID      int    `json:"id"`
+		HTMLURL string `json:"html_url"`
 	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return fmt.Sprintf("%d", result.ID), nil
+	return &ReleaseInfo{ID: fmt.Sprintf("%d", result.ID), URL: result.HTMLURL}, nil
 }
 
-func (f *Forge) uploadGiteaAsset(releaseID, filePath string) error {
+func (f *Forge) uploadGiteaAsset(releaseID, filePath, fileName string) (string, error) {
 	apiURL := "https://" + f.host + "/api/v1"
-	fileName := filepath.Base(filePath)
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
 
@@ -396,7 +986,7 @@ func (f *Forge) uploadGiteaAsset(releaseID, filePath string) error {
 
 	fileContent, err := io.ReadAll(file)
 	if err != nil {
-		return err
+		return "", err
 	}
 	buf.Write(fileContent)
 	buf.WriteString(fmt.Sprintf("\r\n--%s--\r\n", boundary))
@@ -406,32 +996,44 @@ func (f *Forge) uploadGiteaAsset(releaseID, filePath string) error {
 
 	req, err := http.NewRequest("POST", uploadURL, &buf)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	req.Header.Set("Authorization", "token "+f.token)
+	req.Header.Set("Authorization", f.giteaAuthorization())
 	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
+	respBody, _ := io.ReadAll(resp.Body)
+
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload asset: %s", string(body))
+		return "", f.responseError(resp, "upload asset", respBody)
 	}
 
-	return nil
+	var result struct {
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", err
+	}
+
+	return result.BrowserDownloadURL, nil
 }
 
-// ResolveToken resolves a token from argument or environment variables
-func ResolveToken(argToken string, forgeType ForgeType) string {
+// ResolveToken resolves a token from argument, auth configuration rule or environment variables
+func ResolveToken(argToken string, forgeType ForgeType, rule *auth.Rule) string {
 	if argToken != "" {
 		return argToken
 	}
 
+	if rule != nil && rule.Method == auth.MethodToken {
+		return rule.Token
+	}
+
 	switch forgeType {
 	case ForgeGitHub:
 		return os.Getenv("GITHUB_TOKEN")
@@ -439,6 +1041,8 @@ func ResolveToken(argToken string, forgeType ForgeType) string {
 		return os.Getenv("GITLAB_TOKEN")
 	case ForgeGitea, ForgeForgejo:
 		return os.Getenv("GITEA_TOKEN")
+	case ForgeBitbucket:
+		return os.Getenv("BITBUCKET_TOKEN")
 	}
 
 	return ""
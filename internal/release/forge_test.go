@@ -0,0 +1,413 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestGitLabSubgroupRelease(t *testing.T) {
+	var requests []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		switch {
+		case r.Method == "GET" && r.URL.EscapedPath() == "/api/v4/projects/group%2Fsub%2Fsub2%2Frepo":
+			_, _ = w.Write([]byte(`{"id": 42}`))
+		case r.Method == "POST" && r.URL.Path == "/api/v4/projects/42/releases":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"_links": {"self": "https://gitlab/group/sub/sub2/repo/-/releases/v1.0.0"}}`))
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/api/v4/projects/42/packages/generic/"):
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "POST" && r.URL.Path == "/api/v4/projects/42/releases/v1.0.0/assets/links":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"direct_asset_url": "https://gitlab/asset.pm"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "group/sub/sub2/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitLab
+
+	info, err := f.CreateRelease("v1.0.0", "changelog", false)
+	if err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	if info.ID != "v1.0.0" || !strings.HasSuffix(info.URL, "/group/sub/sub2/repo/-/releases/v1.0.0") {
+		t.Errorf("unexpected release info: %+v", info)
+	}
+
+	asset := filepath.Join(t.TempDir(), "model.pm")
+	if err = os.WriteFile(asset, []byte("pm"), 0600); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+
+	assetURL, err := f.UploadAsset(info.ID, asset)
+	if err != nil {
+		t.Fatalf("failed to upload asset: %v", err)
+	}
+	if assetURL != "https://gitlab/asset.pm" {
+		t.Errorf("unexpected asset URL: %s", assetURL)
+	}
+
+	// Project ID is resolved only once.
+	resolves := 0
+	for _, r := range requests {
+		if strings.HasPrefix(r, "GET /api/v4/projects/") {
+			resolves++
+		}
+	}
+	if resolves != 1 {
+		t.Errorf("expected project to be resolved once, got %d: %v", resolves, requests)
+	}
+}
+
+func TestGitLabProjectUploads(t *testing.T) {
+	var link map[string]string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v4/projects/group/repo":
+			_, _ = w.Write([]byte(`{"id": 42}`))
+		case r.Method == "POST" && r.URL.Path == "/api/v4/projects/42/uploads":
+			file, header, err := r.FormFile("file")
+			if err != nil || header.Filename != "model.pm" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = file.Close()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"url": "/uploads/abc/model.pm", "full_path": "/-/project/42/uploads/abc/model.pm"}`))
+		case r.Method == "POST" && r.URL.Path == "/api/v4/projects/42/releases/v1.0.0/assets/links":
+			_ = json.NewDecoder(r.Body).Decode(&link)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"direct_asset_url": "https://gitlab/group/repo/-/releases/v1.0.0/downloads/model.pm"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	f := NewForge(host, "group/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitLab
+	if err := f.SetGitLabAssets("registry"); err == nil {
+		t.Error("expected error of unknown assets storage")
+	}
+	if err := f.SetGitLabAssets(GitLabAssetsUploads); err != nil {
+		t.Fatalf("failed to set assets storage: %v", err)
+	}
+
+	asset := filepath.Join(t.TempDir(), "model.pm")
+	if err := os.WriteFile(asset, []byte("pm"), 0600); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+
+	assetURL, err := f.UploadAsset("v1.0.0", asset)
+	if err != nil {
+		t.Fatalf("failed to upload asset: %v", err)
+	}
+	if !strings.HasSuffix(assetURL, "/-/releases/v1.0.0/downloads/model.pm") {
+		t.Errorf("unexpected asset URL: %s", assetURL)
+	}
+	if link["url"] != "https://"+host+"/-/project/42/uploads/abc/model.pm" || link["direct_asset_path"] != "/model.pm" || link["link_type"] != "other" {
+		t.Errorf("unexpected asset link: %v", link)
+	}
+}
+
+func TestDetectGitHubEnterprise(t *testing.T) {
+	var uploads []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/meta":
+			w.Header().Set("X-GitHub-Enterprise-Version", "3.12.0")
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/v3/repos/org/repo/releases":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 7, "upload_url": "https://` + r.Host + `/uploads-host/repos/org/repo/releases/7/assets{?name,label}"}`))
+		case r.URL.Path == "/uploads-host/repos/org/repo/releases/7/assets":
+			uploads = append(uploads, r.URL.Query().Get("name"))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"browser_download_url": "https://ghes/model.pm"}`))
+		default:
+			// SSO login page in front of other paths
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "org/repo", "token")
+	f.client = srv.Client()
+
+	forgeType, err := f.DetectType()
+	if err != nil || forgeType != ForgeGitHub || f.EnterpriseVersion() != "3.12.0" {
+		t.Fatalf("expected GitHub Enterprise Server 3.12.0, got %s %q: %v", forgeType, f.EnterpriseVersion(), err)
+	}
+
+	info, err := f.CreateRelease("v1.0.0", "changelog", true)
+	if err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+
+	asset := filepath.Join(t.TempDir(), "model.pm")
+	if err = os.WriteFile(asset, []byte("pm"), 0600); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+	if _, err = f.UploadAsset(info.ID, asset); err != nil {
+		t.Fatalf("failed to upload asset: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0] != "model.pm" {
+		t.Errorf("expected asset uploaded to upload URL of the release, got %v", uploads)
+	}
+
+	if err = f.SetType("sourcehut"); err == nil {
+		t.Error("expected error of unknown forge type")
+	}
+}
+
+func TestGiteaTokenPermissions(t *testing.T) {
+	var authorizations []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/org/repo":
+			_, _ = w.Write([]byte(`{"permissions": {"push": true}}`))
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/org/readonly":
+			_, _ = w.Write([]byte(`{"permissions": {"push": false}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "token does not have at least one of required scope(s): [write:repository]"}`))
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	f := NewForge(host, "org/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeForgejo
+
+	if err := f.CheckPermissions(); err != nil {
+		t.Fatalf("CheckPermissions failed: %v", err)
+	}
+	if len(authorizations) != 2 || authorizations[0] != "token token" || authorizations[1] != "Bearer token" {
+		t.Errorf("expected token scheme to fall back to Bearer, got %v", authorizations)
+	}
+
+	_, err := f.CreateRelease("v1.0.0", "changelog", false)
+	if authorizations[len(authorizations)-1] != "Bearer token" {
+		t.Errorf("expected release to be created with Bearer scheme, got %v", authorizations)
+	}
+	var authErr *model.ErrAuthFailed
+	if !errors.As(err, &authErr) || !errors.Is(err, errTokenPermissions) || strings.Contains(err.Error(), "{") {
+		t.Errorf("expected precise token permissions error, got %v", err)
+	}
+
+	f = NewForge(host, "org/readonly", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitea
+	if err = f.CheckPermissions(); !errors.Is(err, errTokenPermissions) {
+		t.Errorf("expected error of missing write access, got %v", err)
+	}
+}
+
+func TestGitLabProjectFallback(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "group/sub/repo", "token")
+	f.client = srv.Client()
+
+	if project := f.gitLabProject(); project != "group%2Fsub%2Frepo" {
+		t.Errorf("expected encoded path, got %s", project)
+	}
+}
+
+func TestDraftReleasePublishAndDelete(t *testing.T) {
+	var requests []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 7, "html_url": "https://forge/org/repo/releases/draft"}`))
+		case "PATCH":
+			_, _ = w.Write([]byte(`{"id": 7, "html_url": "https://forge/org/repo/releases/tag/v1.0.0",
+				"assets": [{"name": "model.pm", "browser_download_url": "https://forge/org/repo/releases/download/v1.0.0/model.pm"}]}`))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "org/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitea
+
+	info, err := f.CreateRelease("v1.0.0", "changelog", true)
+	if err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	if !strings.Contains(requests[0], `"draft":true`) {
+		t.Errorf("expected draft release to be created, got %s", requests[0])
+	}
+
+	published, err := f.PublishRelease(info.ID)
+	if err != nil {
+		t.Fatalf("failed to publish release: %v", err)
+	}
+	if published.URL != "https://forge/org/repo/releases/tag/v1.0.0" || !strings.Contains(requests[1], `"draft":false`) {
+		t.Errorf("unexpected publish: %+v, %s", published, requests[1])
+	}
+	if published.Assets["model.pm"] != "https://forge/org/repo/releases/download/v1.0.0/model.pm" {
+		t.Errorf("expected download URL of the published release, got %v", published.Assets)
+	}
+
+	if err = f.DeleteRelease(info.ID); err != nil {
+		t.Fatalf("failed to delete release: %v", err)
+	}
+	if requests[2] != "DELETE /api/v1/repos/org/repo/releases/7 " {
+		t.Errorf("unexpected delete request: %s", requests[2])
+	}
+}
+
+func TestGitLabDraftRelease(t *testing.T) {
+	var requests []string
+	var release struct {
+		Assets struct {
+			Links []map[string]string `json:"links"`
+		} `json:"assets"`
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v4/projects/group/repo":
+			_, _ = w.Write([]byte(`{"id": 42}`))
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/api/v4/projects/42/packages/generic/"):
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "POST" && r.URL.Path == "/api/v4/projects/42/releases":
+			_ = json.NewDecoder(r.Body).Decode(&release)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"_links": {"self": "https://gitlab/group/repo/-/releases/v1.0.0"},
+				"assets": {"links": [{"name": "model.pm", "direct_asset_url": "https://gitlab/group/repo/-/releases/v1.0.0/downloads/model.pm"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewForge(strings.TrimPrefix(srv.URL, "https://"), "group/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitLab
+
+	// GitLab has no drafts, the release is created by PublishRelease with links of uploaded assets.
+	info, err := f.CreateRelease("v1.0.0", "changelog", true)
+	if err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	asset := filepath.Join(t.TempDir(), "model.pm")
+	if err = os.WriteFile(asset, []byte("pm"), 0600); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+	if _, err = f.UploadAsset(info.ID, asset); err != nil {
+		t.Fatalf("failed to upload asset: %v", err)
+	}
+	for _, r := range requests {
+		if strings.HasSuffix(r, "/releases") || strings.Contains(r, "/assets/links") {
+			t.Fatalf("expected release not to be created before publishing, got %v", requests)
+		}
+	}
+
+	published, err := f.PublishRelease(info.ID)
+	if err != nil {
+		t.Fatalf("failed to publish release: %v", err)
+	}
+	if len(release.Assets.Links) != 1 || release.Assets.Links[0]["name"] != "model.pm" || release.Assets.Links[0]["direct_asset_path"] != "/model.pm" {
+		t.Errorf("expected release to be created with asset links, got %+v", release)
+	}
+	if published.Assets["model.pm"] != "https://gitlab/group/repo/-/releases/v1.0.0/downloads/model.pm" {
+		t.Errorf("unexpected published assets: %v", published.Assets)
+	}
+
+	// Drafts which were never published are dropped without requests.
+	count := len(requests)
+	if _, err = f.CreateRelease("v1.1.0", "changelog", true); err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	if err = f.DeleteRelease("v1.1.0"); err != nil || len(requests) != count {
+		t.Errorf("expected draft to be dropped without requests, got %v, %v", err, requests[count:])
+	}
+}
+
+func TestCreateReleaseAuthFailed(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "bad credentials"}`))
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	f := NewForge(host, "org/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitea
+
+	_, err := f.CreateRelease("v1.0.0", "changelog", false)
+	var authErr *model.ErrAuthFailed
+	if !errors.As(err, &authErr) || authErr.Host != host {
+		t.Fatalf("expected ErrAuthFailed for %s, got %v", host, err)
+	}
+}
+
+func TestReleaseExists(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		switch r.URL.Path {
+		case "/api/v3/repos/org/repo/releases/tags/v1.0.0":
+			_, _ = w.Write([]byte(`{"id": 1}`))
+		case "/api/v3/repos/org/private/releases/tags/v1.0.0":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	f := NewForge(host, "org/repo", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitHub
+
+	if exists, err := f.ReleaseExists("v1.0.0"); err != nil || !exists {
+		t.Errorf("expected release v1.0.0 to exist, got %v, %v", exists, err)
+	}
+	if exists, err := f.ReleaseExists("v1.1.0"); err != nil || exists {
+		t.Errorf("expected release v1.1.0 not to exist, got %v, %v", exists, err)
+	}
+
+	f = NewForge(host, "org/private", "token")
+	f.client = srv.Client()
+	f.forgeType = ForgeGitHub
+	var authErr *model.ErrAuthFailed
+	if _, err := f.ReleaseExists("v1.0.0"); !errors.As(err, &authErr) {
+		t.Errorf("expected rejected token to fail, got %v", err)
+	}
+}
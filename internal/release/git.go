@@ -1,12 +1,16 @@
 package release
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os/exec"
 	"regexp"
 	"strings"
 )
 
+var errLightweightTag = errors.New("tag has no annotation to use as release body, delete it or annotate it")
+
 // GitOps provides git operations for releases
 type GitOps struct {
 	workDir string
@@ -79,8 +83,9 @@ func (g *GitOps) GetLatestSemverTag() (*Version, error) {
 }
 
 // CreateTag creates an annotated tag with the given message
+// The message is kept verbatim, markdown headings would be stripped as comments otherwise.
 func (g *GitOps) CreateTag(tag, message string) error {
-	cmd := exec.Command("git", "tag", "-f", "-a", tag, "-m", message)
+	cmd := exec.Command("git", "tag", "-f", "-a", "--cleanup=verbatim", tag, "-m", message)
 	cmd.Dir = g.workDir
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to create tag %s: %w", tag, err)
@@ -88,6 +93,75 @@ func (g *GitOps) CreateTag(tag, message string) error {
 	return nil
 }
 
+// IsClean checks if the working tree has no changes of tracked files
+func (g *GitOps) IsClean() (bool, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=no")
+	cmd.Dir = g.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get working tree status: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "", nil
+}
+
+// ResolveCommit returns the commit hash of a revision, e.g. HEAD or a tag
+func (g *GitOps) ResolveCommit(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", rev+"^{commit}")
+	cmd.Dir = g.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TagExists checks if a local tag exists
+func (g *GitOps) TagExists(tag string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/tags/"+tag)
+	cmd.Dir = g.workDir
+	return cmd.Run() == nil
+}
+
+// RemoteTagExists checks if a tag exists on origin, without fetching it
+func (g *GitOps) RemoteTagExists(tag string) (bool, error) {
+	cmd := exec.Command("git", "ls-remote", "--tags", "origin", "refs/tags/"+tag)
+	cmd.Dir = g.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list remote tags: %w", err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// TagMessage returns the message of an annotated tag without its signature,
+// lightweight tags have no message and fail
+func (g *GitOps) TagMessage(tag string) (string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(objecttype)", "refs/tags/"+tag)
+	cmd.Dir = g.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read tag %s: %w", tag, err)
+	}
+	if strings.TrimSpace(string(output)) != "tag" {
+		return "", fmt.Errorf("%w: %s", errLightweightTag, tag)
+	}
+
+	cmd = exec.Command("git", "for-each-ref", "--format=%(contents)", "refs/tags/"+tag)
+	cmd.Dir = g.workDir
+	output, err = cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read message of tag %s: %w", tag, err)
+	}
+
+	message := string(output)
+	if i := strings.Index(message, "-----BEGIN "); i >= 0 {
+		// Signature of signed tags follows the message
+		message = message[:i]
+	}
+
+	return strings.TrimSpace(message), nil
+}
+
 // PushTag pushes a tag to origin
 func (g *GitOps) PushTag(tag string) error {
 	cmd := exec.Command("git", "push", "origin", "tag", tag)
@@ -98,6 +172,22 @@ func (g *GitOps) PushTag(tag string) error {
 	return nil
 }
 
+// DeleteTag deletes a tag locally and from origin
+func (g *GitOps) DeleteTag(tag string) error {
+	cmd := exec.Command("git", "push", "origin", "--delete", "refs/tags/"+tag)
+	cmd.Dir = g.workDir
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete remote tag %s: %w", tag, err)
+	}
+
+	cmd = exec.Command("git", "tag", "-d", tag)
+	cmd.Dir = g.workDir
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete tag %s: %w", tag, err)
+	}
+	return nil
+}
+
 // RemoteInfo contains information about the git remote
 type RemoteInfo struct {
 	Host string
@@ -105,8 +195,7 @@ type RemoteInfo struct {
 }
 
 var (
-	sshRemoteRegex   = regexp.MustCompile(`^git@([^:]+):(.+?)(?:\.git)?$`)
-	httpsRemoteRegex = regexp.MustCompile(`^https?://([^/]+)/(.+?)(?:\.git)?$`)
+	scpRemoteRegex = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
 )
 
 // GetRemoteInfo extracts host and repo from the origin remote URL
@@ -118,19 +207,40 @@ func (g *GitOps) GetRemoteInfo() (*RemoteInfo, error) {
 		return nil, fmt.Errorf("failed to get remote URL: %w", err)
 	}
 
-	url := strings.TrimSpace(string(output))
+	return ParseRemoteURL(strings.TrimSpace(string(output)))
+}
+
+// ParseRemoteURL extracts host and repo from a git remote URL
+// Repo keeps the full namespace, e.g. group/subgroup/repo for nested GitLab groups
+func ParseRemoteURL(remoteURL string) (*RemoteInfo, error) {
+	var host, repo string
+
+	if strings.Contains(remoteURL, "://") {
+		// URL format: https://host/group/repo.git, ssh://git@host:2222/group/repo.git
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse remote URL: %s", remoteURL)
+		}
 
-	// Try SSH format: git@host:owner/repo.git
-	if matches := sshRemoteRegex.FindStringSubmatch(url); matches != nil {
-		return &RemoteInfo{Host: matches[1], Repo: matches[2]}, nil
+		host = u.Host
+		if u.Scheme != "http" && u.Scheme != "https" {
+			// SSH port is not relevant for forge API
+			host = u.Hostname()
+		}
+		repo = u.Path
+	} else if matches := scpRemoteRegex.FindStringSubmatch(remoteURL); matches != nil {
+		// SCP format: git@host:group/repo.git
+		host = matches[1]
+		repo = matches[2]
 	}
 
-	// Try HTTPS format: https://host/owner/repo.git
-	if matches := httpsRemoteRegex.FindStringSubmatch(url); matches != nil {
-		return &RemoteInfo{Host: matches[1], Repo: matches[2]}, nil
+	repo = strings.Trim(repo, "/")
+	repo = strings.TrimSuffix(repo, ".git")
+	if host == "" || !strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("could not parse remote URL: %s", remoteURL)
 	}
 
-	return nil, fmt.Errorf("could not parse remote URL: %s", url)
+	return &RemoteInfo{Host: host, Repo: repo}, nil
 }
 
 // HasRemote checks if a remote named "origin" exists
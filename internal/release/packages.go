@@ -0,0 +1,256 @@
+package release
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// PackageChange describes a package difference between two releases
+type PackageChange struct {
+	Name   string
+	OldRef string
+	NewRef string
+	OldURL string
+	NewURL string
+}
+
+// PackageChanges contains package differences between two releases
+type PackageChanges struct {
+	Added   []PackageChange
+	Removed []PackageChange
+	Updated []PackageChange
+}
+
+// IsEmpty checks if there are no package changes
+func (p *PackageChanges) IsEmpty() bool {
+	return len(p.Added) == 0 && len(p.Removed) == 0 && len(p.Updated) == 0
+}
+
+// PackageChanges compares packages of compose.yaml at the given tag with HEAD, refs resolved by compose.lock
+// are reported if it's committed. If fromTag is empty, packages are compared with the commit set by SetSince,
+// without it all packages at HEAD are reported as added
+func (c *ChangelogGenerator) PackageChanges(fromTag string) (*PackageChanges, error) {
+	head, err := c.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	newComposition, err := c.compositionAt(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	oldComposition := &model.Composition{}
+	if fromTag != "" {
+		tagHash, err := c.resolveTag(fromTag)
+		if err != nil {
+			return nil, err
+		}
+
+		oldComposition, err = c.compositionAt(tagHash)
+		if err != nil {
+			return nil, err
+		}
+	} else if c.sinceHash != plumbing.ZeroHash {
+		oldComposition, err = c.compositionAt(c.sinceHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return diffCompositions(oldComposition, newComposition), nil
+}
+
+// composeLock holds refs of packages resolved by compose in compose.lock
+type composeLock struct {
+	Packages []struct {
+		Name string `yaml:"name"`
+		Ref  string `yaml:"ref"`
+	} `yaml:"packages"`
+}
+
+// compositionAt reads compose.yaml from the given commit with refs resolved by compose.lock of the commit,
+// refs of compose.yaml are kept if compose.lock is missing. Empty composition is returned without compose.yaml
+func (c *ChangelogGenerator) compositionAt(hash plumbing.Hash) (*model.Composition, error) {
+	commit, err := c.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+
+	composition := &model.Composition{}
+	found, err := unmarshalFileAt(commit, model.ComposeFile, composition)
+	if err != nil || !found {
+		return composition, err
+	}
+
+	lock := &composeLock{}
+	if _, err = unmarshalFileAt(commit, model.VersionLockFile, lock); err != nil {
+		return nil, err
+	}
+	applyLockedRefs(composition, lock)
+
+	return composition, nil
+}
+
+// unmarshalFileAt parses the YAML file of commit into out, false is returned if the file is missing
+func unmarshalFileAt(commit *object.Commit, name string, out any) (bool, error) {
+	file, err := commit.File(name)
+	if err == object.ErrFileNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s at %s: %w", name, commit.Hash, err)
+	}
+
+	content, err := file.Contents()
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s at %s: %w", name, commit.Hash, err)
+	}
+
+	if err = yaml.Unmarshal([]byte(content), out); err != nil {
+		return false, fmt.Errorf("failed to parse %s at %s: %w", name, commit.Hash, err)
+	}
+
+	return true, nil
+}
+
+// applyLockedRefs replaces refs of dependencies with refs resolved in lock. Refs other than version ranges
+// are kept if the lock records another ref, compose.yaml was changed without composing then.
+func applyLockedRefs(composition *model.Composition, lock *composeLock) {
+	locked := make(map[string]string, len(lock.Packages))
+	for _, lp := range lock.Packages {
+		locked[lp.Name] = lp.Ref
+	}
+
+	for i, dep := range composition.Dependencies {
+		ref, ok := locked[dep.Name]
+		if !ok || ref == "" || ref == dep.Source.Ref {
+			continue
+		}
+		if strings.ContainsAny(dep.Source.Ref[:min(len(dep.Source.Ref), 1)], "^~<>=") {
+			composition.Dependencies[i].Source.Ref = ref
+		}
+	}
+}
+
+func diffCompositions(oldComposition, newComposition *model.Composition) *PackageChanges {
+	changes := &PackageChanges{}
+
+	oldDeps := make(map[string]model.Dependency)
+	for _, dep := range oldComposition.Dependencies {
+		oldDeps[dep.Name] = dep
+	}
+
+	newDeps := make(map[string]bool)
+	for _, dep := range newComposition.Dependencies {
+		newDeps[dep.Name] = true
+		newPkg := dep.ToPackage(dep.Name)
+
+		old, ok := oldDeps[dep.Name]
+		if !ok {
+			changes.Added = append(changes.Added, PackageChange{
+				Name:   dep.Name,
+				NewRef: newPkg.GetTarget(),
+				NewURL: newPkg.GetURL(),
+			})
+			continue
+		}
+
+		oldPkg := old.ToPackage(old.Name)
+		// Templated URLs change with the ref, only changes of the template are reported.
+		if oldPkg.GetTarget() != newPkg.GetTarget() || oldPkg.Source.URL != newPkg.Source.URL {
+			changes.Updated = append(changes.Updated, PackageChange{
+				Name:   dep.Name,
+				OldRef: oldPkg.GetTarget(),
+				NewRef: newPkg.GetTarget(),
+				OldURL: oldPkg.GetURL(),
+				NewURL: newPkg.GetURL(),
+			})
+		}
+	}
+
+	for _, dep := range oldComposition.Dependencies {
+		if newDeps[dep.Name] {
+			continue
+		}
+
+		oldPkg := dep.ToPackage(dep.Name)
+		changes.Removed = append(changes.Removed, PackageChange{
+			Name:   dep.Name,
+			OldRef: oldPkg.GetTarget(),
+			OldURL: oldPkg.GetURL(),
+		})
+	}
+
+	for _, list := range [][]PackageChange{changes.Added, changes.Removed, changes.Updated} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Name < list[j].Name
+		})
+	}
+
+	return changes
+}
+
+// formatPackageChanges formats package changes into a markdown changelog section
+func formatPackageChanges(changes *PackageChanges) string {
+	if changes == nil || changes.IsEmpty() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### Packages\n\n")
+	for _, p := range changes.Added {
+		fmt.Fprintf(&sb, "- Added **%s** %s\n", p.Name, p.NewRef)
+	}
+	for _, p := range changes.Removed {
+		fmt.Fprintf(&sb, "- Removed **%s** %s\n", p.Name, p.OldRef)
+	}
+	for _, p := range changes.Updated {
+		if p.OldRef != p.NewRef {
+			fmt.Fprintf(&sb, "- **%s** %s → %s\n", p.Name, p.OldRef, p.NewRef)
+		}
+		if p.OldURL != p.NewURL {
+			fmt.Fprintf(&sb, "- **%s** source %s → %s\n", p.Name, p.OldURL, p.NewURL)
+		}
+	}
+
+	return sb.String()
+}
+
+// formatMetadata formats composition metadata into a markdown changelog section
+func formatMetadata(meta model.Metadata) string {
+	if meta.Description == "" && len(meta.Maintainers) == 0 && len(meta.Annotations) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### Composition")
+	if meta.Name != "" {
+		sb.WriteString(" " + meta.Name)
+	}
+	sb.WriteString("\n\n")
+	if meta.Description != "" {
+		sb.WriteString(meta.Description + "\n\n")
+	}
+	if len(meta.Maintainers) > 0 {
+		fmt.Fprintf(&sb, "- Maintainers: %s\n", strings.Join(meta.Maintainers, ", "))
+	}
+
+	keys := make([]string, 0, len(meta.Annotations))
+	for key := range meta.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "- **%s**: %s\n", key, meta.Annotations[key])
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n") + "\n"
+}
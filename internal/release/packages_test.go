@@ -0,0 +1,115 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestFormatMetadata(t *testing.T) {
+	if section := formatMetadata(model.Metadata{Name: "platform"}); section != "" {
+		t.Errorf("expected no section without description, maintainers and annotations, got %q", section)
+	}
+
+	section := formatMetadata(model.Metadata{
+		Name:        "platform",
+		Description: "Platform model",
+		Maintainers: []string{"ops@example.com", "dev@example.com"},
+		Annotations: map[string]string{"tier": "prod", "owner": "ops"},
+	})
+	expected := "### Composition platform\n\nPlatform model\n\n" +
+		"- Maintainers: ops@example.com, dev@example.com\n" +
+		"- **owner**: ops\n" +
+		"- **tier**: prod\n"
+	if section != expected {
+		t.Errorf("unexpected section:\n%s", section)
+	}
+}
+
+func TestPackageChanges(t *testing.T) {
+	dep := func(name, url, ref string) model.Dependency {
+		return model.Dependency{Name: name, Source: model.Source{Type: "git", URL: url, Ref: ref}}
+	}
+	core := dep("core", "https://example.com/core.git", "1.2.0")
+
+	for _, tt := range []struct {
+		name     string
+		old, new []model.Dependency
+		expected *PackageChanges
+		section  string
+	}{
+		{
+			name:     "unchanged",
+			old:      []model.Dependency{core},
+			new:      []model.Dependency{core},
+			expected: &PackageChanges{},
+		},
+		{
+			name:     "added",
+			new:      []model.Dependency{core},
+			expected: &PackageChanges{Added: []PackageChange{{Name: "core", NewRef: "1.2.0", NewURL: core.Source.URL}}},
+			section:  "- Added **core** 1.2.0\n",
+		},
+		{
+			name:     "removed",
+			old:      []model.Dependency{core},
+			expected: &PackageChanges{Removed: []PackageChange{{Name: "core", OldRef: "1.2.0", OldURL: core.Source.URL}}},
+			section:  "- Removed **core** 1.2.0\n",
+		},
+		{
+			name: "bumped",
+			old:  []model.Dependency{core},
+			new:  []model.Dependency{dep("core", core.Source.URL, "1.4.1")},
+			expected: &PackageChanges{Updated: []PackageChange{
+				{Name: "core", OldRef: "1.2.0", NewRef: "1.4.1", OldURL: core.Source.URL, NewURL: core.Source.URL},
+			}},
+			section: "- **core** 1.2.0 → 1.4.1\n",
+		},
+		{
+			name: "url changed",
+			old:  []model.Dependency{core},
+			new:  []model.Dependency{dep("core", "https://example.org/core.git", "1.2.0")},
+			expected: &PackageChanges{Updated: []PackageChange{
+				{Name: "core", OldRef: "1.2.0", NewRef: "1.2.0", OldURL: core.Source.URL, NewURL: "https://example.org/core.git"},
+			}},
+			section: "- **core** source https://example.com/core.git → https://example.org/core.git\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffCompositions(&model.Composition{Dependencies: tt.old}, &model.Composition{Dependencies: tt.new})
+			if !reflect.DeepEqual(changes, tt.expected) {
+				t.Errorf("expected changes %+v, got %+v", tt.expected, changes)
+			}
+
+			expected := ""
+			if tt.section != "" {
+				expected = "### Packages\n\n" + tt.section
+			}
+			if section := formatPackageChanges(changes); section != expected {
+				t.Errorf("unexpected section:\n%s", section)
+			}
+		})
+	}
+}
+
+func TestApplyLockedRefs(t *testing.T) {
+	composition := &model.Composition{Dependencies: []model.Dependency{
+		{Name: "range", Source: model.Source{Ref: "^1.2"}},
+		{Name: "tag", Source: model.Source{Ref: "v2.0.0"}},
+		{Name: "unlocked", Source: model.Source{Ref: "~1.0"}},
+	}}
+	lock := &composeLock{}
+	if err := yaml.Unmarshal([]byte("packages:\n  - name: range\n    ref: 1.4.1\n  - name: tag\n    ref: v1.0.0\n"), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	applyLockedRefs(composition, lock)
+	for i, expected := range []string{"1.4.1", "v2.0.0", "~1.0"} {
+		if ref := composition.Dependencies[i].Source.Ref; ref != expected {
+			t.Errorf("%s: expected ref %s, got %s", composition.Dependencies[i].Name, expected, ref)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+var (
+	errNotInCI               = errors.New("release policy requires CI, use --local to release from this machine")
+	errDirtyWorktree         = errors.New("release policy requires a clean working tree")
+	errVersionPattern        = errors.New("version doesn't match release version patterns")
+	errInvalidVersionPattern = errors.New("invalid release version pattern")
+)
+
+// ciVariables maps environment variables set by CI systems to their names. Gitea and Forgejo
+// runners also set GITHUB_ACTIONS, they are checked first.
+var ciVariables = []struct {
+	name     string
+	variable string
+}{
+	{"Forgejo Actions", "FORGEJO_ACTIONS"},
+	{"Gitea Actions", "GITEA_ACTIONS"},
+	{"GitHub Actions", "GITHUB_ACTIONS"},
+	{"GitLab CI", "GITLAB_CI"},
+	{"Jenkins", "JENKINS_URL"},
+	{"Buildkite", "BUILDKITE"},
+	{"CircleCI", "CIRCLECI"},
+	{"Azure Pipelines", "TF_BUILD"},
+	{"CI", "CI"},
+}
+
+// DetectCI returns the name of the CI system the process runs in, empty string outside of CI.
+func DetectCI() string {
+	return detectCI(os.Getenv)
+}
+
+func detectCI(getenv func(string) string) string {
+	for _, ci := range ciVariables {
+		switch strings.ToLower(getenv(ci.variable)) {
+		case "", "false", "0":
+		default:
+			return ci.name
+		}
+	}
+
+	return ""
+}
+
+// CheckPolicy checks the release of version against policy. CI and clean working tree are not required if local is set,
+// version patterns are always checked. Nil policy allows any release.
+func CheckPolicy(policy *model.ReleasePolicy, version string, local bool, g *GitOps) error {
+	if policy == nil {
+		return nil
+	}
+
+	if policy.RequireCI && !local && DetectCI() == "" {
+		return errNotInCI
+	}
+
+	if policy.RequireClean && !local {
+		clean, err := g.IsClean()
+		if err != nil {
+			return err
+		}
+		if !clean {
+			return errDirtyWorktree
+		}
+	}
+
+	return matchVersionPatterns(policy.VersionPatterns, version)
+}
+
+// matchVersionPatterns checks version matches one of glob patterns, no patterns match any version.
+func matchVersionPatterns(patterns []string, version string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, version)
+		if err != nil {
+			return fmt.Errorf("%w %q: %w", errInvalidVersionPattern, pattern, err)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s, expected %s", errVersionPattern, version, strings.Join(patterns, ", "))
+}
@@ -0,0 +1,91 @@
+package release
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		ci   string
+	}{
+		{"none", nil, ""},
+		{"github", map[string]string{"GITHUB_ACTIONS": "true", "CI": "true"}, "GitHub Actions"},
+		{"gitea sets github variables", map[string]string{"GITEA_ACTIONS": "true", "GITHUB_ACTIONS": "true"}, "Gitea Actions"},
+		{"gitlab", map[string]string{"GITLAB_CI": "true"}, "GitLab CI"},
+		{"disabled", map[string]string{"CI": "false"}, ""},
+		{"generic", map[string]string{"CI": "1"}, "CI"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ci := detectCI(func(key string) string { return tt.env[key] }); ci != tt.ci {
+				t.Errorf("expected %q, got %q", tt.ci, ci)
+			}
+		})
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	for _, key := range []string{"FORGEJO_ACTIONS", "GITEA_ACTIONS", "GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL", "BUILDKITE", "CIRCLECI", "TF_BUILD", "CI"} {
+		t.Setenv(key, "")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@test.com"},
+		{"commit", "-q", "--allow-empty", "-m", "feat: initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	g := NewGitOps(dir)
+
+	if err := CheckPolicy(nil, "v1.0.0", false, g); err != nil {
+		t.Errorf("expected no policy to allow release, got %v", err)
+	}
+
+	policy := &model.ReleasePolicy{RequireCI: true, RequireClean: true, VersionPatterns: []string{"v*.*.*"}}
+	if err := CheckPolicy(policy, "v1.0.0", false, g); !errors.Is(err, errNotInCI) {
+		t.Errorf("expected release outside of CI to fail, got %v", err)
+	}
+	if err := CheckPolicy(policy, "v1.0.0", true, g); err != nil {
+		t.Errorf("expected local release to pass, got %v", err)
+	}
+	if err := CheckPolicy(policy, "1.0.0", true, g); !errors.Is(err, errVersionPattern) {
+		t.Errorf("expected version patterns to apply to local releases, got %v", err)
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	if err := CheckPolicy(policy, "v1.0.0", false, g); err != nil {
+		t.Errorf("expected release in CI to pass, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("build output"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPolicy(policy, "v1.0.0", false, g); err != nil {
+		t.Errorf("expected untracked files to be ignored, got %v", err)
+	}
+
+	cmd := exec.Command("git", "add", "untracked.txt")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPolicy(policy, "v1.0.0", false, g); !errors.Is(err, errDirtyWorktree) {
+		t.Errorf("expected staged changes to fail, got %v", err)
+	}
+}
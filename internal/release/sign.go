@@ -0,0 +1,316 @@
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Signing methods of Platform Model bundles
+const (
+	// SignCosign signs with a cosign key file or KMS URI, the key password is read from COSIGN_PASSWORD.
+	SignCosign = "cosign"
+	// SignGPG signs with a key of the gpg keyring, the default key if none is given.
+	SignGPG = "gpg"
+)
+
+// Suffixes of files written next to signed bundles
+const (
+	CosignSignatureSuffix = ".sig"
+	GPGSignatureSuffix    = ".asc"
+	AttestationSuffix     = ".intoto.json"
+)
+
+// In-toto statement fields of bundle attestations
+const (
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	// BundlePredicateType identifies attestations with the bundle manifest as predicate.
+	BundlePredicateType = "https://github.com/plasmash/plasmactl-model/bundle/v1"
+)
+
+var (
+	errInvalidSignMethod = errors.New("invalid signing method")
+	errSignatureNotFound = errors.New("signature not found")
+	errVerifyFailed      = errors.New("signature verification failed")
+)
+
+// rgxGPGKey matches fingerprints and long key IDs pinning gpg signers, short key IDs are ambiguous
+var rgxGPGKey = regexp.MustCompile(`^(?:[0-9A-F]{16}|[0-9A-F]{40}|[0-9A-F]{64})$`)
+
+// runSigner runs cosign or gpg and returns its standard output, output is returned in the error of a failed run
+var runSigner = func(name string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()+string(out)))
+	}
+
+	return string(out), nil
+}
+
+// ValidateSignMethod checks the signing method, empty method disables signing
+func ValidateSignMethod(method string) error {
+	switch method {
+	case "", SignCosign, SignGPG:
+		return nil
+	default:
+		return fmt.Errorf("%w %q, expected one of: %s, %s", errInvalidSignMethod, method, SignCosign, SignGPG)
+	}
+}
+
+// SignatureFile returns path of the detached signature of file made by the method
+func SignatureFile(method, file string) string {
+	if method == SignGPG {
+		return file + GPGSignatureSuffix
+	}
+
+	return file + CosignSignatureSuffix
+}
+
+// FindSignature returns the method and path of a detached signature next to file.
+// If method is set, only signatures of the method are accepted.
+func FindSignature(method, file string) (string, string, error) {
+	if method != "" {
+		if err := ValidateSignMethod(method); err != nil {
+			return "", "", err
+		}
+		sig := SignatureFile(method, file)
+		if _, err := os.Stat(sig); err != nil {
+			return "", "", fmt.Errorf("%w: no %s signature %s", errSignatureNotFound, method, sig)
+		}
+		return method, sig, nil
+	}
+
+	for _, m := range []string{SignCosign, SignGPG} {
+		sig := SignatureFile(m, file)
+		if _, err := os.Stat(sig); err == nil {
+			return m, sig, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: no %s or %s next to %s", errSignatureNotFound, CosignSignatureSuffix, GPGSignatureSuffix, file)
+}
+
+// SignatureMethod returns the method of a signature file by its suffix
+func SignatureMethod(sig string) (string, error) {
+	switch filepath.Ext(sig) {
+	case CosignSignatureSuffix:
+		return SignCosign, nil
+	case GPGSignatureSuffix:
+		return SignGPG, nil
+	default:
+		return "", fmt.Errorf("%w: unknown signature %s, expected %s or %s", errInvalidSignMethod, sig, CosignSignatureSuffix, GPGSignatureSuffix)
+	}
+}
+
+// SignFile writes a detached signature of file next to it and returns its path.
+// Cosign requires a key, GPG uses the default key of the keyring if key is empty.
+func SignFile(method, key, file string) (string, error) {
+	sig := SignatureFile(method, file)
+
+	var err error
+	switch method {
+	case SignCosign:
+		if key == "" {
+			return "", fmt.Errorf("%w: cosign requires a key", errInvalidSignMethod)
+		}
+		_, err = runSigner("cosign", "sign-blob", "--yes", "--key", key, "--output-signature", sig, file)
+	case SignGPG:
+		args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--output", sig}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		_, err = runSigner("gpg", append(args, file)...)
+	default:
+		return "", ValidateSignMethod(method)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", file, err)
+	}
+
+	return sig, nil
+}
+
+// VerifyFile verifies the detached signature of file. Cosign signatures are verified with the public key,
+// GPG signatures with keys of the gpg keyring, signed by key if it's given as a fingerprint or long key ID.
+func VerifyFile(method, key, file, sig string) error {
+	var err error
+	switch method {
+	case SignCosign:
+		if key == "" {
+			return fmt.Errorf("%w: cosign signatures are verified with a public key", errVerifyFailed)
+		}
+		_, err = runSigner("cosign", "verify-blob", "--key", key, "--signature", sig, file)
+	case SignGPG:
+		if key == "" {
+			_, err = runSigner("gpg", "--batch", "--verify", sig, file)
+			break
+		}
+
+		var status string
+		// gpg --verify ignores keys, the signer is checked in the status output.
+		if status, err = runSigner("gpg", "--batch", "--status-fd", "1", "--verify", sig, file); err == nil {
+			err = verifyGPGSigner(status, key)
+		}
+	default:
+		return ValidateSignMethod(method)
+	}
+	if err != nil {
+		return fmt.Errorf("%w of %s: %w", errVerifyFailed, file, err)
+	}
+
+	return nil
+}
+
+// verifyGPGSigner checks that a valid signature reported by the gpg status output is made by key,
+// a fingerprint or long key ID of the signing key or its primary key
+func verifyGPGSigner(status, key string) error {
+	key = strings.ToUpper(strings.TrimPrefix(strings.ReplaceAll(key, " ", ""), "0x"))
+	if !rgxGPGKey.MatchString(key) {
+		return fmt.Errorf("gpg signatures are verified with a key fingerprint or long key ID, got %q", key)
+	}
+
+	var signers []string
+	for _, line := range strings.Split(status, "\n") {
+		// [GNUPG:] VALIDSIG <fingerprint> <date> <timestamp> <expire> <version> <reserved> <algo> <hash> <class> <primary fingerprint>
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" || fields[1] != "VALIDSIG" {
+			continue
+		}
+
+		fingerprints := fields[2:3]
+		if len(fields) > 11 {
+			fingerprints = append(fingerprints, fields[11])
+		}
+		for _, fpr := range fingerprints {
+			if strings.HasSuffix(strings.ToUpper(fpr), key) {
+				return nil
+			}
+		}
+		signers = append(signers, fields[2])
+	}
+	if len(signers) == 0 {
+		return errors.New("gpg reported no valid signature")
+	}
+
+	return fmt.Errorf("signed by %s, expected %s", strings.Join(signers, ", "), key)
+}
+
+// attestationStatement is an in-toto statement about a bundle
+type attestationStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []attestationSubject `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     any                  `json:"predicate"`
+}
+
+type attestationSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// WriteAttestation writes an in-toto statement with the SHA-256 digest of file as subject and
+// the predicate next to file, and returns its path
+func WriteAttestation(file, predicateType string, predicate any) (string, error) {
+	digest, err := DigestFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	statement := attestationStatement{
+		Type:          inTotoStatementType,
+		Subject:       []attestationSubject{{Name: filepath.Base(file), Digest: map[string]string{"sha256": digest}}},
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	attestation := file + AttestationSuffix
+	return attestation, os.WriteFile(attestation, data, 0600)
+}
+
+// VerifyAttestation checks that the in-toto statement names file with its current digest
+func VerifyAttestation(file, attestation string) error {
+	data, err := os.ReadFile(filepath.Clean(attestation))
+	if err != nil {
+		return err
+	}
+
+	var statement attestationStatement
+	if err = json.Unmarshal(data, &statement); err != nil {
+		return fmt.Errorf("%w: invalid attestation %s: %w", errVerifyFailed, attestation, err)
+	}
+
+	digest, err := DigestFile(file)
+	if err != nil {
+		return err
+	}
+	for _, s := range statement.Subject {
+		if s.Name == filepath.Base(file) && s.Digest["sha256"] == digest {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: attestation %s doesn't match digest %s of %s", errVerifyFailed, attestation, digest, file)
+}
+
+// BundleVerification lists files of a verified bundle
+type BundleVerification struct {
+	Method      string `json:"method"`
+	Signature   string `json:"signature"`
+	Attestation string `json:"attestation,omitempty"`
+}
+
+// VerifyBundle verifies the signature of the bundle, found next to it if sig is empty, and its attestation
+// if there is one. If method is set, signatures of other methods are rejected.
+// Attestations must be signed with the method of the bundle signature.
+func VerifyBundle(method, key, file, sig string) (*BundleVerification, error) {
+	var found string
+	var err error
+	if sig == "" {
+		found, sig, err = FindSignature(method, file)
+	} else {
+		found, err = SignatureMethod(sig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if method != "" && found != method {
+		return nil, fmt.Errorf("%w: %s is a %s signature, expected %s", errVerifyFailed, sig, found, method)
+	}
+	method = found
+
+	if err = VerifyFile(method, key, file, sig); err != nil {
+		return nil, err
+	}
+	v := &BundleVerification{Method: method, Signature: sig}
+
+	attestation := file + AttestationSuffix
+	if _, err = os.Stat(attestation); os.IsNotExist(err) {
+		return v, nil
+	}
+	if err = VerifyAttestation(file, attestation); err != nil {
+		return nil, err
+	}
+	attestationSig := SignatureFile(method, attestation)
+	if _, err = os.Stat(attestationSig); err != nil {
+		return nil, fmt.Errorf("%w: attestation %s isn't signed", errVerifyFailed, attestation)
+	}
+	if err = VerifyFile(method, key, attestation, attestationSig); err != nil {
+		return nil, err
+	}
+	v.Attestation = attestation
+
+	return v, nil
+}
@@ -0,0 +1,114 @@
+package release
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignFile(t *testing.T) {
+	var commands []string
+	run := runSigner
+	runSigner = func(name string, args ...string) (string, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return "", nil
+	}
+	defer func() { runSigner = run }()
+
+	bundle := filepath.Join(t.TempDir(), "model-v1.0.0.pm")
+	if err := os.WriteFile(bundle, []byte("pm"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := SignFile(SignCosign, "cosign.key", bundle)
+	if err != nil || sig != bundle+CosignSignatureSuffix {
+		t.Fatalf("unexpected cosign signature %s: %v", sig, err)
+	}
+	if _, err = SignFile(SignCosign, "", bundle); !errors.Is(err, errInvalidSignMethod) {
+		t.Errorf("expected cosign without key to fail, got %v", err)
+	}
+	sig, err = SignFile(SignGPG, "", bundle)
+	if err != nil || sig != bundle+GPGSignatureSuffix {
+		t.Fatalf("unexpected gpg signature %s: %v", sig, err)
+	}
+	if err = VerifyFile(SignGPG, "", bundle, sig); err != nil {
+		t.Errorf("unexpected verification error: %v", err)
+	}
+
+	expected := []string{
+		"cosign sign-blob --yes --key cosign.key --output-signature " + bundle + ".sig " + bundle,
+		"gpg --batch --yes --armor --detach-sign --output " + bundle + ".asc " + bundle,
+		"gpg --batch --verify " + bundle + ".asc " + bundle,
+	}
+	if strings.Join(commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected commands:\n%s", strings.Join(commands, "\n"))
+	}
+
+	if err = os.WriteFile(sig, []byte("sig"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if method, found, err := FindSignature("", bundle); err != nil || method != SignGPG || found != sig {
+		t.Errorf("expected gpg signature, got %s %s: %v", method, found, err)
+	}
+	if _, _, err = FindSignature(SignCosign, bundle); !errors.Is(err, errSignatureNotFound) {
+		t.Errorf("expected gpg signature to be rejected for cosign, got %v", err)
+	}
+	if _, err = VerifyBundle(SignCosign, "cosign.pub", bundle, sig); !errors.Is(err, errVerifyFailed) {
+		t.Errorf("expected gpg signature to fail verification pinned to cosign, got %v", err)
+	}
+}
+
+func TestVerifyFileGPGSigner(t *testing.T) {
+	const (
+		subkey  = "0123456789ABCDEF0123456789ABCDEF01234567"
+		primary = "89ABCDEF0123456789ABCDEF0123456789ABCDEF"
+	)
+	run := runSigner
+	runSigner = func(string, ...string) (string, error) {
+		return "[GNUPG:] GOODSIG 89ABCDEF01234567 Release <release@example.com>\n" +
+			"[GNUPG:] VALIDSIG " + subkey + " 2026-10-16 1792137600 0 4 0 1 10 00 " + primary + "\n", nil
+	}
+	defer func() { runSigner = run }()
+
+	for key, ok := range map[string]bool{
+		subkey:                        true,
+		"0x" + primary:                true,
+		"0123 4567 89ab cdef":         true,
+		"FEDCBA9876543210":            false,
+		"FEDCBA9876543210FEDCBA98765": false,
+		"89ABCDEF":                    false,
+		"release@example.com":         false,
+	} {
+		err := VerifyFile(SignGPG, key, "model-v1.0.0.pm", "model-v1.0.0.pm.asc")
+		if ok && err != nil {
+			t.Errorf("%s: unexpected verification error: %v", key, err)
+		}
+		if !ok && !errors.Is(err, errVerifyFailed) {
+			t.Errorf("%s: expected verification to fail, got %v", key, err)
+		}
+	}
+}
+
+func TestAttestation(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "model-v1.0.0.pm")
+	if err := os.WriteFile(bundle, []byte("pm"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	attestation, err := WriteAttestation(bundle, BundlePredicateType, map[string]interface{}{"version": "v1.0.0"})
+	if err != nil {
+		t.Fatalf("failed to write attestation: %v", err)
+	}
+	if err = VerifyAttestation(bundle, attestation); err != nil {
+		t.Errorf("unexpected verification error: %v", err)
+	}
+
+	if err = os.WriteFile(bundle, []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyAttestation(bundle, attestation); !errors.Is(err, errVerifyFailed) {
+		t.Errorf("expected changed bundle to fail, got %v", err)
+	}
+}
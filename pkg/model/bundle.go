@@ -0,0 +1,118 @@
+package model
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleManifestFile is the path of the manifest inside of a bundle, it's the first entry of the archive.
+const BundleManifestFile = ".plasma/manifest.yaml"
+
+// BundleChecksumsFile is the path of SHA-256 checksums of bundled files in the format of sha256sum,
+// it's the last entry of the archive.
+const BundleChecksumsFile = ".plasma/checksums.sha256"
+
+// BundleManifest describes the bundled composition and its provenance: Commit is the commit the bundle was
+// built from, Tag is set if it's tagged, Packages are the composed packages and Prepare the transformations
+// of model:prepare applied to bundled files. Bundles created by older versions have no provenance.
+type BundleManifest struct {
+	Metadata `yaml:",inline"`
+	Version  string          `json:"version" yaml:"version"`
+	Commit   string          `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Repo     string          `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Tag      string          `json:"tag,omitempty" yaml:"tag,omitempty"`
+	BuiltAt  *time.Time      `json:"built_at,omitempty" yaml:"built_at,omitempty"`
+	Packages []BundlePackage `json:"packages,omitempty" yaml:"packages,omitempty"`
+	Prepare  *PrepareReport  `json:"prepare,omitempty" yaml:"prepare,omitempty"`
+}
+
+// BundlePackage identifies a composed package by its resolved ref, the commit checked out for git packages
+// and the tree hash recorded in compose.lock.
+type BundlePackage struct {
+	Name   string `json:"name" yaml:"name"`
+	Type   string `json:"type,omitempty" yaml:"type,omitempty"`
+	URL    string `json:"url,omitempty" yaml:"url,omitempty"`
+	Ref    string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Hash   string `json:"hash,omitempty" yaml:"hash,omitempty"`
+}
+
+// PrepareReport lists transformations applied by the last model:prepare run, saved to PrepareReportFile.
+type PrepareReport struct {
+	Transformations []string `json:"transformations" yaml:"transformations"`
+	Layers          []string `json:"layers,omitempty" yaml:"layers,omitempty"`
+}
+
+// PrepareReportPath returns the path of the report of prepareDir, it's saved next to the directory
+// so the report isn't bundled with prepared files.
+func PrepareReportPath(prepareDir string) string {
+	return filepath.Clean(prepareDir) + ".json"
+}
+
+// SavePrepareReport writes the report of prepareDir.
+func SavePrepareReport(prepareDir string, report *PrepareReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(PrepareReportPath(prepareDir), append(data, '\n'), 0644) //nolint:gosec // report isn't secret
+}
+
+// LoadPrepareReport reads the report of prepareDir, it's nil if the directory wasn't prepared by this version.
+func LoadPrepareReport(prepareDir string) (*PrepareReport, error) {
+	data, err := os.ReadFile(PrepareReportPath(prepareDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PrepareReport{}
+	if err = json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", PrepareReportPath(prepareDir), err)
+	}
+
+	return report, nil
+}
+
+// ReadBundleManifest reads the manifest of a bundle archive without extracting it, nil is returned if there is none.
+func ReadBundleManifest(r io.Reader) (*BundleManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Name != BundleManifestFile {
+			continue
+		}
+
+		manifest := &BundleManifest{}
+		if err = yaml.NewDecoder(tr).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", BundleManifestFile, err)
+		}
+
+		return manifest, nil
+	}
+}
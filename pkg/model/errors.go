@@ -0,0 +1,50 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConflictPolicy is wrapped by errors of invalid strategies and conflict resolution settings.
+	ErrConflictPolicy = errors.New("invalid conflict policy")
+	// ErrLockOutOfDate is wrapped by errors of compose.lock not matching downloaded packages.
+	ErrLockOutOfDate = errors.New(VersionLockFile + " is out of date")
+)
+
+// ErrAuthFailed is returned when a host rejects or requires credentials which couldn't be provided.
+type ErrAuthFailed struct {
+	Host string
+	Err  error
+}
+
+func (e *ErrAuthFailed) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("authentication to %s failed", e.Host)
+	}
+
+	return fmt.Sprintf("authentication to %s failed: %s", e.Host, e.Err)
+}
+
+func (e *ErrAuthFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrRefNotFound is returned when a ref of a package doesn't exist in its source.
+type ErrRefNotFound struct {
+	Package string
+	Ref     string
+	Err     error
+}
+
+func (e *ErrRefNotFound) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("couldn't find ref %s of package %s", e.Ref, e.Package)
+	}
+
+	return fmt.Sprintf("couldn't find ref %s of package %s: %s", e.Ref, e.Package, e.Err)
+}
+
+func (e *ErrRefNotFound) Unwrap() error {
+	return e.Err
+}
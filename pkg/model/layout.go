@@ -0,0 +1,69 @@
+package model
+
+import (
+	"path"
+)
+
+// BundleDir is the default directory of Platform Model bundles created by model:bundle.
+const BundleDir = "bundle"
+
+// LayoutConfigKey is the key of the layout in the launchr configuration, e.g. .plasmactl/config.yaml:
+//
+//	model:
+//	  layout:
+//	    prepare_dir: build/prepare
+//	    bundle_dir: dist
+const LayoutConfigKey = "model"
+
+// Layout is the directory layout of model operations, paths are relative to the working directory.
+// Actions and services locate model directories through the Layout passed by the plugin.
+//
+// Directories of model:compose aren't configurable: compose keeps its state files and the model lock
+// at fixed paths next to them, and other plugins read the merged result at MergedDir.
+// Output directories of model:prepare and model:bundle may be set in the launchr configuration.
+type Layout struct {
+	ComposeDir  string `yaml:"-"`
+	PackagesDir string `yaml:"-"`
+	MergedDir   string `yaml:"-"`
+	PrepareDir  string `yaml:"prepare_dir,omitempty"`
+	BundleDir   string `yaml:"bundle_dir,omitempty"`
+}
+
+// DefaultLayout returns the layout of the directory constants of the package.
+func DefaultLayout() Layout {
+	return Layout{
+		ComposeDir:  ComposeDir,
+		PackagesDir: PackagesDir,
+		MergedDir:   MergedDir,
+		PrepareDir:  PrepareDir,
+		BundleDir:   BundleDir,
+	}
+}
+
+// WithDefaults returns the layout with compose directories and empty output directories of DefaultLayout.
+func (l Layout) WithDefaults() Layout {
+	d := DefaultLayout()
+	if l.PrepareDir != "" {
+		d.PrepareDir = path.Clean(l.PrepareDir)
+	}
+	if l.BundleDir != "" {
+		d.BundleDir = path.Clean(l.BundleDir)
+	}
+
+	return d
+}
+
+// MergedSrcDir returns the directory of merged source components.
+func (l Layout) MergedSrcDir() string {
+	return path.Join(l.MergedDir, "src")
+}
+
+// PrepareReportFile returns the report of the last model:prepare run, see PrepareReportPath.
+func (l Layout) PrepareReportFile() string {
+	return PrepareReportPath(l.PrepareDir)
+}
+
+// BundleTempDir returns the directory bundles are written to before they are moved to BundleDir.
+func (l Layout) BundleTempDir() string {
+	return path.Join(l.BundleDir, ".tmp")
+}
@@ -3,11 +3,14 @@
 package model
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -17,8 +20,12 @@ import (
 const (
 	// TargetLatest is a fallback to the latest version.
 	TargetLatest = "latest"
+	// RefPlaceholder in URLs of http sources is substituted with the ref of the source.
+	RefPlaceholder = "{ref}"
 	// ComposeFile is the name of the compose configuration file.
 	ComposeFile = "compose.yaml"
+	// VersionLockFile stores package versions resolved by compose, committed along with compose.yaml.
+	VersionLockFile = "compose.lock"
 	// ModelDir is the base directory for model operations.
 	ModelDir = ".plasma/model"
 	// ComposeDir is the base directory for model composition.
@@ -29,8 +36,26 @@ const (
 	MergedSrcDir = MergedDir + "/src"
 	// PackagesDir is the directory containing downloaded packages.
 	PackagesDir = ComposeDir + "/packages"
+	// ComposeSummaryFile stores statistics of the last compose run.
+	ComposeSummaryFile = ComposeDir + "/summary.json"
+	// ComposeConflictsFile lists conflicting paths of the last compose run and how they were resolved.
+	ComposeConflictsFile = ComposeDir + "/conflicts.json"
+	// ComposeMergeFile records how paths of the last merge result were resolved, for partial merges of the next compose.
+	ComposeMergeFile = ComposeDir + "/merge.json"
+	// ComposeProgressFile stores packages completed by an unfinished compose run.
+	ComposeProgressFile = ComposeDir + "/progress.json"
+	// MergedStagingDir is the directory the composition is merged into before it replaces MergedDir.
+	MergedStagingDir = ComposeDir + "/merged.partial"
+	// MergedPreviousDir keeps the previous MergedDir while it is replaced, it is restored if compose is interrupted in between.
+	MergedPreviousDir = ComposeDir + "/merged.previous"
 	// PrepareDir is the directory containing prepared deployment artifacts.
 	PrepareDir = ModelDir + "/prepare"
+	// PrepareReportFile lists transformations of the last prepare run of PrepareDir, recorded in bundle manifests.
+	PrepareReportFile = PrepareDir + ".json"
+	// LockFile is the lock file preventing parallel model operations.
+	LockFile = ModelDir + "/model.lock"
+	// AuthFile is the per-host authentication configuration.
+	AuthFile = ModelDir + "/auth.yaml"
 )
 
 var (
@@ -39,36 +64,165 @@ var (
 )
 
 // Composition stores the model composition definition (packages and their dependencies).
+// Flattened compositions list all nested dependencies, their nested compose files are not followed.
 type Composition struct {
-	Name         string       `yaml:"name"`
-	Dependencies []Dependency `yaml:"dependencies,omitempty"`
+	Name            string            `yaml:"name"`
+	Description     string            `yaml:"description,omitempty"`
+	Maintainers     []string          `yaml:"maintainers,omitempty"`
+	Annotations     map[string]string `yaml:"annotations,omitempty"`
+	Flattened       bool              `yaml:"flattened,omitempty"`
+	Requires        string            `yaml:"requires,omitempty"`
+	ConflictDefault string            `yaml:"conflict_default,omitempty"`
+	ConflictUnit    string            `yaml:"conflict_unit,omitempty"`
+	Strategies      []Strategy        `yaml:"strategies,omitempty"`
+	Dependencies    []Dependency      `yaml:"dependencies,omitempty"`
+	Overlays        []Overlay         `yaml:"overlays,omitempty"`
+	Excluded        *Excluded         `yaml:"excluded,omitempty"`
+	Substitution    *Substitution     `yaml:"substitution,omitempty"`
+	Permissions     *Permissions      `yaml:"permissions,omitempty"`
+	Release         *ReleasePolicy    `yaml:"release,omitempty"`
+}
+
+// Metadata stores descriptive fields of a composition surfaced by model:show, release notes and bundle manifests.
+type Metadata struct {
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Maintainers []string          `json:"maintainers,omitempty" yaml:"maintainers,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Metadata returns descriptive fields of the composition.
+func (c *Composition) Metadata() Metadata {
+	return Metadata{
+		Name:        c.Name,
+		Description: c.Description,
+		Maintainers: c.Maintainers,
+		Annotations: c.Annotations,
+	}
+}
+
+// IsEmpty reports if no metadata is set.
+func (m Metadata) IsEmpty() bool {
+	return m.Name == "" && m.Description == "" && len(m.Maintainers) == 0 && len(m.Annotations) == 0
+}
+
+// ReleasePolicy stores assertions checked by model:release before a release is created.
+// VersionPatterns are globs like protected tag rules of forges, e.g. "v*.*.*", the version must match one of them.
+type ReleasePolicy struct {
+	RequireCI       bool     `yaml:"require_ci,omitempty"`
+	RequireClean    bool     `yaml:"require_clean,omitempty"`
+	VersionPatterns []string `yaml:"version_patterns,omitempty"`
+}
+
+// Permissions stores file and directory modes policy of the merged result.
+// Modes are octal strings, e.g. "0750".
+type Permissions struct {
+	Umask bool             `yaml:"umask,omitempty"`
+	Dir   string           `yaml:"dir,omitempty"`
+	Rules []PermissionRule `yaml:"rules,omitempty"`
+}
+
+// PermissionRule stores explicit modes of merged paths matching a glob pattern.
+type PermissionRule struct {
+	Path string `yaml:"path"`
+	File string `yaml:"file,omitempty"`
+	Dir  string `yaml:"dir,omitempty"`
+}
+
+// Substitution stores files of the merged result where {{ placeholder }} values are replaced
+// and custom placeholder values.
+type Substitution struct {
+	Paths []string          `yaml:"paths"`
+	Vars  map[string]string `yaml:"vars,omitempty"`
+}
+
+// Excluded stores names of folders and files of the domain repo and packages which are never merged,
+// in addition to .plasma and the compose files. Names may be globs, folders are matched at any depth.
+type Excluded struct {
+	Folders []string `yaml:"folders,omitempty"`
+	Files   []string `yaml:"files,omitempty"`
+}
+
+// Overlay stores a local directory applied over the merged composition result.
+// Files with .tmpl suffix are rendered with Vars when Template is enabled.
+type Overlay struct {
+	Name     string            `yaml:"name,omitempty"`
+	Path     string            `yaml:"path"`
+	Template bool              `yaml:"template,omitempty"`
+	Vars     map[string]string `yaml:"vars,omitempty"`
+}
+
+// GetName returns overlay name, defaults to the base name of overlay path.
+func (o *Overlay) GetName() string {
+	if o.Name != "" {
+		return o.Name
+	}
+
+	return filepath.Base(filepath.Clean(o.Path))
 }
 
 // Package stores package definition
+// DeclaredBy is the package which nested compose.yaml declares the package, empty for the domain composition.
+// Commit pins the checkout of a git package to a commit of its ref, it's set when composing from compose.lock.
 type Package struct {
 	Name         string   `yaml:"name"`
 	Source       Source   `yaml:"source,omitempty"`
 	Dependencies []string `yaml:"dependencies,omitempty"`
+	DeclaredBy   string   `yaml:"-"`
+	Frozen       bool     `yaml:"-"`
+	Commit       string   `yaml:"-"`
 }
 
 // Dependency stores Dependency definition
+// Requires lists packages of the composition merged before the dependency.
+// Frozen packages keep their ref, neither model:update nor compose changes it.
 type Dependency struct {
-	Name   string `yaml:"name"`
-	Source Source `yaml:"source,omitempty"`
+	Name     string   `yaml:"name"`
+	Groups   []string `yaml:"groups,omitempty"`
+	Optional bool     `yaml:"optional,omitempty"`
+	Frozen   bool     `yaml:"frozen,omitempty"`
+	Requires []string `yaml:"requires,omitempty"`
+	Source   Source   `yaml:"source,omitempty"`
 }
 
-// Strategy stores packages merge strategy name and Paths
+// Strategy stores packages merge strategy name and Paths.
+// AppliesTo scopes the strategy to whole layers, e.g. interaction/, in addition to Paths.
 type Strategy struct {
-	Name  string   `yaml:"name"`
-	Paths []string `yaml:"path"`
+	Name      string   `yaml:"name"`
+	Paths     []string `yaml:"path,omitempty"`
+	AppliesTo Layers   `yaml:"applies_to,omitempty"`
+}
+
+// Layers stores layer names of a strategy scope, a single layer may be declared as a scalar.
+type Layers []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *Layers) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = Layers{value.Value}
+		return nil
+	}
+
+	var layers []string
+	if err := value.Decode(&layers); err != nil {
+		return err
+	}
+	*l = layers
+
+	return nil
 }
 
 // Source stores package source definition
 type Source struct {
-	Type       string     `yaml:"type"`
-	URL        string     `yaml:"url"`
-	Ref        string     `yaml:"ref,omitempty"`
+	Type    string `yaml:"type"`
+	URL     string `yaml:"url"`
+	Ref     string `yaml:"ref,omitempty"`
+	Subpath string `yaml:"subpath,omitempty"`
+	// Asset is the name pattern of the release asset downloaded by release sources, e.g. model-*.pm.
+	Asset      string     `yaml:"asset,omitempty"`
 	Strategies []Strategy `yaml:"strategy,omitempty"`
+	// Exclude lists paths of the package which are never merged, in the syntax of strategy paths.
+	Exclude []string `yaml:"exclude,omitempty"`
 }
 
 // ToPackage converts dependency to package
@@ -76,6 +230,7 @@ func (d *Dependency) ToPackage(name string) *Package {
 	return &Package{
 		Name:   name,
 		Source: d.Source,
+		Frozen: d.Frozen,
 	}
 }
 
@@ -104,8 +259,12 @@ func (p *Package) GetType() string {
 	return strings.ToLower(t)
 }
 
-// GetURL from package source
+// GetURL from package source, RefPlaceholder in URLs of http sources is replaced by the ref.
 func (p *Package) GetURL() string {
+	if p.GetType() == "http" && p.Source.Ref != "" {
+		return strings.ReplaceAll(p.Source.URL, RefPlaceholder, p.Source.Ref)
+	}
+
 	return p.Source.URL
 }
 
@@ -125,10 +284,17 @@ func (p *Package) GetTarget() string {
 	return target
 }
 
+// GetSubpath returns the directory of the repository holding the package, empty for the whole repository.
+func (p *Package) GetSubpath() string {
+	subpath := path.Clean("/" + filepath.ToSlash(p.Source.Subpath))
+	return strings.TrimPrefix(subpath, "/")
+}
+
 // GetIdentifier returns a Go-style package identifier: domain/path/name@ref
 // e.g., "projects.skilld.cloud/skilld/pla-plasma@prepare"
 func (p *Package) GetIdentifier() string {
-	rawURL := p.GetURL()
+	// Templated URLs of http sources are kept, the ref is appended anyway.
+	rawURL := p.Source.URL
 	ref := p.GetRef()
 
 	// Parse URL to extract domain and path
@@ -169,6 +335,24 @@ func Lookup(fsys fs.FS) (*Composition, error) {
 	return cfg, nil
 }
 
+// LookupStrict is like Lookup, but also fails on fields unknown to Composition.
+// Parsing errors contain line information.
+func LookupStrict(fsys fs.FS) (*Composition, error) {
+	f, err := fs.ReadFile(fsys, ComposeFile)
+	if err != nil {
+		return &Composition{}, ErrComposeNotExists
+	}
+
+	cfg := Composition{}
+	dec := yaml.NewDecoder(bytes.NewReader(f))
+	dec.KnownFields(true)
+	if err = dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return &Composition{}, fmt.Errorf("compose.yaml parsing failed - %w", err)
+	}
+
+	return &cfg, nil
+}
+
 func parseComposeYaml(input []byte) (*Composition, error) {
 	cfg := Composition{}
 	err := yaml.Unmarshal(input, &cfg)
@@ -0,0 +1,99 @@
+package model
+
+import "time"
+
+// Compose phases tracked in ComposeSummary.
+const (
+	PhaseFetch      = "fetch"
+	PhaseVerify     = "verify"
+	PhaseMerge      = "merge"
+	PhaseCopy       = "copy"
+	PhaseOverlay    = "overlay"
+	PhaseSubstitute = "substitute"
+)
+
+// PackageFiles stores number of files merged from a package.
+type PackageFiles struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+}
+
+// ComponentFiles stores files merged into a component {layer}.{type}.{component} by origin
+// and conflicts resolved within it.
+type ComponentFiles struct {
+	Name                string         `json:"name"`
+	Files               []PackageFiles `json:"files"`
+	OverriddenLocally   int            `json:"overridden_locally,omitempty"`
+	OverriddenByPackage int            `json:"overridden_by_package,omitempty"`
+}
+
+// Package cache states reported in PackageMetrics.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// PackageMetrics stores download metrics of a package. Bytes are transferred by archive and release asset
+// downloads, DiskBytes is growth of git repositories on disk by clones and fetches, also of cached checkouts.
+type PackageMetrics struct {
+	Name      string        `json:"name"`
+	Cache     string        `json:"cache"`
+	Bytes     int64         `json:"bytes"`
+	DiskBytes int64         `json:"disk_bytes"`
+	Duration  time.Duration `json:"duration"`
+	Auth      string        `json:"auth,omitempty"`
+}
+
+// PhaseTiming stores elapsed time of a compose phase.
+type PhaseTiming struct {
+	Name    string        `json:"name"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// PartialMerge stores paths merged again by a partial merge: paths of changed packages, Changed starts with
+// the domain repo if local files changed, and paths reused from the previous merge result.
+type PartialMerge struct {
+	Changed []string `json:"changed"`
+	Merged  int      `json:"merged"`
+	Reused  int      `json:"reused"`
+}
+
+// NestedStrategy stores a strategy declared for a package by the nested compose.yaml of another package.
+type NestedStrategy struct {
+	Package    string   `json:"package"`
+	DeclaredBy string   `json:"declared_by"`
+	Strategy   string   `json:"strategy"`
+	Paths      []string `json:"paths,omitempty"`
+	AppliesTo  []string `json:"applies_to,omitempty"`
+	Ignored    bool     `json:"ignored,omitempty"`
+}
+
+// Normalization is a path of a package moved into the canonical layout by compose,
+// paths inside of it follow the move.
+type Normalization struct {
+	Package string `json:"package"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// ComposeSummary collects statistics of a compose run, it's stored in ComposeSummaryFile
+// and returned by the compose service.
+type ComposeSummary struct {
+	Fetched            []string         `json:"fetched"`
+	Cached             []string         `json:"cached"`
+	Resumed            []string         `json:"resumed,omitempty"`
+	Skipped            []string         `json:"skipped,omitempty"`
+	NestedStrategies   []NestedStrategy `json:"nested_strategies,omitempty"`
+	Packages           []PackageMetrics `json:"packages"`
+	Files              []PackageFiles   `json:"files"`
+	Components         []ComponentFiles `json:"components,omitempty"`
+	Normalized         []Normalization  `json:"normalized,omitempty"`
+	Overlays           []PackageFiles   `json:"overlays,omitempty"`
+	ConflictsToLocal   int              `json:"conflicts_to_local"`
+	ConflictsToPackage int              `json:"conflicts_to_package"`
+	ConflictsMerged    int              `json:"conflicts_merged,omitempty"`
+	BytesCopied        int64            `json:"bytes_copied"`
+	Substituted        int              `json:"substituted,omitempty"`
+	Partial            *PartialMerge    `json:"partial,omitempty"`
+	Phases             []PhaseTiming    `json:"phases"`
+}
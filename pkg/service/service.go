@@ -0,0 +1,113 @@
+// Package service exposes model operations to other launchr plugins.
+//
+// The plugin registers a [ModelService] with the launchr app, sibling plugins get it in their OnAppInit
+// and run compose, prepare, bundle and query in-process instead of invoking model:* actions:
+//
+//	var models service.ModelService
+//	app.GetService(&models)
+//	res, err := models.Compose(&compose.Compose{SkipNotVersioned: true})
+package service
+
+import (
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-model/actions/bundle"
+	"github.com/plasmash/plasmactl-model/actions/compose"
+	"github.com/plasmash/plasmactl-model/actions/prepare"
+	"github.com/plasmash/plasmactl-model/actions/query"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// ModelService runs model operations of the platform in the working directory of the app.
+//
+// Operations take the action structs of the model:* actions, their fields are the options of the action.
+// Empty directories, the keyring and the layout are filled like the actions get them from the plugin.
+// Loggers and terminals set on the structs are used, the launchr ones otherwise.
+type ModelService interface {
+	launchr.Service
+	// Compose fetches packages and merges them like model:compose.
+	Compose(c *compose.Compose) (*compose.ComposeResult, error)
+	// Prepare transforms the merged model for Ansible like model:prepare.
+	Prepare(p *prepare.Prepare) (*prepare.PrepareResult, error)
+	// Bundle creates the bundle of the prepared or merged model like model:bundle.
+	Bundle(b *bundle.Bundle) (*bundle.BundleResult, error)
+	// Query finds packages providing a component, zone or node like model:query.
+	Query(q *query.Query) (*query.QueryResult, error)
+}
+
+type modelService struct {
+	wd     string
+	k      keyring.Keyring
+	layout model.Layout
+}
+
+// New returns ModelService of the platform in wd, layout is the model.layout configuration.
+func New(wd string, k keyring.Keyring, layout model.Layout) ModelService {
+	return &modelService{wd: wd, k: k, layout: layout.WithDefaults()}
+}
+
+// ServiceInfo implements [launchr.Service] interface.
+func (s *modelService) ServiceInfo() launchr.ServiceInfo {
+	return launchr.ServiceInfo{}
+}
+
+// Compose implements [ModelService] interface.
+func (s *modelService) Compose(c *compose.Compose) (*compose.ComposeResult, error) {
+	if c.Keyring == nil {
+		c.Keyring = s.k
+	}
+	if c.BaseDir == "" {
+		c.BaseDir = s.wd
+	}
+	if c.WorkingDir == "" {
+		c.WorkingDir = s.layout.PackagesDir
+	}
+
+	err := c.Execute()
+	res, _ := c.Result().(*compose.ComposeResult)
+	return res, err
+}
+
+// Prepare implements [ModelService] interface.
+func (s *modelService) Prepare(p *prepare.Prepare) (*prepare.PrepareResult, error) {
+	if p.ComposeDir == "" {
+		p.ComposeDir = s.layout.MergedDir
+	}
+	if p.PrepareDir == "" {
+		p.PrepareDir = s.layout.PrepareDir
+	}
+
+	err := p.Execute()
+	res, _ := p.Result().(*prepare.PrepareResult)
+	return res, err
+}
+
+// Bundle implements [ModelService] interface. The prepare step is available, like for model:bundle.
+func (s *modelService) Bundle(b *bundle.Bundle) (*bundle.BundleResult, error) {
+	if b.Layout == (model.Layout{}) {
+		b.Layout = s.layout
+	}
+	b.HasPrepareAction = true
+
+	err := b.Execute()
+	res, _ := b.Result().(*bundle.BundleResult)
+	return res, err
+}
+
+// Query implements [ModelService] interface.
+func (s *modelService) Query(q *query.Query) (*query.QueryResult, error) {
+	if q.WorkingDir == "" {
+		q.WorkingDir = s.wd
+	}
+	if q.Layout == (model.Layout{}) {
+		q.Layout = s.layout
+	}
+
+	err := q.Execute()
+	res, ok := q.Result().(query.QueryResult)
+	if !ok {
+		return nil, err
+	}
+	return &res, err
+}
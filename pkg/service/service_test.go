@@ -0,0 +1,156 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/plasmash/plasmactl-model/actions/bundle"
+	"github.com/plasmash/plasmactl-model/actions/compose"
+	"github.com/plasmash/plasmactl-model/actions/prepare"
+	"github.com/plasmash/plasmactl-model/actions/query"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+)
+
+// platformFixture creates a domain repository without packages, tagged v1.0.0, and changes into it.
+func platformFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for path, content := range map[string]string{
+		model.ComposeFile: "name: platform\n",
+		"src/platform/services/nginx/tasks/main.yaml":    "- name: nginx\n",
+		"src/platform/services/nginx/defaults/main.yaml": "port: 80\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"https://example.com/platform.git"}}); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := w.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.CreateTag("v1.0.0", commit, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func newTestService(dir string) ModelService {
+	return New(dir, nil, model.Layout{BundleDir: "dist"})
+}
+
+func TestServiceCompose(t *testing.T) {
+	dir := platformFixture(t)
+	s := newTestService(dir)
+
+	restore := output.SetPlain(false)
+	defer restore()
+
+	c := &compose.Compose{NoKeyring: true, Plain: true}
+	res, err := s.Compose(c)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	if res == nil || res.Status != "completed" || res.Summary == nil {
+		t.Fatalf("unexpected compose result %+v", res)
+	}
+	if c.BaseDir != dir || c.WorkingDir != model.PackagesDir {
+		t.Errorf("expected directories of the service, got %s and %s", c.BaseDir, c.WorkingDir)
+	}
+	if _, err = os.Stat(filepath.Join(dir, model.MergedSrcDir, "platform/services/nginx/tasks/main.yaml")); err != nil {
+		t.Errorf("expected merged component: %v", err)
+	}
+	if output.IsPlain() {
+		t.Error("expected plain output mode of the caller to be restored")
+	}
+}
+
+func TestServicePrepareBundle(t *testing.T) {
+	dir := platformFixture(t)
+	s := newTestService(dir)
+
+	if _, err := s.Compose(&compose.Compose{NoKeyring: true}); err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	p := &prepare.Prepare{}
+	prepared, err := s.Prepare(p)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if prepared == nil || p.ComposeDir != model.MergedDir || p.PrepareDir != model.PrepareDir {
+		t.Fatalf("unexpected prepare result %+v of %s and %s", prepared, p.ComposeDir, p.PrepareDir)
+	}
+	if _, err = os.Stat(filepath.Join(dir, model.PrepareDir, "ansible.cfg")); err != nil {
+		t.Errorf("expected prepared model: %v", err)
+	}
+
+	b := &bundle.Bundle{}
+	bundled, err := s.Bundle(b)
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	if bundled == nil || bundled.Version != "v1.0.0" {
+		t.Fatalf("unexpected bundle result %+v", bundled)
+	}
+	if filepath.Dir(bundled.BundlePath) != "dist" {
+		t.Errorf("expected bundle in the bundle directory of the layout, got %s", bundled.BundlePath)
+	}
+	if _, err = os.Stat(bundled.BundlePath); err != nil {
+		t.Errorf("expected bundle: %v", err)
+	}
+}
+
+func TestServiceQuery(t *testing.T) {
+	dir := platformFixture(t)
+	s := newTestService(dir)
+	if _, err := s.Compose(&compose.Compose{NoKeyring: true}); err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	q := &query.Query{Identifier: "platform.services.nginx"}
+	res, err := s.Query(q)
+	if q.WorkingDir != dir || q.Layout.PackagesDir != model.PackagesDir {
+		t.Errorf("expected directories of the service, got %s and %+v", q.WorkingDir, q.Layout)
+	}
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if res == nil {
+		t.Fatal("expected query result")
+	}
+
+	if err = os.Remove(filepath.Join(dir, model.ComposeFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Query(&query.Query{Identifier: "platform.services.nginx"}); err == nil {
+		t.Error("expected query of a directory without compose.yaml to fail")
+	}
+}
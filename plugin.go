@@ -4,6 +4,7 @@ package plasmactlmodel
 import (
 	"context"
 	"embed"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -14,14 +15,25 @@ import (
 	"github.com/plasmash/plasmactl-model/actions/add"
 	"github.com/plasmash/plasmactl-model/actions/bundle"
 	"github.com/plasmash/plasmactl-model/actions/compose"
+	"github.com/plasmash/plasmactl-model/actions/explain"
+	"github.com/plasmash/plasmactl-model/actions/export"
+	"github.com/plasmash/plasmactl-model/actions/freeze"
+	"github.com/plasmash/plasmactl-model/actions/graph"
 	"github.com/plasmash/plasmactl-model/actions/list"
+	"github.com/plasmash/plasmactl-model/actions/migrate"
+	"github.com/plasmash/plasmactl-model/actions/outdated"
 	"github.com/plasmash/plasmactl-model/actions/prepare"
+	"github.com/plasmash/plasmactl-model/actions/prune"
 	"github.com/plasmash/plasmactl-model/actions/query"
 	"github.com/plasmash/plasmactl-model/actions/release"
 	"github.com/plasmash/plasmactl-model/actions/remove"
 	"github.com/plasmash/plasmactl-model/actions/show"
 	"github.com/plasmash/plasmactl-model/actions/update"
-	icompose "github.com/plasmash/plasmactl-model/internal/compose"
+	"github.com/plasmash/plasmactl-model/actions/verify"
+	"github.com/plasmash/plasmactl-model/internal/listing"
+	"github.com/plasmash/plasmactl-model/internal/output"
+	"github.com/plasmash/plasmactl-model/pkg/model"
+	"github.com/plasmash/plasmactl-model/pkg/service"
 )
 
 //go:embed actions/*/*.yaml
@@ -33,9 +45,10 @@ func init() {
 
 // Plugin is [launchr.Plugin] plugin providing model composition.
 type Plugin struct {
-	wd string
-	k  keyring.Keyring
-	m  action.Manager
+	wd     string
+	k      keyring.Keyring
+	m      action.Manager
+	layout model.Layout
 }
 
 // PluginInfo implements [launchr.Plugin] interface.
@@ -49,11 +62,25 @@ func (p *Plugin) OnAppInit(app launchr.App) error {
 	app.GetService(&p.m)
 	p.wd = app.GetWD()
 
+	// Output directories of prepare and bundle may be configured, see model.LayoutConfigKey.
+	var cfg launchr.Config
+	app.GetService(&cfg)
+	var modelCfg struct {
+		Layout model.Layout `yaml:"layout"`
+	}
+	if err := cfg.Get(model.LayoutConfigKey, &modelCfg); err != nil {
+		return fmt.Errorf("invalid %s configuration: %w", model.LayoutConfigKey, err)
+	}
+	p.layout = modelCfg.Layout.WithDefaults()
+
+	// Other plugins run model operations in-process with service.ModelService.
+	app.AddService(service.New(p.wd, p.k, p.layout))
+
 	// Register composed packages directory as a discovery root if it exists.
 	// This is needed because launchr skips hidden directories (starting with .)
 	// during discovery, so .plasma/ would be skipped otherwise.
 	// This replaces the old launchr-compose plugin's registration of .compose/build.
-	composePath := filepath.Join(p.wd, icompose.BuildDir)
+	composePath := filepath.Join(p.wd, p.layout.MergedDir)
 	if stat, err := os.Stat(composePath); err == nil && stat.IsDir() {
 		app.RegisterFS(action.NewDiscoveryFS(os.DirFS(composePath), p.wd))
 	}
@@ -72,11 +99,30 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		c := &compose.Compose{
 			Keyring:            p.k,
 			BaseDir:            p.wd,
-			WorkingDir:         input.Opt("working-dir").(string),
+			WorkingDir:         optOr(input.Opt("working-dir").(string), p.layout.PackagesDir),
 			Clean:              input.Opt("clean").(bool),
 			SkipNotVersioned:   input.Opt("skip-not-versioned").(bool),
 			ConflictsVerbosity: input.Opt("conflicts-verbosity").(bool),
 			Interactive:        input.Opt("interactive").(bool),
+			Strict:             input.Opt("strict").(bool),
+			Wait:               input.Opt("wait").(bool),
+			Overlays:           action.InputOptSlice[string](input, "overlay"),
+			Permissions:        input.Opt("permissions").(string),
+			ArchiveLinks:       input.Opt("archive-links").(string),
+			Symlinks:           input.Opt("symlinks").(string),
+			Groups:             action.InputOptSlice[string](input, "group"),
+			ExcludeGroups:      action.InputOptSlice[string](input, "exclude-group"),
+			IgnoreNested:       input.Opt("ignore-nested-strategies").(bool),
+			Integrity:          input.Opt("integrity").(string),
+			Locked:             input.Opt("locked").(bool),
+			FrozenLockfile:     input.Opt("frozen-lockfile").(bool),
+			NoKeyring:          input.Opt("no-keyring").(bool),
+			DebugGit:           input.Opt("debug-git").(bool),
+			RejectLegacy:       input.Opt("reject-legacy-layout").(bool),
+			RespectGitignore:   input.Opt("respect-gitignore").(bool),
+			IncludeStaged:      input.Opt("include-staged").(bool),
+			FullMerge:          input.Opt("full-merge").(bool),
+			Plain:              input.Opt("plain").(bool),
 		}
 		c.SetLogger(log)
 		c.SetTerm(term)
@@ -91,12 +137,15 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		input := a.Input()
 		log, term := getLogger(a)
 		ad := &add.Add{
+			Keyring:      p.k,
 			WorkingDir:   p.wd,
 			AllowCreate:  input.Opt("allow-create").(bool),
 			Package:      input.Opt("package").(string),
 			Type:         input.Opt("type").(string),
 			Ref:          input.Opt("ref").(string),
 			URL:          input.Opt("url").(string),
+			Subpath:      input.Opt("subpath").(string),
+			Asset:        input.Opt("asset").(string),
 			Strategy:     action.InputOptSlice[string](input, "strategy"),
 			StrategyPath: action.InputOptSlice[string](input, "strategy-path"),
 		}
@@ -113,6 +162,7 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		input := a.Input()
 		log, term := getLogger(a)
 		u := &update.Update{
+			Keyring:      p.k,
 			WorkingDir:   p.wd,
 			Package:      input.Opt("package").(string),
 			Type:         input.Opt("type").(string),
@@ -120,6 +170,7 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			URL:          input.Opt("url").(string),
 			Strategy:     action.InputOptSlice[string](input, "strategy"),
 			StrategyPath: action.InputOptSlice[string](input, "strategy-path"),
+			Yes:          input.Opt("yes").(bool),
 		}
 		u.SetLogger(log)
 		u.SetTerm(term)
@@ -136,6 +187,7 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		rm := &remove.Remove{
 			WorkingDir: p.wd,
 			Packages:   action.InputOptSlice[string](input, "packages"),
+			Yes:        input.Opt("yes").(bool),
 		}
 		rm.SetLogger(log)
 		rm.SetTerm(term)
@@ -143,6 +195,119 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		return rm.Result(), err
 	}))
 
+	// Actions model:freeze and model:unfreeze - toggle frozen flag of a package.
+	freezeRuntime := func(frozen bool) action.Runtime {
+		return action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+			input := a.Input()
+			log, term := getLogger(a)
+			fr := &freeze.Freeze{
+				WorkingDir: p.wd,
+				Package:    input.Arg("package").(string),
+				Frozen:     frozen,
+			}
+			fr.SetLogger(log)
+			fr.SetTerm(term)
+			err := fr.Execute()
+			return fr.Result(), err
+		})
+	}
+	freezeYaml, _ := actionYamlFS.ReadFile("actions/freeze/freeze.yaml")
+	freezeAction := action.NewFromYAML("model:freeze", freezeYaml)
+	freezeAction.SetRuntime(freezeRuntime(true))
+	unfreezeYaml, _ := actionYamlFS.ReadFile("actions/freeze/unfreeze.yaml")
+	unfreezeAction := action.NewFromYAML("model:unfreeze", unfreezeYaml)
+	unfreezeAction.SetRuntime(freezeRuntime(false))
+
+	// Action model:prune - removes packages no longer referenced by compose.yaml.
+	pruneYaml, _ := actionYamlFS.ReadFile("actions/prune/prune.yaml")
+	pruneAction := action.NewFromYAML("model:prune", pruneYaml)
+	pruneAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		pr := &prune.Prune{
+			BaseDir:    p.wd,
+			WorkingDir: optOr(input.Opt("working-dir").(string), p.layout.PackagesDir),
+			DryRun:     input.Opt("dry-run").(bool),
+			Wait:       input.Opt("wait").(bool),
+		}
+		pr.SetLogger(log)
+		pr.SetTerm(term)
+		err := pr.Execute()
+		return pr.Result(), err
+	}))
+
+	// Action model:explain - traces merge decisions of a merged path.
+	explainYaml, _ := actionYamlFS.ReadFile("actions/explain/explain.yaml")
+	explainAction := action.NewFromYAML("model:explain", explainYaml)
+	explainAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		ex := &explain.Explain{
+			BaseDir:      p.wd,
+			WorkingDir:   optOr(input.Opt("working-dir").(string), p.layout.PackagesDir),
+			Path:         input.Arg("path").(string),
+			IgnoreNested: input.Opt("ignore-nested-strategies").(bool),
+		}
+		ex.SetLogger(log)
+		ex.SetTerm(term)
+		err := ex.Execute()
+		return ex.Result(), err
+	}))
+
+	// Action model:graph - outputs the dependency graph of packages.
+	graphYaml, _ := actionYamlFS.ReadFile("actions/graph/graph.yaml")
+	graphAction := action.NewFromYAML("model:graph", graphYaml)
+	graphAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		g := &graph.Graph{
+			BaseDir:    p.wd,
+			WorkingDir: optOr(input.Opt("working-dir").(string), p.layout.PackagesDir),
+			Format:     input.Opt("format").(string),
+		}
+		g.SetLogger(log)
+		g.SetTerm(term)
+		err := g.Execute()
+		return g.Result(), err
+	}))
+
+	// Action model:export - writes the composition with locked refs or its flattened snapshot.
+	exportYaml, _ := actionYamlFS.ReadFile("actions/export/export.yaml")
+	exportAction := action.NewFromYAML("model:export", exportYaml)
+	exportAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		ex := &export.Export{
+			BaseDir:    p.wd,
+			WorkingDir: optOr(input.Opt("working-dir").(string), p.layout.PackagesDir),
+			Output:     input.Opt("output").(string),
+			Flatten:    input.Opt("flatten").(bool),
+			Force:      input.Opt("force").(bool),
+		}
+		ex.SetLogger(log)
+		ex.SetTerm(term)
+		err := ex.Execute()
+		return ex.Result(), err
+	}))
+
+	// Action model:migrate - converts legacy plasma-compose.yaml to compose.yaml.
+	migrateYaml, _ := actionYamlFS.ReadFile("actions/migrate/migrate.yaml")
+	migrateAction := action.NewFromYAML("model:migrate", migrateYaml)
+	migrateAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		mg := &migrate.Migrate{
+			WorkingDir: p.wd,
+			Layout:     p.layout,
+			DryRun:     input.Opt("dry-run").(bool),
+			Force:      input.Opt("force").(bool),
+		}
+		mg.SetLogger(log)
+		mg.SetTerm(term)
+		err := mg.Execute()
+		return mg.Result(), err
+	}))
+
 	// Action model:prepare - transforms composed model for Ansible deployment.
 	prepareYaml, _ := actionYamlFS.ReadFile("actions/prepare/prepare.yaml")
 	prepareActionDef := action.NewFromYAML("model:prepare", prepareYaml)
@@ -150,8 +315,8 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		input := a.Input()
 		log, term := getLogger(a)
 		pr := &prepare.Prepare{
-			ComposeDir: input.Opt("compose-dir").(string),
-			PrepareDir: input.Opt("prepare-dir").(string),
+			ComposeDir: optOr(input.Opt("compose-dir").(string), p.layout.MergedDir),
+			PrepareDir: optOr(input.Opt("prepare-dir").(string), p.layout.PrepareDir),
 			Clean:      input.Opt("clean").(bool),
 		}
 		pr.SetLogger(log)
@@ -164,9 +329,16 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 	bundleYaml, _ := actionYamlFS.ReadFile("actions/bundle/bundle.yaml")
 	bundleAction := action.NewFromYAML("model:bundle", bundleYaml)
 	bundleAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
 		log, term := getLogger(a)
 		b := &bundle.Bundle{
+			Layout:           p.layout,
 			HasPrepareAction: true,
+			SourceDir:        input.Opt("source-dir").(string),
+			Sign:             input.Opt("sign").(string),
+			Key:              input.Opt("key").(string),
+			Attest:           input.Opt("attest").(bool),
+			Reproducible:     input.Opt("reproducible").(bool),
 		}
 		b.SetLogger(log)
 		b.SetTerm(term)
@@ -174,6 +346,56 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		return b.Result(), err
 	}))
 
+	// Action model:bundle:list - lists contents of a bundle.
+	bundleListYaml, _ := actionYamlFS.ReadFile("actions/bundle/list.yaml")
+	bundleListAction := action.NewFromYAML("model:bundle:list", bundleListYaml)
+	bundleListAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		log, term := getLogger(a)
+		bl := &bundle.List{
+			File: a.Input().Arg("file").(string),
+		}
+		bl.SetLogger(log)
+		bl.SetTerm(term)
+		err := bl.Execute()
+		return bl.Result(), err
+	}))
+
+	// Action model:unbundle - extracts a bundle.
+	unbundleYaml, _ := actionYamlFS.ReadFile("actions/bundle/unbundle.yaml")
+	unbundleAction := action.NewFromYAML("model:unbundle", unbundleYaml)
+	unbundleAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		u := &bundle.Unbundle{
+			File:      input.Arg("file").(string),
+			TargetDir: input.Opt("target-dir").(string),
+			List:      input.Opt("list").(bool),
+			Force:     input.Opt("force").(bool),
+		}
+		u.SetLogger(log)
+		u.SetTerm(term)
+		err := u.Execute()
+		return u.Result(), err
+	}))
+
+	// Action model:verify - verifies the signature of a bundle.
+	verifyYaml, _ := actionYamlFS.ReadFile("actions/verify/verify.yaml")
+	verifyAction := action.NewFromYAML("model:verify", verifyYaml)
+	verifyAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		v := &verify.Verify{
+			File:      input.Arg("file").(string),
+			Key:       input.Opt("key").(string),
+			Signature: input.Opt("signature").(string),
+			Method:    input.Opt("method").(string),
+		}
+		v.SetLogger(log)
+		v.SetTerm(term)
+		err := v.Execute()
+		return v.Result(), err
+	}))
+
 	// Action model:release - creates git tags with changelog and uploads artifact to forge.
 	releaseYaml, _ := actionYamlFS.ReadFile("actions/release/release.yaml")
 	releaseAction := action.NewFromYAML("model:release", releaseYaml)
@@ -181,12 +403,27 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		input := a.Input()
 		log, term := getLogger(a)
 		rel := &release.Release{
-			Keyring:  p.k,
-			Version:  input.Arg("version").(string),
-			DryRun:   input.Opt("dry-run").(bool),
-			TagOnly:  input.Opt("tag-only").(bool),
-			ForgeURL: input.Opt("forge-url").(string),
-			Token:    input.Opt("token").(string),
+			Keyring:          p.k,
+			Version:          input.Arg("version").(string),
+			DryRun:           input.Opt("dry-run").(bool),
+			CheckForge:       input.Opt("check-forge").(bool),
+			TagOnly:          input.Opt("tag-only").(bool),
+			Forge:            input.Opt("forge").(string),
+			ForgeURL:         input.Opt("forge-url").(string),
+			Token:            input.Opt("token").(string),
+			RollbackTag:      input.Opt("rollback-tag").(bool),
+			Local:            input.Opt("local").(bool),
+			Since:            input.Opt("since").(string),
+			Notes:            input.Opt("notes").(string),
+			NotesFile:        input.Opt("notes-file").(string),
+			DigestNames:      input.Opt("digest-names").(bool),
+			AllowStaleBundle: input.Opt("allow-stale-bundle").(bool),
+			BuildBundle:      input.Opt("build-bundle").(bool),
+			GitLabAssets:     input.Opt("gitlab-assets").(string),
+			VerifySignature:  input.Opt("verify-signature").(bool),
+			VerifyKey:        input.Opt("verify-key").(string),
+			VerifyMethod:     input.Opt("verify-method").(string),
+			Layout:           p.layout,
 		}
 		rel.SetLogger(log)
 		rel.SetTerm(term)
@@ -202,7 +439,12 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		log, term := getLogger(a)
 		l := &list.List{
 			WorkingDir: p.wd,
+			Layout:     p.layout,
 			Tree:       input.Opt("tree").(bool),
+			Plain:      input.Opt("plain").(bool),
+			Stats:      input.Opt("stats").(bool),
+			Listing:    listingOptions(input),
+			Output:     outputMode(input),
 		}
 		l.SetLogger(log)
 		l.SetTerm(term)
@@ -222,10 +464,15 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		}
 		s := &show.Show{
 			WorkingDir: p.wd,
+			Layout:     p.layout,
 			Package:    pkg,
 			Packages:   input.Opt("packages").(bool),
 			Src:        input.Opt("src").(bool),
 			Composed:   input.Opt("composed").(bool),
+			Conflicts:  input.Opt("conflicts").(bool),
+			Bundle:     input.Opt("bundle").(string),
+			Listing:    listingOptions(input),
+			Output:     outputMode(input),
 		}
 		s.SetLogger(log)
 		s.SetTerm(term)
@@ -241,8 +488,10 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		log, term := getLogger(a)
 		q := &query.Query{
 			WorkingDir: p.wd,
+			Layout:     p.layout,
 			Identifier: input.Arg("identifier").(string),
 			Kind:       input.Opt("kind").(string),
+			Output:     outputMode(input),
 		}
 		q.SetLogger(log)
 		q.SetTerm(term)
@@ -250,17 +499,45 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		return q.Result(), err
 	}))
 
+	outdatedYaml, _ := actionYamlFS.ReadFile("actions/outdated/outdated.yaml")
+	outdatedAction := action.NewFromYAML("model:outdated", outdatedYaml)
+	outdatedAction.SetRuntime(action.NewFnRuntimeWithResult(func(_ context.Context, a *action.Action) (any, error) {
+		input := a.Input()
+		log, term := getLogger(a)
+		o := &outdated.Outdated{
+			Keyring:    p.k,
+			WorkingDir: p.wd,
+			Layout:     p.layout,
+			Output:     outputMode(input),
+		}
+		o.SetLogger(log)
+		o.SetTerm(term)
+		err := o.Execute()
+		return o.Result(), err
+	}))
+
 	return []*action.Action{
 		composeAction,
 		addAction,
 		updateAction,
 		removeAction,
+		freezeAction,
+		unfreezeAction,
+		pruneAction,
+		migrateAction,
+		explainAction,
+		graphAction,
+		exportAction,
 		prepareActionDef,
 		bundleAction,
+		bundleListAction,
+		unbundleAction,
+		verifyAction,
 		releaseAction,
 		listAction,
 		showAction,
 		queryAction,
+		outdatedAction,
 	}, nil
 }
 
@@ -277,3 +554,27 @@ func getLogger(a *action.Action) (*launchr.Logger, *launchr.Terminal) {
 
 	return log, term
 }
+
+func outputMode(input *action.Input) output.Mode {
+	return output.Mode{
+		Quiet:     input.Opt("quiet").(bool),
+		Porcelain: input.Opt("porcelain").(bool),
+	}
+}
+
+func listingOptions(input *action.Input) listing.Options {
+	return listing.Options{
+		Sort:   input.Opt("sort").(string),
+		Limit:  input.Opt("limit").(int),
+		Offset: input.Opt("offset").(int),
+	}
+}
+
+// optOr returns the value of a path option, the layout path def if it's empty.
+func optOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}